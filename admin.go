@@ -0,0 +1,207 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Admin implements the key management operations on top of a Store. It is
+// shared by the grpc and http management surfaces.
+type Admin struct {
+	options
+	store Store
+}
+
+func NewAdmin(store Store, opts ...Option) *Admin {
+	o := newOptions(opts)
+	return &Admin{options: o, store: o.wrapStore(store)}
+}
+
+// Create generates a new key and adds its record to the store. The returned
+// string is the encoded api key. It is the only time the secret is available
+// and must be delivered to the key holder. A named key fails with ErrExists
+// if the name is already used in its tenant. The check is not atomic with
+// the create; a store that must guarantee unique names under concurrent
+// creates needs its own unique index on tenant id and name.
+func (a *Admin) Create(ctx context.Context, alg string, opts ...KeyOption) (string, Key, error) {
+	requested := alg
+	if alg == "" {
+		alg = StandardAlg
+	}
+	ak, err := NewKey(alg, a.keyOptions(opts)...)
+	if err != nil {
+		return "", Key{}, err
+	}
+	if err := a.applyTenantPolicy(ctx, &ak, requested); err != nil {
+		return "", Key{}, err
+	}
+	if err := a.checkNameFree(ctx, ak); err != nil {
+		return "", Key{}, err
+	}
+	if err := a.applyKeyTypePolicy(&ak, a.now()); err != nil {
+		return "", Key{}, err
+	}
+	if ak.CreatedBy == "" {
+		ak.CreatedBy = PrincipalFromContext(ctx)
+	}
+	if err := a.applyApprovalPolicy(ctx, &ak); err != nil {
+		return "", Key{}, err
+	}
+	apikey, err := a.generate(ctx, &ak)
+	if err != nil {
+		return "", Key{}, err
+	}
+	ak.CreatedAt = a.now()
+	if err := a.store.Create(ctx, ak); err != nil {
+		return "", Key{}, err
+	}
+	a.emit(ctx, AuditKeyCreated, ak, nil)
+	if a.hooks.OnCreate != nil {
+		a.hooks.OnCreate(ctx, ak)
+	}
+	if ak.Pending() && a.hooks.OnApprovalRequested != nil {
+		a.hooks.OnApprovalRequested(ctx, ak)
+	}
+	return apikey, ak, nil
+}
+
+func (a *Admin) Get(ctx context.Context, clientID string) (Key, error) {
+	return a.store.Get(ctx, clientID)
+}
+
+// List returns the records selected by every filter, all of them if there
+// are none, ordered by client id
+func (a *Admin) List(ctx context.Context, filters ...KeyFilter) ([]Key, error) {
+	keys, err := a.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return FilterKeys(keys, filters...), nil
+}
+
+// Revoke marks the key for clientID as revoked. Revoking an already revoked
+// key is not an error and does not change the time it was revoked.
+func (a *Admin) Revoke(ctx context.Context, clientID string) (Key, error) {
+	ak, err := a.store.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	if ak.Revoked() {
+		return ak, nil
+	}
+	ak.RevokedAt = a.now()
+	if err := a.store.Update(ctx, ak); err != nil {
+		return Key{}, err
+	}
+	a.emit(ctx, AuditKeyRevoked, ak, nil)
+	if a.hooks.OnRevoke != nil {
+		a.hooks.OnRevoke(ctx, ak)
+	}
+	return ak, nil
+}
+
+// Rotate generates a new secret for an existing client id. The previous
+// secret stops verifying as soon as the record is updated, see
+// RotateGracefully to keep it for a while. If alg is empty the alg of the
+// stored record is used, falling back to StandardAlg if the store does not
+// retain it. A tenant alg set with WithTenantPolicy takes precedence over
+// both.
+func (a *Admin) Rotate(ctx context.Context, clientID, alg string) (string, Key, error) {
+	return a.rotate(ctx, clientID, alg, 0)
+}
+
+// rotate keeps the previous derived key verifying for grace, if positive
+func (a *Admin) rotate(ctx context.Context, clientID, alg string, grace time.Duration) (string, Key, error) {
+	ak, err := a.store.Get(ctx, clientID)
+	if err != nil {
+		return "", Key{}, err
+	}
+	if ak.Revoked() {
+		return "", Key{}, fmt.Errorf("can't rotate `%s': %w", clientID, ErrRevoked)
+	}
+	requested := alg
+	if alg == "" {
+		alg = ak.alg.String
+	}
+	if alg == "" {
+		alg = StandardAlg
+	}
+	if err := ak.SetOptions(alg, a.keyOptions(nil)...); err != nil {
+		return "", Key{}, err
+	}
+	if err := a.applyTenantPolicy(ctx, &ak, requested); err != nil {
+		return "", Key{}, err
+	}
+	previous := ak.DerivedKey
+	apikey, err := a.generate(ctx, &ak)
+	if err != nil {
+		return "", Key{}, err
+	}
+	ak.RotatedAt = a.now()
+	clear(ak.PreviousDerivedKey)
+	ak.PreviousDerivedKey, ak.PreviousExpiresAt = nil, time.Time{}
+	if grace > 0 && len(previous) > 0 {
+		ak.PreviousDerivedKey, ak.PreviousExpiresAt = previous, ak.RotatedAt.Add(grace)
+	}
+	if err := a.store.Update(ctx, ak); err != nil {
+		return "", Key{}, err
+	}
+	a.emit(ctx, AuditKeyRotated, ak, nil)
+	return apikey, ak, nil
+}
+
+// keyOptions puts the Admin's separators, if any, ahead of opts so that keys
+// are checked against the separators they are encoded with
+func (a *Admin) keyOptions(opts []KeyOption) []KeyOption {
+	if a.seps == (Separators{}) {
+		return opts
+	}
+	return append([]KeyOption{WithKeySeparators(a.seps)}, opts...)
+}
+
+// generate calls ak.Generate in a span recording the derivation time
+func (a *Admin) generate(ctx context.Context, ak *Key) (string, error) {
+	if ak.seps == (Separators{}) && a.seps != (Separators{}) {
+		ak.seps = a.seps
+		defer func() { ak.seps = Separators{} }()
+		if err := ak.checkSeparators(); err != nil {
+			return "", err
+		}
+	}
+	release, err := a.acquire(ctx, ak.alg)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	_, span := a.startSpan(ctx, SpanGenerate)
+	span.SetAttribute(AttrClientID, ak.ClientID)
+	span.SetAttribute(AttrAlg, ak.alg.String)
+	if ak.rand == nil && a.entropy != nil {
+		ak.rand = a.entropy
+		defer func() { ak.rand = nil }()
+	}
+	start := time.Now()
+	apikey, err := ak.Generate()
+	elapsed := time.Since(start)
+	a.observeDerive(ak.alg, elapsed)
+	if a.metrics != nil {
+		a.metrics.ObserveGenerate(ak.alg.String, err)
+	}
+	span.SetAttribute(AttrDeriveDuration, durationMS(elapsed))
+	span.End(err)
+	if errors.Is(err, ErrEntropy) && a.logger != nil {
+		a.logger.ErrorContext(ctx, "entropy source failed, no api keys can be generated",
+			slog.String("client_id", ak.ClientID), slog.Any("error", err))
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := a.checkLeak(ctx, *ak, apikey); err != nil {
+		return "", err
+	}
+	return apikey, nil
+}
@@ -0,0 +1,126 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const testAlg = "argon2id 1 16MB 16"
+
+func TestAdminLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	verifier := NewStoreVerifier(store)
+
+	apikey, ak, err := admin.Create(ctx, testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if ak.ClientID != "client-1" || ak.CreatedAt.IsZero() {
+		t.Errorf("Create() = %v, want client-1 with a creation time", ak)
+	}
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("client-1")); !errors.Is(err, ErrExists) {
+		t.Errorf("Create() duplicate error = %v, want %v", err, ErrExists)
+	}
+
+	got, err := verifier.Verify(ctx, apikey)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got.ClientID != "client-1" {
+		t.Errorf("Verify() ClientID = %s, want client-1", got.ClientID)
+	}
+
+	rotated, _, err := admin.Rotate(ctx, "client-1", "")
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if _, err := verifier.Verify(ctx, apikey); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() old key error = %v, want %v", err, ErrMismatch)
+	}
+	if _, err := verifier.Verify(ctx, rotated); err != nil {
+		t.Errorf("Verify() rotated key error = %v", err)
+	}
+
+	revoked, err := admin.Revoke(ctx, "client-1")
+	if err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	again, err := admin.Revoke(ctx, "client-1")
+	if err != nil || !again.RevokedAt.Equal(revoked.RevokedAt) {
+		t.Errorf("Revoke() twice = %v, %v, want unchanged revocation time", again.RevokedAt, err)
+	}
+	if _, err := verifier.Verify(ctx, rotated); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Verify() revoked key error = %v, want %v", err, ErrRevoked)
+	}
+	// Without the secret the revocation isn't revealed
+	if _, err := verifier.Verify(ctx, apikey); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() revoked key with the old secret error = %v, want %v", err, ErrMismatch)
+	}
+	if _, _, err := admin.Rotate(ctx, "client-1", ""); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Rotate() revoked key error = %v, want %v", err, ErrRevoked)
+	}
+	if _, err := admin.Revoke(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revoke() missing error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+// jsonStore returns records as read back from their json, like the stores
+// which serialize them
+type jsonStore struct {
+	*MemStore
+}
+
+func (s jsonStore) Get(ctx context.Context, clientID string) (Key, error) {
+	ak, err := s.MemStore.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	b, err := json.Marshal(ak)
+	if err != nil {
+		return Key{}, err
+	}
+	var got Key
+	return got, json.Unmarshal(b, &got)
+}
+
+func TestRegenerateKeepsLengths(t *testing.T) {
+	ctx := t.Context()
+	admin := NewAdmin(jsonStore{NewMemStore()})
+	_, ak, err := admin.Create(ctx, testAlg, WithClientID("client-1"), WithSaltLength(48), WithSecretLength(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, _, err := admin.Rotate(ctx, ak.ClientID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transferred, _, err := admin.Transfer(ctx, ak.ClientID, ToClientID("client-2"), WithReissue(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type args struct {
+		apikey string
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"rotated", args{rotated}},
+		{"transferred", args{transferred}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presented, password, err := Decode(tt.args.apikey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(presented.Salt) != 48 || len(password) != 20 {
+				t.Errorf("salt and secret lengths = %d, %d, want 48, 20", len(presented.Salt), len(password))
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when admission control turns a verification
+// away. It wraps ErrOverloaded.
+var ErrRateLimited = fmt.Errorf("%w: verification rate exceeded", ErrOverloaded)
+
+// AdmissionConfig tunes an Admission
+type AdmissionConfig struct {
+	// Rate is the sustained number of verifications per second
+	Rate float64
+	// Burst is how many verifications may go ahead at once after a quiet
+	// period, Rate rounded up by default
+	Burst int
+	// MaxWait, if set, makes a verification beyond the rate wait up to this
+	// long for its turn rather than being rejected at once
+	MaxWait time.Duration
+	// Clock refills the bucket, the system clock if nil
+	Clock Clock
+}
+
+// Admission is a token bucket limiting the verification throughput of a
+// whole process, independently of any per key quota, so that a flood of
+// guessed keys is turned away before it reaches the store or the
+// derivation. It bounds the rate; to bound the derivations running at once
+// use a Deriver, which queues and rejects in the same way.
+type Admission struct {
+	cfg AdmissionConfig
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewAdmission(cfg AdmissionConfig) *Admission {
+	if cfg.Burst <= 0 {
+		cfg.Burst = max(int(math.Ceil(cfg.Rate)), 1)
+	}
+	return &Admission{cfg: cfg, tokens: float64(cfg.Burst)}
+}
+
+func (a *Admission) now() time.Time {
+	if a.cfg.Clock == nil {
+		return time.Now()
+	}
+	return a.cfg.Clock.Now()
+}
+
+// Admit takes a token, waiting up to MaxWait for one, and returns
+// ErrRateLimited if none is available in time
+func (a *Admission) Admit(ctx context.Context) error {
+	wait, err := a.reserve()
+	if err != nil || wait <= 0 {
+		return err
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		a.cancel()
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// reserve takes a token, which may be one yet to be refilled, and returns how
+// long until it is
+func (a *Admission) reserve() (time.Duration, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	if !a.last.IsZero() {
+		a.tokens = min(a.tokens+now.Sub(a.last).Seconds()*a.cfg.Rate, float64(a.cfg.Burst))
+	}
+	a.last = now
+	if a.tokens >= 1 {
+		a.tokens--
+		return 0, nil
+	}
+	if a.cfg.Rate <= 0 {
+		return 0, ErrRateLimited
+	}
+	wait := time.Duration((1 - a.tokens) / a.cfg.Rate * float64(time.Second))
+	if wait > a.cfg.MaxWait {
+		return 0, ErrRateLimited
+	}
+	a.tokens--
+	return wait, nil
+}
+
+// cancel returns a reserved token which was not used
+func (a *Admission) cancel() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens = min(a.tokens+1, float64(a.cfg.Burst))
+}
+
+// WithAdmission makes every verification of a StoreVerifier take a token from
+// a first. Rejected verifications fail with ErrRateLimited, which is
+// reported as overloaded.
+func WithAdmission(a *Admission) Option {
+	return func(o *options) {
+		o.admission = a
+	}
+}
+
+// admit applies the WithAdmission limit, if any
+func (o *options) admit(ctx context.Context) error {
+	if o.admission == nil {
+		return nil
+	}
+	return o.admission.Admit(ctx)
+}
@@ -0,0 +1,97 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdmission(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAdmission(AdmissionConfig{Rate: 2, Burst: 3, Clock: ClockFunc(func() time.Time { return now })})
+
+	type args struct {
+		advance time.Duration
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{"burst 1", args{0}, nil},
+		{"burst 2", args{0}, nil},
+		{"burst 3", args{0}, nil},
+		{"empty", args{0}, ErrRateLimited},
+		{"refilled one", args{500 * time.Millisecond}, nil},
+		{"empty again", args{100 * time.Millisecond}, ErrRateLimited},
+		{"refill capped at burst", args{time.Hour}, nil},
+		{"burst 2 after refill", args{0}, nil},
+		{"burst 3 after refill", args{0}, nil},
+		{"empty after refill", args{0}, ErrRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.args.advance)
+			if err := a.Admit(ctx); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Admit() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if !errors.Is(ErrRateLimited, ErrOverloaded) || VerifyResult(ErrRateLimited) != ResultOverloaded {
+		t.Errorf("ErrRateLimited is not reported as overloaded")
+	}
+}
+
+func TestAdmissionWait(t *testing.T) {
+	ctx := context.Background()
+	a := NewAdmission(AdmissionConfig{Rate: 100, Burst: 1, MaxWait: time.Second})
+	start := time.Now()
+	for range 3 {
+		if err := a.Admit(ctx); err != nil {
+			t.Fatalf("Admit() error = %v", err)
+		}
+	}
+	// The second and third waited about 10ms each
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("Admit() did not wait, took %s", elapsed)
+	}
+
+	short := NewAdmission(AdmissionConfig{Rate: 1, Burst: 1, MaxWait: 10 * time.Millisecond})
+	short.Admit(ctx)
+	if err := short.Admit(ctx); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Admit() beyond MaxWait error = %v, want ErrRateLimited", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	waiting := NewAdmission(AdmissionConfig{Rate: 1, Burst: 1, MaxWait: time.Hour})
+	waiting.Admit(ctx)
+	if err := waiting.Admit(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Admit() canceled error = %v, want context.Canceled", err)
+	}
+}
+
+func TestWithAdmission(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	apikey, ak, err := NewAdmin(store).Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counters := NewCounters()
+	v := NewStoreVerifier(store, WithMetrics(counters), WithAdmission(NewAdmission(AdmissionConfig{Rate: 0.001, Burst: 1})))
+	if _, err := v.Verify(ctx, apikey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(ctx, apikey); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Verify() error = %v, want ErrRateLimited", err)
+	}
+	if _, err := v.VerifySecret(ctx, ak.ClientID, []byte("anything")); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("VerifySecret() error = %v, want ErrRateLimited", err)
+	}
+	if s := counters.Stats(); s.Verifications != 3 || s.Failures != 2 || s.Derivations != 1 {
+		t.Errorf("Stats() = %+v, want 3 verifications, 2 failures and 1 derivation", s)
+	}
+}
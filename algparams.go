@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"golang.org/x/crypto/argon2"
 )
 
 const (
@@ -18,71 +20,298 @@ const (
 	maxTime       = 5
 	minTime       = 1
 	argon2idAlgID = "argon2id "
+
+	defaultThreads = 1
+	memKBSuffix    = "KB"
+
+	// named grammar
+	namedSep     = ","
+	namedAssign  = "="
+	namedTime    = "t"
+	namedMemory  = "m"
+	namedKeyLen  = "len"
+	namedThreads = "p"
+	namedVersion = "v"
+	namedLookup  = "lookup"
+	namedPrehash = "prehash"
+
+	prehashSHA512 = "sha512"
+
+	minLookupLen = 4
+	maxLookupLen = 16
 )
 
 type ParamsArgon2ID struct {
 	Time   uint32
 	Memory uint32
 	KeyLen uint32
+	// Threads is the argon2 parallelism, 1 unless set with the named grammar
+	Threads uint8
 }
 
 type Alg struct {
 	String string
 	ParamsArgon2ID
+	// LookupLen, if set, prefixes argon2id derived keys with a fast tag of
+	// that many bytes, see Key.LookupTag
+	LookupLen uint32
+	// Prehash, if "sha512", hashes the password with SHA-512 before argon2
+	// so secrets of any length cost the same to derive
+	Prehash string
+
+	// hasher is set for algs registered with RegisterAlg
+	hasher Hasher
 }
 
+// ParseAlg parses either of two grammars. The positional grammar
+//
+//	argon2id <time> <memory>MB <keylen>
+//
+// or the named grammar, whose parameters may appear in any order
+//
+//	argon2id t=<time>,m=<memory>MB,len=<keylen>[,p=<threads>][,v=19][,lookup=<n>][,prehash=sha512]
+//
+// The named grammar is self describing and can be extended without
+// ambiguity. In either, memory may be given in KB rather than MB, eg
+// 19456KB, and p may be any parallelism argon2 supports. lookup adds a fast
+// tag to derived keys, which lets a verifier reject a wrong secret before
+// running argon2. prehash=sha512 bounds the cost of long secrets, such as
+// those imported from other systems and verified with VerifySecret. The
+// string is kept exactly as given, so keys encode the grammar they were
+// created with.
+//
+// Keys which are already unguessable, eg for internal service to service
+// calls, can skip argon2's cost with keyed BLAKE2b or, for verifiers
+// handling tens of thousands of checks a second, HMAC-SHA256. Both need a
+// pepper registered with RegisterPepper. argon2id remains the alg for keys
+// distributed outside.
+//
+//	blake2b key=<pepper id>,len=<keylen>
+//	hmac-sha256 key=<pepper id>[,len=<keylen>]
 func ParseAlg(alg string) (Alg, error) {
+	switch {
+	case strings.HasPrefix(alg, blake2bAlgID):
+		return parseBLAKE2bAlg(alg)
+	case strings.HasPrefix(alg, hmacSHA256AlgID):
+		return parseHMACSHA256Alg(alg)
+	}
 	if !strings.HasPrefix(alg, argon2idAlgID) {
+		if parser, ok := lookupAlg(alg); ok {
+			return parseRegisteredAlg(alg, parser)
+		}
 		return Alg{}, fmt.Errorf("missing or unsupportred algorithm name `%s'", alg)
 	}
 
 	a := Alg{String: alg}
+	a.Threads = defaultThreads
 
 	alg = alg[len(argon2idAlgID):]
+	if strings.Contains(alg, namedAssign) {
+		return parseNamedAlg(a, alg)
+	}
 
-	parts := strings.SplitN(alg, space, algParts)
-	if len(parts) != 3 {
+	var parts [algParts]string
+	var ok1, ok2 bool
+	parts[0], parts[1], ok1 = strings.Cut(alg, space)
+	parts[1], parts[2], ok2 = strings.Cut(parts[1], space)
+	if !ok1 || !ok2 {
 		return Alg{}, fmt.Errorf("bad alg string `%s'", alg)
 	}
-	u, err := strconv.ParseUint(parts[0], 10, 32)
+	var err error
+	if a.Time, err = parseTime(parts[0]); err != nil {
+		return Alg{}, err
+	}
+	if a.Memory, err = parseMemory(parts[1]); err != nil {
+		return Alg{}, err
+	}
+	if a.KeyLen, err = parseKeyLen(parts[2]); err != nil {
+		return Alg{}, err
+	}
+	return a, nil
+}
+
+// Canonical returns the alg in a single form for comparison and
+// serialization, whichever grammar and order it was parsed from. argon2id
+// algs use the named grammar with t, m and len always given, in that order,
+// followed by p only if it isn't the default and then lookup and prehash if
+// set, eg "argon2id t=3,m=64MB,len=32". Keyed algs give their length even
+// when it is the default. Registered algs are returned as they were given.
+func (a Alg) Canonical() string {
+	for _, id := range keyedAlgIDs {
+		if params, ok := strings.CutPrefix(a.String, id); ok {
+			lens := keyedAlgLens[id]
+			p, err := parseKeyedParams(params, lens[0], lens[1])
+			if err != nil {
+				return a.String
+			}
+			return fmt.Sprintf("%s%s=%s%s%s=%d", id, namedKey, p.pepperID, namedSep, namedKeyLen, p.keyLen)
+		}
+	}
+	if a.hasher != nil || !strings.HasPrefix(a.String, argon2idAlgID) {
+		return a.String
+	}
+	memory := fmt.Sprintf("%d%s", a.Memory, memKBSuffix)
+	if a.Memory%memoryUnits == 0 {
+		memory = fmt.Sprintf("%d%s", a.Memory/memoryUnits, memSuffix)
+	}
+	params := []string{
+		namedTime + namedAssign + strconv.FormatUint(uint64(a.Time), 10),
+		namedMemory + namedAssign + memory,
+		namedKeyLen + namedAssign + strconv.FormatUint(uint64(a.KeyLen), 10),
+	}
+	if a.Threads != defaultThreads {
+		params = append(params, namedThreads+namedAssign+strconv.Itoa(int(a.Threads)))
+	}
+	if a.LookupLen > 0 {
+		params = append(params, namedLookup+namedAssign+strconv.FormatUint(uint64(a.LookupLen), 10))
+	}
+	if a.Prehash != "" {
+		params = append(params, namedPrehash+namedAssign+a.Prehash)
+	}
+	return argon2idAlgID + strings.Join(params, namedSep)
+}
+
+// Equal reports whether a and b are the same alg, however they were written
+func (a Alg) Equal(b Alg) bool {
+	return a.Canonical() == b.Canonical()
+}
+
+// MarshalText encodes the canonical form of the alg, so configs serialize it
+// consistently
+func (a Alg) MarshalText() ([]byte, error) {
+	return []byte(a.Canonical()), nil
+}
+
+// UnmarshalText parses an alg in either grammar, see ParseAlg
+func (a *Alg) UnmarshalText(text []byte) error {
+	parsed, err := ParseAlg(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// parseNamedAlg parses the comma separated name=value parameters of the named
+// grammar into a
+func parseNamedAlg(a Alg, params string) (Alg, error) {
+	seen := map[string]bool{}
+	for _, param := range strings.Split(params, namedSep) {
+		name, value, ok := strings.Cut(param, namedAssign)
+		if !ok || value == "" {
+			return Alg{}, fmt.Errorf("bad alg parameter `%s'", param)
+		}
+		if seen[name] {
+			return Alg{}, fmt.Errorf("duplicate alg parameter `%s'", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case namedTime:
+			a.Time, err = parseTime(value)
+		case namedMemory:
+			a.Memory, err = parseMemory(value)
+		case namedKeyLen:
+			a.KeyLen, err = parseKeyLen(value)
+		case namedThreads:
+			a.Threads, err = parseThreads(value)
+		case namedLookup:
+			a.LookupLen, err = parseLookupLen(value)
+		case namedPrehash:
+			if value != prehashSHA512 {
+				err = fmt.Errorf("unsupported prehash `%s'. want %s", value, prehashSHA512)
+			}
+			a.Prehash = value
+		case namedVersion:
+			if value != strconv.Itoa(argon2.Version) {
+				err = fmt.Errorf("unsupported argon2 version `%s'. want %d", value, argon2.Version)
+			}
+		default:
+			err = fmt.Errorf("unknown alg parameter `%s'", name)
+		}
+		if err != nil {
+			return Alg{}, err
+		}
+	}
+	for _, name := range []string{namedTime, namedMemory, namedKeyLen} {
+		if !seen[name] {
+			return Alg{}, fmt.Errorf("missing alg parameter `%s'", name)
+		}
+	}
+	return a, nil
+}
+
+func parseTime(s string) (uint32, error) {
+	u, err := strconv.ParseUint(s, 10, 32)
 	if err != nil {
-		return Alg{}, fmt.Errorf("bad times component `%s': %v", parts[0], err)
+		return 0, fmt.Errorf("bad times component `%s': %v", s, err)
 	}
-	a.Time = uint32(u)
-	if a.Time > maxTime {
-		return Alg{}, fmt.Errorf("time `%s' to large. max=%d", parts[0], maxTime)
+	if u > maxTime {
+		return 0, fmt.Errorf("time `%s' to large. max=%d", s, maxTime)
 	}
-	if a.Time < minTime {
-		return Alg{}, fmt.Errorf("time `%s' to small. min=%d", parts[0], minTime)
+	if u < minTime {
+		return 0, fmt.Errorf("time `%s' to small. min=%d", s, minTime)
 	}
+	return uint32(u), nil
+}
 
-	if !strings.HasSuffix(parts[1], memSuffix) {
-		return Alg{}, fmt.Errorf("bad memory component `%s' (wrong or missing suffix)", parts[1])
+// parseMemory parses a memory size in MB, or KB for sizes which are not a
+// whole number of MB, and returns it in KB
+func parseMemory(s string) (uint32, error) {
+	units := uint64(memoryUnits)
+	digits, ok := strings.CutSuffix(s, memSuffix)
+	if !ok {
+		if digits, ok = strings.CutSuffix(s, memKBSuffix); !ok {
+			return 0, fmt.Errorf("bad memory component `%s' (wrong or missing suffix)", s)
+		}
+		units = 1
 	}
-	u, err = strconv.ParseUint(parts[1][:len(parts[1])-len(memSuffix)], 10, 32)
+	u, err := strconv.ParseUint(digits, 10, 32)
 	if err != nil {
-		return Alg{}, fmt.Errorf("bad memory component `%s': %v", parts[1], err)
+		return 0, fmt.Errorf("bad memory component `%s': %v", s, err)
 	}
-	a.Memory = uint32(u)
-	if a.Memory > maxMem {
-		return Alg{}, fmt.Errorf("time `%s' to large. max=%d", parts[1], maxMem)
+	if u*units > maxMem*memoryUnits {
+		return 0, fmt.Errorf("memory `%s' to large. max=%dMB", s, maxMem)
 	}
-	if a.Memory < minMem {
-		return Alg{}, fmt.Errorf("memory `%s' to small. min=%d", parts[1], minMem)
+	if u*units < minMem*memoryUnits {
+		return 0, fmt.Errorf("memory `%s' to small. min=%dMB", s, minMem)
 	}
-	a.Memory = a.Memory * memoryUnits
+	return uint32(u * units), nil
+}
 
-	u, err = strconv.ParseUint(parts[2], 10, 32)
+func parseKeyLen(s string) (uint32, error) {
+	u, err := strconv.ParseUint(s, 10, 32)
 	if err != nil {
-		return Alg{}, fmt.Errorf("bad keylength `%s': %v", parts[2], err)
+		return 0, fmt.Errorf("bad keylength `%s': %v", s, err)
 	}
 	if u > maxKeyLength {
-		return Alg{}, fmt.Errorf("key length `%s' to large. max=%d", parts[2], maxKeyLength)
+		return 0, fmt.Errorf("key length `%s' to large. max=%d", s, maxKeyLength)
 	}
-	a.KeyLen = uint32(u)
-	if a.KeyLen < minKeyLength {
-		return Alg{}, fmt.Errorf("key length `%s' to small. min=%d", parts[2], minKeyLength)
+	if u < minKeyLength {
+		return 0, fmt.Errorf("key length `%s' to small. min=%d", s, minKeyLength)
 	}
+	return uint32(u), nil
+}
 
-	return a, nil
+func parseLookupLen(s string) (uint32, error) {
+	u, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad lookup length `%s': %v", s, err)
+	}
+	if u < minLookupLen || u > maxLookupLen {
+		return 0, fmt.Errorf("lookup length `%s' outside %d-%d", s, minLookupLen, maxLookupLen)
+	}
+	return uint32(u), nil
+}
+
+func parseThreads(s string) (uint8, error) {
+	u, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("bad threads component `%s': %v", s, err)
+	}
+	if u < defaultThreads {
+		return 0, fmt.Errorf("threads `%s' to small. min=%d", s, defaultThreads)
+	}
+	return uint8(u), nil
 }
@@ -1,6 +1,8 @@
 package apikeys
 
 import (
+	"bytes"
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -16,8 +18,8 @@ func TestParseAlg(t *testing.T) {
 		wantErr bool
 	}{
 		// TODO: Add test cases.
-		{"happy standard", args{alg: "argon2id 3 64MB 32"}, Alg{String: "argon2id 3 64MB 32", ParamsArgon2ID: ParamsArgon2ID{3, 64 * 1024, 32}}, false},
-		{"happy small and fast", args{alg: "argon2id 1 16MB 16"}, Alg{String: "argon2id 1 16MB 16", ParamsArgon2ID: ParamsArgon2ID{1, 16 * 1024, 16}}, false},
+		{"happy standard", args{alg: "argon2id 3 64MB 32"}, Alg{String: "argon2id 3 64MB 32", ParamsArgon2ID: ParamsArgon2ID{3, 64 * 1024, 32, 1}}, false},
+		{"happy small and fast", args{alg: "argon2id 1 16MB 16"}, Alg{String: "argon2id 1 16MB 16", ParamsArgon2ID: ParamsArgon2ID{1, 16 * 1024, 16, 1}}, false},
 		{"missing alg", args{alg: "3 64MB 32"}, Alg{}, true},
 		{"bad alg", args{alg: "argon2id3 64MB 32"}, Alg{}, true},
 		{"missing part", args{alg: "argon2id 64M 32"}, Alg{}, true},
@@ -30,6 +32,22 @@ func TestParseAlg(t *testing.T) {
 		{"bad memory suffix", args{alg: "argon2id 3 64M 32"}, Alg{}, true},
 		{"bad memory suffix", args{alg: "argon2id 3 64MX 32"}, Alg{}, true},
 		{"bad memory suffix", args{alg: "argon2id 3 64 32"}, Alg{}, true},
+		{"named", args{alg: "argon2id t=3,m=64MB,p=2,len=32"}, Alg{String: "argon2id t=3,m=64MB,p=2,len=32", ParamsArgon2ID: ParamsArgon2ID{3, 64 * 1024, 32, 2}}, false},
+		{"named any order", args{alg: "argon2id len=16,m=16MB,t=1"}, Alg{String: "argon2id len=16,m=16MB,t=1", ParamsArgon2ID: ParamsArgon2ID{1, 16 * 1024, 16, 1}}, false},
+		{"named version", args{alg: "argon2id t=1,m=16MB,len=16,v=19"}, Alg{String: "argon2id t=1,m=16MB,len=16,v=19", ParamsArgon2ID: ParamsArgon2ID{1, 16 * 1024, 16, 1}}, false},
+		{"named bad version", args{alg: "argon2id t=1,m=16MB,len=16,v=16"}, Alg{}, true},
+		{"named missing param", args{alg: "argon2id t=1,m=16MB"}, Alg{}, true},
+		{"named duplicate", args{alg: "argon2id t=1,t=2,m=16MB,len=16"}, Alg{}, true},
+		{"named unknown", args{alg: "argon2id t=1,m=16MB,len=16,salt=8"}, Alg{}, true},
+		{"named trailing comma", args{alg: "argon2id t=1,m=16MB,len=16,"}, Alg{}, true},
+		{"named empty value", args{alg: "argon2id t=,m=16MB,len=16"}, Alg{}, true},
+		{"named threads to large", args{alg: "argon2id t=1,m=16MB,len=16,p=256"}, Alg{}, true},
+		{"named memory KB", args{alg: "argon2id t=2,m=19456KB,len=32"}, Alg{String: "argon2id t=2,m=19456KB,len=32", ParamsArgon2ID: ParamsArgon2ID{2, 19456, 32, 1}}, false},
+		{"memory KB to small", args{alg: "argon2id 1 16383KB 16"}, Alg{}, true},
+		{"named threads to small", args{alg: "argon2id t=1,m=16MB,len=16,p=0"}, Alg{}, true},
+		{"named time to large", args{alg: "argon2id t=6,m=16MB,len=16"}, Alg{}, true},
+		{"named prehash", args{alg: "argon2id t=1,m=16MB,len=16,prehash=sha512"}, Alg{String: "argon2id t=1,m=16MB,len=16,prehash=sha512", ParamsArgon2ID: ParamsArgon2ID{1, 16 * 1024, 16, 1}, Prehash: "sha512"}, false},
+		{"named bad prehash", args{alg: "argon2id t=1,m=16MB,len=16,prehash=md5"}, Alg{}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -44,3 +62,90 @@ func TestParseAlg(t *testing.T) {
 		})
 	}
 }
+
+func FuzzParseAlg(f *testing.F) {
+	for _, s := range []string{StandardAlg, "argon2id 1 16MB 16", "argon2id 3 64M 32", "argon2id ", "argon2id 1  16MB", "argon2id t=3,m=64MB,p=2,len=32", "argon2id t=1,,m=16MB"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		a, err := ParseAlg(s)
+		if err != nil {
+			return
+		}
+		if a.Time < minTime || a.Time > maxTime ||
+			a.Memory < minMem*memoryUnits || a.Memory > maxMem*memoryUnits ||
+			a.KeyLen < minKeyLength || a.KeyLen > maxKeyLength ||
+			a.Threads < defaultThreads {
+			t.Errorf("ParseAlg(%q) = %+v, outside the permitted bounds", s, a)
+		}
+		if a.String != s {
+			t.Errorf("ParseAlg(%q).String = %q", s, a.String)
+		}
+	})
+}
+
+func TestAlgCanonical(t *testing.T) {
+	if err := RegisterPepper("canonical-1", bytes.Repeat([]byte{7}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	type args struct {
+		alg string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"positional", args{"argon2id 3 64MB 32"}, "argon2id t=3,m=64MB,len=32"},
+		{"named threads", args{"argon2id p=4,t=3,m=64MB,len=32"}, "argon2id t=3,m=64MB,len=32,p=4"},
+		{"named reordered", args{"argon2id len=32,p=1,m=65536KB,t=3,v=19"}, "argon2id t=3,m=64MB,len=32"},
+		{"named kilobytes", args{"argon2id t=1,m=20000KB,len=16"}, "argon2id t=1,m=20000KB,len=16"},
+		{"named extras", args{"argon2id prehash=sha512,lookup=8,t=1,m=16MB,len=16"}, "argon2id t=1,m=16MB,len=16,lookup=8,prehash=sha512"},
+		{"keyed reordered", args{"blake2b len=32,key=canonical-1"}, "blake2b key=canonical-1,len=32"},
+		{"keyed default len", args{"hmac-sha256 key=canonical-1"}, "hmac-sha256 key=canonical-1,len=32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAlg(tt.args.alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Canonical(); got != tt.want {
+				t.Errorf("Canonical() = %q, want %q", got, tt.want)
+			}
+			b, err := ParseAlg(a.Canonical())
+			if err != nil {
+				t.Fatalf("ParseAlg(Canonical()) = %v", err)
+			}
+			if !a.Equal(b) || a.ParamsArgon2ID != b.ParamsArgon2ID {
+				t.Errorf("canonical form parses as %+v, want %+v", b, a)
+			}
+		})
+	}
+}
+
+func TestAlgText(t *testing.T) {
+	type config struct {
+		Alg Alg `json:"alg"`
+	}
+	var c config
+	if err := json.Unmarshal([]byte(`{"alg": "argon2id 3  64MB 32"}`), &c); err == nil {
+		t.Error("Unmarshal() of a bad alg, want error")
+	}
+	if err := json.Unmarshal([]byte(`{"alg": "argon2id 3 64MB 32"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Alg.Time != 3 || c.Alg.Memory != 64*1024 || c.Alg.KeyLen != 32 {
+		t.Errorf("Unmarshal() = %+v", c.Alg)
+	}
+	out, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"alg":"argon2id t=3,m=64MB,len=32"}`; string(out) != want {
+		t.Errorf("Marshal() = %s, want %s", out, want)
+	}
+	if out, _ := json.Marshal(config{}); string(out) != `{"alg":""}` {
+		t.Errorf("Marshal() of the zero alg = %s", out)
+	}
+}
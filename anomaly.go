@@ -0,0 +1,133 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// ErrAnomaly is returned when an AnomalyDetector vetoes the use of a key
+var ErrAnomaly = errors.New("api key use vetoed as anomalous")
+
+// Geo is where a client is, as resolved by the integrator, eg from a GeoIP
+// database or the headers of a CDN. Empty fields are unknown.
+type Geo struct {
+	// Country is an ISO 3166-1 alpha-2 code
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+	// Latitude and Longitude are only meaningful if HasCoordinates
+	Latitude       float64 `json:"latitude,omitempty"`
+	Longitude      float64 `json:"longitude,omitempty"`
+	HasCoordinates bool    `json:"has_coordinates,omitempty"`
+}
+
+// KeyUse describes a request made with a verified key
+type KeyUse struct {
+	// Key is the stored record the presented key matched
+	Key Key
+	// Addr is the client address, invalid if unknown
+	Addr      netip.Addr
+	UserAgent string
+	Geo       Geo
+	// Time defaults to the verifier's clock
+	Time time.Time
+}
+
+// AnomalyVerdict is the decision of an AnomalyDetector
+type AnomalyVerdict int
+
+const (
+	// AnomalyAllow lets the request through unremarked
+	AnomalyAllow AnomalyVerdict = iota
+	// AnomalyFlag lets the request through but audits it as suspicious
+	AnomalyFlag
+	// AnomalyVeto rejects the request with ErrAnomaly
+	AnomalyVeto
+)
+
+func (v AnomalyVerdict) String() string {
+	switch v {
+	case AnomalyAllow:
+		return "allowed"
+	case AnomalyFlag:
+		return "flagged"
+	case AnomalyVeto:
+		return "vetoed"
+	}
+	return fmt.Sprintf("AnomalyVerdict(%d)", int(v))
+}
+
+// AnomalyResult is a verdict and why it was reached
+type AnomalyResult struct {
+	Verdict AnomalyVerdict
+	Reason  string
+}
+
+// AnomalyDetector inspects each use of a verified key, eg for impossible
+// travel between the locations of consecutive uses or a sudden rise in
+// volume. It is called synchronously on the request path, so detectors which
+// consult a backend should bound their own latency and decide for
+// themselves whether to fail open.
+type AnomalyDetector func(ctx context.Context, use KeyUse) AnomalyResult
+
+// AnomalyDetectors calls each of detectors and returns the most severe
+// verdict, with the reasons of every detector which reached it
+func AnomalyDetectors(detectors ...AnomalyDetector) AnomalyDetector {
+	return func(ctx context.Context, use KeyUse) AnomalyResult {
+		var res AnomalyResult
+		var reasons []string
+		for _, d := range detectors {
+			r := d(ctx, use)
+			if r.Verdict > res.Verdict {
+				res.Verdict = r.Verdict
+				reasons = reasons[:0]
+			}
+			if r.Verdict == res.Verdict && r.Verdict != AnomalyAllow && r.Reason != "" {
+				reasons = append(reasons, r.Reason)
+			}
+		}
+		res.Reason = strings.Join(reasons, "; ")
+		return res
+	}
+}
+
+// WithAnomalyDetector has StoreVerifier.CheckAnomaly consult d. The keyshttp
+// middleware calls CheckAnomaly for every request with a verified key.
+func WithAnomalyDetector(d AnomalyDetector) Option {
+	return func(o *options) {
+		o.anomaly = d
+	}
+}
+
+// CheckAnomaly runs the anomaly detector, if there is one, on use. Flagged
+// and vetoed uses are audited as AuditKeyAnomaly with the verdict as the
+// result and the reason as the error. A veto returns ErrAnomaly.
+func (v *StoreVerifier) CheckAnomaly(ctx context.Context, use KeyUse) (AnomalyResult, error) {
+	if v.anomaly == nil {
+		return AnomalyResult{}, nil
+	}
+	if use.Time.IsZero() {
+		use.Time = v.now()
+	}
+	res := v.anomaly(ctx, use)
+	if res.Verdict == AnomalyAllow {
+		return res, nil
+	}
+	if v.audit != nil {
+		v.emitEvent(ctx, AuditEvent{
+			Time: use.Time, Type: AuditKeyAnomaly, ClientID: use.Key.ClientID, Alg: use.Key.alg.String,
+			Result: res.Verdict.String(), Error: res.Reason, Actor: PrincipalFromContext(ctx), Team: use.Key.Team,
+		})
+	}
+	if res.Verdict == AnomalyVeto {
+		if res.Reason == "" {
+			return res, ErrAnomaly
+		}
+		return res, fmt.Errorf("%w: %s", ErrAnomaly, res.Reason)
+	}
+	return res, nil
+}
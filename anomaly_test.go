@@ -0,0 +1,88 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestCheckAnomaly(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	_, ak, err := NewAdmin(store).Create(ctx, testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// impossible travel: the key was last used from another country
+	travel := func(ctx context.Context, use KeyUse) AnomalyResult {
+		if use.Geo.Country != "" && use.Geo.Country != "GB" {
+			return AnomalyResult{Verdict: AnomalyVeto, Reason: "impossible travel to " + use.Geo.Country}
+		}
+		return AnomalyResult{}
+	}
+	unknownAgent := func(ctx context.Context, use KeyUse) AnomalyResult {
+		if use.UserAgent == "" {
+			return AnomalyResult{Verdict: AnomalyFlag, Reason: "no user agent"}
+		}
+		return AnomalyResult{}
+	}
+	newAddr := func(ctx context.Context, use KeyUse) AnomalyResult {
+		if !use.Addr.IsValid() {
+			return AnomalyResult{Verdict: AnomalyFlag, Reason: "no address"}
+		}
+		return AnomalyResult{}
+	}
+
+	type args struct {
+		use KeyUse
+	}
+	tests := []struct {
+		name       string
+		args       args
+		want       AnomalyResult
+		wantErr    error
+		wantEvents int
+	}{
+		{
+			name: "allowed",
+			args: args{use: KeyUse{Addr: netip.MustParseAddr("203.0.113.9"), UserAgent: "curl", Geo: Geo{Country: "GB"}}},
+		},
+		{
+			name:       "flagged by both",
+			args:       args{use: KeyUse{Geo: Geo{Country: "GB"}}},
+			want:       AnomalyResult{Verdict: AnomalyFlag, Reason: "no user agent; no address"},
+			wantEvents: 1,
+		},
+		{
+			name:       "veto wins",
+			args:       args{use: KeyUse{Geo: Geo{Country: "NZ"}}},
+			want:       AnomalyResult{Verdict: AnomalyVeto, Reason: "impossible travel to NZ"},
+			wantErr:    ErrAnomaly,
+			wantEvents: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := NewMemAuditStore()
+			v := NewStoreVerifier(store, WithAudit(audit), WithAnomalyDetector(AnomalyDetectors(travel, unknownAgent, newAddr)))
+			use := tt.args.use
+			use.Key = ak
+			got, err := v.CheckAnomaly(ctx, use)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckAnomaly() = %+v, %v, want %+v, %v", got, err, tt.want, tt.wantErr)
+			}
+			events, _ := audit.Query(ctx, AuditQuery{Types: []string{AuditKeyAnomaly}})
+			if len(events) != tt.wantEvents {
+				t.Fatalf("got %d anomaly events, want %d", len(events), tt.wantEvents)
+			}
+			if len(events) > 0 && (events[0].ClientID != "client-1" || events[0].Result != tt.want.Verdict.String() || events[0].Error != tt.want.Reason) {
+				t.Errorf("anomaly event = %+v", events[0])
+			}
+		})
+	}
+
+	if res, err := NewStoreVerifier(store).CheckAnomaly(ctx, KeyUse{Key: ak}); err != nil || res.Verdict != AnomalyAllow {
+		t.Errorf("CheckAnomaly() without a detector = %+v, %v", res, err)
+	}
+}
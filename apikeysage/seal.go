@@ -0,0 +1,105 @@
+// Package apikeysage encrypts a generated api key to its recipient's age or
+// ssh public key, so the one time secret can be emailed or posted in a
+// ticket. Only the holder of the matching private key can decrypt it, eg
+// with `age -d -i ~/.ssh/id_ed25519`.
+//
+//	apikey, _, err := admin.Create(ctx, "", apikeys.WithClientID("client-1"))
+//	if err != nil {
+//		return err
+//	}
+//	blob, err := apikeysage.Seal(apikey, "ssh-ed25519 AAAA... alice@example.com")
+//
+// The blob is ascii armored, the api key was never written anywhere in the
+// clear.
+package apikeysage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"github.com/robinbryce/apikeys"
+)
+
+// ErrNoRecipients is returned by Seal when it is given no recipients
+var ErrNoRecipients = errors.New("no recipients to seal the api key to")
+
+// ParseRecipient parses an age public key, "age1...", or an ssh-ed25519 or
+// ssh-rsa public key in authorized_keys format
+func ParseRecipient(s string) (age.Recipient, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "ssh-") {
+		r, err := agessh.ParseRecipient(s)
+		if err != nil {
+			return nil, fmt.Errorf("bad ssh recipient: %w", err)
+		}
+		return r, nil
+	}
+	rs, err := age.ParseRecipients(strings.NewReader(s))
+	if err != nil {
+		return nil, fmt.Errorf("bad age recipient: %w", err)
+	}
+	if len(rs) != 1 {
+		return nil, fmt.Errorf("bad age recipient `%s'", s)
+	}
+	return rs[0], nil
+}
+
+// Seal encrypts apikey to each of recipients, see ParseRecipient, and returns
+// the ascii armored age file
+func Seal(apikey string, recipients ...string) ([]byte, error) {
+	return SealBytes(apikeys.Secret(apikey), recipients...)
+}
+
+// SealBytes is Seal for an api key from Key.GenerateBytes, which the caller
+// can Wipe once it is sealed
+func SealBytes(apikey apikeys.Secret, recipients ...string) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+	var rs []age.Recipient
+	for _, s := range recipients {
+		r, err := ParseRecipient(s)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	var out bytes.Buffer
+	aw := armor.NewWriter(&out)
+	w, err := age.Encrypt(aw, rs...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(apikey); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Open decrypts a blob from Seal with any of identities, see
+// age.ParseIdentities and agessh.ParseIdentity. It is for recipients
+// receiving keys in code, eg a provisioning agent.
+func Open(blob []byte, identities ...age.Identity) (apikeys.Secret, error) {
+	r, err := age.Decrypt(armor.NewReader(bytes.NewReader(blob)), identities...)
+	if err != nil {
+		return nil, err
+	}
+	apikey, err := io.ReadAll(r)
+	if err != nil {
+		clear(apikey)
+		return nil, err
+	}
+	return apikeys.Secret(apikey), nil
+}
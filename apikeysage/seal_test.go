@@ -0,0 +1,86 @@
+package apikeysage
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"github.com/robinbryce/apikeys"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSeal(t *testing.T) {
+	ak, err := apikeys.NewKey("argon2id 1 16MB 16", apikeys.WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x25519, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshID, err := agessh.NewEd25519Identity(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := age.GenerateX25519Identity()
+
+	type args struct {
+		recipients []string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		identity age.Identity
+		wantErr  bool
+	}{
+		{"age", args{[]string{x25519.Recipient().String()}}, x25519, false},
+		{"ssh", args{[]string{string(ssh.MarshalAuthorizedKey(sshPub))}}, sshID, false},
+		{"either of two", args{[]string{other.Recipient().String(), x25519.Recipient().String()}}, x25519, false},
+		{"bad recipient", args{[]string{"age1nope"}}, nil, true},
+		{"no recipients", args{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := Seal(apikey, tt.args.recipients...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Seal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !bytes.HasPrefix(blob, []byte("-----BEGIN AGE ENCRYPTED FILE-----")) || bytes.Contains(blob, []byte(apikey)) {
+				t.Fatalf("Seal() = %s, want an armored age file", blob)
+			}
+			got, err := Open(blob, tt.identity)
+			if err != nil || string(got) != apikey {
+				t.Errorf("Open() = %v, %v, want the api key", got, err)
+			}
+		})
+	}
+
+	blob, err := Seal(apikey, x25519.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var noMatch *age.NoIdentityMatchError
+	if _, err := Open(blob, other); !errors.As(err, &noMatch) {
+		t.Errorf("Open() by someone else error = %v, want no identity match", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package apikeyskafka publishes api key audit and lifecycle events to kafka
+// as apikeys.AuditEnvelope json, for data platforms which ingest from a
+// broker rather than webhooks.
+//
+//	p, err := apikeyskafka.NewPublisher(&kafka.Writer{
+//		Addr:         kafka.TCP(brokers...),
+//		Topic:        "apikeys-events",
+//		RequiredAcks: kafka.RequireAll,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	admin := apikeys.NewAdmin(store, apikeys.WithAudit(p))
+package apikeyskafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/robinbryce/apikeys"
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers set on every message
+const (
+	SchemaHeader = "apikeys-schema"
+	EventHeader  = "apikeys-event"
+	IDHeader     = "apikeys-id"
+)
+
+// MessageWriter is implemented by *kafka.Writer
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Publisher is an apikeys.AuditSink writing each event as a message keyed by
+// client id, so the events of a key stay in order on one partition. Emit
+// returns once the writer has had the message acknowledged, retrying as the
+// writer is configured to, so a retried write may deliver an event twice;
+// consumers drop duplicates by the envelope id. Publisher keeps no outbox:
+// an event the writer gives up on is logged by the Admin or StoreVerifier
+// and lost.
+type Publisher struct {
+	w      MessageWriter
+	topic  string
+	events []string
+}
+
+var _ apikeys.AuditSink = (*Publisher)(nil)
+
+type Option func(*Publisher)
+
+// WithTopic sets the topic of each message, for a writer without a Topic
+func WithTopic(topic string) Option {
+	return func(p *Publisher) {
+		p.topic = topic
+	}
+}
+
+// WithEvents publishes only the given audit event types. By default every
+// event is published except the high volume verification events, which
+// would put a broker round trip on every Verify.
+func WithEvents(types ...string) Option {
+	return func(p *Publisher) {
+		p.events = types
+	}
+}
+
+// NewPublisher returns a Publisher writing to w. A *kafka.Writer must be
+// synchronous and wait for acknowledgement, as an async writer or one
+// requiring no acks loses messages silently.
+func NewPublisher(w MessageWriter, opts ...Option) (*Publisher, error) {
+	if kw, ok := w.(*kafka.Writer); ok {
+		if kw.Async {
+			return nil, errors.New("kafka writer for api key events must not be async")
+		}
+		if kw.RequiredAcks == kafka.RequireNone {
+			return nil, errors.New("kafka writer for api key events must require acks")
+		}
+	}
+	p := &Publisher{w: w}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+func (p *Publisher) wants(typ string) bool {
+	if len(p.events) == 0 {
+		return typ != apikeys.AuditVerifySuccess && typ != apikeys.AuditVerifyFailed
+	}
+	return slices.Contains(p.events, typ)
+}
+
+func (p *Publisher) Emit(ctx context.Context, ev apikeys.AuditEvent) error {
+	if !p.wants(ev.Type) {
+		return nil
+	}
+	env, err := apikeys.NewAuditEnvelope(ev)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	msg := kafka.Message{
+		Topic: p.topic,
+		Key:   []byte(ev.ClientID),
+		Value: b,
+		Time:  ev.Time,
+		Headers: []kafka.Header{
+			{Key: SchemaHeader, Value: []byte(env.Schema)},
+			{Key: EventHeader, Value: []byte(ev.Type)},
+			{Key: IDHeader, Value: []byte(env.ID)},
+		},
+	}
+	if err := p.w.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("publishing `%s' event to kafka: %w", ev.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package apikeyskafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/robinbryce/apikeys"
+	"github.com/segmentio/kafka-go"
+)
+
+type fakeWriter struct {
+	msgs []kafka.Message
+	err  error
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func header(m kafka.Message, key string) string {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+	w := &fakeWriter{}
+	p, err := NewPublisher(w, WithTopic("apikeys-events"), WithEvents(apikeys.AuditKeyCreated, apikeys.AuditKeyRevoked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := apikeys.NewAdmin(apikeys.NewMemStore(), apikeys.WithAudit(p))
+	if _, _, err := admin.Create(ctx, "argon2id 1 16MB 16", apikeys.WithClientID("client-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Emit(ctx, apikeys.AuditEvent{Type: apikeys.AuditVerifySuccess, ClientID: "client-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Revoke(ctx, "client-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.msgs) != 2 {
+		t.Fatalf("wrote %d messages, want 2", len(w.msgs))
+	}
+	for i, typ := range []string{apikeys.AuditKeyCreated, apikeys.AuditKeyRevoked} {
+		m := w.msgs[i]
+		var env apikeys.AuditEnvelope
+		if err := json.Unmarshal(m.Value, &env); err != nil {
+			t.Fatal(err)
+		}
+		if m.Topic != "apikeys-events" || string(m.Key) != "client-1" || env.Event.Type != typ || env.Schema != apikeys.AuditSchema {
+			t.Errorf("message %d = %s %s %s", i, m.Topic, m.Key, m.Value)
+		}
+		if header(m, SchemaHeader) != apikeys.AuditSchema || header(m, EventHeader) != typ || header(m, IDHeader) != env.ID {
+			t.Errorf("message %d headers = %v", i, m.Headers)
+		}
+	}
+
+	// Verification events are left out unless asked for
+	p, err = NewPublisher(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Emit(ctx, apikeys.AuditEvent{Type: apikeys.AuditVerifySuccess, ClientID: "client-1"}); err != nil || len(w.msgs) != 2 {
+		t.Errorf("Emit() verify by default = %v, wrote %d messages, want 2", err, len(w.msgs))
+	}
+
+	w.err = errors.New("leader not available")
+	if err := p.Emit(ctx, apikeys.AuditEvent{Type: apikeys.AuditKeyRevoked}); !errors.Is(err, w.err) {
+		t.Errorf("Emit() = %v, want the writer error", err)
+	}
+}
+
+func TestNewPublisher(t *testing.T) {
+	type args struct {
+		w *kafka.Writer
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{name: "acks all", args: args{w: &kafka.Writer{RequiredAcks: kafka.RequireAll}}},
+		{name: "acks one", args: args{w: &kafka.Writer{RequiredAcks: kafka.RequireOne}}},
+		{name: "no acks", args: args{w: &kafka.Writer{}}, wantErr: true},
+		{name: "async", args: args{w: &kafka.Writer{RequiredAcks: kafka.RequireAll, Async: true}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPublisher(tt.args.w); (err != nil) != tt.wantErr {
+				t.Errorf("NewPublisher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
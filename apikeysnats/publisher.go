@@ -0,0 +1,116 @@
+// Package apikeysnats publishes api key audit and lifecycle events to NATS
+// JetStream as apikeys.AuditEnvelope json. Each event type has its own
+// subject, eg apikeys.key.revoked, so consumers can subscribe to just the
+// events they handle.
+//
+//	nc, err := nats.Connect(url)
+//	if err != nil {
+//		return err
+//	}
+//	js, err := jetstream.New(nc)
+//	if err != nil {
+//		return err
+//	}
+//	admin := apikeys.NewAdmin(store, apikeys.WithAudit(apikeysnats.NewPublisher(js)))
+//
+// The stream capturing the subjects must exist already.
+package apikeysnats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/robinbryce/apikeys"
+)
+
+// DefaultSubjectPrefix is put before the event type to make the subject
+const DefaultSubjectPrefix = "apikeys."
+
+// SchemaHeader carries apikeys.AuditSchema on every message
+const SchemaHeader = "Apikeys-Schema"
+
+// JetStream is the part of jetstream.JetStream a Publisher uses
+type JetStream interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// Publisher is an apikeys.AuditSink publishing each event to JetStream and
+// waiting for the stream to acknowledge it. The envelope id is also the
+// JetStream message id, so a retried publish within the stream's duplicate
+// window is stored once. Publisher keeps no outbox: an event the stream does
+// not acknowledge is logged by the Admin or StoreVerifier and lost.
+type Publisher struct {
+	js      JetStream
+	prefix  string
+	events  []string
+	pubOpts []jetstream.PublishOpt
+}
+
+var _ apikeys.AuditSink = (*Publisher)(nil)
+
+type Option func(*Publisher)
+
+// WithSubjectPrefix replaces DefaultSubjectPrefix
+func WithSubjectPrefix(prefix string) Option {
+	return func(p *Publisher) {
+		p.prefix = prefix
+	}
+}
+
+// WithEvents publishes only the given audit event types. By default every
+// event is published except the high volume verification events, which
+// would put a stream round trip on every Verify.
+func WithEvents(types ...string) Option {
+	return func(p *Publisher) {
+		p.events = types
+	}
+}
+
+// WithPublishOptions are passed to every PublishMsg, eg
+// jetstream.WithRetryAttempts to ride out a stream leader election
+func WithPublishOptions(opts ...jetstream.PublishOpt) Option {
+	return func(p *Publisher) {
+		p.pubOpts = opts
+	}
+}
+
+func NewPublisher(js JetStream, opts ...Option) *Publisher {
+	p := &Publisher{js: js, prefix: DefaultSubjectPrefix}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Publisher) wants(typ string) bool {
+	if len(p.events) == 0 {
+		return typ != apikeys.AuditVerifySuccess && typ != apikeys.AuditVerifyFailed
+	}
+	return slices.Contains(p.events, typ)
+}
+
+func (p *Publisher) Emit(ctx context.Context, ev apikeys.AuditEvent) error {
+	if !p.wants(ev.Type) {
+		return nil
+	}
+	env, err := apikeys.NewAuditEnvelope(ev)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(p.prefix + ev.Type)
+	msg.Data = b
+	msg.Header.Set(SchemaHeader, env.Schema)
+	msg.Header.Set(jetstream.MsgIDHeader, env.ID)
+	if _, err := p.js.PublishMsg(ctx, msg, p.pubOpts...); err != nil {
+		return fmt.Errorf("publishing `%s' event to nats: %w", ev.Type, err)
+	}
+	return nil
+}
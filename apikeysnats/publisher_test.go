@@ -0,0 +1,98 @@
+package apikeysnats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/robinbryce/apikeys"
+)
+
+type fakeJetStream struct {
+	msgs []*nats.Msg
+	err  error
+}
+
+func (js *fakeJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if js.err != nil {
+		return nil, js.err
+	}
+	js.msgs = append(js.msgs, msg)
+	return &jetstream.PubAck{Stream: "APIKEYS", Sequence: uint64(len(js.msgs))}, nil
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+	type args struct {
+		opts []Option
+		ev   apikeys.AuditEvent
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantSubject string
+	}{
+		{
+			name:        "default prefix",
+			args:        args{ev: apikeys.AuditEvent{Type: apikeys.AuditKeyRevoked, ClientID: "client-1"}},
+			wantSubject: "apikeys.key.revoked",
+		},
+		{
+			name:        "custom prefix",
+			args:        args{opts: []Option{WithSubjectPrefix("audit.")}, ev: apikeys.AuditEvent{Type: apikeys.AuditKeyCreated}},
+			wantSubject: "audit.key.created",
+		},
+		{
+			name: "filtered out",
+			args: args{opts: []Option{WithEvents(apikeys.AuditKeyRevoked)}, ev: apikeys.AuditEvent{Type: apikeys.AuditVerifySuccess}},
+		},
+		{
+			name: "verify excluded by default",
+			args: args{ev: apikeys.AuditEvent{Type: apikeys.AuditVerifyFailed}},
+		},
+		{
+			name:        "verify requested",
+			args:        args{opts: []Option{WithEvents(apikeys.AuditVerifySuccess)}, ev: apikeys.AuditEvent{Type: apikeys.AuditVerifySuccess}},
+			wantSubject: "apikeys.key.verified",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js := &fakeJetStream{}
+			if err := NewPublisher(js, tt.args.opts...).Emit(ctx, tt.args.ev); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSubject == "" {
+				if len(js.msgs) != 0 {
+					t.Errorf("published %d messages, want none", len(js.msgs))
+				}
+				return
+			}
+			if len(js.msgs) != 1 {
+				t.Fatalf("published %d messages, want 1", len(js.msgs))
+			}
+			msg := js.msgs[0]
+			var env apikeys.AuditEnvelope
+			if err := json.Unmarshal(msg.Data, &env); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Subject != tt.wantSubject || env.Event.Type != tt.args.ev.Type || env.Schema != apikeys.AuditSchema {
+				t.Errorf("published %s %s", msg.Subject, msg.Data)
+			}
+			if msg.Header.Get(jetstream.MsgIDHeader) != env.ID || msg.Header.Get(SchemaHeader) != apikeys.AuditSchema {
+				t.Errorf("headers = %v", msg.Header)
+			}
+		})
+	}
+}
+
+func TestPublisherError(t *testing.T) {
+	js := &fakeJetStream{err: jetstream.ErrNoStreamResponse}
+	err := NewPublisher(js).Emit(context.Background(), apikeys.AuditEvent{Type: apikeys.AuditKeyRevoked})
+	if !errors.Is(err, jetstream.ErrNoStreamResponse) {
+		t.Errorf("Emit() = %v, want the publish error", err)
+	}
+}
@@ -0,0 +1,57 @@
+// Package apikeysotel adapts an OpenTelemetry TracerProvider to the
+// apikeys.Tracer interface.
+//
+//	v := apikeys.NewStoreVerifier(store, apikeys.WithTracer(apikeysotel.NewTracer(otel.GetTracerProvider())))
+package apikeysotel
+
+import (
+	"context"
+
+	"github.com/robinbryce/apikeys"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/robinbryce/apikeys"
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns an apikeys.Tracer which creates spans using tp
+func NewTracer(tp trace.TracerProvider) apikeys.Tracer {
+	return &tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+func (t *tracer) Start(ctx context.Context, name string) (context.Context, apikeys.Span) {
+	ctx, s := t.tracer.Start(ctx, name)
+	return ctx, span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int64:
+		s.span.SetAttributes(attribute.Int64(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	case float64:
+		s.span.SetAttributes(attribute.Float64(key, v))
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, v))
+	}
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
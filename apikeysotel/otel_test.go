@@ -0,0 +1,61 @@
+package apikeysotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/robinbryce/apikeys"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const testAlg = "argon2id 1 16MB 16"
+
+func TestTracer(t *testing.T) {
+	ctx := context.Background()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracer := NewTracer(tp)
+
+	store := apikeys.NewMemStore()
+	admin := apikeys.NewAdmin(store, apikeys.WithTracer(tracer))
+	verifier := apikeys.NewStoreVerifier(store, apikeys.WithTracer(tracer))
+
+	apikey, _, err := admin.Create(ctx, testAlg, apikeys.WithClientID("client-1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	names := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended() {
+		names[s.Name()] = s
+	}
+	for _, want := range []string{
+		apikeys.SpanGenerate, apikeys.SpanStore + "Create",
+		apikeys.SpanVerify, apikeys.SpanDecode, apikeys.SpanStore + "Get", apikeys.SpanDerive,
+	} {
+		if _, ok := names[want]; !ok {
+			t.Errorf("missing span %s", want)
+		}
+	}
+
+	derive := names[apikeys.SpanDerive]
+	if derive == nil {
+		return
+	}
+	if derive.Parent().SpanID() != names[apikeys.SpanVerify].SpanContext().SpanID() {
+		t.Errorf("%s is not a child of %s", apikeys.SpanDerive, apikeys.SpanVerify)
+	}
+	found := false
+	for _, kv := range derive.Attributes() {
+		if string(kv.Key) == apikeys.AttrDeriveDuration && kv.Value.AsFloat64() > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("%s missing %s attribute", apikeys.SpanDerive, apikeys.AttrDeriveDuration)
+	}
+}
@@ -0,0 +1,231 @@
+package apikeyspb
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// KeyToProto converts ak to the Key message returned to api clients. The
+// salt is not included.
+func KeyToProto(ak apikeys.Key) *Key {
+	return &Key{
+		ClientId:   ak.ClientID,
+		Alg:        ak.Alg().String,
+		DerivedKey: ak.DerivedKey,
+		CreatedAt:  timestamp(ak.CreatedAt),
+		RevokedAt:  timestamp(ak.RevokedAt),
+		ExpiresAt:  timestamp(ak.ExpiresAt),
+		TenantId:   ak.TenantID,
+
+		Name:        ak.Name,
+		Description: ak.Description,
+		Owner:       ak.Owner,
+		Labels:      maps.Clone(ak.Labels),
+		RotatedAt:   timestamp(ak.RotatedAt),
+		Type:        string(ak.Type),
+		CreatedBy:   ak.CreatedBy,
+		Team:        ak.Team,
+
+		Restrictions: RestrictionsToProto(ak.Restrictions),
+
+		LastUsedAt:         timestamp(ak.LastUsedAt),
+		IdleTimeoutSeconds: ak.IdleTimeoutSeconds,
+
+		Approval:   string(ak.Approval),
+		ReviewedBy: ak.ReviewedBy,
+
+		PreviousExpiresAt: timestamp(ak.PreviousExpiresAt),
+	}
+}
+
+// KeyFromProto converts a Key message. The result has no salt, so it can not
+// re-derive the key; use KeyRecord for that.
+func KeyFromProto(p *Key) (apikeys.Key, error) {
+	ak := apikeys.Key{
+		ClientID:   p.GetClientId(),
+		DerivedKey: p.GetDerivedKey(),
+		CreatedAt:  fromTimestamp(p.GetCreatedAt()),
+		RevokedAt:  fromTimestamp(p.GetRevokedAt()),
+		ExpiresAt:  fromTimestamp(p.GetExpiresAt()),
+		TenantID:   p.GetTenantId(),
+
+		Name:        p.GetName(),
+		Description: p.GetDescription(),
+		Owner:       p.GetOwner(),
+		Labels:      maps.Clone(p.GetLabels()),
+		RotatedAt:   fromTimestamp(p.GetRotatedAt()),
+		Type:        apikeys.KeyType(p.GetType()),
+		CreatedBy:   p.GetCreatedBy(),
+		Team:        p.GetTeam(),
+
+		Restrictions: RestrictionsFromProto(p.GetRestrictions()),
+
+		LastUsedAt:         fromTimestamp(p.GetLastUsedAt()),
+		IdleTimeoutSeconds: p.GetIdleTimeoutSeconds(),
+
+		Approval:   apikeys.ApprovalState(p.GetApproval()),
+		ReviewedBy: p.GetReviewedBy(),
+
+		PreviousExpiresAt: fromTimestamp(p.GetPreviousExpiresAt()),
+	}
+	if p.GetAlg() != "" {
+		if err := ak.SetAlg(p.GetAlg()); err != nil {
+			return apikeys.Key{}, err
+		}
+	}
+	return ak, nil
+}
+
+// AlgToProto converts a, nil if a is the zero Alg
+func AlgToProto(a apikeys.Alg) *Alg {
+	if a.String == "" {
+		return nil
+	}
+	return &Alg{Spec: a.String, Time: a.Time, Memory: a.Memory, KeyLen: a.KeyLen, Threads: uint32(a.Threads)}
+}
+
+// AlgFromProto parses the spec of p and checks it agrees with the numeric
+// fields
+func AlgFromProto(p *Alg) (apikeys.Alg, error) {
+	a, err := apikeys.ParseAlg(p.GetSpec())
+	if err != nil {
+		return apikeys.Alg{}, err
+	}
+	if a.Time != p.GetTime() || a.Memory != p.GetMemory() || a.KeyLen != p.GetKeyLen() || uint32(a.Threads) != p.GetThreads() {
+		return apikeys.Alg{}, fmt.Errorf("alg parameters do not match spec `%s'", p.GetSpec())
+	}
+	return a, nil
+}
+
+// ToProto converts ak to a KeyRecord, including the salt
+func ToProto(ak apikeys.Key) *KeyRecord {
+	return &KeyRecord{
+		ClientId:   ak.ClientID,
+		Alg:        AlgToProto(ak.Alg()),
+		Salt:       ak.Salt,
+		DerivedKey: ak.DerivedKey,
+		CreatedAt:  timestamp(ak.CreatedAt),
+		RevokedAt:  timestamp(ak.RevokedAt),
+		ExpiresAt:  timestamp(ak.ExpiresAt),
+
+		ImportedHash: ak.ImportedHash,
+		TenantId:     ak.TenantID,
+
+		Name:        ak.Name,
+		Description: ak.Description,
+		Owner:       ak.Owner,
+		Labels:      maps.Clone(ak.Labels),
+		RotatedAt:   timestamp(ak.RotatedAt),
+		Type:        string(ak.Type),
+		CreatedBy:   ak.CreatedBy,
+		Team:        ak.Team,
+
+		Restrictions: RestrictionsToProto(ak.Restrictions),
+
+		LastUsedAt:         timestamp(ak.LastUsedAt),
+		IdleTimeoutSeconds: ak.IdleTimeoutSeconds,
+
+		Approval:   string(ak.Approval),
+		ReviewedBy: ak.ReviewedBy,
+
+		PreviousDerivedKey: ak.PreviousDerivedKey,
+		PreviousExpiresAt:  timestamp(ak.PreviousExpiresAt),
+	}
+}
+
+// FromProto converts a KeyRecord back to the Key it was made from
+func FromProto(p *KeyRecord) (apikeys.Key, error) {
+	ak := apikeys.Key{
+		ClientID:   p.GetClientId(),
+		Salt:       p.GetSalt(),
+		DerivedKey: p.GetDerivedKey(),
+		CreatedAt:  fromTimestamp(p.GetCreatedAt()),
+		RevokedAt:  fromTimestamp(p.GetRevokedAt()),
+		ExpiresAt:  fromTimestamp(p.GetExpiresAt()),
+
+		ImportedHash: p.GetImportedHash(),
+		TenantID:     p.GetTenantId(),
+
+		Name:        p.GetName(),
+		Description: p.GetDescription(),
+		Owner:       p.GetOwner(),
+		Labels:      maps.Clone(p.GetLabels()),
+		RotatedAt:   fromTimestamp(p.GetRotatedAt()),
+		Type:        apikeys.KeyType(p.GetType()),
+		CreatedBy:   p.GetCreatedBy(),
+		Team:        p.GetTeam(),
+
+		Restrictions: RestrictionsFromProto(p.GetRestrictions()),
+
+		LastUsedAt:         fromTimestamp(p.GetLastUsedAt()),
+		IdleTimeoutSeconds: p.GetIdleTimeoutSeconds(),
+
+		Approval:   apikeys.ApprovalState(p.GetApproval()),
+		ReviewedBy: p.GetReviewedBy(),
+
+		PreviousDerivedKey: p.GetPreviousDerivedKey(),
+		PreviousExpiresAt:  fromTimestamp(p.GetPreviousExpiresAt()),
+	}
+	if p.GetAlg() != nil {
+		a, err := AlgFromProto(p.GetAlg())
+		if err != nil {
+			return apikeys.Key{}, err
+		}
+		if err := ak.SetAlg(a.String); err != nil {
+			return apikeys.Key{}, err
+		}
+	}
+	return ak, nil
+}
+
+// RestrictionsToProto converts r, nil if there are no restrictions
+func RestrictionsToProto(r apikeys.Restrictions) *Restrictions {
+	if r.IsZero() {
+		return nil
+	}
+	return &Restrictions{
+		AllowedCidrs:   slices.Clone(r.AllowedCIDRs),
+		AllowedOrigins: slices.Clone(r.AllowedOrigins),
+		AllowedRoutes:  slices.Clone(r.AllowedRoutes),
+		Quota:          quotaToProto(r.Quota),
+	}
+}
+
+// RestrictionsFromProto converts a Restrictions message
+func RestrictionsFromProto(p *Restrictions) apikeys.Restrictions {
+	return apikeys.Restrictions{
+		AllowedCIDRs:   slices.Clone(p.GetAllowedCidrs()),
+		AllowedOrigins: slices.Clone(p.GetAllowedOrigins()),
+		AllowedRoutes:  slices.Clone(p.GetAllowedRoutes()),
+		Quota: apikeys.Quota{
+			Limit:         p.GetQuota().GetLimit(),
+			WindowSeconds: p.GetQuota().GetWindowSeconds(),
+		},
+	}
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func quotaToProto(q apikeys.Quota) *Quota {
+	if q == (apikeys.Quota{}) {
+		return nil
+	}
+	return &Quota{Limit: q.Limit, WindowSeconds: q.WindowSeconds}
+}
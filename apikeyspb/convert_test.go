@@ -0,0 +1,97 @@
+package apikeyspb
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+	"google.golang.org/protobuf/proto"
+)
+
+const testAlg = "argon2id 1 16MB 16"
+
+func TestRecordRoundTrip(t *testing.T) {
+	ak, err := apikeys.NewKey(testAlg, apikeys.WithClientID("client-1"), apikeys.WithTenant("acme"), apikeys.WithName("ci"), apikeys.WithLabels(map[string]string{"env": "prod"}), apikeys.WithKeyType(apikeys.KeyTypeService), apikeys.WithTeam("payments"), apikeys.WithAllowedCIDRs("203.0.113.0/24"), apikeys.WithAllowedOrigins("https://*.example.com"), apikeys.WithAllowedRoutes("GET /v1/reports/*"), apikeys.WithQuota(100, time.Hour), apikeys.WithIdleTimeout(30*24*time.Hour), apikeys.WithExpiresAt(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ak.CreatedAt = time.Date(2029, 1, 1, 0, 0, 0, 1, time.UTC)
+	ak.LastUsedAt = time.Date(2029, 6, 1, 0, 0, 0, 1, time.UTC)
+	ak.Approval, ak.ReviewedBy = apikeys.ApprovalApproved, "bob"
+	ak.PreviousDerivedKey, ak.PreviousExpiresAt = apikeys.Blob("previous-derived-key"), time.Date(2029, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	// Through the wire format, not just the message
+	b, err := proto.Marshal(ToProto(ak))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec KeyRecord
+	if err := proto.Unmarshal(b, &rec); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromProto(&rec)
+	if err != nil {
+		t.Fatalf("FromProto() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, ak) {
+		t.Errorf("FromProto() = %+v, want %+v", got, ak)
+	}
+
+	store := apikeys.NewMemStore()
+	if err := store.Create(t.Context(), got); err != nil {
+		t.Fatal(err)
+	}
+	v := apikeys.NewStoreVerifier(store)
+	if _, err := v.Verify(t.Context(), apikey); err != nil {
+		t.Errorf("Verify() with converted record error = %v", err)
+	}
+}
+
+func TestKeyFromProto(t *testing.T) {
+	ak, err := apikeys.NewKey(testAlg, apikeys.WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ak.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := KeyFromProto(KeyToProto(ak))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Salt != nil || got.Alg() != ak.Alg() || got.ClientID != ak.ClientID {
+		t.Errorf("KeyFromProto() = %+v", got)
+	}
+	if _, err := KeyFromProto(&Key{Alg: "argon2id 0 1MB 1"}); err == nil {
+		t.Errorf("KeyFromProto() accepted an invalid alg")
+	}
+}
+
+func TestAlgFromProto(t *testing.T) {
+	type args struct {
+		p *Alg
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"happy", args{&Alg{Spec: testAlg, Time: 1, Memory: 16 * 1024, KeyLen: 16, Threads: 1}}, false},
+		{"happy named", args{&Alg{Spec: "argon2id t=1,m=16MB,len=16,p=2", Time: 1, Memory: 16 * 1024, KeyLen: 16, Threads: 2}}, false},
+		{"mismatch", args{&Alg{Spec: testAlg, Time: 2, Memory: 16 * 1024, KeyLen: 16, Threads: 1}}, true},
+		{"threads mismatch", args{&Alg{Spec: testAlg, Time: 1, Memory: 16 * 1024, KeyLen: 16, Threads: 4}}, true},
+		{"bad spec", args{&Alg{Spec: "md5"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := AlgFromProto(tt.args.p); (err != nil) != tt.wantErr {
+				t.Errorf("AlgFromProto() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
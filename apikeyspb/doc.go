@@ -0,0 +1,6 @@
+// Package apikeyspb contains the protobuf definitions and generated grpc
+// stubs for the api key management service, and converters between the
+// messages and apikeys.Key.
+package apikeyspb
+
+//go:generate protoc -I . --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative keys.proto
@@ -0,0 +1,1597 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v3.21.12
+// source: keys.proto
+
+package apikeyspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Key is the stored record for an api key. It never carries the secret.
+type Key struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ClientId string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// alg is the argon2id parameter string, eg "argon2id 3 64MB 32"
+	Alg        string                 `protobuf:"bytes,2,opt,name=alg,proto3" json:"alg,omitempty"`
+	DerivedKey []byte                 `protobuf:"bytes,3,opt,name=derived_key,json=derivedKey,proto3" json:"derived_key,omitempty"`
+	CreatedAt  *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	RevokedAt  *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=revoked_at,json=revokedAt,proto3" json:"revoked_at,omitempty"`
+	ExpiresAt  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// tenant_id is the tenant the key belongs to, if any
+	TenantId    string                 `protobuf:"bytes,7,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	Name        string                 `protobuf:"bytes,8,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                 `protobuf:"bytes,9,opt,name=description,proto3" json:"description,omitempty"`
+	Owner       string                 `protobuf:"bytes,10,opt,name=owner,proto3" json:"owner,omitempty"`
+	Labels      map[string]string      `protobuf:"bytes,11,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	RotatedAt   *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=rotated_at,json=rotatedAt,proto3" json:"rotated_at,omitempty"`
+	// type is service, personal or ephemeral, or empty if unclassified
+	Type string `protobuf:"bytes,13,opt,name=type,proto3" json:"type,omitempty"`
+	// created_by is the principal that created the key
+	CreatedBy          string                 `protobuf:"bytes,14,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	Team               string                 `protobuf:"bytes,15,opt,name=team,proto3" json:"team,omitempty"`
+	Restrictions       *Restrictions          `protobuf:"bytes,16,opt,name=restrictions,proto3" json:"restrictions,omitempty"`
+	LastUsedAt         *timestamppb.Timestamp `protobuf:"bytes,17,opt,name=last_used_at,json=lastUsedAt,proto3" json:"last_used_at,omitempty"`
+	IdleTimeoutSeconds int64                  `protobuf:"varint,18,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"`
+	// approval is pending, approved or rejected for keys that required
+	// approval, and reviewed_by the principal that reviewed them
+	Approval   string `protobuf:"bytes,19,opt,name=approval,proto3" json:"approval,omitempty"`
+	ReviewedBy string `protobuf:"bytes,20,opt,name=reviewed_by,json=reviewedBy,proto3" json:"reviewed_by,omitempty"`
+	// previous_expires_at is set while the secret replaced by a graceful
+	// rotation still verifies
+	PreviousExpiresAt *timestamppb.Timestamp `protobuf:"bytes,21,opt,name=previous_expires_at,json=previousExpiresAt,proto3" json:"previous_expires_at,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Key) Reset() {
+	*x = Key{}
+	mi := &file_keys_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Key) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Key) ProtoMessage() {}
+
+func (x *Key) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Key.ProtoReflect.Descriptor instead.
+func (*Key) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Key) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *Key) GetAlg() string {
+	if x != nil {
+		return x.Alg
+	}
+	return ""
+}
+
+func (x *Key) GetDerivedKey() []byte {
+	if x != nil {
+		return x.DerivedKey
+	}
+	return nil
+}
+
+func (x *Key) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Key) GetRevokedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RevokedAt
+	}
+	return nil
+}
+
+func (x *Key) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Key) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *Key) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Key) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Key) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *Key) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Key) GetRotatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RotatedAt
+	}
+	return nil
+}
+
+func (x *Key) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Key) GetCreatedBy() string {
+	if x != nil {
+		return x.CreatedBy
+	}
+	return ""
+}
+
+func (x *Key) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *Key) GetRestrictions() *Restrictions {
+	if x != nil {
+		return x.Restrictions
+	}
+	return nil
+}
+
+func (x *Key) GetLastUsedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUsedAt
+	}
+	return nil
+}
+
+func (x *Key) GetIdleTimeoutSeconds() int64 {
+	if x != nil {
+		return x.IdleTimeoutSeconds
+	}
+	return 0
+}
+
+func (x *Key) GetApproval() string {
+	if x != nil {
+		return x.Approval
+	}
+	return ""
+}
+
+func (x *Key) GetReviewedBy() string {
+	if x != nil {
+		return x.ReviewedBy
+	}
+	return ""
+}
+
+func (x *Key) GetPreviousExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PreviousExpiresAt
+	}
+	return nil
+}
+
+// Restrictions limit where a key may be used. Empty lists allow everything.
+type Restrictions struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AllowedCidrs   []string               `protobuf:"bytes,1,rep,name=allowed_cidrs,json=allowedCidrs,proto3" json:"allowed_cidrs,omitempty"`
+	AllowedOrigins []string               `protobuf:"bytes,2,rep,name=allowed_origins,json=allowedOrigins,proto3" json:"allowed_origins,omitempty"`
+	AllowedRoutes  []string               `protobuf:"bytes,3,rep,name=allowed_routes,json=allowedRoutes,proto3" json:"allowed_routes,omitempty"`
+	Quota          *Quota                 `protobuf:"bytes,4,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Restrictions) Reset() {
+	*x = Restrictions{}
+	mi := &file_keys_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Restrictions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Restrictions) ProtoMessage() {}
+
+func (x *Restrictions) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Restrictions.ProtoReflect.Descriptor instead.
+func (*Restrictions) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Restrictions) GetAllowedCidrs() []string {
+	if x != nil {
+		return x.AllowedCidrs
+	}
+	return nil
+}
+
+func (x *Restrictions) GetAllowedOrigins() []string {
+	if x != nil {
+		return x.AllowedOrigins
+	}
+	return nil
+}
+
+func (x *Restrictions) GetAllowedRoutes() []string {
+	if x != nil {
+		return x.AllowedRoutes
+	}
+	return nil
+}
+
+func (x *Restrictions) GetQuota() *Quota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+// Quota limits a key to limit requests in each window of window_seconds.
+type Quota struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int64                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	WindowSeconds int64                  `protobuf:"varint,2,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Quota) Reset() {
+	*x = Quota{}
+	mi := &file_keys_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Quota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quota) ProtoMessage() {}
+
+func (x *Quota) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quota.ProtoReflect.Descriptor instead.
+func (*Quota) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Quota) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *Quota) GetWindowSeconds() int64 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+// Alg is an argon2id parameter set.
+type Alg struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// spec is the parameter string the other fields are parsed from
+	Spec string `protobuf:"bytes,1,opt,name=spec,proto3" json:"spec,omitempty"`
+	Time uint32 `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	// memory is in KiB
+	Memory        uint32 `protobuf:"varint,3,opt,name=memory,proto3" json:"memory,omitempty"`
+	KeyLen        uint32 `protobuf:"varint,4,opt,name=key_len,json=keyLen,proto3" json:"key_len,omitempty"`
+	Threads       uint32 `protobuf:"varint,5,opt,name=threads,proto3" json:"threads,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Alg) Reset() {
+	*x = Alg{}
+	mi := &file_keys_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Alg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alg) ProtoMessage() {}
+
+func (x *Alg) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alg.ProtoReflect.Descriptor instead.
+func (*Alg) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Alg) GetSpec() string {
+	if x != nil {
+		return x.Spec
+	}
+	return ""
+}
+
+func (x *Alg) GetTime() uint32 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Alg) GetMemory() uint32 {
+	if x != nil {
+		return x.Memory
+	}
+	return 0
+}
+
+func (x *Alg) GetKeyLen() uint32 {
+	if x != nil {
+		return x.KeyLen
+	}
+	return 0
+}
+
+func (x *Alg) GetThreads() uint32 {
+	if x != nil {
+		return x.Threads
+	}
+	return 0
+}
+
+// KeyRecord is everything a store holds for a key, including the salt, so it
+// carries an apikeys.Key losslessly. It is for replication and backup between
+// trusted services and must not be returned to api clients.
+type KeyRecord struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	ClientId   string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Alg        *Alg                   `protobuf:"bytes,2,opt,name=alg,proto3" json:"alg,omitempty"`
+	Salt       []byte                 `protobuf:"bytes,3,opt,name=salt,proto3" json:"salt,omitempty"`
+	DerivedKey []byte                 `protobuf:"bytes,4,opt,name=derived_key,json=derivedKey,proto3" json:"derived_key,omitempty"`
+	CreatedAt  *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	RevokedAt  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=revoked_at,json=revokedAt,proto3" json:"revoked_at,omitempty"`
+	ExpiresAt  *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// imported_hash is set for records imported from another system until
+	// they are upgraded
+	ImportedHash       string                 `protobuf:"bytes,8,opt,name=imported_hash,json=importedHash,proto3" json:"imported_hash,omitempty"`
+	TenantId           string                 `protobuf:"bytes,9,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	Name               string                 `protobuf:"bytes,10,opt,name=name,proto3" json:"name,omitempty"`
+	Description        string                 `protobuf:"bytes,11,opt,name=description,proto3" json:"description,omitempty"`
+	Owner              string                 `protobuf:"bytes,12,opt,name=owner,proto3" json:"owner,omitempty"`
+	Labels             map[string]string      `protobuf:"bytes,13,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	RotatedAt          *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=rotated_at,json=rotatedAt,proto3" json:"rotated_at,omitempty"`
+	Type               string                 `protobuf:"bytes,15,opt,name=type,proto3" json:"type,omitempty"`
+	CreatedBy          string                 `protobuf:"bytes,16,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	Team               string                 `protobuf:"bytes,17,opt,name=team,proto3" json:"team,omitempty"`
+	Restrictions       *Restrictions          `protobuf:"bytes,18,opt,name=restrictions,proto3" json:"restrictions,omitempty"`
+	LastUsedAt         *timestamppb.Timestamp `protobuf:"bytes,19,opt,name=last_used_at,json=lastUsedAt,proto3" json:"last_used_at,omitempty"`
+	IdleTimeoutSeconds int64                  `protobuf:"varint,20,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"`
+	Approval           string                 `protobuf:"bytes,21,opt,name=approval,proto3" json:"approval,omitempty"`
+	ReviewedBy         string                 `protobuf:"bytes,22,opt,name=reviewed_by,json=reviewedBy,proto3" json:"reviewed_by,omitempty"`
+	PreviousDerivedKey []byte                 `protobuf:"bytes,23,opt,name=previous_derived_key,json=previousDerivedKey,proto3" json:"previous_derived_key,omitempty"`
+	PreviousExpiresAt  *timestamppb.Timestamp `protobuf:"bytes,24,opt,name=previous_expires_at,json=previousExpiresAt,proto3" json:"previous_expires_at,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *KeyRecord) Reset() {
+	*x = KeyRecord{}
+	mi := &file_keys_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyRecord) ProtoMessage() {}
+
+func (x *KeyRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyRecord.ProtoReflect.Descriptor instead.
+func (*KeyRecord) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *KeyRecord) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetAlg() *Alg {
+	if x != nil {
+		return x.Alg
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetSalt() []byte {
+	if x != nil {
+		return x.Salt
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetDerivedKey() []byte {
+	if x != nil {
+		return x.DerivedKey
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetRevokedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RevokedAt
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetImportedHash() string {
+	if x != nil {
+		return x.ImportedHash
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetRotatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RotatedAt
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetCreatedBy() string {
+	if x != nil {
+		return x.CreatedBy
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetRestrictions() *Restrictions {
+	if x != nil {
+		return x.Restrictions
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetLastUsedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUsedAt
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetIdleTimeoutSeconds() int64 {
+	if x != nil {
+		return x.IdleTimeoutSeconds
+	}
+	return 0
+}
+
+func (x *KeyRecord) GetApproval() string {
+	if x != nil {
+		return x.Approval
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetReviewedBy() string {
+	if x != nil {
+		return x.ReviewedBy
+	}
+	return ""
+}
+
+func (x *KeyRecord) GetPreviousDerivedKey() []byte {
+	if x != nil {
+		return x.PreviousDerivedKey
+	}
+	return nil
+}
+
+func (x *KeyRecord) GetPreviousExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PreviousExpiresAt
+	}
+	return nil
+}
+
+type CreateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// alg defaults to the package StandardAlg if empty
+	Alg string `protobuf:"bytes,1,opt,name=alg,proto3" json:"alg,omitempty"`
+	// client_id is generated if empty
+	ClientId     string            `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	TenantId     string            `protobuf:"bytes,3,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	Name         string            `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Description  string            `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	Owner        string            `protobuf:"bytes,6,opt,name=owner,proto3" json:"owner,omitempty"`
+	Labels       map[string]string `protobuf:"bytes,7,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Type         string            `protobuf:"bytes,8,opt,name=type,proto3" json:"type,omitempty"`
+	Team         string            `protobuf:"bytes,9,opt,name=team,proto3" json:"team,omitempty"`
+	Restrictions *Restrictions     `protobuf:"bytes,10,opt,name=restrictions,proto3" json:"restrictions,omitempty"`
+	// idle_timeout_seconds expires the key once unused for that long
+	IdleTimeoutSeconds int64 `protobuf:"varint,11,opt,name=idle_timeout_seconds,json=idleTimeoutSeconds,proto3" json:"idle_timeout_seconds,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CreateRequest) Reset() {
+	*x = CreateRequest{}
+	mi := &file_keys_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRequest) ProtoMessage() {}
+
+func (x *CreateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRequest.ProtoReflect.Descriptor instead.
+func (*CreateRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateRequest) GetAlg() string {
+	if x != nil {
+		return x.Alg
+	}
+	return ""
+}
+
+func (x *CreateRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *CreateRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *CreateRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreateRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *CreateRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *CreateRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CreateRequest) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *CreateRequest) GetRestrictions() *Restrictions {
+	if x != nil {
+		return x.Restrictions
+	}
+	return nil
+}
+
+func (x *CreateRequest) GetIdleTimeoutSeconds() int64 {
+	if x != nil {
+		return x.IdleTimeoutSeconds
+	}
+	return 0
+}
+
+type CreateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ApiKey        string                 `protobuf:"bytes,1,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	Key           *Key                   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateResponse) Reset() {
+	*x = CreateResponse{}
+	mi := &file_keys_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateResponse) ProtoMessage() {}
+
+func (x *CreateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateResponse.ProtoReflect.Descriptor instead.
+func (*CreateResponse) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateResponse) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+func (x *CreateResponse) GetKey() *Key {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+type GetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_keys_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+// ListRequest filters the keys listed. Empty fields match everything, and a
+// key must have all of the labels.
+type ListRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Name     string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Owner    string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	Labels   map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Type     string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Team     string                 `protobuf:"bytes,5,opt,name=team,proto3" json:"team,omitempty"`
+	Approval string                 `protobuf:"bytes,6,opt,name=approval,proto3" json:"approval,omitempty"`
+	TenantId string                 `protobuf:"bytes,7,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	// created_before matches keys created before it
+	CreatedBefore *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`
+	// weaker_than is an alg, matching keys whose alg is weaker, see
+	// apikeys.WeakerAlgFilter
+	WeakerThan    string `protobuf:"bytes,9,opt,name=weaker_than,json=weakerThan,proto3" json:"weaker_than,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_keys_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ListRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *ListRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ListRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ListRequest) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *ListRequest) GetApproval() string {
+	if x != nil {
+		return x.Approval
+	}
+	return ""
+}
+
+func (x *ListRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *ListRequest) GetCreatedBefore() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return nil
+}
+
+func (x *ListRequest) GetWeakerThan() string {
+	if x != nil {
+		return x.WeakerThan
+	}
+	return ""
+}
+
+type ListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []*Key                 `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_keys_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListResponse) GetKeys() []*Key {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type RevokeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeRequest) Reset() {
+	*x = RevokeRequest{}
+	mi := &file_keys_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeRequest) ProtoMessage() {}
+
+func (x *RevokeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeRequest.ProtoReflect.Descriptor instead.
+func (*RevokeRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RevokeRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+type RotateRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ClientId string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// alg defaults to the alg of the existing key if empty
+	Alg string `protobuf:"bytes,2,opt,name=alg,proto3" json:"alg,omitempty"`
+	// grace_seconds keeps the previous secret verifying for that long
+	GraceSeconds  int64 `protobuf:"varint,3,opt,name=grace_seconds,json=graceSeconds,proto3" json:"grace_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateRequest) Reset() {
+	*x = RotateRequest{}
+	mi := &file_keys_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateRequest) ProtoMessage() {}
+
+func (x *RotateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateRequest.ProtoReflect.Descriptor instead.
+func (*RotateRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RotateRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *RotateRequest) GetAlg() string {
+	if x != nil {
+		return x.Alg
+	}
+	return ""
+}
+
+func (x *RotateRequest) GetGraceSeconds() int64 {
+	if x != nil {
+		return x.GraceSeconds
+	}
+	return 0
+}
+
+type VerifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ApiKey        string                 `protobuf:"bytes,1,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyRequest) Reset() {
+	*x = VerifyRequest{}
+	mi := &file_keys_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRequest) ProtoMessage() {}
+
+func (x *VerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *VerifyRequest) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+type ApproveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveRequest) Reset() {
+	*x = ApproveRequest{}
+	mi := &file_keys_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveRequest) ProtoMessage() {}
+
+func (x *ApproveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveRequest.ProtoReflect.Descriptor instead.
+func (*ApproveRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ApproveRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+type RejectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RejectRequest) Reset() {
+	*x = RejectRequest{}
+	mi := &file_keys_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RejectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectRequest) ProtoMessage() {}
+
+func (x *RejectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectRequest.ProtoReflect.Descriptor instead.
+func (*RejectRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RejectRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+type FinalizeRotationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ClientId      string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FinalizeRotationRequest) Reset() {
+	*x = FinalizeRotationRequest{}
+	mi := &file_keys_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FinalizeRotationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FinalizeRotationRequest) ProtoMessage() {}
+
+func (x *FinalizeRotationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keys_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FinalizeRotationRequest.ProtoReflect.Descriptor instead.
+func (*FinalizeRotationRequest) Descriptor() ([]byte, []int) {
+	return file_keys_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *FinalizeRotationRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+var File_keys_proto protoreflect.FileDescriptor
+
+const file_keys_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"keys.proto\x12\n" +
+	"apikeys.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x98\a\n" +
+	"\x03Key\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x10\n" +
+	"\x03alg\x18\x02 \x01(\tR\x03alg\x12\x1f\n" +
+	"\vderived_key\x18\x03 \x01(\fR\n" +
+	"derivedKey\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"revoked_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\trevokedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x1b\n" +
+	"\ttenant_id\x18\a \x01(\tR\btenantId\x12\x12\n" +
+	"\x04name\x18\b \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\t \x01(\tR\vdescription\x12\x14\n" +
+	"\x05owner\x18\n" +
+	" \x01(\tR\x05owner\x123\n" +
+	"\x06labels\x18\v \x03(\v2\x1b.apikeys.v1.Key.LabelsEntryR\x06labels\x129\n" +
+	"\n" +
+	"rotated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\trotatedAt\x12\x12\n" +
+	"\x04type\x18\r \x01(\tR\x04type\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\x0e \x01(\tR\tcreatedBy\x12\x12\n" +
+	"\x04team\x18\x0f \x01(\tR\x04team\x12<\n" +
+	"\frestrictions\x18\x10 \x01(\v2\x18.apikeys.v1.RestrictionsR\frestrictions\x12<\n" +
+	"\flast_used_at\x18\x11 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastUsedAt\x120\n" +
+	"\x14idle_timeout_seconds\x18\x12 \x01(\x03R\x12idleTimeoutSeconds\x12\x1a\n" +
+	"\bapproval\x18\x13 \x01(\tR\bapproval\x12\x1f\n" +
+	"\vreviewed_by\x18\x14 \x01(\tR\n" +
+	"reviewedBy\x12J\n" +
+	"\x13previous_expires_at\x18\x15 \x01(\v2\x1a.google.protobuf.TimestampR\x11previousExpiresAt\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xac\x01\n" +
+	"\fRestrictions\x12#\n" +
+	"\rallowed_cidrs\x18\x01 \x03(\tR\fallowedCidrs\x12'\n" +
+	"\x0fallowed_origins\x18\x02 \x03(\tR\x0eallowedOrigins\x12%\n" +
+	"\x0eallowed_routes\x18\x03 \x03(\tR\rallowedRoutes\x12'\n" +
+	"\x05quota\x18\x04 \x01(\v2\x11.apikeys.v1.QuotaR\x05quota\"D\n" +
+	"\x05Quota\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x03R\x05limit\x12%\n" +
+	"\x0ewindow_seconds\x18\x02 \x01(\x03R\rwindowSeconds\"x\n" +
+	"\x03Alg\x12\x12\n" +
+	"\x04spec\x18\x01 \x01(\tR\x04spec\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\rR\x04time\x12\x16\n" +
+	"\x06memory\x18\x03 \x01(\rR\x06memory\x12\x17\n" +
+	"\akey_len\x18\x04 \x01(\rR\x06keyLen\x12\x18\n" +
+	"\athreads\x18\x05 \x01(\rR\athreads\"\xa0\b\n" +
+	"\tKeyRecord\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12!\n" +
+	"\x03alg\x18\x02 \x01(\v2\x0f.apikeys.v1.AlgR\x03alg\x12\x12\n" +
+	"\x04salt\x18\x03 \x01(\fR\x04salt\x12\x1f\n" +
+	"\vderived_key\x18\x04 \x01(\fR\n" +
+	"derivedKey\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"revoked_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\trevokedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12#\n" +
+	"\rimported_hash\x18\b \x01(\tR\fimportedHash\x12\x1b\n" +
+	"\ttenant_id\x18\t \x01(\tR\btenantId\x12\x12\n" +
+	"\x04name\x18\n" +
+	" \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\v \x01(\tR\vdescription\x12\x14\n" +
+	"\x05owner\x18\f \x01(\tR\x05owner\x129\n" +
+	"\x06labels\x18\r \x03(\v2!.apikeys.v1.KeyRecord.LabelsEntryR\x06labels\x129\n" +
+	"\n" +
+	"rotated_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\trotatedAt\x12\x12\n" +
+	"\x04type\x18\x0f \x01(\tR\x04type\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\x10 \x01(\tR\tcreatedBy\x12\x12\n" +
+	"\x04team\x18\x11 \x01(\tR\x04team\x12<\n" +
+	"\frestrictions\x18\x12 \x01(\v2\x18.apikeys.v1.RestrictionsR\frestrictions\x12<\n" +
+	"\flast_used_at\x18\x13 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastUsedAt\x120\n" +
+	"\x14idle_timeout_seconds\x18\x14 \x01(\x03R\x12idleTimeoutSeconds\x12\x1a\n" +
+	"\bapproval\x18\x15 \x01(\tR\bapproval\x12\x1f\n" +
+	"\vreviewed_by\x18\x16 \x01(\tR\n" +
+	"reviewedBy\x120\n" +
+	"\x14previous_derived_key\x18\x17 \x01(\fR\x12previousDerivedKey\x12J\n" +
+	"\x13previous_expires_at\x18\x18 \x01(\v2\x1a.google.protobuf.TimestampR\x11previousExpiresAt\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb9\x03\n" +
+	"\rCreateRequest\x12\x10\n" +
+	"\x03alg\x18\x01 \x01(\tR\x03alg\x12\x1b\n" +
+	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12\x1b\n" +
+	"\ttenant_id\x18\x03 \x01(\tR\btenantId\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x05 \x01(\tR\vdescription\x12\x14\n" +
+	"\x05owner\x18\x06 \x01(\tR\x05owner\x12=\n" +
+	"\x06labels\x18\a \x03(\v2%.apikeys.v1.CreateRequest.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04type\x18\b \x01(\tR\x04type\x12\x12\n" +
+	"\x04team\x18\t \x01(\tR\x04team\x12<\n" +
+	"\frestrictions\x18\n" +
+	" \x01(\v2\x18.apikeys.v1.RestrictionsR\frestrictions\x120\n" +
+	"\x14idle_timeout_seconds\x18\v \x01(\x03R\x12idleTimeoutSeconds\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"L\n" +
+	"\x0eCreateResponse\x12\x17\n" +
+	"\aapi_key\x18\x01 \x01(\tR\x06apiKey\x12!\n" +
+	"\x03key\x18\x02 \x01(\v2\x0f.apikeys.v1.KeyR\x03key\")\n" +
+	"\n" +
+	"GetRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\"\xf4\x02\n" +
+	"\vListRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\x12;\n" +
+	"\x06labels\x18\x03 \x03(\v2#.apikeys.v1.ListRequest.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x12\n" +
+	"\x04team\x18\x05 \x01(\tR\x04team\x12\x1a\n" +
+	"\bapproval\x18\x06 \x01(\tR\bapproval\x12\x1b\n" +
+	"\ttenant_id\x18\a \x01(\tR\btenantId\x12A\n" +
+	"\x0ecreated_before\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\rcreatedBefore\x12\x1f\n" +
+	"\vweaker_than\x18\t \x01(\tR\n" +
+	"weakerThan\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"3\n" +
+	"\fListResponse\x12#\n" +
+	"\x04keys\x18\x01 \x03(\v2\x0f.apikeys.v1.KeyR\x04keys\",\n" +
+	"\rRevokeRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\"c\n" +
+	"\rRotateRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12\x10\n" +
+	"\x03alg\x18\x02 \x01(\tR\x03alg\x12#\n" +
+	"\rgrace_seconds\x18\x03 \x01(\x03R\fgraceSeconds\"(\n" +
+	"\rVerifyRequest\x12\x17\n" +
+	"\aapi_key\x18\x01 \x01(\tR\x06apiKey\"-\n" +
+	"\x0eApproveRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\",\n" +
+	"\rRejectRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\"6\n" +
+	"\x17FinalizeRotationRequest\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId2\x9e\x04\n" +
+	"\vKeysService\x12?\n" +
+	"\x06Create\x12\x19.apikeys.v1.CreateRequest\x1a\x1a.apikeys.v1.CreateResponse\x12.\n" +
+	"\x03Get\x12\x16.apikeys.v1.GetRequest\x1a\x0f.apikeys.v1.Key\x129\n" +
+	"\x04List\x12\x17.apikeys.v1.ListRequest\x1a\x18.apikeys.v1.ListResponse\x124\n" +
+	"\x06Revoke\x12\x19.apikeys.v1.RevokeRequest\x1a\x0f.apikeys.v1.Key\x12?\n" +
+	"\x06Rotate\x12\x19.apikeys.v1.RotateRequest\x1a\x1a.apikeys.v1.CreateResponse\x12H\n" +
+	"\x10FinalizeRotation\x12#.apikeys.v1.FinalizeRotationRequest\x1a\x0f.apikeys.v1.Key\x124\n" +
+	"\x06Verify\x12\x19.apikeys.v1.VerifyRequest\x1a\x0f.apikeys.v1.Key\x126\n" +
+	"\aApprove\x12\x1a.apikeys.v1.ApproveRequest\x1a\x0f.apikeys.v1.Key\x124\n" +
+	"\x06Reject\x12\x19.apikeys.v1.RejectRequest\x1a\x0f.apikeys.v1.KeyB)Z'github.com/robinbryce/apikeys/apikeyspbb\x06proto3"
+
+var (
+	file_keys_proto_rawDescOnce sync.Once
+	file_keys_proto_rawDescData []byte
+)
+
+func file_keys_proto_rawDescGZIP() []byte {
+	file_keys_proto_rawDescOnce.Do(func() {
+		file_keys_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_keys_proto_rawDesc), len(file_keys_proto_rawDesc)))
+	})
+	return file_keys_proto_rawDescData
+}
+
+var file_keys_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_keys_proto_goTypes = []any{
+	(*Key)(nil),                     // 0: apikeys.v1.Key
+	(*Restrictions)(nil),            // 1: apikeys.v1.Restrictions
+	(*Quota)(nil),                   // 2: apikeys.v1.Quota
+	(*Alg)(nil),                     // 3: apikeys.v1.Alg
+	(*KeyRecord)(nil),               // 4: apikeys.v1.KeyRecord
+	(*CreateRequest)(nil),           // 5: apikeys.v1.CreateRequest
+	(*CreateResponse)(nil),          // 6: apikeys.v1.CreateResponse
+	(*GetRequest)(nil),              // 7: apikeys.v1.GetRequest
+	(*ListRequest)(nil),             // 8: apikeys.v1.ListRequest
+	(*ListResponse)(nil),            // 9: apikeys.v1.ListResponse
+	(*RevokeRequest)(nil),           // 10: apikeys.v1.RevokeRequest
+	(*RotateRequest)(nil),           // 11: apikeys.v1.RotateRequest
+	(*VerifyRequest)(nil),           // 12: apikeys.v1.VerifyRequest
+	(*ApproveRequest)(nil),          // 13: apikeys.v1.ApproveRequest
+	(*RejectRequest)(nil),           // 14: apikeys.v1.RejectRequest
+	(*FinalizeRotationRequest)(nil), // 15: apikeys.v1.FinalizeRotationRequest
+	nil,                             // 16: apikeys.v1.Key.LabelsEntry
+	nil,                             // 17: apikeys.v1.KeyRecord.LabelsEntry
+	nil,                             // 18: apikeys.v1.CreateRequest.LabelsEntry
+	nil,                             // 19: apikeys.v1.ListRequest.LabelsEntry
+	(*timestamppb.Timestamp)(nil),   // 20: google.protobuf.Timestamp
+}
+var file_keys_proto_depIdxs = []int32{
+	20, // 0: apikeys.v1.Key.created_at:type_name -> google.protobuf.Timestamp
+	20, // 1: apikeys.v1.Key.revoked_at:type_name -> google.protobuf.Timestamp
+	20, // 2: apikeys.v1.Key.expires_at:type_name -> google.protobuf.Timestamp
+	16, // 3: apikeys.v1.Key.labels:type_name -> apikeys.v1.Key.LabelsEntry
+	20, // 4: apikeys.v1.Key.rotated_at:type_name -> google.protobuf.Timestamp
+	1,  // 5: apikeys.v1.Key.restrictions:type_name -> apikeys.v1.Restrictions
+	20, // 6: apikeys.v1.Key.last_used_at:type_name -> google.protobuf.Timestamp
+	20, // 7: apikeys.v1.Key.previous_expires_at:type_name -> google.protobuf.Timestamp
+	2,  // 8: apikeys.v1.Restrictions.quota:type_name -> apikeys.v1.Quota
+	3,  // 9: apikeys.v1.KeyRecord.alg:type_name -> apikeys.v1.Alg
+	20, // 10: apikeys.v1.KeyRecord.created_at:type_name -> google.protobuf.Timestamp
+	20, // 11: apikeys.v1.KeyRecord.revoked_at:type_name -> google.protobuf.Timestamp
+	20, // 12: apikeys.v1.KeyRecord.expires_at:type_name -> google.protobuf.Timestamp
+	17, // 13: apikeys.v1.KeyRecord.labels:type_name -> apikeys.v1.KeyRecord.LabelsEntry
+	20, // 14: apikeys.v1.KeyRecord.rotated_at:type_name -> google.protobuf.Timestamp
+	1,  // 15: apikeys.v1.KeyRecord.restrictions:type_name -> apikeys.v1.Restrictions
+	20, // 16: apikeys.v1.KeyRecord.last_used_at:type_name -> google.protobuf.Timestamp
+	20, // 17: apikeys.v1.KeyRecord.previous_expires_at:type_name -> google.protobuf.Timestamp
+	18, // 18: apikeys.v1.CreateRequest.labels:type_name -> apikeys.v1.CreateRequest.LabelsEntry
+	1,  // 19: apikeys.v1.CreateRequest.restrictions:type_name -> apikeys.v1.Restrictions
+	0,  // 20: apikeys.v1.CreateResponse.key:type_name -> apikeys.v1.Key
+	19, // 21: apikeys.v1.ListRequest.labels:type_name -> apikeys.v1.ListRequest.LabelsEntry
+	20, // 22: apikeys.v1.ListRequest.created_before:type_name -> google.protobuf.Timestamp
+	0,  // 23: apikeys.v1.ListResponse.keys:type_name -> apikeys.v1.Key
+	5,  // 24: apikeys.v1.KeysService.Create:input_type -> apikeys.v1.CreateRequest
+	7,  // 25: apikeys.v1.KeysService.Get:input_type -> apikeys.v1.GetRequest
+	8,  // 26: apikeys.v1.KeysService.List:input_type -> apikeys.v1.ListRequest
+	10, // 27: apikeys.v1.KeysService.Revoke:input_type -> apikeys.v1.RevokeRequest
+	11, // 28: apikeys.v1.KeysService.Rotate:input_type -> apikeys.v1.RotateRequest
+	15, // 29: apikeys.v1.KeysService.FinalizeRotation:input_type -> apikeys.v1.FinalizeRotationRequest
+	12, // 30: apikeys.v1.KeysService.Verify:input_type -> apikeys.v1.VerifyRequest
+	13, // 31: apikeys.v1.KeysService.Approve:input_type -> apikeys.v1.ApproveRequest
+	14, // 32: apikeys.v1.KeysService.Reject:input_type -> apikeys.v1.RejectRequest
+	6,  // 33: apikeys.v1.KeysService.Create:output_type -> apikeys.v1.CreateResponse
+	0,  // 34: apikeys.v1.KeysService.Get:output_type -> apikeys.v1.Key
+	9,  // 35: apikeys.v1.KeysService.List:output_type -> apikeys.v1.ListResponse
+	0,  // 36: apikeys.v1.KeysService.Revoke:output_type -> apikeys.v1.Key
+	6,  // 37: apikeys.v1.KeysService.Rotate:output_type -> apikeys.v1.CreateResponse
+	0,  // 38: apikeys.v1.KeysService.FinalizeRotation:output_type -> apikeys.v1.Key
+	0,  // 39: apikeys.v1.KeysService.Verify:output_type -> apikeys.v1.Key
+	0,  // 40: apikeys.v1.KeysService.Approve:output_type -> apikeys.v1.Key
+	0,  // 41: apikeys.v1.KeysService.Reject:output_type -> apikeys.v1.Key
+	33, // [33:42] is the sub-list for method output_type
+	24, // [24:33] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
+}
+
+func init() { file_keys_proto_init() }
+func file_keys_proto_init() {
+	if File_keys_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_keys_proto_rawDesc), len(file_keys_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_keys_proto_goTypes,
+		DependencyIndexes: file_keys_proto_depIdxs,
+		MessageInfos:      file_keys_proto_msgTypes,
+	}.Build()
+	File_keys_proto = out.File
+	file_keys_proto_goTypes = nil
+	file_keys_proto_depIdxs = nil
+}
@@ -0,0 +1,447 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v3.21.12
+// source: keys.proto
+
+package apikeyspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	KeysService_Create_FullMethodName           = "/apikeys.v1.KeysService/Create"
+	KeysService_Get_FullMethodName              = "/apikeys.v1.KeysService/Get"
+	KeysService_List_FullMethodName             = "/apikeys.v1.KeysService/List"
+	KeysService_Revoke_FullMethodName           = "/apikeys.v1.KeysService/Revoke"
+	KeysService_Rotate_FullMethodName           = "/apikeys.v1.KeysService/Rotate"
+	KeysService_FinalizeRotation_FullMethodName = "/apikeys.v1.KeysService/FinalizeRotation"
+	KeysService_Verify_FullMethodName           = "/apikeys.v1.KeysService/Verify"
+	KeysService_Approve_FullMethodName          = "/apikeys.v1.KeysService/Approve"
+	KeysService_Reject_FullMethodName           = "/apikeys.v1.KeysService/Reject"
+)
+
+// KeysServiceClient is the client API for KeysService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// KeysService manages api keys held in a store.
+type KeysServiceClient interface {
+	// Create generates a new key. The api_key in the response is the only copy
+	// of the secret.
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateResponse, error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Key, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*Key, error)
+	// Rotate replaces the secret for an existing client id. With a
+	// grace_seconds the previous secret verifies for that long.
+	Rotate(ctx context.Context, in *RotateRequest, opts ...grpc.CallOption) (*CreateResponse, error)
+	// FinalizeRotation stops the previous secret of a graceful rotation
+	// verifying.
+	FinalizeRotation(ctx context.Context, in *FinalizeRotationRequest, opts ...grpc.CallOption) (*Key, error)
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*Key, error)
+	// Approve lets a key created pending approval verify. The reviewer must
+	// not be the key's creator.
+	Approve(ctx context.Context, in *ApproveRequest, opts ...grpc.CallOption) (*Key, error)
+	// Reject revokes a key pending approval.
+	Reject(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (*Key, error)
+}
+
+type keysServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKeysServiceClient(cc grpc.ClientConnInterface) KeysServiceClient {
+	return &keysServiceClient{cc}
+}
+
+func (c *keysServiceClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*CreateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateResponse)
+	err := c.cc.Invoke(ctx, KeysService_Create_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keysServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Key, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Key)
+	err := c.cc.Invoke(ctx, KeysService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keysServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, KeysService_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keysServiceClient) Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*Key, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Key)
+	err := c.cc.Invoke(ctx, KeysService_Revoke_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keysServiceClient) Rotate(ctx context.Context, in *RotateRequest, opts ...grpc.CallOption) (*CreateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateResponse)
+	err := c.cc.Invoke(ctx, KeysService_Rotate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keysServiceClient) FinalizeRotation(ctx context.Context, in *FinalizeRotationRequest, opts ...grpc.CallOption) (*Key, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Key)
+	err := c.cc.Invoke(ctx, KeysService_FinalizeRotation_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keysServiceClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*Key, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Key)
+	err := c.cc.Invoke(ctx, KeysService_Verify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keysServiceClient) Approve(ctx context.Context, in *ApproveRequest, opts ...grpc.CallOption) (*Key, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Key)
+	err := c.cc.Invoke(ctx, KeysService_Approve_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keysServiceClient) Reject(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (*Key, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Key)
+	err := c.cc.Invoke(ctx, KeysService_Reject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// KeysServiceServer is the server API for KeysService service.
+// All implementations must embed UnimplementedKeysServiceServer
+// for forward compatibility.
+//
+// KeysService manages api keys held in a store.
+type KeysServiceServer interface {
+	// Create generates a new key. The api_key in the response is the only copy
+	// of the secret.
+	Create(context.Context, *CreateRequest) (*CreateResponse, error)
+	Get(context.Context, *GetRequest) (*Key, error)
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	Revoke(context.Context, *RevokeRequest) (*Key, error)
+	// Rotate replaces the secret for an existing client id. With a
+	// grace_seconds the previous secret verifies for that long.
+	Rotate(context.Context, *RotateRequest) (*CreateResponse, error)
+	// FinalizeRotation stops the previous secret of a graceful rotation
+	// verifying.
+	FinalizeRotation(context.Context, *FinalizeRotationRequest) (*Key, error)
+	Verify(context.Context, *VerifyRequest) (*Key, error)
+	// Approve lets a key created pending approval verify. The reviewer must
+	// not be the key's creator.
+	Approve(context.Context, *ApproveRequest) (*Key, error)
+	// Reject revokes a key pending approval.
+	Reject(context.Context, *RejectRequest) (*Key, error)
+	mustEmbedUnimplementedKeysServiceServer()
+}
+
+// UnimplementedKeysServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedKeysServiceServer struct{}
+
+func (UnimplementedKeysServiceServer) Create(context.Context, *CreateRequest) (*CreateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedKeysServiceServer) Get(context.Context, *GetRequest) (*Key, error) {
+	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedKeysServiceServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedKeysServiceServer) Revoke(context.Context, *RevokeRequest) (*Key, error) {
+	return nil, status.Error(codes.Unimplemented, "method Revoke not implemented")
+}
+func (UnimplementedKeysServiceServer) Rotate(context.Context, *RotateRequest) (*CreateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Rotate not implemented")
+}
+func (UnimplementedKeysServiceServer) FinalizeRotation(context.Context, *FinalizeRotationRequest) (*Key, error) {
+	return nil, status.Error(codes.Unimplemented, "method FinalizeRotation not implemented")
+}
+func (UnimplementedKeysServiceServer) Verify(context.Context, *VerifyRequest) (*Key, error) {
+	return nil, status.Error(codes.Unimplemented, "method Verify not implemented")
+}
+func (UnimplementedKeysServiceServer) Approve(context.Context, *ApproveRequest) (*Key, error) {
+	return nil, status.Error(codes.Unimplemented, "method Approve not implemented")
+}
+func (UnimplementedKeysServiceServer) Reject(context.Context, *RejectRequest) (*Key, error) {
+	return nil, status.Error(codes.Unimplemented, "method Reject not implemented")
+}
+func (UnimplementedKeysServiceServer) mustEmbedUnimplementedKeysServiceServer() {}
+func (UnimplementedKeysServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeKeysServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KeysServiceServer will
+// result in compilation errors.
+type UnsafeKeysServiceServer interface {
+	mustEmbedUnimplementedKeysServiceServer()
+}
+
+func RegisterKeysServiceServer(s grpc.ServiceRegistrar, srv KeysServiceServer) {
+	// If the following call panics, it indicates UnimplementedKeysServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&KeysService_ServiceDesc, srv)
+}
+
+func _KeysService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeysService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeysService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeysService_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_Revoke_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).Revoke(ctx, req.(*RevokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeysService_Rotate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).Rotate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_Rotate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).Rotate(ctx, req.(*RotateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeysService_FinalizeRotation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinalizeRotationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).FinalizeRotation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_FinalizeRotation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).FinalizeRotation(ctx, req.(*FinalizeRotationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeysService_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_Verify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeysService_Approve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).Approve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_Approve_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).Approve(ctx, req.(*ApproveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeysService_Reject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RejectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeysServiceServer).Reject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeysService_Reject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeysServiceServer).Reject(ctx, req.(*RejectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// KeysService_ServiceDesc is the grpc.ServiceDesc for KeysService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var KeysService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "apikeys.v1.KeysService",
+	HandlerType: (*KeysServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _KeysService_Create_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _KeysService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _KeysService_List_Handler,
+		},
+		{
+			MethodName: "Revoke",
+			Handler:    _KeysService_Revoke_Handler,
+		},
+		{
+			MethodName: "Rotate",
+			Handler:    _KeysService_Rotate_Handler,
+		},
+		{
+			MethodName: "FinalizeRotation",
+			Handler:    _KeysService_FinalizeRotation_Handler,
+		},
+		{
+			MethodName: "Verify",
+			Handler:    _KeysService_Verify_Handler,
+		},
+		{
+			MethodName: "Approve",
+			Handler:    _KeysService_Approve_Handler,
+		},
+		{
+			MethodName: "Reject",
+			Handler:    _KeysService_Reject_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "keys.proto",
+}
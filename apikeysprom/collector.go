@@ -0,0 +1,214 @@
+// Package apikeysprom provides a prometheus.Collector which implements
+// apikeys.Metrics.
+//
+//	c := apikeysprom.NewCollector()
+//	prometheus.MustRegister(c)
+//	v := apikeys.NewStoreVerifier(store, apikeys.WithMetrics(c))
+package apikeysprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robinbryce/apikeys"
+)
+
+const namespace = "apikeys"
+
+// Collector exposes the apikeys measurements as prometheus metrics
+type Collector struct {
+	generated     *prometheus.CounterVec
+	verifications *prometheus.CounterVec
+	verifySeconds *prometheus.HistogramVec
+	deriveSeconds *prometheus.HistogramVec
+	storeSeconds  *prometheus.HistogramVec
+	queueDepth    prometheus.Gauge
+	queueWait     prometheus.Histogram
+	rotationUses  *prometheus.CounterVec
+	cacheLookups  *prometheus.CounterVec
+	fallbacks     prometheus.Counter
+	deprecated    *prometheus.CounterVec
+	deriveMemory  *prometheus.GaugeVec
+	byteSeconds   *prometheus.CounterVec
+}
+
+var (
+	_ apikeys.Metrics            = (*Collector)(nil)
+	_ apikeys.RotationMetrics    = (*Collector)(nil)
+	_ apikeys.CacheMetrics       = (*Collector)(nil)
+	_ apikeys.FallbackMetrics    = (*Collector)(nil)
+	_ apikeys.DeprecationMetrics = (*Collector)(nil)
+	_ apikeys.AlgMetrics         = (*Collector)(nil)
+	_ prometheus.Collector       = (*Collector)(nil)
+)
+
+// deriveBuckets cover the range from the smallest permitted parameters to
+// a heavily loaded host running the largest
+var deriveBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+func NewCollector() *Collector {
+	return &Collector{
+		generated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "generated_total",
+			Help:      "Api keys generated by result.",
+		}, []string{"result"}),
+		verifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "verifications_total",
+			Help:      "Api key verifications by result.",
+		}, []string{"result"}),
+		verifySeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "verification_seconds",
+			Help:      "Total time taken to verify an api key, including store access and derivation.",
+			Buckets:   deriveBuckets,
+		}, []string{"result"}),
+		deriveSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "argon2_derivation_seconds",
+			Help:      "Time taken by argon2 key derivation.",
+			Buckets:   deriveBuckets,
+		}, []string{"alg"}),
+		storeSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "store_operation_seconds",
+			Help:      "Latency of key store operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"op", "result"}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "derivation_queue_depth",
+			Help:      "Derivations waiting for a worker, sampled as each one is dequeued.",
+		}),
+		queueWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "derivation_queue_wait_seconds",
+			Help:      "Time derivations spent queued before a worker was available.",
+			Buckets:   deriveBuckets,
+		}),
+		rotationUses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rotation_verifications_total",
+			Help:      "Verifications of keys in a graceful rotation by the secret used, current or previous.",
+		}, []string{"secret"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Verify cache lookups by result, hit or miss.",
+		}, []string{"result"}),
+		fallbacks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_fallback_verifications_total",
+			Help:      "Verifications answered from the cache because the store was unavailable.",
+		}),
+		deprecated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deprecated_verifications_total",
+			Help:      "Successful verifications of keys under an alg with a deprecation scheduled.",
+		}, []string{"alg"}),
+		deriveMemory: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "derivation_memory_bytes",
+			Help:      "Memory committed by each derivation under an alg.",
+		}, []string{"alg"}),
+		byteSeconds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "derivation_memory_byte_seconds_total",
+			Help:      "Memory held by derivations under an alg times the time it was held.",
+		}, []string{"alg"}),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.generated.Describe(ch)
+	c.verifications.Describe(ch)
+	c.verifySeconds.Describe(ch)
+	c.deriveSeconds.Describe(ch)
+	c.storeSeconds.Describe(ch)
+	c.queueDepth.Describe(ch)
+	c.queueWait.Describe(ch)
+	c.rotationUses.Describe(ch)
+	c.cacheLookups.Describe(ch)
+	c.fallbacks.Describe(ch)
+	c.deprecated.Describe(ch)
+	c.deriveMemory.Describe(ch)
+	c.byteSeconds.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.generated.Collect(ch)
+	c.verifications.Collect(ch)
+	c.verifySeconds.Collect(ch)
+	c.deriveSeconds.Collect(ch)
+	c.storeSeconds.Collect(ch)
+	c.queueDepth.Collect(ch)
+	c.queueWait.Collect(ch)
+	c.rotationUses.Collect(ch)
+	c.cacheLookups.Collect(ch)
+	c.fallbacks.Collect(ch)
+	c.deprecated.Collect(ch)
+	c.deriveMemory.Collect(ch)
+	c.byteSeconds.Collect(ch)
+}
+
+func (c *Collector) ObserveGenerate(alg string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	c.generated.WithLabelValues(result).Inc()
+}
+
+func (c *Collector) ObserveVerify(result string, d time.Duration) {
+	c.verifications.WithLabelValues(result).Inc()
+	c.verifySeconds.WithLabelValues(result).Observe(d.Seconds())
+}
+
+func (c *Collector) ObserveDerive(alg string, d time.Duration) {
+	c.deriveSeconds.WithLabelValues(alg).Observe(d.Seconds())
+}
+
+func (c *Collector) ObserveStore(op string, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = apikeys.VerifyResult(err)
+	}
+	c.storeSeconds.WithLabelValues(op, result).Observe(d.Seconds())
+}
+
+func (c *Collector) ObserveQueue(depth int, wait time.Duration) {
+	c.queueDepth.Set(float64(depth))
+	c.queueWait.Observe(wait.Seconds())
+}
+
+func (c *Collector) ObserveRotationUse(previous bool) {
+	secret := "current"
+	if previous {
+		secret = "previous"
+	}
+	c.rotationUses.WithLabelValues(secret).Inc()
+}
+
+func (c *Collector) ObserveCacheHit() {
+	c.cacheLookups.WithLabelValues("hit").Inc()
+}
+
+func (c *Collector) ObserveCacheMiss() {
+	c.cacheLookups.WithLabelValues("miss").Inc()
+}
+
+func (c *Collector) ObserveCacheFallback() {
+	c.fallbacks.Inc()
+}
+
+func (c *Collector) ObserveDeprecated(alg string) {
+	c.deprecated.WithLabelValues(alg).Inc()
+}
+
+// ObserveAlgDerive records the memory of alg; the time is already in
+// argon2_derivation_seconds, which is labelled by alg
+func (c *Collector) ObserveAlgDerive(alg string, d time.Duration, memory int64) {
+	c.deriveMemory.WithLabelValues(alg).Set(float64(memory))
+	c.byteSeconds.WithLabelValues(alg).Add(float64(memory) * d.Seconds())
+}
@@ -0,0 +1,151 @@
+package apikeysprom
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/robinbryce/apikeys"
+)
+
+const testAlg = "argon2id 1 16MB 16"
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector()
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	store := apikeys.NewMemStore()
+	admin := apikeys.NewAdmin(store, apikeys.WithMetrics(c))
+	verifier := apikeys.NewStoreVerifier(store, apikeys.WithMetrics(c))
+
+	apikey, _, err := admin.Create(ctx, testAlg, apikeys.WithClientID("client-1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	other, _, err := apikeys.NewAdmin(apikeys.NewMemStore()).Create(ctx, testAlg, apikeys.WithClientID("client-1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	verifier.Verify(ctx, apikey)
+	verifier.Verify(ctx, apikey)
+	verifier.Verify(ctx, other)
+	verifier.Verify(ctx, "not base64!")
+
+	want := `
+# HELP apikeys_generated_total Api keys generated by result.
+# TYPE apikeys_generated_total counter
+apikeys_generated_total{result="ok"} 1
+# HELP apikeys_verifications_total Api key verifications by result.
+# TYPE apikeys_verifications_total counter
+apikeys_verifications_total{result="invalid"} 1
+apikeys_verifications_total{result="mismatch"} 1
+apikeys_verifications_total{result="ok"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "apikeys_generated_total", "apikeys_verifications_total"); err != nil {
+		t.Error(err)
+	}
+	// one derivation for generate and three for the verifications which
+	// reached the derivation step
+	if n := testutil.CollectAndCount(c.deriveSeconds); n != 1 {
+		t.Errorf("derivation series = %d, want 1", n)
+	}
+	if got := histogramCount(t, reg, "apikeys_argon2_derivation_seconds"); got != 4 {
+		t.Errorf("derivation count = %d, want 4", got)
+	}
+	if got := histogramCount(t, reg, "apikeys_store_operation_seconds"); got != 4 {
+		t.Errorf("store operation count = %d, want 4", got)
+	}
+	wantMemory := `
+# HELP apikeys_derivation_memory_bytes Memory committed by each derivation under an alg.
+# TYPE apikeys_derivation_memory_bytes gauge
+apikeys_derivation_memory_bytes{alg="argon2id t=1,m=16MB,len=16"} 1.6777216e+07
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(wantMemory), "apikeys_derivation_memory_bytes"); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.ToFloat64(c.byteSeconds.WithLabelValues("argon2id t=1,m=16MB,len=16")); got <= 0 {
+		t.Errorf("memory byte seconds = %v, want > 0", got)
+	}
+}
+
+func TestCollectorRotation(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector()
+	store := apikeys.NewMemStore()
+	admin := apikeys.NewAdmin(store)
+	verifier := apikeys.NewStoreVerifier(store, apikeys.WithMetrics(c))
+
+	old, ak, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// Not in rotation, so not counted
+	verifier.Verify(ctx, old)
+	current, _, err := admin.RotateGracefully(ctx, ak.ClientID, "", time.Hour)
+	if err != nil {
+		t.Fatalf("RotateGracefully() error = %v", err)
+	}
+	verifier.Verify(ctx, old)
+	verifier.Verify(ctx, old)
+	verifier.Verify(ctx, current)
+
+	want := `
+# HELP apikeys_rotation_verifications_total Verifications of keys in a graceful rotation by the secret used, current or previous.
+# TYPE apikeys_rotation_verifications_total counter
+apikeys_rotation_verifications_total{secret="current"} 1
+apikeys_rotation_verifications_total{secret="previous"} 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "apikeys_rotation_verifications_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectorCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector()
+	store := apikeys.NewMemStore()
+	verifier := apikeys.NewStoreVerifier(store, apikeys.WithMetrics(c),
+		apikeys.WithVerifyCache(apikeys.NewMemVerifyCache(), time.Hour))
+	apikey, _, err := apikeys.NewAdmin(store).Create(ctx, testAlg)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	verifier.Verify(ctx, apikey)
+	verifier.Verify(ctx, apikey)
+	verifier.Verify(ctx, apikey)
+
+	want := `
+# HELP apikeys_cache_lookups_total Verify cache lookups by result, hit or miss.
+# TYPE apikeys_cache_lookups_total counter
+apikeys_cache_lookups_total{result="hit"} 2
+apikeys_cache_lookups_total{result="miss"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "apikeys_cache_lookups_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func histogramCount(t *testing.T, reg *prometheus.Registry, name string) uint64 {
+	t.Helper()
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	var n uint64
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			n += m.GetHistogram().GetSampleCount()
+		}
+	}
+	return n
+}
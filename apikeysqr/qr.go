@@ -0,0 +1,58 @@
+// Package apikeysqr renders a generated api key as a QR code, for pairing
+// mobile and IoT devices with their credentials.
+//
+//	g, err := admin.CreateKey(ctx, "", apikeys.WithClientID("sensor-7"))
+//	if err != nil {
+//		return err
+//	}
+//	img, err := apikeysqr.PNG(g.Encoded, 0)
+//
+// The code holds the secret: treat the image, and any screen it is shown on,
+// as the api key itself.
+package apikeysqr
+
+import (
+	"errors"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// DefaultSize is the width and height of PNG images, in pixels, when none is
+// given
+const DefaultSize = 256
+
+// ErrEmpty is returned when there is no api key to render
+var ErrEmpty = errors.New("no api key to render")
+
+func encode(apikey string) (*qrcode.QRCode, error) {
+	if apikey == "" {
+		return nil, ErrEmpty
+	}
+	// Medium recovers from 15% damage, which a photographed screen needs,
+	// while keeping a 100 character key small enough for cheap cameras
+	return qrcode.New(apikey, qrcode.Medium)
+}
+
+// PNG renders apikey as a PNG image size pixels square, DefaultSize if size
+// is 0
+func PNG(apikey string, size int) ([]byte, error) {
+	q, err := encode(apikey)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		size = DefaultSize
+	}
+	return q.PNG(size)
+}
+
+// Terminal renders apikey as lines of unicode half blocks, two modules to a
+// character, for printing to a terminal. Set inverse for terminals drawing
+// light text on a dark background.
+func Terminal(apikey string, inverse bool) (string, error) {
+	q, err := encode(apikey)
+	if err != nil {
+		return "", err
+	}
+	return q.ToSmallString(inverse), nil
+}
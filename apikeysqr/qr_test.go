@@ -0,0 +1,76 @@
+package apikeysqr
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/robinbryce/apikeys"
+)
+
+const testAlg = "argon2id 1 16MB 16"
+
+func TestPNG(t *testing.T) {
+	g, err := apikeys.NewAdmin(apikeys.NewMemStore()).CreateKey(t.Context(), testAlg, apikeys.WithClientID("sensor-7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type args struct {
+		size int
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{"default", args{0}, DefaultSize},
+		{"sized", args{512}, 512},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := PNG(g.Encoded, tt.args.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bounds := img.Bounds(); bounds.Dx() != tt.want || bounds.Dy() != tt.want {
+				t.Errorf("image is %v, want %dx%d", bounds, tt.want, tt.want)
+			}
+		})
+	}
+	if _, err := PNG("", 0); !errors.Is(err, ErrEmpty) {
+		t.Errorf("PNG(\"\") = %v, want ErrEmpty", err)
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	apikey := "Y2xpZW50LTE6YXJnb24yaWQgMSAxNk1CIDE2LnNhbHQuc2VjcmV0"
+	s, err := Terminal(apikey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := encode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two rows of modules to a line
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if want := (len(q.Bitmap()) + 1) / 2; len(lines) != want {
+		t.Errorf("Terminal() has %d lines, want %d", len(lines), want)
+	}
+	inverse, err := Terminal(apikey, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inverse == s {
+		t.Error("inverse rendering is the same")
+	}
+	if _, err := Terminal("", false); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Terminal(\"\") = %v, want ErrEmpty", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Package apikeysredis shares verification caches, revocations and quota
+// counts between the instances of a service through redis. ClusterVerifier
+// wires them together.
+//
+//	v, err := apikeysredis.NewClusterVerifier(ctx, store, redis.NewClient(&redis.Options{Addr: addr}))
+//	if err != nil {
+//		return err
+//	}
+//	defer v.Close()
+//	admin := v.Admin()
+package apikeysredis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/robinbryce/apikeys"
+)
+
+// DefaultPrefix namespaces the redis keys and channel
+const DefaultPrefix = "apikeys:"
+
+// Cache is an apikeys.VerifyCache held in redis. The entries of a client id
+// are fields of one hash so that Invalidate is a single delete, and each
+// Invalidate is published so that subscribers can drop their own copies.
+type Cache struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+var _ apikeys.VerifyCache = (*Cache)(nil)
+
+// NewCache creates a Cache using keys starting with prefix, DefaultPrefix if
+// empty
+func NewCache(rdb redis.UniversalClient, prefix string) *Cache {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &Cache{rdb: rdb, prefix: prefix}
+}
+
+// cacheEntry is the json form of an apikeys.CachedKey
+type cacheEntry struct {
+	Record     apikeys.OutputRecord `json:"record"`
+	Previous   bool                 `json:"previous,omitempty"`
+	Expires    time.Time            `json:"expires"`
+	StaleUntil time.Time            `json:"stale_until,omitzero"`
+}
+
+func (c *Cache) key(clientID string) string {
+	return c.prefix + "cache:" + clientID
+}
+
+func (c *Cache) channel() string {
+	return c.prefix + "invalidate"
+}
+
+func (c *Cache) Get(ctx context.Context, clientID, digest string) (apikeys.CachedKey, bool, error) {
+	b, err := c.rdb.HGet(ctx, c.key(clientID), digest).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return apikeys.CachedKey{}, false, nil
+	}
+	if err != nil {
+		return apikeys.CachedKey{}, false, err
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return apikeys.CachedKey{}, false, err
+	}
+	ak, err := e.Record.Key()
+	if err != nil {
+		return apikeys.CachedKey{}, false, err
+	}
+	return apikeys.CachedKey{Key: ak, Previous: e.Previous, Expires: e.Expires, StaleUntil: e.StaleUntil}, true, nil
+}
+
+// Set stores the entry and extends the expiry of the client's hash to cover
+// it. Entries which outlive their own are ignored by the verifier.
+func (c *Cache) Set(ctx context.Context, digest string, ck apikeys.CachedKey) error {
+	b, err := json.Marshal(cacheEntry{Record: apikeys.NewOutputRecord(ck.Key), Previous: ck.Previous, Expires: ck.Expires, StaleUntil: ck.StaleUntil})
+	if err != nil {
+		return err
+	}
+	key := c.key(ck.Key.ClientID)
+	if err := c.rdb.HSet(ctx, key, digest, b).Err(); err != nil {
+		return err
+	}
+	return c.rdb.PExpireAt(ctx, key, ck.Retain()).Err()
+}
+
+// Invalidate deletes the client's entries and publishes its client id
+func (c *Cache) Invalidate(ctx context.Context, clientID string) error {
+	if err := c.rdb.Del(ctx, c.key(clientID)).Err(); err != nil {
+		return err
+	}
+	return c.rdb.Publish(ctx, c.channel(), clientID).Err()
+}
+
+// Subscribe calls fn with the client id of every Invalidate, from any
+// instance, until ctx is done. It returns once subscribed, and the returned
+// channel receives the error which ended the subscription. Redis pub/sub does
+// not redeliver, so invalidations sent while disconnected are lost; keep
+// whatever fn drops short lived.
+func (c *Cache) Subscribe(ctx context.Context, fn func(clientID string)) (<-chan error, error) {
+	ps := c.rdb.Subscribe(ctx, c.channel())
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+	done := make(chan error, 1)
+	// Closing is what interrupts a blocked receive
+	stop := context.AfterFunc(ctx, func() { ps.Close() })
+	go func() {
+		defer stop()
+		defer ps.Close()
+		for {
+			msg, err := ps.ReceiveMessage(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				done <- err
+				return
+			}
+			fn(msg.Payload)
+		}
+	}()
+	return done, nil
+}
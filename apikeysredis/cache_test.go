@@ -0,0 +1,85 @@
+package apikeysredis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+const testAlg = "argon2id 1 16MB 16"
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	f, rdb := newFakeRedis(t)
+	c := NewCache(rdb, "")
+
+	ak, err := apikeys.NewKey(testAlg, apikeys.WithClientID("client-1"), apikeys.WithTeam("payments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ak.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+
+	type args struct {
+		clientID string
+		digest   string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"hit", args{"client-1", "digest-1"}, true},
+		{"other digest", args{"client-1", "digest-2"}, false},
+		{"other client", args{"client-2", "digest-1"}, false},
+	}
+	if err := c.Set(ctx, "digest-1", apikeys.CachedKey{Key: ak, Previous: true, Expires: expires}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := c.Get(ctx, tt.args.clientID, tt.args.digest)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if ok != tt.want {
+				t.Fatalf("Get() ok = %v, want %v", ok, tt.want)
+			}
+			if !ok {
+				return
+			}
+			if got.Key.Team != "payments" || got.Key.Alg().String != testAlg || !got.Previous || !got.Expires.Equal(expires) {
+				t.Errorf("Get() = %+v", got)
+			}
+		})
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	invalidated := make(chan string, 1)
+	ended, err := c.Subscribe(cctx, func(clientID string) { invalidated <- clientID })
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := c.Invalidate(ctx, "client-1"); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if f.has(DefaultPrefix + "cache:client-1") {
+		t.Errorf("Invalidate() left the client's entries")
+	}
+	select {
+	case got := <-invalidated:
+		if got != "client-1" {
+			t.Errorf("subscriber got %q, want client-1", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber not called")
+	}
+	cancel()
+	if err := <-ended; err != context.Canceled {
+		t.Errorf("subscription ended with %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,201 @@
+package apikeysredis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/robinbryce/apikeys"
+)
+
+const (
+	// DefaultLocalTTL bounds how long an instance trusts its own copy of a
+	// verification, and so how stale it can be if an invalidation is missed
+	DefaultLocalTTL = 5 * time.Second
+	// DefaultSharedTTL is how long verifications are cached in redis
+	DefaultSharedTTL = time.Minute
+)
+
+// Option configures a ClusterVerifier
+type Option func(*config)
+
+type config struct {
+	prefix    string
+	localTTL  time.Duration
+	sharedTTL time.Duration
+	opts      []apikeys.Option
+}
+
+// WithPrefix sets the prefix of the redis keys and channel, DefaultPrefix by
+// default. Services sharing a redis but not their keys need different
+// prefixes.
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithLocalTTL sets how long verifications are cached in process
+func WithLocalTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.localTTL = d
+	}
+}
+
+// WithSharedTTL sets how long verifications are cached in redis
+func WithSharedTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.sharedTTL = d
+	}
+}
+
+// WithVerifierOptions passes opts to the apikeys.StoreVerifier, eg
+// apikeys.WithMetrics
+func WithVerifierOptions(opts ...apikeys.Option) Option {
+	return func(c *config) {
+		c.opts = append(c.opts, opts...)
+	}
+}
+
+// ClusterVerifier is an apikeys.Verifier for a service running as several
+// instances. Verifications are cached in process and in redis, revocations
+// and other updates made through its Admin are published so every instance
+// drops its copy at once, and key quotas are counted in redis so they apply
+// across the cluster.
+type ClusterVerifier struct {
+	verifier  *apikeys.StoreVerifier
+	store     apikeys.Store
+	local     *apikeys.MemVerifyCache
+	shared    *Cache
+	counter   *UsageCounter
+	sharedTTL time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+var _ apikeys.Verifier = (*ClusterVerifier)(nil)
+
+// NewClusterVerifier verifies keys held in store. It subscribes to
+// invalidations before returning; Close ends the subscription.
+func NewClusterVerifier(ctx context.Context, store apikeys.Store, rdb redis.UniversalClient, opts ...Option) (*ClusterVerifier, error) {
+	c := config{localTTL: DefaultLocalTTL, sharedTTL: DefaultSharedTTL}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	v := &ClusterVerifier{
+		store:     store,
+		local:     apikeys.NewMemVerifyCache(),
+		shared:    NewCache(rdb, c.prefix),
+		counter:   NewUsageCounter(rdb, c.prefix),
+		sharedTTL: c.sharedTTL,
+		done:      make(chan struct{}),
+	}
+	cache := &tieredCache{local: v.local, shared: v.shared, localTTL: c.localTTL}
+	v.verifier = apikeys.NewStoreVerifier(store, append(c.opts, apikeys.WithVerifyCache(cache, c.sharedTTL))...)
+
+	ctx, v.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	ended, err := v.shared.Subscribe(ctx, func(clientID string) {
+		v.local.Invalidate(ctx, clientID)
+	})
+	if err != nil {
+		v.cancel()
+		return nil, err
+	}
+	go v.run(ctx, ended, c.localTTL)
+	return v, nil
+}
+
+// run sweeps the local cache until the subscription ends
+func (v *ClusterVerifier) run(ctx context.Context, ended <-chan error, every time.Duration) {
+	defer close(v.done)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case err := <-ended:
+			if ctx.Err() == nil {
+				v.err = err
+			}
+			return
+		case now := <-t.C:
+			v.local.Sweep(now)
+		}
+	}
+}
+
+// Verify verifies apikey and counts a use against its quota, failing with
+// apikeys.ErrQuotaExceeded once it is used up
+func (v *ClusterVerifier) Verify(ctx context.Context, apikey string) (apikeys.Identity, error) {
+	id, err := v.verifier.Identify(ctx, apikey)
+	if err != nil {
+		return apikeys.Identity{}, err
+	}
+	if _, err := id.Key.UseQuota(ctx, v.counter, time.Now()); err != nil {
+		return apikeys.Identity{}, err
+	}
+	return id, nil
+}
+
+// StoreVerifier is the underlying verifier, eg for keyshttp.NewMiddleware
+// with UsageCounter
+func (v *ClusterVerifier) StoreVerifier() *apikeys.StoreVerifier {
+	return v.verifier
+}
+
+// UsageCounter is the cluster wide quota counter
+func (v *ClusterVerifier) UsageCounter() apikeys.UsageCounter {
+	return v.counter
+}
+
+// Admin returns an apikeys.Admin on the same store whose changes invalidate
+// the cached verifications of every instance
+func (v *ClusterVerifier) Admin(opts ...apikeys.Option) *apikeys.Admin {
+	return apikeys.NewAdmin(v.store, append(opts, apikeys.WithVerifyCache(v.shared, v.sharedTTL))...)
+}
+
+// Close ends the invalidation subscription. It returns the error if the
+// subscription had already failed, after which the local cache was only
+// bounded by its ttl.
+func (v *ClusterVerifier) Close() error {
+	v.cancel()
+	<-v.done
+	return v.err
+}
+
+// tieredCache keeps a short lived local copy of the shared entries
+type tieredCache struct {
+	local    *apikeys.MemVerifyCache
+	shared   *Cache
+	localTTL time.Duration
+}
+
+func (c *tieredCache) Get(ctx context.Context, clientID, digest string) (apikeys.CachedKey, bool, error) {
+	if ck, ok, _ := c.local.Get(ctx, clientID, digest); ok && time.Now().Before(ck.Expires) {
+		return ck, true, nil
+	}
+	ck, ok, err := c.shared.Get(ctx, clientID, digest)
+	if err != nil || !ok {
+		return ck, ok, err
+	}
+	c.local.Set(ctx, digest, c.localCopy(ck))
+	return ck, true, nil
+}
+
+func (c *tieredCache) Set(ctx context.Context, digest string, ck apikeys.CachedKey) error {
+	c.local.Set(ctx, digest, c.localCopy(ck))
+	return c.shared.Set(ctx, digest, ck)
+}
+
+func (c *tieredCache) Invalidate(ctx context.Context, clientID string) error {
+	c.local.Invalidate(ctx, clientID)
+	return c.shared.Invalidate(ctx, clientID)
+}
+
+func (c *tieredCache) localCopy(ck apikeys.CachedKey) apikeys.CachedKey {
+	if exp := time.Now().Add(c.localTTL); exp.Before(ck.Expires) {
+		ck.Expires = exp
+	}
+	return ck
+}
@@ -0,0 +1,71 @@
+package apikeysredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+func TestClusterVerifier(t *testing.T) {
+	ctx := context.Background()
+	_, rdb := newFakeRedis(t)
+	store := apikeys.NewMemStore()
+
+	// Two instances of a service sharing the store and redis
+	a, err := NewClusterVerifier(ctx, store, rdb, WithLocalTTL(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := NewClusterVerifier(ctx, store, rdb, WithLocalTTL(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	admin := a.Admin()
+
+	apikey, ak, err := admin.Create(ctx, testAlg, apikeys.WithQuota(3, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []*ClusterVerifier{a, b} {
+		id, err := v.Verify(ctx, apikey)
+		if err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+		if id.ClientID != ak.ClientID {
+			t.Errorf("Verify() ClientID = %s, want %s", id.ClientID, ak.ClientID)
+		}
+	}
+	// The quota is counted across the instances
+	if _, err := a.Verify(ctx, apikey); err != nil {
+		t.Fatalf("Verify() third use error = %v", err)
+	}
+	if _, err := b.Verify(ctx, apikey); !errors.Is(err, apikeys.ErrQuotaExceeded) {
+		t.Errorf("Verify() fourth use error = %v, want ErrQuotaExceeded", err)
+	}
+
+	// Both instances now hold the verification locally, so only the
+	// invalidation stops b accepting the revoked key
+	if _, err := admin.Revoke(ctx, ak.ClientID); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := b.Verify(ctx, apikey)
+		if errors.Is(err, apikeys.ErrRevoked) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Verify() after revoke error = %v, want ErrRevoked", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
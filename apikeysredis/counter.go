@@ -0,0 +1,44 @@
+package apikeysredis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/robinbryce/apikeys"
+)
+
+// UsageCounter is an apikeys.UsageCounter shared by every instance using the
+// same redis. Its windows are aligned like those of
+// apikeys.MemUsageCounter, see apikeys.Quota.
+type UsageCounter struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+var _ apikeys.UsageCounter = (*UsageCounter)(nil)
+
+// NewUsageCounter creates a UsageCounter using keys starting with prefix,
+// DefaultPrefix if empty
+func NewUsageCounter(rdb redis.UniversalClient, prefix string) *UsageCounter {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &UsageCounter{rdb: rdb, prefix: prefix}
+}
+
+func (c *UsageCounter) Add(ctx context.Context, clientID string, window time.Duration, now time.Time) (int64, error) {
+	start := now.Truncate(window)
+	key := c.prefix + "usage:" + clientID + ":" + strconv.FormatInt(start.Unix(), 10)
+	n, err := c.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		if err := c.rdb.PExpireAt(ctx, key, start.Add(window)).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,43 @@
+package apikeysredis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUsageCounter(t *testing.T) {
+	ctx := context.Background()
+	_, rdb := newFakeRedis(t)
+	// Two instances share the counts
+	a, b := NewUsageCounter(rdb, ""), NewUsageCounter(rdb, "")
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type args struct {
+		c        *UsageCounter
+		clientID string
+		at       time.Duration
+	}
+	tests := []struct {
+		name string
+		args args
+		want int64
+	}{
+		{"first", args{a, "client-1", 0}, 1},
+		{"other instance", args{b, "client-1", time.Second}, 2},
+		{"other client", args{a, "client-2", time.Second}, 1},
+		{"same window", args{b, "client-1", 59 * time.Second}, 3},
+		{"next window", args{a, "client-1", time.Minute}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.c.Add(ctx, tt.args.clientID, time.Minute, start.Add(tt.args.at))
+			if err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Add() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package apikeysredis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeRedis speaks enough of the redis protocol for the commands this
+// package uses
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+	expires map[string]time.Time
+	subs    map[string][]*fakeConn
+}
+
+type fakeConn struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+// newFakeRedis starts a fake server and returns a client connected to it
+func newFakeRedis(t *testing.T) (*fakeRedis, *redis.Client) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{
+		strings: map[string]string{},
+		hashes:  map[string]map[string]string{},
+		expires: map[string]time.Time{},
+		subs:    map[string][]*fakeConn{},
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	rdb := redis.NewClient(&redis.Options{Addr: ln.Addr().String()})
+	t.Cleanup(func() {
+		rdb.Close()
+		ln.Close()
+	})
+	return f, rdb
+}
+
+func (f *fakeRedis) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	conn := &fakeConn{w: bufio.NewWriter(c)}
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		reply := f.do(conn, args)
+		conn.mu.Lock()
+		conn.w.WriteString(reply)
+		err = conn.w.Flush()
+		conn.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func integer(n int) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+func array(items ...string) string {
+	return fmt.Sprintf("*%d\r\n%s", len(items), strings.Join(items, ""))
+}
+
+// expire drops key if it has expired, f.mu must be held
+func (f *fakeRedis) expire(key string) {
+	if at, ok := f.expires[key]; ok && !time.Now().Before(at) {
+		delete(f.strings, key)
+		delete(f.hashes, key)
+		delete(f.expires, key)
+	}
+}
+
+func (f *fakeRedis) do(conn *fakeConn, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range args[1:min(2, len(args))] {
+		f.expire(key)
+	}
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "HGET":
+		v, ok := f.hashes[args[1]][args[2]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "HSET":
+		h := f.hashes[args[1]]
+		if h == nil {
+			h = map[string]string{}
+			f.hashes[args[1]] = h
+		}
+		n := 0
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := h[args[i]]; !ok {
+				n++
+			}
+			h[args[i]] = args[i+1]
+		}
+		return integer(n)
+	case "PEXPIREAT":
+		ms, _ := strconv.ParseInt(args[2], 10, 64)
+		f.expires[args[1]] = time.UnixMilli(ms)
+		return integer(1)
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			_, s := f.strings[key]
+			_, h := f.hashes[key]
+			if s || h {
+				n++
+			}
+			delete(f.strings, key)
+			delete(f.hashes, key)
+			delete(f.expires, key)
+		}
+		return integer(n)
+	case "INCR":
+		n, _ := strconv.Atoi(f.strings[args[1]])
+		n++
+		f.strings[args[1]] = strconv.Itoa(n)
+		return integer(n)
+	case "SUBSCRIBE":
+		var replies []string
+		for _, ch := range args[1:] {
+			f.subs[ch] = append(f.subs[ch], conn)
+			replies = append(replies, array(bulk("subscribe"), bulk(ch), integer(1)))
+		}
+		return strings.Join(replies, "")
+	case "PUBLISH":
+		msg := array(bulk("message"), bulk(args[1]), bulk(args[2]))
+		for _, sub := range f.subs[args[1]] {
+			sub.mu.Lock()
+			sub.w.WriteString(msg)
+			sub.w.Flush()
+			sub.mu.Unlock()
+		}
+		return integer(len(f.subs[args[1]]))
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+// has is true if key exists and has not expired
+func (f *fakeRedis) has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expire(key)
+	_, s := f.strings[key]
+	_, h := f.hashes[key]
+	return s || h
+}
@@ -0,0 +1,43 @@
+// Package apikeystest provides fakes and fixtures for testing code which uses
+// apikeys: a fault injecting Store, a controllable Clock, deterministic key
+// generation, golden api keys with their derived keys and a Verifier which
+// does no argon2 work at all.
+package apikeystest
+
+import (
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// FastAlg is the cheapest alg ParseAlg accepts. Use it in tests which must do
+// real derivations.
+const FastAlg = "argon2id 1 16MB 16"
+
+// Rand returns a deterministic, NOT cryptographically secure, entropy source
+// for apikeys.WithRand. The same seed always produces the same keys.
+func Rand(seed uint64) io.Reader {
+	var s [32]byte
+	for i := 0; i < 8; i++ {
+		s[i] = byte(seed >> (8 * i))
+	}
+	return rand.NewChaCha8(s)
+}
+
+// Generate deterministically generates a FastAlg key for clientID from seed.
+// It fails the test on error.
+func Generate(tb testing.TB, clientID string, seed uint64, opts ...apikeys.KeyOption) (string, apikeys.Key) {
+	tb.Helper()
+	opts = append([]apikeys.KeyOption{apikeys.WithClientID(clientID), apikeys.WithRand(Rand(seed))}, opts...)
+	ak, err := apikeys.NewKey(FastAlg, opts...)
+	if err != nil {
+		tb.Fatalf("apikeystest.Generate: %v", err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		tb.Fatalf("apikeystest.Generate: %v", err)
+	}
+	return apikey, ak
+}
@@ -0,0 +1,85 @@
+package apikeystest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+func TestGenerateDeterministic(t *testing.T) {
+	a, _ := Generate(t, "c", 7)
+	b, _ := Generate(t, "c", 7)
+	c, _ := Generate(t, "c", 8)
+	if a != b || a == c {
+		t.Errorf("Generate() not deterministic by seed: %s %s %s", a, b, c)
+	}
+}
+
+// TestGolden checks the golden fixtures against a real verification, so they
+// can't silently drift from the encoding.
+func TestGolden(t *testing.T) {
+	ctx := context.Background()
+	store := GoldenStore()
+	v := apikeys.NewStoreVerifier(store)
+	for i, g := range Golden {
+		apikey, ak := Generate(t, g.ClientID, uint64(i+1))
+		if apikey != g.APIKey {
+			t.Errorf("Golden[%d].APIKey is stale, want %s", i, apikey)
+		}
+		if got := g.Record(); string(got.DerivedKey) != string(ak.DerivedKey) {
+			t.Errorf("Golden[%d].DerivedKey is stale", i)
+		}
+		if _, err := v.Verify(ctx, g.APIKey); err != nil {
+			t.Errorf("Verify(Golden[%d]) error = %v", i, err)
+		}
+		if _, err := GoldenVerifier().Verify(ctx, g.APIKey); err != nil {
+			t.Errorf("GoldenVerifier().Verify(Golden[%d]) error = %v", i, err)
+		}
+	}
+}
+
+func TestStoreFailWith(t *testing.T) {
+	ctx := context.Background()
+	s := GoldenStore()
+	boom := errors.New("boom")
+	s.FailWith(boom)
+	if _, err := s.Get(ctx, Golden[0].ClientID); !errors.Is(err, boom) {
+		t.Errorf("Get() error = %v, want %v", err, boom)
+	}
+	s.FailWith(nil)
+	if _, err := s.Get(ctx, Golden[0].ClientID); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+	if calls := s.Calls(); len(calls) != 2 || calls[0] != "Get" {
+		t.Errorf("Calls() = %v", calls)
+	}
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+	c.Advance(time.Hour)
+	if !c.Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("Now() = %v after Advance", c.Now())
+	}
+	var _ apikeys.Clock = c
+}
+
+func TestVerifier(t *testing.T) {
+	ctx := context.Background()
+	v := NewVerifier()
+	v.Accept("k", apikeys.Key{ClientID: "c"})
+	if ak, err := v.Verify(ctx, "k"); err != nil || ak.ClientID != "c" {
+		t.Errorf("Verify() = %v, %v", ak, err)
+	}
+	v.Reject("k", apikeys.ErrRevoked)
+	if _, err := v.Verify(ctx, "k"); !errors.Is(err, apikeys.ErrRevoked) {
+		t.Errorf("Verify() rejected error = %v", err)
+	}
+	if _, err := v.Verify(ctx, "unknown"); !errors.Is(err, apikeys.ErrMismatch) {
+		t.Errorf("Verify() unknown error = %v", err)
+	}
+}
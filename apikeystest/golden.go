@@ -0,0 +1,56 @@
+package apikeystest
+
+import (
+	"encoding/base64"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// GoldenKey is a pre-generated FastAlg api key and the derived key stored for
+// it. They are fixed so tests can embed them and never need real randomness.
+type GoldenKey struct {
+	ClientID   string
+	APIKey     string
+	DerivedKey string // url safe base64
+}
+
+// Golden keys, generated with Generate(t, ClientID, seed) for seeds 1 and 2
+var Golden = []GoldenKey{
+	{
+		ClientID:   "golden-client-1",
+		APIKey:     "Z29sZGVuLWNsaWVudC0xOmFyZ29uMmlkIDEgMTZNQiAxNi5hdVo0UDAtOTZSdHV1SXR6cEk3U1I5dmxpQzRsZVdnME1zR194U1ZGU3QwPS5ETmh5ZE5ad2hNcXZEZzAyeUVsdHRfNzFYLURoSlhVS3BnalY0Z184TFJJPQ==",
+		DerivedKey: "OXz701eD-jzCNY-JMb2HAA==",
+	},
+	{
+		ClientID:   "golden-client-2",
+		APIKey:     "Z29sZGVuLWNsaWVudC0yOmFyZ29uMmlkIDEgMTZNQiAxNi5GSkFHYTNlSk5iQzdTU1hqMnhVY1cwSksxOFk3M0RCNjBSOS1oeHJtV19vPS5NNEFmRTVEazFILU8td1JZc2Ftc3N6Zko5Ylk5VklBVWVoanl1eV9xb2hvPQ==",
+		DerivedKey: "2EPUV_6-F3AFgnmx_sRjVw==",
+	},
+}
+
+// Record returns the store record for the golden key
+func (g GoldenKey) Record() apikeys.Key {
+	dk, err := base64.URLEncoding.DecodeString(g.DerivedKey)
+	if err != nil {
+		panic(err)
+	}
+	return apikeys.Key{ClientID: g.ClientID, DerivedKey: dk}
+}
+
+// GoldenStore returns a Store holding the records for all the Golden keys
+func GoldenStore() *Store {
+	keys := make([]apikeys.Key, 0, len(Golden))
+	for _, g := range Golden {
+		keys = append(keys, g.Record())
+	}
+	return NewStore(keys...)
+}
+
+// GoldenVerifier returns a Verifier which accepts all the Golden keys
+func GoldenVerifier() *Verifier {
+	v := NewVerifier()
+	for _, g := range Golden {
+		v.Accept(g.APIKey, g.Record())
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package apikeystest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// Store is an in memory apikeys.Store which records the operations made on
+// it and can be told to fail them.
+type Store struct {
+	*apikeys.MemStore
+
+	mu    sync.Mutex
+	err   error
+	calls []string
+}
+
+var _ apikeys.Store = (*Store)(nil)
+
+// NewStore returns a Store holding keys
+func NewStore(keys ...apikeys.Key) *Store {
+	s := &Store{MemStore: apikeys.NewMemStore()}
+	for _, ak := range keys {
+		s.MemStore.Create(context.Background(), ak)
+	}
+	return s
+}
+
+// FailWith makes every subsequent operation return err. Pass nil to recover.
+func (s *Store) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Calls returns the names of the Store methods called so far
+func (s *Store) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func (s *Store) call(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, name)
+	return s.err
+}
+
+func (s *Store) Create(ctx context.Context, ak apikeys.Key) error {
+	if err := s.call("Create"); err != nil {
+		return err
+	}
+	return s.MemStore.Create(ctx, ak)
+}
+
+func (s *Store) Get(ctx context.Context, clientID string) (apikeys.Key, error) {
+	if err := s.call("Get"); err != nil {
+		return apikeys.Key{}, err
+	}
+	return s.MemStore.Get(ctx, clientID)
+}
+
+func (s *Store) Update(ctx context.Context, ak apikeys.Key) error {
+	if err := s.call("Update"); err != nil {
+		return err
+	}
+	return s.MemStore.Update(ctx, ak)
+}
+
+func (s *Store) Delete(ctx context.Context, clientID string) error {
+	if err := s.call("Delete"); err != nil {
+		return err
+	}
+	return s.MemStore.Delete(ctx, clientID)
+}
+
+func (s *Store) List(ctx context.Context) ([]apikeys.Key, error) {
+	if err := s.call("List"); err != nil {
+		return nil, err
+	}
+	return s.MemStore.List(ctx)
+}
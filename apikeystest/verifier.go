@@ -0,0 +1,59 @@
+package apikeystest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// Verifier accepts a fixed set of api keys without decoding them or doing any
+// derivation. Unknown keys fail with apikeys.ErrMismatch. It has the same
+// Verify and Identify methods as apikeys.StoreVerifier.
+type Verifier struct {
+	mu   sync.RWMutex
+	keys map[string]apikeys.Key
+	errs map[string]error
+}
+
+func NewVerifier() *Verifier {
+	return &Verifier{keys: map[string]apikeys.Key{}, errs: map[string]error{}}
+}
+
+// Accept makes Verify(apikey) succeed with ak
+func (v *Verifier) Accept(apikey string, ak apikeys.Key) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys[apikey] = ak
+	delete(v.errs, apikey)
+}
+
+// Reject makes Verify(apikey) fail with err, eg apikeys.ErrRevoked
+func (v *Verifier) Reject(apikey string, err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.errs[apikey] = err
+	delete(v.keys, apikey)
+}
+
+func (v *Verifier) Verify(ctx context.Context, apikey string) (apikeys.Key, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if err, ok := v.errs[apikey]; ok {
+		return apikeys.Key{}, err
+	}
+	if ak, ok := v.keys[apikey]; ok {
+		return ak, nil
+	}
+	return apikeys.Key{}, apikeys.ErrMismatch
+}
+
+// Identify is Verify returning the Identity, like
+// apikeys.StoreVerifier.Identify
+func (v *Verifier) Identify(ctx context.Context, apikey string) (apikeys.Identity, error) {
+	ak, err := v.Verify(ctx, apikey)
+	if err != nil {
+		return apikeys.Identity{}, err
+	}
+	return apikeys.Identity{ClientID: ak.ClientID, TenantID: ak.TenantID, Team: ak.Team, Type: ak.Type, Key: ak}, nil
+}
@@ -0,0 +1,16 @@
+// Loads apikeys.wasm, built from ./apikeyswasm, and returns its decoder.
+// wasm_exec.js, from $(go env GOROOT)/lib/wasm, must be loaded first.
+//
+//   const apikeys = await load("/static/apikeys.wasm");
+//   const key = apikeys.decode(input.value, ["live_"]);
+//   if (!key.valid) showError(key.error);
+//
+// decode(apikey, prefixes) returns {valid, clientId, tenantId, alg, error}.
+export async function load(url = "apikeys.wasm") {
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(instance);
+  return {
+    decode: (apikey, prefixes = []) => globalThis.apikeysDecode(apikey, prefixes),
+  };
+}
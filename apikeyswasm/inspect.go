@@ -0,0 +1,38 @@
+// Command apikeyswasm exposes api key decoding to javascript, so a browser
+// can check the format of a key before submitting it. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o apikeys.wasm ./apikeyswasm
+//
+// and load it with apikeys.js and the wasm_exec.js of the same go release,
+// from $(go env GOROOT)/lib/wasm. Nothing is derived and the secret never
+// leaves the call: decoding reports the client id, tenant and alg, or why
+// the key is malformed.
+package main
+
+import (
+	"github.com/robinbryce/apikeys"
+)
+
+// decoded is what javascript receives for a key
+type decoded struct {
+	Valid    bool   `json:"valid"`
+	ClientID string `json:"clientId,omitempty"`
+	TenantID string `json:"tenantId,omitempty"`
+	Alg      string `json:"alg,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// decode checks apikey as ValidateEncodedKey does, requiring one of prefixes
+// if any are given
+func decode(apikey string, prefixes []string) decoded {
+	opts := []apikeys.DecodeOption{apikeys.WithStrict()}
+	if len(prefixes) > 0 {
+		opts = append(opts, apikeys.WithPrefix(prefixes...))
+	}
+	ak, password, err := apikeys.Decode(apikey, opts...)
+	if err != nil {
+		return decoded{Error: err.Error()}
+	}
+	password.Wipe()
+	return decoded{Valid: true, ClientID: ak.ClientID, TenantID: ak.TenantID, Alg: ak.Alg().String}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/robinbryce/apikeys"
+)
+
+func TestDecode(t *testing.T) {
+	ak, err := apikeys.NewKey("argon2id 1 16MB 16", apikeys.WithClientID("client-1"), apikeys.WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type args struct {
+		apikey   string
+		prefixes []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want decoded
+	}{
+		{"valid", args{apikey, nil}, decoded{Valid: true, ClientID: "client-1", TenantID: "acme", Alg: "argon2id 1 16MB 16"}},
+		{"prefixed", args{"live_" + apikey, []string{"live_"}}, decoded{Valid: true, ClientID: "client-1", TenantID: "acme", Alg: "argon2id 1 16MB 16"}},
+		{"missing prefix", args{apikey, []string{"live_"}}, decoded{}},
+		{"malformed", args{apikey[:10], nil}, decoded{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decode(tt.args.apikey, tt.args.prefixes)
+			if !tt.want.Valid {
+				if got.Valid || got.Error == "" {
+					t.Errorf("decode() = %+v, want an error", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("decode() = %+v, want %+v", got, tt.want)
+			}
+			if strings.Contains(got.Error, apikey) {
+				t.Errorf("decode() error leaks the key")
+			}
+		})
+	}
+}
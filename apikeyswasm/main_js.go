@@ -0,0 +1,26 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// main installs apikeysDecode(apikey, prefixes?) on the global object and
+// keeps the program running to serve it
+func main() {
+	js.Global().Set("apikeysDecode", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return map[string]any{"valid": false, "error": "apikeysDecode wants a string"}
+		}
+		var prefixes []string
+		if len(args) > 1 && args[1].InstanceOf(js.Global().Get("Array")) {
+			for i := range args[1].Length() {
+				prefixes = append(prefixes, args[1].Index(i).String())
+			}
+		}
+		d := decode(args[0].String(), prefixes)
+		return map[string]any{"valid": d.Valid, "clientId": d.ClientID, "tenantId": d.TenantID, "alg": d.Alg, "error": d.Error}
+	}))
+	select {}
+}
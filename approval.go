@@ -0,0 +1,145 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrPendingApproval is returned when verifying a key that has not been
+	// approved yet. A wrong secret fails with ErrMismatch as usual, so the
+	// state of a key is only revealed to the holder of its secret.
+	ErrPendingApproval = errors.New("api key pending approval")
+	// ErrApproval is returned when approving or rejecting a key that is not
+	// pending approval
+	ErrApproval = errors.New("api key not pending approval")
+	// ErrSelfApproval is returned when the reviewer is not known or is the
+	// principal that created the key
+	ErrSelfApproval = errors.New("api key must be reviewed by someone other than its creator")
+)
+
+// ApprovalState records the review of a key that required approval. Keys
+// that never required it have the empty state.
+type ApprovalState string
+
+const (
+	ApprovalPending  ApprovalState = "pending"
+	ApprovalApproved ApprovalState = "approved"
+	ApprovalRejected ApprovalState = "rejected"
+)
+
+// Valid is true for the empty state and the ApprovalState constants
+func (s ApprovalState) Valid() bool {
+	switch s {
+	case "", ApprovalPending, ApprovalApproved, ApprovalRejected:
+		return true
+	}
+	return false
+}
+
+// RequireApproval creates the key pending approval. It does not verify until
+// someone other than its creator calls Admin.Approve. Admin.Create needs a
+// principal on the context to record as the creator, see WithPrincipal.
+func RequireApproval() KeyOption {
+	return func(ak *Key) {
+		ak.Approval = ApprovalPending
+	}
+}
+
+// WithApprovalPolicy makes Admin.Create require approval for the keys
+// required returns true for, eg those with a privileged label or team
+func WithApprovalPolicy(required func(ak Key) bool) Option {
+	return func(o *options) {
+		o.approvalRequired = required
+	}
+}
+
+// Pending is true if the key is waiting for approval
+func (ak Key) Pending() bool {
+	return ak.Approval == ApprovalPending
+}
+
+// ApprovalFilter selects records in state s
+func ApprovalFilter(s ApprovalState) KeyFilter {
+	return func(ak Key) bool { return ak.Approval == s }
+}
+
+// Approve lets a pending key verify. The principal on ctx, see WithPrincipal,
+// is recorded as the reviewer and must not be the key's creator.
+func (a *Admin) Approve(ctx context.Context, clientID string) (Key, error) {
+	ak, err := a.review(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	ak.Approval = ApprovalApproved
+	if err := a.store.Update(ctx, ak); err != nil {
+		return Key{}, err
+	}
+	a.emit(ctx, AuditKeyApproved, ak, nil)
+	if a.hooks.OnApprove != nil {
+		a.hooks.OnApprove(ctx, ak)
+	}
+	return ak, nil
+}
+
+// Reject revokes a pending key. As with Approve the reviewer must not be the
+// key's creator.
+func (a *Admin) Reject(ctx context.Context, clientID string) (Key, error) {
+	ak, err := a.review(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	ak.Approval = ApprovalRejected
+	ak.RevokedAt = a.now()
+	if err := a.store.Update(ctx, ak); err != nil {
+		return Key{}, err
+	}
+	a.emit(ctx, AuditKeyRejected, ak, nil)
+	if a.hooks.OnReject != nil {
+		a.hooks.OnReject(ctx, ak)
+	}
+	return ak, nil
+}
+
+// review loads a pending key and records the reviewer from ctx on it
+func (a *Admin) review(ctx context.Context, clientID string) (Key, error) {
+	ak, err := a.store.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	if ak.Revoked() {
+		return Key{}, fmt.Errorf("can't review `%s': %w", clientID, ErrRevoked)
+	}
+	if !ak.Pending() {
+		return Key{}, fmt.Errorf("%w: `%s'", ErrApproval, clientID)
+	}
+	reviewer := PrincipalFromContext(ctx)
+	if reviewer == "" || reviewer == ak.CreatedBy {
+		return Key{}, fmt.Errorf("%w: `%s'", ErrSelfApproval, clientID)
+	}
+	ak.ReviewedBy = reviewer
+	return ak, nil
+}
+
+// applyApprovalPolicy marks ak pending if the policy requires it. A pending
+// key must be created by the principal on ctx, so that review can tell its
+// creator apart: it fails with ErrSelfApproval if there is none, or if
+// WithCreatedBy attributed the key to someone else.
+func (o *options) applyApprovalPolicy(ctx context.Context, ak *Key) error {
+	if o.approvalRequired != nil && o.approvalRequired(*ak) {
+		ak.Approval = ApprovalPending
+	}
+	if !ak.Pending() {
+		return nil
+	}
+	principal := PrincipalFromContext(ctx)
+	if principal == "" {
+		return fmt.Errorf("%w: `%s' requires approval and has no principal to create it", ErrSelfApproval, ak.ClientID)
+	}
+	if ak.CreatedBy != principal {
+		return fmt.Errorf("%w: `%s' requires approval and can't be created by `%s' on behalf of `%s'",
+			ErrSelfApproval, ak.ClientID, principal, ak.CreatedBy)
+	}
+	return nil
+}
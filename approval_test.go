@@ -0,0 +1,98 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestApproval(t *testing.T) {
+	ctx := context.Background()
+	alice := WithPrincipal(ctx, "alice")
+	bob := WithPrincipal(ctx, "bob")
+	var requested, approved, rejected []string
+	hooks := WithHooks(Hooks{
+		OnApprovalRequested: func(ctx context.Context, ak Key) { requested = append(requested, ak.ClientID) },
+		OnApprove:           func(ctx context.Context, ak Key) { approved = append(approved, ak.ClientID) },
+		OnReject:            func(ctx context.Context, ak Key) { rejected = append(rejected, ak.ClientID) },
+	})
+	privileged := WithApprovalPolicy(func(ak Key) bool { return ak.Team == "platform" })
+	store := NewMemStore()
+	admin := NewAdmin(store, hooks, privileged)
+	verifier := NewStoreVerifier(store)
+
+	apikey, ak, err := admin.Create(alice, testAlg, WithClientID("root"), WithTeam("platform"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ak.Pending() {
+		t.Fatalf("Create() approval = %s, want pending", ak.Approval)
+	}
+	if _, err := verifier.Verify(ctx, apikey); !errors.Is(err, ErrPendingApproval) {
+		t.Errorf("Verify() pending error = %v, want ErrPendingApproval", err)
+	}
+	// Only the holder of the secret learns the key awaits approval
+	guess, err := NewKey(testAlg, WithClientID("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	guessed, err := guess.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, guessed); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() pending with a wrong secret error = %v, want ErrMismatch", err)
+	}
+	if _, err := admin.Approve(alice, "root"); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("Approve() by creator error = %v, want ErrSelfApproval", err)
+	}
+	if _, err := admin.Approve(ctx, "root"); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("Approve() without principal error = %v, want ErrSelfApproval", err)
+	}
+	got, err := admin.Approve(bob, "root")
+	if err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if got.Approval != ApprovalApproved || got.ReviewedBy != "bob" {
+		t.Errorf("Approve() = %s by %s, want approved by bob", got.Approval, got.ReviewedBy)
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Errorf("Verify() approved error = %v", err)
+	}
+	if _, err := admin.Approve(bob, "root"); !errors.Is(err, ErrApproval) {
+		t.Errorf("Approve() again error = %v, want ErrApproval", err)
+	}
+
+	// Keys outside the policy can still ask for approval
+	apikey, _, err = admin.Create(alice, testAlg, WithClientID("ops"), RequireApproval())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = admin.Reject(bob, "ops")
+	if err != nil {
+		t.Fatalf("Reject() error = %v", err)
+	}
+	if got.Approval != ApprovalRejected || !got.Revoked() {
+		t.Errorf("Reject() = %s revoked %v, want rejected and revoked", got.Approval, got.Revoked())
+	}
+	if _, err := verifier.Verify(ctx, apikey); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Verify() rejected error = %v, want ErrRevoked", err)
+	}
+
+	// The creator must be known and can't be attributed to the reviewer
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("anon"), RequireApproval()); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("Create() pending without principal error = %v, want ErrSelfApproval", err)
+	}
+	if _, _, err := admin.Create(alice, testAlg, WithClientID("forged"), RequireApproval(), WithCreatedBy("bob")); !errors.Is(err, ErrSelfApproval) {
+		t.Errorf("Create() pending WithCreatedBy other error = %v, want ErrSelfApproval", err)
+	}
+	if _, _, err := admin.Create(alice, testAlg, WithClientID("plain"), WithCreatedBy("bob")); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := admin.List(ctx, ApprovalFilter(ApprovalApproved)); len(keys) != 1 || keys[0].ClientID != "root" {
+		t.Errorf("List(approved) = %v, want root", keys)
+	}
+	if len(requested) != 2 || len(approved) != 1 || len(rejected) != 1 {
+		t.Errorf("hooks requested %v approved %v rejected %v", requested, approved, rejected)
+	}
+}
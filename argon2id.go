@@ -2,49 +2,144 @@ package apikeys
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
-	"strings"
+	"io"
+	"maps"
+	"time"
 
 	nanoid "github.com/matoous/go-nanoid"
-	"golang.org/x/crypto/argon2"
 )
 
 const (
-	StandardAlg   = "argon2id 3 64MB 32"
-	saltLen       = 32
-	passwordLen   = 32
-	apiKeyNameLen = 16
-	argon2Threads = 1
+	StandardAlg = "argon2id 3 64MB 32"
+	saltLen     = 32
+	passwordLen = 32
 
 	// 21 gives us similar properties to uuid.
 	defaultClientNanoIDLen = 21
 
-	apiKeySecretParts  = 3
-	apiKeyAlgPart      = 0 // alg.salt.password encoded together
-	apiKeySaltPart     = 1
-	apiKeyPasswordPart = 2
+	apiKeySecretParts = 3 // alg.salt.password encoded together
+	// apiKeyTenantParts is apiKeySecretParts with a leading tenant segment
+	apiKeyTenantParts = apiKeySecretParts + 1
+
+	// MaxTenantIDLen is the longest tenant id WithTenant accepts
+	MaxTenantIDLen = 64
+
+	// MaxEncodedKeyLen is the longest api key ValidateEncodedKey accepts
+	MaxEncodedKeyLen = 1024
+	// minSecretLen is the shortest salt or password ValidateEncodedKey accepts
+	minSecretLen = 16
+	// maxSaltLen is the longest salt WithSaltLength accepts
+	maxSaltLen = 64
+	// maxSecretLen is the longest password WithSecretLength accepts
+	maxSecretLen = 64
+
+	// encodeStackSize comfortably fits the inner layer of an api key with a
+	// generated client id and the default salt and password lengths.
+	encodeStackSize = 512
 )
 
 type Key struct {
-	alg Alg `firestore:"-" json:"-" protobuf:"-" mapstructure:"-"`
+	alg Alg `firestore:"-" json:"-" bson:"-" protobuf:"-" mapstructure:"-"`
+	// rand is the entropy source for generation, crypto/rand if nil
+	rand io.Reader `firestore:"-" json:"-" bson:"-" protobuf:"-" mapstructure:"-"`
+	// saltLen is the length of generated salts, saltLen if 0
+	saltLen int `firestore:"-" json:"-" bson:"-" protobuf:"-" mapstructure:"-"`
+	// secretLen is the length of generated passwords, passwordLen if 0
+	secretLen int `firestore:"-" json:"-" bson:"-" protobuf:"-" mapstructure:"-"`
+	// seps structure the encoded key, DefaultSeparators if zero
+	seps Separators `firestore:"-" json:"-" bson:"-" protobuf:"-" mapstructure:"-"`
 	// Salt is randomly generated when the password is generated. It is safe to (and must be) return to the api key holder
-	Salt []byte `firestore:"-" json:"-" protobuf:"-" mapstructure:"-"`
+	Salt Secret `firestore:"-" json:"-" bson:"-" protobuf:"-" mapstructure:"-"`
 	// DerivedKey is derived from a randomly generated password. The key is
 	// persistently stored. In the api key usage model this key is NOT
 	// sensitive. But also is NOT returned to the user - instead, we return the
 	// password and salt to the user. The password is NOT stored in this type
 	// ever.
-	DerivedKey []byte `firestore:"derived_key" json:"derived_key" protobuf:"derived_key" mapstructure:"derived_key"`
-
-	ClientID string `firestore:"client_id" json:"client_id" protobuf:"client_id" mapstructure:"client_id"`
+	DerivedKey Blob `firestore:"derived_key" json:"derived_key" bson:"derived_key" protobuf:"derived_key" mapstructure:"derived_key"`
+	// PreviousDerivedKey is the derived key replaced by Admin.RotateGracefully.
+	// It verifies until PreviousExpiresAt.
+	PreviousDerivedKey Blob      `firestore:"previous_derived_key" json:"previous_derived_key,omitempty" bson:"previous_derived_key" protobuf:"previous_derived_key" mapstructure:"previous_derived_key"`
+	PreviousExpiresAt  time.Time `firestore:"previous_expires_at" json:"previous_expires_at,omitzero" bson:"previous_expires_at" protobuf:"previous_expires_at" mapstructure:"previous_expires_at"`
+
+	ClientID string `firestore:"client_id" json:"client_id" bson:"client_id" protobuf:"client_id" mapstructure:"client_id"`
+	// TenantID, if set, is the tenant the key belongs to. It is embedded in
+	// the encoded key and must match the stored record, see TenantStore.
+	TenantID string `firestore:"tenant_id" json:"tenant_id,omitempty" bson:"tenant_id" protobuf:"tenant_id" mapstructure:"tenant_id"`
+
+	// Name, Description and Owner let operators tell keys apart. None of the
+	// metadata is part of the encoded key.
+	Name        string `firestore:"name" json:"name,omitempty" bson:"name" protobuf:"name" mapstructure:"name"`
+	Description string `firestore:"description" json:"description,omitempty" bson:"description" protobuf:"description" mapstructure:"description"`
+	Owner       string `firestore:"owner" json:"owner,omitempty" bson:"owner" protobuf:"owner" mapstructure:"owner"`
+	// Labels are free form key value pairs, eg for filtering Admin.List
+	Labels map[string]string `firestore:"labels" json:"labels,omitempty" bson:"labels" protobuf:"labels" mapstructure:"labels"`
+	// Type classifies the key, see KeyTypePolicy
+	Type KeyType `firestore:"type" json:"type,omitempty" bson:"type" protobuf:"type" mapstructure:"type"`
+
+	// CreatedBy is the principal that created the key, see WithPrincipal
+	CreatedBy string `firestore:"created_by" json:"created_by,omitempty" bson:"created_by" protobuf:"created_by" mapstructure:"created_by"`
+	// Team owns the key
+	Team string `firestore:"team" json:"team,omitempty" bson:"team" protobuf:"team" mapstructure:"team"`
+
+	// Restrictions limit where the key may be used
+	Restrictions Restrictions `firestore:"restrictions" json:"restrictions,omitzero" bson:"restrictions" protobuf:"restrictions" mapstructure:"restrictions"`
+
+	// Approval is set for keys that required approval, and ReviewedBy to the
+	// principal that approved or rejected them
+	Approval   ApprovalState `firestore:"approval" json:"approval,omitempty" bson:"approval" protobuf:"approval" mapstructure:"approval"`
+	ReviewedBy string        `firestore:"reviewed_by" json:"reviewed_by,omitempty" bson:"reviewed_by" protobuf:"reviewed_by" mapstructure:"reviewed_by"`
+
+	// CreatedAt is set when the key is added to a Store
+	CreatedAt time.Time `firestore:"created_at" json:"created_at" bson:"created_at" protobuf:"created_at" mapstructure:"created_at"`
+	// RotatedAt is set when the secret is replaced by Admin.Rotate
+	RotatedAt time.Time `firestore:"rotated_at" json:"rotated_at" bson:"rotated_at" protobuf:"rotated_at" mapstructure:"rotated_at"`
+	// RevokedAt is set when the key is revoked. A revoked key never verifies.
+	RevokedAt time.Time `firestore:"revoked_at" json:"revoked_at" bson:"revoked_at" protobuf:"revoked_at" mapstructure:"revoked_at"`
+	// ExpiresAt, if set, is the time after which the key no longer verifies
+	ExpiresAt time.Time `firestore:"expires_at" json:"expires_at" bson:"expires_at" protobuf:"expires_at" mapstructure:"expires_at"`
+	// LastUsedAt is when the key last verified, if recorded, see WithLastUsed
+	LastUsedAt time.Time `firestore:"last_used_at" json:"last_used_at" bson:"last_used_at" protobuf:"last_used_at" mapstructure:"last_used_at"`
+	// IdleTimeoutSeconds, if set, expires the key once it has gone unused for
+	// that long, see WithIdleTimeout
+	IdleTimeoutSeconds int64 `firestore:"idle_timeout_seconds" json:"idle_timeout_seconds,omitempty" bson:"idle_timeout_seconds" protobuf:"idle_timeout_seconds" mapstructure:"idle_timeout_seconds"`
+
+	// ImportedHash is a hash created by another system, set for records
+	// created by ImportHash until they are upgraded. See VerifySecret.
+	ImportedHash string `firestore:"imported_hash" json:"imported_hash,omitempty" bson:"imported_hash" protobuf:"imported_hash" mapstructure:"imported_hash"`
 }
 
 func (ak Key) Alg() Alg {
 	return ak.alg
 }
 
+// Revoked is true if the key has been revoked
+func (ak Key) Revoked() bool {
+	return !ak.RevokedAt.IsZero()
+}
+
+// Expired is true if the key has an expiry time and it is not after now
+func (ak Key) Expired(now time.Time) bool {
+	return !ak.ExpiresAt.IsZero() && !now.Before(ak.ExpiresAt)
+}
+
+// clone returns a copy of the key which does not share any byte slices with
+// the original
+func (ak Key) clone() Key {
+	c := ak
+	c.Salt = append([]byte(nil), ak.Salt...)
+	c.DerivedKey = append([]byte(nil), ak.DerivedKey...)
+	if ak.PreviousDerivedKey != nil {
+		c.PreviousDerivedKey = append([]byte(nil), ak.PreviousDerivedKey...)
+	}
+	c.Labels = maps.Clone(ak.Labels)
+	c.Restrictions = ak.Restrictions.clone()
+	return c
+}
+
 type KeyOption func(*Key)
 
 func WithClientID(clientID string) KeyOption {
@@ -53,6 +148,68 @@ func WithClientID(clientID string) KeyOption {
 	}
 }
 
+// WithTenant sets the tenant the key belongs to. Tenant ids are printable
+// ascii without the separators, ':' and '.' by default, at most
+// MaxTenantIDLen long.
+func WithTenant(tenantID string) KeyOption {
+	return func(ak *Key) {
+		ak.TenantID = tenantID
+	}
+}
+
+// ValidTenantID reports whether tenantID can be used with WithTenant and the
+// DefaultSeparators. Keys encoded with other separators, see WithSeparators,
+// may instead have tenant ids containing ':' or '.' but not their own.
+func ValidTenantID(tenantID string) bool {
+	return wellFormedTenantID(tenantID) && DefaultSeparators.check("", tenantID, "") == nil
+}
+
+// wellFormedTenantID checks the rules for tenant ids which don't depend on
+// the separators
+func wellFormedTenantID(tenantID string) bool {
+	return tenantID != "" && len(tenantID) <= MaxTenantIDLen && printable(tenantID)
+}
+
+// WithRand sets the source of randomness used to generate the salt and
+// password. The default is crypto/rand.Reader. It exists so tests can be
+// deterministic and so deployments can route entropy through a hardware RNG;
+// anything other than a cryptographically secure source produces guessable
+// keys. The reader must be safe for concurrent use if the key options are
+// shared, as they are by GenerateBatch.
+func WithRand(r io.Reader) KeyOption {
+	return func(ak *Key) {
+		ak.rand = r
+	}
+}
+
+// WithSaltLength sets the length of the salt generated for the key, 16 to 64
+// bytes, 32 by default. The salt is encoded in the api key, so keys with any
+// salt length decode and verify. Rotate and Transfer keep the length of the
+// stored salt.
+func WithSaltLength(n int) KeyOption {
+	return func(ak *Key) {
+		ak.saltLen = n
+	}
+}
+
+// WithSecretLength sets the length of the random password generated for the
+// key, 16 to 64 bytes, 32 by default. Shorter secrets are easier to type,
+// longer ones carry more entropy. Like the salt, the password is encoded in
+// the api key. The length is kept with the alg and salt in the json, bson and
+// firestore forms of the record, so Rotate and Transfer keep it.
+func WithSecretLength(n int) KeyOption {
+	return func(ak *Key) {
+		ak.secretLen = n
+	}
+}
+
+// WithExpiresAt sets the time after which the key no longer verifies
+func WithExpiresAt(t time.Time) KeyOption {
+	return func(ak *Key) {
+		ak.ExpiresAt = t
+	}
+}
+
 func NewKey(alg string, opts ...KeyOption) (Key, error) {
 
 	ak := Key{}
@@ -60,6 +217,18 @@ func NewKey(alg string, opts ...KeyOption) (Key, error) {
 	return ak, err
 }
 
+// SetAlg parses alg and sets it as the algorithm for the key, without
+// applying any other defaults. It is for restoring a record read from
+// storage or the wire.
+func (ak *Key) SetAlg(alg string) error {
+	a, err := ParseAlg(alg)
+	if err != nil {
+		return err
+	}
+	ak.alg = a
+	return nil
+}
+
 func (ak *Key) SetOptions(alg string, opts ...KeyOption) error {
 	var err error
 
@@ -68,61 +237,282 @@ func (ak *Key) SetOptions(alg string, opts ...KeyOption) error {
 		return err
 	}
 
+	clientID := ak.ClientID
 	for _, o := range opts {
 		o(ak)
 	}
+	// Existing records keep their client id, even if it has been reserved
+	// since, so they can still be rotated
+	if ak.ClientID != "" && ak.ClientID != clientID {
+		if err := checkClientID(ak.ClientID); err != nil {
+			return err
+		}
+	}
+	if ak.TenantID != "" && !wellFormedTenantID(ak.TenantID) {
+		return fmt.Errorf("bad tenant id `%s'", ak.TenantID)
+	}
+	if err := ak.validateMetadata(); err != nil {
+		return err
+	}
+	if !ak.Type.Valid() {
+		return fmt.Errorf("unknown key type `%s'", ak.Type)
+	}
+	if err := ak.Restrictions.validate(); err != nil {
+		return err
+	}
+	if !ak.Approval.Valid() {
+		return fmt.Errorf("unknown approval state `%s'", ak.Approval)
+	}
+	if ak.IdleTimeoutSeconds < 0 {
+		return fmt.Errorf("bad idle timeout %ds", ak.IdleTimeoutSeconds)
+	}
+	if ak.saltLen != 0 && (ak.saltLen < minSecretLen || ak.saltLen > maxSaltLen) {
+		return fmt.Errorf("salt length %d outside %d-%d", ak.saltLen, minSecretLen, maxSaltLen)
+	}
+	if ak.secretLen != 0 && (ak.secretLen < minSecretLen || ak.secretLen > maxSecretLen) {
+		return fmt.Errorf("secret length %d outside %d-%d", ak.secretLen, minSecretLen, maxSecretLen)
+	}
+	if err := ak.checkSeparators(); err != nil {
+		return err
+	}
 
 	// If we didn't get an explicit client id, make one up
-	if len(ak.ClientID) == 0 {
+	for i := 0; len(ak.ClientID) == 0; i++ {
+		if i == reservedAttempts {
+			return fmt.Errorf("%w: every generated client id", ErrReservedClientID)
+		}
 		ak.ClientID, err = nanoid.ID(defaultClientNanoIDLen)
 		if err != nil {
 			return nil
 		}
+		if ClientIDReserved(ak.ClientID) {
+			ak.ClientID = ""
+		}
 	}
 	return nil
 }
 
-func Decode(apikey string) (Key, []byte, error) {
+// checkSeparators checks the separators of ak are valid and that they can
+// structure its fields
+func (ak *Key) checkSeparators() error {
+	if err := ak.seps.Validate(); err != nil {
+		return err
+	}
+	return ak.seps.check(ak.ClientID, ak.TenantID, ak.alg.String)
+}
 
-	b, err := base64.URLEncoding.DecodeString(apikey)
+// Decode splits apikey into the presented Key, with its client id, alg and
+// salt, and the password. By default any well formed url safe base64 key is
+// accepted; opts tighten that.
+func Decode(apikey string, opts ...DecodeOption) (Key, Secret, error) {
+	var o decodeOptions
+	if len(opts) > 0 {
+		// Applying options moves them to the heap, don't pay for that on the
+		// common path
+		o = newDecodeOptions(opts)
+	}
+	return decode(apikey, nil, &o)
+}
+
+// ValidateEncodedKey checks that apikey is well formed without deriving
+// anything, so untrusted input can be rejected before it reaches a store or
+// the verifier. It is Decode with WithStrict: the encoded key must not exceed
+// MaxEncodedKeyLen, the client id must be printable ascii, the salt and
+// password must each be at least 16 bytes, and the key must be canonically
+// encoded.
+func ValidateEncodedKey(apikey string) error {
+	_, _, err := Decode(apikey, WithStrict())
+	return err
+}
+
+// printable reports whether s is entirely printable, non space, ascii
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// decode is Decode but the salt and password are decoded into buf if it has
+// the capacity. The caller owns buf and must not release it while the
+// returned Key or password are in use.
+func decode(apikey string, buf []byte, o *decodeOptions) (Key, Secret, error) {
+	if o.lenient {
+		apikey = trimPasted(apikey)
+	}
+	if maxLen := o.maxLength(); maxLen > 0 && len(apikey) > maxLen {
+		return Key{}, nil, fmt.Errorf("api key too long. got %d, max=%d", len(apikey), maxLen)
+	}
+	apikey, err := o.stripPrefix(apikey)
 	if err != nil {
 		return Key{}, nil, err
 	}
 
-	parts := strings.SplitN(string(b), ":", 3)
-	if len(parts) > 2 {
-		return Key{}, nil, fmt.Errorf("outer structure invalid want a single ':' separating client id from secret")
+	// The decoded outer layer only lives for the duration of the call, keep
+	// it on the stack when the key is a typical size.
+	var srcStack, innerStack [encodeStackSize]byte
+	src := append(srcStack[:0], apikey...)
+	// Decoding never produces more bytes than the source, whichever of the
+	// accepted encodings succeeds.
+	inner := innerStack[:0]
+	if len(src) > len(innerStack) {
+		inner = make([]byte, len(src))
 	}
+	inner = inner[:len(src)]
+	// The inner layer holds the encoded password, don't leave it behind.
+	defer clear(inner)
+	defer clear(src)
+
+	// Only the outer layer may be re-encoded by clients, the inner layer is
+	// always as Generate produced it.
+	var n int
+	var outer *base64.Encoding
+	for _, outer = range o.accepted() {
+		if n, err = outer.Decode(inner, src); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return Key{}, nil, err
+	}
+	inner = inner[:n]
+	if o.strict && !canonical(outer, inner, src) {
+		return Key{}, nil, fmt.Errorf("api key is not canonically encoded")
+	}
+	enc := base64.URLEncoding
+	if err := o.seps.Validate(); err != nil {
+		return Key{}, nil, err
+	}
+	seps := o.seps.orDefault()
 
-	ak := Key{ClientID: parts[0]}
-
-	parts = strings.SplitN(string(parts[1]), ".", apiKeySecretParts+1)
+	clientID, secret, ok := bytes.Cut(inner, []byte{seps.ID})
+	if !ok || bytes.IndexByte(secret, seps.ID) >= 0 {
+		return Key{}, nil, fmt.Errorf("outer structure invalid want a single '%c' separating client id from secret", seps.ID)
+	}
+	if len(clientID) == 0 {
+		return Key{}, nil, fmt.Errorf("missing client id")
+	}
 
-	if len(parts) != apiKeySecretParts {
+	var tenantPart []byte
+	nparts := bytes.Count(secret, []byte{seps.Part}) + 1
+	switch nparts {
+	case apiKeyTenantParts:
+		tenantPart, secret, _ = bytes.Cut(secret, []byte{seps.Part})
+	case apiKeySecretParts:
+	default:
 		return Key{}, nil, fmt.Errorf(
-			"invalid number of '.' seperated secret parts in api key. got %d, wanted %d", len(parts), apiKeySecretParts)
+			"invalid number of '%c' seperated secret parts in api key. got %d, wanted %d", seps.Part, nparts, apiKeySecretParts)
 	}
-
-	ak.alg, err = ParseAlg(parts[apiKeyAlgPart])
+	algPart, rest, _ := bytes.Cut(secret, []byte{seps.Part})
+	saltPart, passwordPart, _ := bytes.Cut(rest, []byte{seps.Part})
+
+	ak := Key{ClientID: string(clientID)}
+	if tenantPart != nil {
+		ak.TenantID = string(tenantPart)
+		if !wellFormedTenantID(ak.TenantID) {
+			return Key{}, nil, fmt.Errorf("bad tenant id %q", ak.TenantID)
+		}
+	}
+	ak.alg, err = ParseAlg(string(algPart))
 	if err != nil {
 		return Key{}, nil, err
 	}
+	if !o.allowAlg(ak.alg.String) {
+		return Key{}, nil, fmt.Errorf("alg `%s' not allowed", ak.alg.String)
+	}
+	if o.strict && !printable(ak.ClientID) {
+		return Key{}, nil, fmt.Errorf("client id contains invalid characters %q", ak.ClientID)
+	}
+
+	// salt and password share a single allocation
+	saltMax := enc.DecodedLen(len(saltPart))
+	if need := saltMax + enc.DecodedLen(len(passwordPart)); cap(buf) >= need {
+		buf = buf[:need]
+	} else {
+		buf = make([]byte, need)
+	}
 
-	ak.Salt, err = base64.URLEncoding.DecodeString(parts[apiKeySaltPart])
+	n, err = enc.Decode(buf[:saltMax], saltPart)
 	if err != nil {
 		return Key{}, nil, err
 	}
-	password, err := base64.URLEncoding.DecodeString(parts[apiKeyPasswordPart])
+	if n == 0 {
+		return Key{}, nil, fmt.Errorf("missing salt")
+	}
+	ak.Salt = buf[:n:n]
+
+	n, err = enc.Decode(buf[saltMax:], passwordPart)
 	if err != nil {
 		return Key{}, nil, err
 	}
+	if n == 0 {
+		return Key{}, nil, fmt.Errorf("missing password")
+	}
+	password := buf[saltMax : saltMax+n : saltMax+n]
+
+	if o.strict {
+		if !canonical(enc, ak.Salt, saltPart) || !canonical(enc, password, passwordPart) {
+			return Key{}, nil, fmt.Errorf("salt or password is not canonically encoded")
+		}
+		if len(ak.Salt) < minSecretLen {
+			return Key{}, nil, fmt.Errorf("salt to small. got %d, min=%d", len(ak.Salt), minSecretLen)
+		}
+		if len(password) < minSecretLen {
+			return Key{}, nil, fmt.Errorf("password to small. got %d, min=%d", len(password), minSecretLen)
+		}
+	}
 
 	return ak, password, nil
 }
 
+// canonical reports whether src is exactly how enc encodes decoded. Decoding
+// skips newlines and ignores the unused bits of the last character, so
+// several strings decode the same way; only one of them is canonical.
+func canonical(enc *base64.Encoding, decoded, src []byte) bool {
+	if enc.EncodedLen(len(decoded)) != len(src) {
+		return false
+	}
+	var stack [encodeStackSize]byte
+	buf := stack[:0]
+	if len(src) > len(stack) {
+		buf = make([]byte, len(src))
+	}
+	buf = buf[:len(src)]
+	defer clear(buf)
+	enc.Encode(buf, decoded)
+	return bytes.Equal(buf, src)
+}
+
 func (ak *Key) RecoverKey(password []byte) []byte {
 
-	return argon2.IDKey(password, ak.Salt, ak.alg.Time, ak.alg.Memory, argon2Threads, ak.alg.KeyLen)
+	return ak.alg.derive(password, ak.Salt)
+}
+
+// RecoverKeyContext is RecoverKey but returns ctx.Err() instead of starting
+// the derivation if ctx is already done. A derivation can not be interrupted
+// once started.
+func (ak *Key) RecoverKeyContext(ctx context.Context, password []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ak.RecoverKey(password), nil
+}
+
+// VerifyContext is the context aware form of MatchPassword. It returns nil if
+// password derives key, ErrMismatch if it does not, or ctx.Err() if ctx was
+// done before the derivation started.
+func (ak *Key) VerifyContext(ctx context.Context, password, key []byte) error {
+	derived, err := ak.RecoverKeyContext(ctx, password)
+	if err != nil {
+		return err
+	}
+	ak.DerivedKey = derived
+	if !bytes.Equal(derived, key) {
+		return ErrMismatch
+	}
+	return nil
 }
 
 func (ak *Key) MatchPassword(password, key []byte) bool {
@@ -137,29 +527,43 @@ func (ak *Key) EncodedKey() string {
 	return base64.URLEncoding.EncodeToString(ak.DerivedKey)
 }
 
-func (ak *Key) generatePasword() ([]byte, error) {
-
-	ak.Salt = make([]byte, saltLen)
-	n, err := rand.Read(ak.Salt)
-	if err != nil {
-		return nil, err
+// passwordLen is the length of the password Generate creates
+func (ak *Key) passwordLen() int {
+	if ak.secretLen != 0 {
+		return ak.secretLen
 	}
-	if n != saltLen {
-		return nil, fmt.Errorf("insufficient rand bytes generating salt")
+	return passwordLen
+}
+
+// generatePasword fills password with random bytes, generates a new salt and
+// derives the key
+func (ak *Key) generatePasword(password []byte) error {
+
+	r := ak.rand
+	if r == nil {
+		r = rand.Reader
 	}
 
-	password := make([]byte, passwordLen)
-	n, err = rand.Read(password)
-	if err != nil {
-		return nil, err
+	n := ak.saltLen
+	if n == 0 {
+		// A stored key being regenerated keeps the length of its salt
+		n = len(ak.Salt)
+	}
+	if n == 0 {
+		n = saltLen
 	}
-	if n != passwordLen {
-		return nil, fmt.Errorf("insufficient rand bytes generating password")
+	ak.Salt = make([]byte, n)
+	if _, err := io.ReadFull(r, ak.Salt); err != nil {
+		return fmt.Errorf("insufficient rand bytes generating salt: %w", err)
+	}
+
+	if _, err := io.ReadFull(r, password); err != nil {
+		return fmt.Errorf("insufficient rand bytes generating password: %w", err)
 	}
 
-	ak.DerivedKey = argon2.IDKey(password, ak.Salt, ak.alg.Time, ak.alg.Memory, argon2Threads, ak.alg.KeyLen)
+	ak.DerivedKey = ak.alg.derive(password, ak.Salt)
 
-	return password, nil
+	return nil
 }
 
 // Generate creates a new random password and salt and encodes it for delivery
@@ -168,16 +572,84 @@ func (ak *Key) generatePasword() ([]byte, error) {
 // The format is chosen to be compatible with client_credentials flow where the
 // client_id:secret are delivered together in a in an "Authorization: Basic
 // base64(id:secret)" header. The token endpoint needs to be aware of what to do
-// with the secret part in order for that to work.
+// with the secret part in order for that to work. A key with a TenantID
+// carries it as a leading segment of the secret, clientid:tenant.alg...
 func (ak *Key) Generate() (string, error) {
-	password, err := ak.generatePasword()
-	if err != nil {
+	buf := getSecretBuf()
+	defer putSecretBuf(buf)
+	password := (*buf)[:ak.passwordLen()]
+
+	if err := ak.generatePasword(password); err != nil {
 		return "", err
 	}
-	salt := base64.URLEncoding.EncodeToString(ak.Salt)
-	secret := base64.URLEncoding.EncodeToString(password)
+	return ak.encode(password), nil
+}
 
-	secret = strings.Join([]string{ak.alg.String, salt, secret}, ".")
-	secret = strings.Join([]string{ak.ClientID, secret}, ":")
-	return base64.URLEncoding.EncodeToString([]byte(secret)), nil
+// GenerateBytes is Generate but returns the encoded api key as a Secret. Go
+// strings can't be cleared, so the string returned by Generate stays in
+// memory until it is garbage collected and the memory reused. Callers which
+// need to control the lifetime of the only copy of the password should use
+// GenerateBytes and call Wipe once the key has been delivered.
+func (ak *Key) GenerateBytes() (Secret, error) {
+	buf := getSecretBuf()
+	defer putSecretBuf(buf)
+	password := (*buf)[:ak.passwordLen()]
+
+	if err := ak.generatePasword(password); err != nil {
+		return nil, err
+	}
+	return ak.appendEncode(nil, password), nil
+}
+
+// Wipe zeroes the Salt, DerivedKey and PreviousDerivedKey of the key in
+// place. Use it once a record is no longer needed, eg after a presented key
+// has been verified.
+func (ak *Key) Wipe() {
+	clear(ak.Salt)
+	clear(ak.DerivedKey)
+	clear(ak.PreviousDerivedKey)
+	ak.Salt = nil
+	ak.DerivedKey = nil
+	ak.PreviousDerivedKey = nil
+}
+
+// encode formats the api key for password
+func (ak *Key) encode(password []byte) string {
+	return string(ak.appendEncode(nil, password))
+}
+
+// appendEncode appends the api key for password to dst. The inner layer is
+// built in a single pre-sized buffer, on the stack for typical sizes, and
+// cleared once the outer encoding is done so the plaintext does not linger.
+func (ak *Key) appendEncode(dst, password []byte) []byte {
+	enc := base64.URLEncoding
+	n := len(ak.ClientID) + 1 + len(ak.alg.String) + 1 + enc.EncodedLen(len(ak.Salt)) + 1 + enc.EncodedLen(len(password))
+	if ak.TenantID != "" {
+		n += len(ak.TenantID) + 1
+	}
+
+	var stack [encodeStackSize]byte
+	inner := stack[:0]
+	if n > len(stack) {
+		inner = make([]byte, 0, n)
+	}
+	defer clear(inner[:n])
+
+	seps := ak.seps.orDefault()
+	inner = append(inner, ak.ClientID...)
+	inner = append(inner, seps.ID)
+	if ak.TenantID != "" {
+		inner = append(inner, ak.TenantID...)
+		inner = append(inner, seps.Part)
+	}
+	inner = append(inner, ak.alg.String...)
+	inner = append(inner, seps.Part)
+	inner = enc.AppendEncode(inner, ak.Salt)
+	inner = append(inner, seps.Part)
+	inner = enc.AppendEncode(inner, password)
+
+	if dst == nil {
+		dst = make([]byte, 0, enc.EncodedLen(n))
+	}
+	return enc.AppendEncode(dst, inner)
 }
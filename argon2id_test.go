@@ -1,8 +1,16 @@
 package apikeys
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+
+	"golang.org/x/crypto/argon2"
 )
 
 func TestNewAPIKey(t *testing.T) {
@@ -24,7 +32,7 @@ func TestNewAPIKey(t *testing.T) {
 			Key{
 				alg: Alg{
 					String:         "argon2id 3 64MB 32",
-					ParamsArgon2ID: ParamsArgon2ID{Time: 3, Memory: 64 * memoryUnits, KeyLen: 32}},
+					ParamsArgon2ID: ParamsArgon2ID{Time: 3, Memory: 64 * memoryUnits, KeyLen: 32, Threads: 1}},
 			}, "", false, false,
 		},
 	}
@@ -93,3 +101,316 @@ func TestNewAPIKey(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyContext(t *testing.T) {
+	ak, err := NewKey("argon2id 1 16MB 16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	presented, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := presented.VerifyContext(context.Background(), password, ak.DerivedKey); err != nil {
+		t.Errorf("VerifyContext() error = %v", err)
+	}
+	if err := presented.VerifyContext(context.Background(), []byte("wrong"), ak.DerivedKey); !errors.Is(err, ErrMismatch) {
+		t.Errorf("VerifyContext() wrong password error = %v, want %v", err, ErrMismatch)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := presented.RecoverKeyContext(ctx, password); !errors.Is(err, context.Canceled) {
+		t.Errorf("RecoverKeyContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func benchmarkKey(b *testing.B) (Key, []byte) {
+	b.Helper()
+	ak, err := NewKey(StandardAlg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ak.Salt = make([]byte, saltLen)
+	return ak, make([]byte, passwordLen)
+}
+
+func BenchmarkEncode(b *testing.B) {
+	ak, password := benchmarkKey(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ak.encode(password)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	ak, password := benchmarkKey(b)
+	apikey := ak.encode(password)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Decode(apikey); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	enc := func(s string) string { return base64.URLEncoding.EncodeToString([]byte(s)) }
+	salt := base64.URLEncoding.EncodeToString(make([]byte, saltLen))
+	tests := []struct {
+		name   string
+		apikey string
+	}{
+		{"not base64", "not base64!"},
+		{"two colons", enc("a:b:" + StandardAlg + "." + salt + "." + salt)},
+		{"too few parts", enc("a:" + StandardAlg + "." + salt)},
+		{"too many parts", enc("a:" + StandardAlg + "." + salt + "." + salt + "." + salt)},
+		{"bad alg", enc("a:argon2id 9 64MB 32." + salt + "." + salt)},
+		{"bad salt", enc("a:" + StandardAlg + ".!!!." + salt)},
+		{"bad password", enc("a:" + StandardAlg + "." + salt + ".!!!")},
+		{"empty client id", enc(":" + StandardAlg + "." + salt + "." + salt)},
+		{"empty salt", enc("a:" + StandardAlg + ".." + salt)},
+		{"empty password", enc("a:" + StandardAlg + "." + salt + ".")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Decode(tt.apikey); err == nil {
+				t.Errorf("Decode(%s) succeeded", tt.apikey)
+			}
+		})
+	}
+}
+
+func TestWithRand(t *testing.T) {
+	generate := func(seed byte) (string, Key) {
+		t.Helper()
+		r := bytes.NewReader(bytes.Repeat([]byte{seed}, saltLen+passwordLen))
+		ak, err := NewKey("argon2id 1 16MB 16", WithClientID("client-1"), WithRand(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		apikey, err := ak.Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		return apikey, ak
+	}
+
+	a, ak := generate(1)
+	b, _ := generate(1)
+	c, _ := generate(2)
+	if a != b {
+		t.Errorf("same entropy gave different keys %s and %s", a, b)
+	}
+	if a == c {
+		t.Errorf("different entropy gave the same key")
+	}
+	if !bytes.Equal(ak.Salt, bytes.Repeat([]byte{1}, saltLen)) {
+		t.Errorf("salt %x not read from the injected source", ak.Salt)
+	}
+
+	short, err := NewKey("argon2id 1 16MB 16", WithRand(bytes.NewReader(make([]byte, saltLen))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := short.Generate(); err == nil {
+		t.Errorf("Generate() with exhausted entropy succeeded")
+	}
+}
+
+func TestWithSaltLength(t *testing.T) {
+	type args struct {
+		n int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{"default", args{0}, saltLen, false},
+		{"min", args{16}, 16, false},
+		{"max", args{64}, 64, false},
+		{"to small", args{15}, 0, true},
+		{"to large", args{65}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ak, err := NewKey(testAlg, WithClientID("client-1"), WithSaltLength(tt.args.n))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			apikey, err := ak.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ak.Salt) != tt.want {
+				t.Errorf("generated a %d byte salt, want %d", len(ak.Salt), tt.want)
+			}
+			presented, password, err := Decode(apikey, WithStrict())
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !bytes.Equal(presented.Salt, ak.Salt) || !presented.MatchPassword(password, ak.DerivedKey) {
+				t.Errorf("salt of %d bytes did not round trip", tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSecretLength(t *testing.T) {
+	type args struct {
+		n int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{"default", args{0}, passwordLen, false},
+		{"human entry", args{16}, 16, false},
+		{"high security", args{64}, 64, false},
+		{"to small", args{15}, 0, true},
+		{"to large", args{65}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ak, err := NewKey(testAlg, WithClientID("client-1"), WithSecretLength(tt.args.n))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			apikey, err := ak.GenerateBytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			presented, password, err := Decode(string(apikey), WithStrict())
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if len(password) != tt.want {
+				t.Errorf("generated a %d byte secret, want %d", len(password), tt.want)
+			}
+			if !presented.MatchPassword(password, ak.DerivedKey) {
+				t.Errorf("secret of %d bytes does not verify", tt.want)
+			}
+		})
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	ak, err := NewKey(StandardAlg, WithClientID("client-1"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	ak.Salt = make([]byte, saltLen)
+	salt := base64.URLEncoding.EncodeToString(ak.Salt)
+	f.Add(ak.encode(make([]byte, passwordLen)), true)
+	f.Add("client-1:"+StandardAlg+"."+salt+"."+salt, false)
+	f.Add("no colon", false)
+	f.Add("a:b", false)
+	f.Add("a:"+StandardAlg+"..", false)
+	f.Add("", true)
+	// raw selects fuzzing the encoded key directly, otherwise the fuzzed
+	// string is the inner layer and is base64 encoded first, which gets the
+	// fuzzer past the outer encoding far more often.
+	f.Fuzz(func(t *testing.T, apikey string, raw bool) {
+		if !raw {
+			apikey = base64.URLEncoding.EncodeToString([]byte(apikey))
+		}
+		presented, password, err := Decode(apikey)
+		if err != nil {
+			if ValidateEncodedKey(apikey) == nil {
+				t.Errorf("ValidateEncodedKey(%q) accepted a key Decode rejects: %v", apikey, err)
+			}
+			return
+		}
+		// Whatever decodes must re-encode to something that decodes the same
+		again, againPassword, err := Decode(presented.encode(password))
+		if err != nil {
+			t.Fatalf("re-encoded key for %q does not decode: %v", apikey, err)
+		}
+		if again.ClientID != presented.ClientID || again.alg != presented.alg ||
+			!bytes.Equal(again.Salt, presented.Salt) || !bytes.Equal(againPassword, password) {
+			t.Errorf("round trip of %q changed the key", apikey)
+		}
+	})
+}
+
+func TestValidateEncodedKey(t *testing.T) {
+	enc := func(s string) string { return base64.URLEncoding.EncodeToString([]byte(s)) }
+	salt := base64.URLEncoding.EncodeToString(make([]byte, saltLen))
+	short := base64.URLEncoding.EncodeToString(make([]byte, 8))
+	tests := []struct {
+		name    string
+		apikey  string
+		wantErr bool
+	}{
+		{"good", enc("client-1:" + StandardAlg + "." + salt + "." + salt), false},
+		{"undecodable", "!!", true},
+		{"too long", strings.Repeat("A", MaxEncodedKeyLen+4), true},
+		{"space in client id", enc("client 1:" + StandardAlg + "." + salt + "." + salt), true},
+		{"control char in client id", enc("client\x001:" + StandardAlg + "." + salt + "." + salt), true},
+		{"short salt", enc("client-1:" + StandardAlg + "." + short + "." + salt), true},
+		{"short password", enc("client-1:" + StandardAlg + "." + salt + "." + short), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateEncodedKey(tt.apikey); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEncodedKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNamedAlgThreads(t *testing.T) {
+	ak, err := NewKey("argon2id t=1,m=16MB,len=16,p=2", WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	presented, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !presented.MatchPassword(password, ak.DerivedKey) {
+		t.Errorf("named alg key does not verify")
+	}
+
+	// Parallelism is part of the derivation, not just the alg string
+	single := presented
+	single.alg.Threads = 1
+	if bytes.Equal(single.RecoverKey(password), ak.DerivedKey) {
+		t.Errorf("p=2 derived the same key as p=1")
+	}
+}
+
+func TestPrehash(t *testing.T) {
+	ak, err := NewKey("argon2id t=1,m=16MB,len=16,prehash=sha512", WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An imported secret far longer than a generated one
+	secret := bytes.Repeat([]byte("long secret "), 1<<16)
+	sum := sha512.Sum512(secret)
+	want := argon2.IDKey(sum[:], ak.Salt, 1, 16*1024, 1, 16)
+	if got := ak.RecoverKey(secret); !bytes.Equal(got, want) {
+		t.Errorf("RecoverKey() = %x, want argon2id of the sha512 %x", got, want)
+	}
+	if !ak.MatchPassword(secret, want) || ak.MatchPassword(secret[1:], want) {
+		t.Errorf("MatchPassword() of a prehashed secret")
+	}
+}
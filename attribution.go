@@ -0,0 +1,45 @@
+package apikeys
+
+import "context"
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the id of the principal, eg the
+// authenticated operator, on whose behalf admin operations are performed.
+// Admin.Create records it as CreatedBy and audit events report it as Actor.
+func WithPrincipal(ctx context.Context, principalID string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principalID)
+}
+
+// PrincipalFromContext returns the principal set by WithPrincipal, or ""
+func PrincipalFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(principalKey{}).(string)
+	return id
+}
+
+// WithCreatedBy records principalID as the creator of the key, overriding
+// the principal in the context. Keys requiring approval can't be attributed
+// to anyone but that principal.
+func WithCreatedBy(principalID string) KeyOption {
+	return func(ak *Key) {
+		ak.CreatedBy = principalID
+	}
+}
+
+// WithTeam sets the team that owns the key, eg so leaked-key incidents can be
+// routed to it
+func WithTeam(team string) KeyOption {
+	return func(ak *Key) {
+		ak.Team = team
+	}
+}
+
+// TeamFilter selects records owned by team
+func TeamFilter(team string) KeyFilter {
+	return func(ak Key) bool { return ak.Team == team }
+}
+
+// CreatedByFilter selects records created by principalID
+func CreatedByFilter(principalID string) KeyFilter {
+	return func(ak Key) bool { return ak.CreatedBy == principalID }
+}
@@ -0,0 +1,58 @@
+package apikeys
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAttribution(t *testing.T) {
+	var audit bytes.Buffer
+	admin := NewAdmin(NewMemStore(), WithAudit(NewWriterAuditSink(&audit)))
+	ctx := WithPrincipal(t.Context(), "alice@example.com")
+
+	_, ak, err := admin.Create(ctx, testAlg, WithClientID("client-1"), WithTeam("payments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ak.CreatedBy != "alice@example.com" || ak.Team != "payments" {
+		t.Errorf("Create() CreatedBy = %q, Team = %q", ak.CreatedBy, ak.Team)
+	}
+	_, other, err := admin.Create(ctx, testAlg, WithCreatedBy("provisioner"))
+	if err != nil || other.CreatedBy != "provisioner" {
+		t.Errorf("Create(WithCreatedBy) CreatedBy = %q, %v", other.CreatedBy, err)
+	}
+	if _, err := admin.Revoke(WithPrincipal(t.Context(), "bob@example.com"), "client-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := decodeEvents(t, audit.Bytes())
+	if len(events) != 3 {
+		t.Fatalf("got %d events", len(events))
+	}
+	if ev := events[0]; ev.Actor != "alice@example.com" || ev.Team != "payments" {
+		t.Errorf("created event = %+v", ev)
+	}
+	if ev := events[2]; ev.Type != AuditKeyRevoked || ev.Actor != "bob@example.com" || ev.Team != "payments" {
+		t.Errorf("revoked event = %+v", ev)
+	}
+
+	keys, err := admin.List(t.Context(), TeamFilter("payments"), CreatedByFilter("alice@example.com"))
+	if err != nil || len(keys) != 1 || keys[0].ClientID != "client-1" {
+		t.Errorf("List() = %v, %v", keys, err)
+	}
+}
+
+func TestAttributionSIEM(t *testing.T) {
+	ev := AuditEvent{Type: AuditKeyCreated, ClientID: "client-1", Actor: "alice", Team: "payments"}
+	if got := string(formatCEF(ev, "v", "p")); !strings.Contains(got, "cs2Label=actor cs2=alice cs3Label=team cs3=payments") {
+		t.Errorf("CEF = %s", got)
+	}
+	b, err := formatECS(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, `"actor":"alice"`) || !strings.Contains(got, `"team":"payments"`) {
+		t.Errorf("ECS = %s", got)
+	}
+}
@@ -0,0 +1,201 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit event types
+const (
+	AuditKeyCreated     = "key.created"
+	AuditKeyRotated     = "key.rotated"
+	AuditKeyRevoked     = "key.revoked"
+	AuditKeyImported    = "key.imported"
+	AuditKeyUpgraded    = "key.upgraded"
+	AuditKeyTransferred = "key.transferred"
+	AuditKeyShredded    = "key.shredded"
+	AuditKeyPurged      = "key.purged"
+	AuditKeyApproved    = "key.approved"
+	AuditKeyRejected    = "key.rejected"
+	AuditKeyFinalized   = "key.rotation_finalized"
+	AuditKeyExpired     = "key.expired"
+	AuditKeyExpiring    = "key.expiring_soon"
+	AuditKeyDeleted     = "key.deleted"
+	AuditKeyAnomaly     = "key.anomaly"
+	AuditKeyLeaked      = "key.leaked"
+	AuditVerifySuccess  = "key.verified"
+	AuditVerifyFailed   = "key.verify_failed"
+)
+
+// AuditEvent is a single record in the audit trail. It never carries secrets
+// or derived keys.
+type AuditEvent struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	ClientID string    `json:"client_id,omitempty"`
+	Alg      string    `json:"alg,omitempty"`
+	// Result is one of the Result constants for verification events
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Actor is the principal performing the operation, see WithPrincipal
+	Actor string `json:"actor,omitempty"`
+	// Team owns the key, if known
+	Team string `json:"team,omitempty"`
+}
+
+// AuditSink receives audit events. Emit is called synchronously from the
+// operation being audited, so slow sinks slow down verification. The
+// operations do not fail if the sink returns an error; sinks which must not
+// lose events need to buffer and retry internally.
+type AuditSink interface {
+	Emit(ctx context.Context, ev AuditEvent) error
+}
+
+// WithAudit sends audit events for key creation, rotation, revocation and
+// every verification to sink.
+func WithAudit(sink AuditSink) Option {
+	return func(o *options) {
+		o.audit = sink
+	}
+}
+
+func (o *options) emit(ctx context.Context, typ string, ak Key, err error) {
+	if o.audit == nil {
+		return
+	}
+	ev := AuditEvent{
+		Time: o.now(), Type: typ, ClientID: ak.ClientID, Alg: ak.alg.String,
+		Actor: PrincipalFromContext(ctx), Team: ak.Team,
+	}
+	if typ == AuditVerifySuccess || typ == AuditVerifyFailed {
+		ev.Result = VerifyResult(err)
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	o.emitEvent(ctx, ev)
+}
+
+func (o *options) emitEvent(ctx context.Context, ev AuditEvent) {
+	if err := o.audit.Emit(ctx, ev); err != nil {
+		o.warn(ctx, "audit sink failed", slog.String("type", ev.Type), slog.String("client_id", ev.ClientID), slog.Any("error", err))
+	}
+}
+
+// WriterAuditSink writes each event as a line of json
+type WriterAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterAuditSink(w io.Writer) *WriterAuditSink {
+	return &WriterAuditSink{w: w}
+}
+
+// NewStdoutAuditSink writes json lines to stdout
+func NewStdoutAuditSink() *WriterAuditSink {
+	return NewWriterAuditSink(os.Stdout)
+}
+
+func (s *WriterAuditSink) Emit(ctx context.Context, ev AuditEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(b)
+	return err
+}
+
+// FileAuditSink appends json lines to a file. The file is opened append only
+// so existing records are never rewritten.
+type FileAuditSink struct {
+	WriterAuditSink
+	f *os.File
+}
+
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{WriterAuditSink: WriterAuditSink{w: f}, f: f}, nil
+}
+
+// Emit writes the event and syncs the file so that it survives a crash
+func (s *FileAuditSink) Emit(ctx context.Context, ev AuditEvent) error {
+	if err := s.WriterAuditSink.Emit(ctx, ev); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *FileAuditSink) Close() error {
+	return s.f.Close()
+}
+
+// WebhookAuditSink posts each event as json to a url
+type WebhookAuditSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookAuditSink posts to url using client, or http.DefaultClient if
+// client is nil
+func NewWebhookAuditSink(url string, client *http.Client) *WebhookAuditSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookAuditSink{url: url, client: client}
+}
+
+func (s *WebhookAuditSink) Emit(ctx context.Context, ev AuditEvent) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook `%s' returned %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+type multiAuditSink []AuditSink
+
+// MultiAuditSink emits every event to each of sinks in turn, eg to both an
+// audit log and a WebhookDispatcher
+func MultiAuditSink(sinks ...AuditSink) AuditSink {
+	return multiAuditSink(sinks)
+}
+
+func (m multiAuditSink) Emit(ctx context.Context, ev AuditEvent) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Emit(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
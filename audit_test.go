@@ -0,0 +1,130 @@
+package apikeys
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func decodeEvents(t *testing.T, b []byte) []AuditEvent {
+	t.Helper()
+	var events []AuditEvent
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		var ev AuditEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatalf("bad audit line %s: %v", sc.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	sink := NewWriterAuditSink(&buf)
+	store := NewMemStore()
+	admin := NewAdmin(store, WithAudit(sink))
+	verifier := NewStoreVerifier(store, WithAudit(sink))
+
+	apikey, _, err := admin.Create(ctx, testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	verifier.Verify(ctx, apikey)
+	if _, _, err := admin.Rotate(ctx, "client-1", ""); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	verifier.Verify(ctx, apikey)
+	if _, err := admin.Revoke(ctx, "client-1"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	want := []AuditEvent{
+		{Type: AuditKeyCreated, ClientID: "client-1", Alg: testAlg},
+		{Type: AuditVerifySuccess, ClientID: "client-1", Alg: testAlg, Result: ResultOK},
+		{Type: AuditKeyRotated, ClientID: "client-1", Alg: testAlg},
+		{Type: AuditVerifyFailed, ClientID: "client-1", Alg: testAlg, Result: ResultMismatch, Error: ErrMismatch.Error()},
+		{Type: AuditKeyRevoked, ClientID: "client-1", Alg: testAlg},
+	}
+	got := decodeEvents(t, buf.Bytes())
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+		got[i].Time = want[i].Time
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileAuditSink(path)
+		if err != nil {
+			t.Fatalf("NewFileAuditSink() error = %v", err)
+		}
+		if err := sink.Emit(context.Background(), AuditEvent{Type: AuditKeyCreated}); err != nil {
+			t.Fatalf("Emit() error = %v", err)
+		}
+		sink.Close()
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reopening must append rather than truncate
+	if got := decodeEvents(t, b); len(got) != 2 {
+		t.Errorf("got %d events, want 2", len(got))
+	}
+}
+
+func TestWebhookAuditSink(t *testing.T) {
+	var got AuditEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	sink := NewWebhookAuditSink(srv.URL, nil)
+	if err := sink.Emit(context.Background(), AuditEvent{Type: AuditKeyRevoked, ClientID: "c"}); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	if got.Type != AuditKeyRevoked || got.ClientID != "c" {
+		t.Errorf("webhook received %+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := NewWebhookAuditSink(failing.URL, nil).Emit(context.Background(), AuditEvent{}); err == nil {
+		t.Errorf("Emit() to failing webhook succeeded")
+	}
+}
+
+func TestMultiAuditSink(t *testing.T) {
+	var a, b bytes.Buffer
+	failing := NewWebhookAuditSink("http://127.0.0.1:0", nil)
+	sink := MultiAuditSink(NewWriterAuditSink(&a), failing, NewWriterAuditSink(&b))
+	if err := sink.Emit(context.Background(), AuditEvent{Type: AuditKeyRevoked}); err == nil {
+		t.Error("Emit() with a failing sink succeeded")
+	}
+	if len(decodeEvents(t, a.Bytes())) != 1 || len(decodeEvents(t, b.Bytes())) != 1 {
+		t.Errorf("sinks got %q and %q, want an event each", a.String(), b.String())
+	}
+}
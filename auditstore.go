@@ -0,0 +1,98 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AuditQuery selects audit events. Zero fields don't restrict the result.
+type AuditQuery struct {
+	ClientID string
+	// Types are the event types wanted, any of them
+	Types []string
+	// Since is the earliest event time wanted and Until the first time past
+	// the ones wanted
+	Since time.Time
+	Until time.Time
+	// Limit caps the number of events, which are returned newest first
+	Limit int
+}
+
+func (q AuditQuery) match(ev AuditEvent) bool {
+	return (q.ClientID == "" || ev.ClientID == q.ClientID) &&
+		(len(q.Types) == 0 || slices.Contains(q.Types, ev.Type)) &&
+		(q.Since.IsZero() || !ev.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || ev.Time.Before(q.Until))
+}
+
+// AuditStore keeps the audit trail so it can be searched, eg by support staff
+// asking when a key was last rotated and by whom. As an AuditSink it records
+// every event emitted to it; use MultiAuditSink to also forward them.
+type AuditStore interface {
+	AuditSink
+	Query(ctx context.Context, q AuditQuery) ([]AuditEvent, error)
+	// Prune deletes the events before t, returning how many there were, to
+	// enforce a retention period. See SweepConfig.AuditRetention.
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LastEvent returns the newest event of type typ for clientID, or ErrNotFound
+func LastEvent(ctx context.Context, s AuditStore, clientID, typ string) (AuditEvent, error) {
+	events, err := s.Query(ctx, AuditQuery{ClientID: clientID, Types: []string{typ}, Limit: 1})
+	if err != nil {
+		return AuditEvent{}, err
+	}
+	if len(events) == 0 {
+		return AuditEvent{}, fmt.Errorf("no `%s' event for `%s': %w", typ, clientID, ErrNotFound)
+	}
+	return events[0], nil
+}
+
+// MemAuditStore is an in memory AuditStore, for tests and single instance
+// services whose trail need not survive a restart
+type MemAuditStore struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+var _ AuditStore = (*MemAuditStore)(nil)
+
+func NewMemAuditStore() *MemAuditStore {
+	return &MemAuditStore{}
+}
+
+func (s *MemAuditStore) Emit(ctx context.Context, ev AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, ev)
+	return nil
+}
+
+func (s *MemAuditStore) Query(ctx context.Context, q AuditQuery) ([]AuditEvent, error) {
+	s.mu.Lock()
+	var events []AuditEvent
+	for _, ev := range s.events {
+		if q.match(ev) {
+			events = append(events, ev)
+		}
+	}
+	s.mu.Unlock()
+	// newest first, and of events with the same time the last emitted first
+	slices.Reverse(events)
+	slices.SortStableFunc(events, func(a, b AuditEvent) int { return b.Time.Compare(a.Time) })
+	if q.Limit > 0 && len(events) > q.Limit {
+		events = events[:q.Limit]
+	}
+	return events, nil
+}
+
+func (s *MemAuditStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.events)
+	s.events = slices.DeleteFunc(s.events, func(ev AuditEvent) bool { return ev.Time.Before(before) })
+	return int64(n - len(s.events)), nil
+}
@@ -0,0 +1,79 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMemAuditStore(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	s := NewMemAuditStore()
+	admin := NewAdmin(NewMemStore(), WithAudit(s), WithClock(ClockFunc(func() time.Time { return now })))
+	if _, _, err := admin.Create(WithPrincipal(ctx, "alice"), testAlg, WithClientID("client-1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("client-2")); err != nil {
+		t.Fatal(err)
+	}
+	now = start.Add(time.Hour)
+	if _, _, err := admin.Rotate(WithPrincipal(ctx, "bob"), "client-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	now = start.Add(2 * time.Hour)
+	if _, _, err := admin.Rotate(WithPrincipal(ctx, "carol"), "client-1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		q AuditQuery
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{name: "all", want: []string{"carol", "bob", "", "alice"}},
+		{name: "client", args: args{q: AuditQuery{ClientID: "client-1"}}, want: []string{"carol", "bob", "alice"}},
+		{name: "type", args: args{q: AuditQuery{Types: []string{AuditKeyCreated}}}, want: []string{"", "alice"}},
+		{name: "since", args: args{q: AuditQuery{Since: start.Add(time.Hour)}}, want: []string{"carol", "bob"}},
+		{name: "until", args: args{q: AuditQuery{Until: start.Add(time.Hour)}}, want: []string{"", "alice"}},
+		{name: "limit", args: args{q: AuditQuery{ClientID: "client-1", Limit: 2}}, want: []string{"carol", "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := s.Query(ctx, tt.args.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var actors []string
+			for _, ev := range events {
+				actors = append(actors, ev.Actor)
+			}
+			if !slices.Equal(actors, tt.want) {
+				t.Errorf("Query() actors = %q, want %q", actors, tt.want)
+			}
+		})
+	}
+
+	ev, err := LastEvent(ctx, s, "client-1", AuditKeyRotated)
+	if err != nil || ev.Actor != "carol" || !ev.Time.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("LastEvent() = %+v, %v", ev, err)
+	}
+	if _, err := LastEvent(ctx, s, "client-2", AuditKeyRotated); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LastEvent() of a key never rotated = %v, want ErrNotFound", err)
+	}
+
+	sweeper := NewSweeper(admin, SweepConfig{Audit: s, AuditRetention: 30 * time.Minute})
+	res, err := sweeper.Sweep(ctx)
+	if err != nil || res.AuditPruned != 3 {
+		t.Errorf("Sweep() = %+v, %v, want 3 events pruned", res, err)
+	}
+	if events, _ := s.Query(ctx, AuditQuery{}); len(events) != 1 {
+		t.Errorf("%d events left after pruning, want 1", len(events))
+	}
+}
@@ -0,0 +1,245 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	backupMagic   = "apikeys-backup\n"
+	backupVersion = 1
+	backupSaltLen = 16
+
+	// The passphrase is stretched with argon2id at these parameters, which
+	// are written to the header so they can change without breaking old
+	// backups
+	backupTime    = 3
+	backupMemory  = 64 * memoryUnits
+	backupThreads = 4
+
+	// backupChunk is the plaintext encrypted under each nonce
+	backupChunk = 64 << 10
+)
+
+// ErrBackup is returned for backups that are malformed, truncated, modified
+// or encrypted with a different passphrase
+var ErrBackup = errors.New("invalid apikeys backup")
+
+// backupHeader precedes the chunks and is authenticated by each of them
+type backupHeader struct {
+	Version uint8
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	Salt    [backupSaltLen]byte
+}
+
+// Backup writes an encrypted snapshot of every record in the store to w, see
+// ExportSnapshot. The snapshot is encrypted with XChaCha20-Poly1305 under a
+// key stretched from passphrase with argon2id, in chunks so that a backup
+// which has been truncated, reordered or modified fails to restore. Use a
+// long random passphrase kept apart from the backups. It returns the number
+// of records written.
+func (a *Admin) Backup(ctx context.Context, w io.Writer, passphrase Secret) (int, error) {
+	if len(passphrase) == 0 {
+		return 0, fmt.Errorf("%w: empty passphrase", ErrBackup)
+	}
+	h := backupHeader{Version: backupVersion, Time: backupTime, Memory: backupMemory, Threads: backupThreads}
+	if _, err := io.ReadFull(a.entropySource(), h.Salt[:]); err != nil {
+		return 0, fmt.Errorf("insufficient rand bytes generating backup salt: %w", err)
+	}
+	var hdr bytes.Buffer
+	hdr.WriteString(backupMagic)
+	binary.Write(&hdr, binary.BigEndian, h)
+	if _, err := w.Write(hdr.Bytes()); err != nil {
+		return 0, err
+	}
+	bw, err := newBackupWriter(w, h, hdr.Bytes(), passphrase)
+	if err != nil {
+		return 0, err
+	}
+	n, err := ExportSnapshot(ctx, a.store, bw)
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Close()
+}
+
+// Restore reads a backup written by Backup into the store, as by
+// ImportSnapshot. Each chunk is authenticated before any record in it is
+// imported, but an error part way leaves the records before it imported. It
+// returns the number of records imported.
+func (a *Admin) Restore(ctx context.Context, r io.Reader, passphrase Secret) (int, error) {
+	if len(passphrase) == 0 {
+		return 0, fmt.Errorf("%w: empty passphrase", ErrBackup)
+	}
+	hdr := make([]byte, len(backupMagic)+binary.Size(backupHeader{}))
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return 0, fmt.Errorf("%w: bad header: %v", ErrBackup, err)
+	}
+	if string(hdr[:len(backupMagic)]) != backupMagic {
+		return 0, fmt.Errorf("%w: not a backup", ErrBackup)
+	}
+	var h backupHeader
+	binary.Read(bytes.NewReader(hdr[len(backupMagic):]), binary.BigEndian, &h)
+	if h.Version != backupVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrBackup, h.Version)
+	}
+	// The header is not authenticated until the first chunk is, so bound the
+	// work it can demand
+	if h.Time < minTime || h.Time > importMaxTime || h.Memory < minMem*memoryUnits || h.Memory > importMaxMemory || h.Threads < 1 {
+		return 0, fmt.Errorf("%w: bad key derivation parameters", ErrBackup)
+	}
+	br, err := newBackupReader(r, h, hdr, passphrase)
+	if err != nil {
+		return 0, err
+	}
+	n, err := ImportSnapshot(ctx, a.store, br)
+	if br.err != nil && !errors.Is(br.err, io.EOF) {
+		return n, br.err
+	}
+	if err != nil {
+		return n, err
+	}
+	// The snapshot is complete, make sure nothing follows it
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+func backupAEAD(h backupHeader, passphrase Secret) (cipher.AEAD, error) {
+	key := argon2.IDKey(passphrase, h.Salt[:], h.Time, h.Memory, h.Threads, chacha20poly1305.KeySize)
+	defer clear(key)
+	return chacha20poly1305.NewX(key)
+}
+
+// backupNonce is the chunk counter, with the last byte set on the final
+// chunk so that a backup truncated at a chunk boundary is detected
+func backupNonce(nonce []byte, counter uint64, last bool) {
+	clear(nonce)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-9:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+// backupWriter encrypts full chunks as they fill. The final chunk, written
+// by Close, is always shorter than a full one, possibly empty.
+type backupWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	ad      []byte
+	nonce   []byte
+	counter uint64
+	buf     []byte
+}
+
+func newBackupWriter(w io.Writer, h backupHeader, ad []byte, passphrase Secret) (*backupWriter, error) {
+	aead, err := backupAEAD(h, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &backupWriter{w: w, aead: aead, ad: ad, nonce: make([]byte, aead.NonceSize()),
+		buf: make([]byte, 0, backupChunk+aead.Overhead())}, nil
+}
+
+func (bw *backupWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		take := min(len(p), backupChunk-len(bw.buf))
+		bw.buf = append(bw.buf, p[:take]...)
+		p, n = p[take:], n+take
+		if len(bw.buf) == backupChunk {
+			if err := bw.seal(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (bw *backupWriter) Close() error {
+	return bw.seal(true)
+}
+
+func (bw *backupWriter) seal(last bool) error {
+	backupNonce(bw.nonce, bw.counter, last)
+	bw.counter++
+	sealed := bw.aead.Seal(bw.buf[:0], bw.nonce, bw.buf, bw.ad)
+	_, err := bw.w.Write(sealed)
+	clear(sealed)
+	bw.buf = bw.buf[:0]
+	return err
+}
+
+// backupReader authenticates each chunk before returning any of it
+type backupReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	ad      []byte
+	nonce   []byte
+	counter uint64
+	buf     []byte
+	plain   []byte
+	err     error
+}
+
+func newBackupReader(r io.Reader, h backupHeader, ad []byte, passphrase Secret) (*backupReader, error) {
+	aead, err := backupAEAD(h, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &backupReader{r: r, aead: aead, ad: ad, nonce: make([]byte, aead.NonceSize()),
+		buf: make([]byte, backupChunk+aead.Overhead())}, nil
+}
+
+func (br *backupReader) Read(p []byte) (int, error) {
+	for len(br.plain) == 0 {
+		if br.err != nil {
+			return 0, br.err
+		}
+		br.err = br.open()
+	}
+	n := copy(p, br.plain)
+	br.plain = br.plain[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk, returning io.EOF after the final
+// chunk
+func (br *backupReader) open() error {
+	n, err := io.ReadFull(br.r, br.buf)
+	last := false
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: missing final chunk, the backup is truncated", ErrBackup)
+	case err != nil:
+		return err
+	}
+	if n < br.aead.Overhead() {
+		return fmt.Errorf("%w: short chunk", ErrBackup)
+	}
+	backupNonce(br.nonce, br.counter, last)
+	br.counter++
+	plain, err := br.aead.Open(br.buf[:0], br.nonce, br.buf[:n], br.ad)
+	if err != nil {
+		return fmt.Errorf("%w: wrong passphrase, or the backup has been modified", ErrBackup)
+	}
+	br.plain = plain
+	if last {
+		// Read returns any final plaintext before the io.EOF
+		return io.EOF
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package apikeys
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := t.Context()
+	src := NewMemStore()
+	admin := NewAdmin(src)
+	apikey, _, err := admin.Create(ctx, testAlg, WithClientID("client-0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enough records to span several chunks
+	description := strings.Repeat("x", 1000)
+	for i := 1; i < 200; i++ {
+		ak, err := NewKey(testAlg, WithClientID(fmt.Sprintf("client-%d", i)), WithDescription(description))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ak.DerivedKey = bytes.Repeat([]byte{byte(i)}, 16)
+		if err := src.Create(ctx, ak); err != nil {
+			t.Fatal(err)
+		}
+	}
+	passphrase := Secret("correct horse battery staple")
+	var buf bytes.Buffer
+	if n, err := admin.Backup(ctx, &buf, passphrase); err != nil || n != 200 {
+		t.Fatalf("Backup() = %d, %v", n, err)
+	}
+	backup := buf.Bytes()
+	if len(backup) < 2*backupChunk || bytes.Contains(backup, []byte("client-1")) || bytes.Contains(backup, []byte(description[:32])) {
+		t.Fatalf("backup of %d bytes is not an encrypted multi chunk archive", len(backup))
+	}
+
+	dst := NewMemStore()
+	if n, err := NewAdmin(dst).Restore(ctx, bytes.NewReader(backup), passphrase); err != nil || n != 200 {
+		t.Fatalf("Restore() = %d, %v", n, err)
+	}
+	want, _ := src.List(ctx)
+	got, _ := dst.List(ctx)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("restored %d records, want the %d backed up", len(got), len(want))
+	}
+	if _, err := NewStoreVerifier(dst).Verify(ctx, apikey); err != nil {
+		t.Errorf("Verify() against restored store error = %v", err)
+	}
+
+	header := len(backupMagic) + 1 + 4 + 4 + 1 + backupSaltLen
+	chunk := backupChunk + chacha20poly1305.Overhead
+	flipped := bytes.Clone(backup)
+	flipped[header+chunk+10] ^= 1
+	type args struct {
+		backup     []byte
+		passphrase Secret
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"wrong passphrase", args{backup, Secret("incorrect horse")}},
+		{"modified", args{flipped, passphrase}},
+		{"truncated", args{backup[:len(backup)-1], passphrase}},
+		{"missing final chunk", args{backup[:header+2*chunk], passphrase}},
+		{"trailing data", args{append(bytes.Clone(backup), 0), passphrase}},
+		{"not a backup", args{[]byte("{}\n"), passphrase}},
+		{"empty passphrase", args{backup, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAdmin(NewMemStore()).Restore(ctx, bytes.NewReader(tt.args.backup), tt.args.passphrase)
+			if !errors.Is(err, ErrBackup) {
+				t.Errorf("Restore() error = %v, want ErrBackup", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// GenerateBatch generates n keys for alg concurrently, using at most
+// GOMAXPROCS derivations at a time. The records and the encoded api keys are
+// returned in matching order. Each key gets its own generated client id, so
+// opts must not set one. If ctx is done, or any generation fails, the
+// remaining keys are not started and the error is returned. A batch of 0
+// keys is empty.
+func GenerateBatch(ctx context.Context, alg string, n int, opts ...KeyOption) ([]Key, []string, error) {
+	if n < 0 {
+		return nil, nil, fmt.Errorf("bad batch size %d, want >= 0", n)
+	}
+	if _, err := ParseAlg(alg); err != nil {
+		return nil, nil, err
+	}
+	var probe Key
+	for _, o := range opts {
+		o(&probe)
+	}
+	if probe.ClientID != "" {
+		return nil, nil, fmt.Errorf("a batch can't share the client id `%s'", probe.ClientID)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make([]Key, n)
+	secrets := make([]string, n)
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ak, err := NewKey(alg, opts...)
+			if err != nil {
+				fail(err)
+				return
+			}
+			secret, err := ak.Generate()
+			if err != nil {
+				fail(err)
+				return
+			}
+			keys[i], secrets[i] = ak, secret
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return keys, secrets, nil
+}
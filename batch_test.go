@@ -0,0 +1,53 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGenerateBatch(t *testing.T) {
+	ctx := context.Background()
+	keys, secrets, err := GenerateBatch(ctx, testAlg, 20)
+	if err != nil {
+		t.Fatalf("GenerateBatch() error = %v", err)
+	}
+	if len(keys) != 20 || len(secrets) != 20 {
+		t.Fatalf("GenerateBatch() = %d keys, %d secrets, want 20", len(keys), len(secrets))
+	}
+	seen := map[string]bool{}
+	for i := range keys {
+		if seen[keys[i].ClientID] {
+			t.Errorf("duplicate client id %s", keys[i].ClientID)
+		}
+		seen[keys[i].ClientID] = true
+
+		presented, password, err := Decode(secrets[i])
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if presented.ClientID != keys[i].ClientID || !presented.MatchPassword(password, keys[i].DerivedKey) {
+			t.Errorf("secret %d does not verify against record %d", i, i)
+		}
+	}
+}
+
+func TestGenerateBatchErrors(t *testing.T) {
+	if _, _, err := GenerateBatch(context.Background(), "bogus", 2); err == nil {
+		t.Errorf("GenerateBatch() with bad alg succeeded")
+	}
+	if _, _, err := GenerateBatch(context.Background(), testAlg, 2, WithClientID("fixed")); err == nil {
+		t.Errorf("GenerateBatch() with a fixed client id succeeded")
+	}
+	if _, _, err := GenerateBatch(context.Background(), testAlg, -1); err == nil {
+		t.Errorf("GenerateBatch() of -1 keys succeeded")
+	}
+	if keys, secrets, err := GenerateBatch(context.Background(), testAlg, 0); err != nil || len(keys) != 0 || len(secrets) != 0 {
+		t.Errorf("GenerateBatch() of 0 keys = %v, %v, %v, want none", keys, secrets, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := GenerateBatch(ctx, testAlg, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateBatch() canceled error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,46 @@
+package apikeys
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	blake2bAlgID = "blake2b "
+	namedKey     = "key"
+)
+
+// blake2bHasher derives with BLAKE2b keyed with a pepper. The salt is length
+// prefixed so that no salt and password pair collides with another.
+type blake2bHasher struct {
+	pepper Secret
+	size   int
+}
+
+func (h blake2bHasher) Derive(password, salt []byte) []byte {
+	m, err := blake2b.New(h.size, h.pepper)
+	if err != nil {
+		// parseBLAKE2bAlg has checked the size and pepper
+		panic(err)
+	}
+	m.Write(binary.AppendUvarint(nil, uint64(len(salt))))
+	m.Write(salt)
+	m.Write(password)
+	return m.Sum(nil)
+}
+
+// parseBLAKE2bAlg parses a keyed BLAKE2b alg,
+//
+//	blake2b key=<pepper id>,len=<keylen>
+func parseBLAKE2bAlg(alg string) (Alg, error) {
+	p, pepper, err := parseKeyedAlg(blake2bAlgID, alg)
+	if err != nil {
+		return Alg{}, err
+	}
+	if len(pepper) > blake2b.Size {
+		return Alg{}, fmt.Errorf("pepper `%s' too long for blake2b. got %d, max=%d", p.pepperID, len(pepper), blake2b.Size)
+	}
+	return Alg{String: alg, hasher: blake2bHasher{pepper: pepper, size: int(p.keyLen)}}, nil
+}
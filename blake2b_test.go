@@ -0,0 +1,87 @@
+package apikeys
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestBLAKE2bAlg(t *testing.T) {
+	ctx := t.Context()
+	for id, b := range map[string]byte{"svc-1": 1, "svc-2": 2} {
+		if err := RegisterPepper(id, bytes.Repeat([]byte{b}, 32)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RegisterPepper("too-long", bytes.Repeat([]byte{3}, 65)); err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	verifier := NewStoreVerifier(store)
+	apikey, ak, err := admin.Create(ctx, "blake2b key=svc-1,len=32", WithClientID("service-1"), WithKeyType(KeyTypeService))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ak.DerivedKey) != 32 {
+		t.Errorf("derived key is %d bytes, want 32", len(ak.DerivedKey))
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// The same salt and password under another pepper derive another key
+	other, err := ParseAlg("blake2b key=svc-2,len=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other.derive(password, ak.Salt), ak.DerivedKey) {
+		t.Error("derivations under different peppers are equal")
+	}
+
+	// Rotating to a new pepper leaves existing keys verifying
+	apikey2, _, err := admin.Rotate(ctx, "service-1", "blake2b key=svc-2,len=32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, apikey2); err != nil {
+		t.Errorf("Verify() after rotation error = %v", err)
+	}
+	if _, err := verifier.Verify(ctx, apikey); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() of the rotated out key = %v, want ErrMismatch", err)
+	}
+
+	type args struct {
+		alg string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"ok", args{"blake2b key=svc-1,len=16"}, false},
+		{"either order", args{"blake2b len=64,key=svc-1"}, false},
+		{"unregistered pepper", args{"blake2b key=nope,len=32"}, true},
+		{"pepper too long", args{"blake2b key=too-long,len=32"}, true},
+		{"missing key", args{"blake2b len=32"}, true},
+		{"missing len", args{"blake2b key=svc-1"}, true},
+		{"len too large", args{"blake2b key=svc-1,len=65"}, true},
+		{"unknown param", args{"blake2b key=svc-1,len=32,t=1"}, true},
+		{"duplicate param", args{"blake2b key=svc-1,key=svc-2,len=32"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAlg(tt.args.alg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAlg() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && a.String != tt.args.alg {
+				t.Errorf("ParseAlg().String = %s", a.String)
+			}
+		})
+	}
+}
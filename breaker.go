@@ -0,0 +1,267 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling the store while a
+// CircuitBreaker is open
+var ErrCircuitOpen = errors.New("api key store circuit open")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	// BreakerClosed passes every call to the store
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every call with ErrCircuitOpen
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of probes through to decide
+	// whether to close again
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerConfig tunes a CircuitBreaker. Zero fields take the defaults.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures which open the
+	// circuit, 5 by default
+	FailureThreshold int
+	// OpenFor is how long the circuit stays open before probing, 10s by
+	// default
+	OpenFor time.Duration
+	// Probes is how many calls may be in flight while half open, and how
+	// many of them must succeed to close the circuit, 1 by default
+	Probes int
+	// CallTimeout, if set, bounds each call so that a store which hangs
+	// counts as failing rather than holding on to its callers
+	CallTimeout time.Duration
+	// IsFailure classifies an error from the store. By default every error
+	// is a failure except ErrNotFound, ErrExists and cancellation by the
+	// caller.
+	IsFailure func(error) bool
+	// OnStateChange, if set, is called with the new state whenever it
+	// changes. It is called with the breaker locked and must not use it.
+	OnStateChange func(BreakerState)
+	// Clock times OpenFor, the system clock if nil
+	Clock Clock
+}
+
+// CircuitBreaker stops calling a store which keeps failing, so that callers
+// fail fast with ErrCircuitOpen instead of queueing behind slow calls. After
+// OpenFor it lets probes through and closes again once they succeed.
+type CircuitBreaker struct {
+	cfg BreakerConfig
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	inFlight  int
+	successes int
+}
+
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenFor <= 0 {
+		cfg.OpenFor = 10 * time.Second
+	}
+	if cfg.Probes <= 0 {
+		cfg.Probes = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = isStoreFailure
+	}
+	return &CircuitBreaker{cfg: cfg}
+}
+
+func (b *CircuitBreaker) now() time.Time {
+	if b.cfg.Clock == nil {
+		return time.Now()
+	}
+	return b.cfg.Clock.Now()
+}
+
+func isStoreFailure(err error) bool {
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExists) && !errors.Is(err, context.Canceled)
+}
+
+// State reports the current state. An open circuit whose OpenFor has passed
+// reports half open.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && !b.now().Before(b.openedAt.Add(b.cfg.OpenFor)) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Do calls fn unless the circuit is open, and records the outcome
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+	if b.cfg.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.cfg.CallTimeout)
+		defer cancel()
+	}
+	err = fn(ctx)
+	b.record(probe, err)
+	return err
+}
+
+// allow reports whether a call may go ahead, and if so whether it is a probe
+// of a half open circuit
+func (b *CircuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Before(b.openedAt.Add(b.cfg.OpenFor)) {
+			return false, ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		b.successes = 0
+	case BreakerClosed:
+		return false, nil
+	}
+	if b.inFlight >= b.cfg.Probes {
+		return false, ErrCircuitOpen
+	}
+	b.inFlight++
+	return true, nil
+}
+
+// record updates the state for the outcome of a call. A call cancelled by
+// its caller says nothing about the store, so it only frees its probe slot.
+func (b *CircuitBreaker) record(probe bool, err error) {
+	failed := err != nil && b.cfg.IsFailure(err)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.inFlight--
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if b.state == BreakerHalfOpen {
+		if !probe {
+			return
+		}
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.Probes {
+			b.failures = 0
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+	if b.state != BreakerClosed {
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.cfg.FailureThreshold {
+		b.open()
+	}
+}
+
+// open must be called with b.mu held
+func (b *CircuitBreaker) open() {
+	b.openedAt = b.now()
+	b.failures = 0
+	b.setState(BreakerOpen)
+}
+
+// setState must be called with b.mu held
+func (b *CircuitBreaker) setState(s BreakerState) {
+	if s == b.state {
+		return
+	}
+	b.state = s
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(s)
+	}
+}
+
+// WithCircuitBreaker guards the store of an Admin or StoreVerifier with b.
+// Share one breaker between the two to trip both together.
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return func(o *options) {
+		o.breaker = b
+	}
+}
+
+// BreakStore wraps store so that every operation goes through b
+func BreakStore(store Store, b *CircuitBreaker) Store {
+	return &breakerStore{store: store, breaker: b}
+}
+
+type breakerStore struct {
+	store   Store
+	breaker *CircuitBreaker
+}
+
+func (s *breakerStore) Create(ctx context.Context, ak Key) error {
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		return s.store.Create(ctx, ak)
+	})
+}
+
+func (s *breakerStore) Get(ctx context.Context, clientID string) (Key, error) {
+	var ak Key
+	err := s.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		ak, err = s.store.Get(ctx, clientID)
+		return err
+	})
+	return ak, err
+}
+
+func (s *breakerStore) Update(ctx context.Context, ak Key) error {
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		return s.store.Update(ctx, ak)
+	})
+}
+
+func (s *breakerStore) Delete(ctx context.Context, clientID string) error {
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		return s.store.Delete(ctx, clientID)
+	})
+}
+
+func (s *breakerStore) List(ctx context.Context) ([]Key, error) {
+	var keys []Key
+	err := s.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		keys, err = s.store.List(ctx)
+		return err
+	})
+	return keys, err
+}
@@ -0,0 +1,160 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// downStore fails Get with errDown while down is set
+type downStore struct {
+	*MemStore
+	down  atomic.Bool
+	calls atomic.Int64
+}
+
+var errDown = errors.New("store unreachable")
+
+func (s *downStore) Get(ctx context.Context, clientID string) (Key, error) {
+	s.calls.Add(1)
+	if s.down.Load() {
+		return Key{}, errDown
+	}
+	return s.MemStore.Get(ctx, clientID)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	var states []BreakerState
+	b := NewCircuitBreaker(BreakerConfig{
+		FailureThreshold: 2, OpenFor: time.Minute,
+		Clock:         ClockFunc(func() time.Time { return now }),
+		OnStateChange: func(s BreakerState) { states = append(states, s) },
+	})
+	store := &downStore{MemStore: NewMemStore()}
+	apikey, _, err := NewAdmin(store).Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewStoreVerifier(store, WithCircuitBreaker(b))
+
+	type args struct {
+		down    bool
+		advance time.Duration
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantErr   error
+		wantCalls int64
+		wantState BreakerState
+	}{
+		{"healthy", args{false, 0}, nil, 1, BreakerClosed},
+		{"first failure", args{true, 0}, errDown, 1, BreakerClosed},
+		{"opens", args{true, 0}, errDown, 1, BreakerOpen},
+		{"fails fast", args{true, 30 * time.Second}, ErrCircuitOpen, 0, BreakerOpen},
+		{"failed probe reopens", args{true, 30 * time.Second}, errDown, 1, BreakerOpen},
+		{"fails fast again", args{false, 59 * time.Second}, ErrCircuitOpen, 0, BreakerOpen},
+		{"probe closes", args{false, time.Second}, nil, 1, BreakerClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.down.Store(tt.args.down)
+			now = now.Add(tt.args.advance)
+			before := store.calls.Load()
+			_, err := verifier.Verify(ctx, apikey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := store.calls.Load() - before; got != tt.wantCalls {
+				t.Errorf("store calls = %d, want %d", got, tt.wantCalls)
+			}
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("State() = %s, want %s", got, tt.wantState)
+			}
+		})
+	}
+	want := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if len(states) != len(want) {
+		t.Fatalf("state changes = %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("state changes = %v, want %v", states, want)
+			break
+		}
+	}
+	if got := VerifyResult(ErrCircuitOpen); got != ResultOverloaded {
+		t.Errorf("VerifyResult(ErrCircuitOpen) = %s, want %s", got, ResultOverloaded)
+	}
+}
+
+func TestCircuitBreakerProbes(t *testing.T) {
+	ctx := context.Background()
+	b := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, OpenFor: time.Nanosecond, Probes: 1})
+	if err := b.Do(ctx, func(ctx context.Context) error { return errDown }); !errors.Is(err, errDown) {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	// While one probe is in flight others fail fast
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(ctx, func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+	}()
+	for b.State() != BreakerHalfOpen || !func() bool { b.mu.Lock(); defer b.mu.Unlock(); return b.inFlight == 1 }() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := b.Do(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Do() during probe error = %v, want ErrCircuitOpen", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Errorf("State() after probe = %s, want closed", got)
+	}
+
+	timeout := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, CallTimeout: time.Millisecond})
+	err := timeout.Do(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || timeout.State() != BreakerOpen {
+		t.Errorf("Do() slow call error = %v state %s, want deadline exceeded and open", err, timeout.State())
+	}
+}
+
+func TestCircuitBreakerCancelledProbe(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(BreakerConfig{
+		FailureThreshold: 1, OpenFor: time.Minute,
+		Clock: ClockFunc(func() time.Time { return now }),
+	})
+	if err := b.Do(ctx, func(ctx context.Context) error { return errDown }); !errors.Is(err, errDown) {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if err := b.Do(ctx, func(ctx context.Context) error { return context.Canceled }); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	if got := b.State(); got != BreakerHalfOpen {
+		t.Errorf("State() after a cancelled probe = %s, want half open", got)
+	}
+	// The cancelled probe gave up its slot
+	if err := b.Do(ctx, func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Do() after a cancelled probe error = %v", err)
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Errorf("State() after probe = %s, want closed", got)
+	}
+}
@@ -0,0 +1,135 @@
+package apikeys
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// keyBSON is the document form of a Key. It lists every field with a bson
+// tag on Key, plus the alg, salt and secret length which the tags exclude.
+type keyBSON struct {
+	Alg        string    `bson:"alg,omitempty"`
+	Salt       []byte    `bson:"salt,omitempty"`
+	SecretLen  int       `bson:"secret_len,omitempty"`
+	DerivedKey []byte    `bson:"derived_key"`
+	ClientID   string    `bson:"client_id"`
+	CreatedAt  time.Time `bson:"created_at"`
+	RotatedAt  time.Time `bson:"rotated_at,omitempty"`
+	RevokedAt  time.Time `bson:"revoked_at"`
+	ExpiresAt  time.Time `bson:"expires_at"`
+	LastUsedAt time.Time `bson:"last_used_at,omitempty"`
+
+	PreviousDerivedKey []byte    `bson:"previous_derived_key,omitempty"`
+	PreviousExpiresAt  time.Time `bson:"previous_expires_at,omitempty"`
+
+	IdleTimeoutSeconds int64 `bson:"idle_timeout_seconds,omitempty"`
+
+	ImportedHash string `bson:"imported_hash,omitempty"`
+	TenantID     string `bson:"tenant_id,omitempty"`
+
+	Name        string            `bson:"name,omitempty"`
+	Description string            `bson:"description,omitempty"`
+	Owner       string            `bson:"owner,omitempty"`
+	Labels      map[string]string `bson:"labels,omitempty"`
+	Type        KeyType           `bson:"type,omitempty"`
+
+	CreatedBy string `bson:"created_by,omitempty"`
+	Team      string `bson:"team,omitempty"`
+
+	Restrictions Restrictions `bson:"restrictions,omitempty"`
+
+	Approval   ApprovalState `bson:"approval,omitempty"`
+	ReviewedBy string        `bson:"reviewed_by,omitempty"`
+}
+
+// MarshalBSON implements bson.Marshaler. Salt and DerivedKey are stored as
+// binary and the alg as its string, so the record can verify once read back.
+// Times are stored with millisecond precision.
+func (ak Key) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(keyBSON{
+		Alg:        ak.alg.String,
+		Salt:       ak.Salt,
+		SecretLen:  ak.secretLen,
+		DerivedKey: ak.DerivedKey,
+		ClientID:   ak.ClientID,
+		CreatedAt:  ak.CreatedAt,
+		RotatedAt:  ak.RotatedAt,
+		RevokedAt:  ak.RevokedAt,
+		ExpiresAt:  ak.ExpiresAt,
+		LastUsedAt: ak.LastUsedAt,
+
+		PreviousDerivedKey: ak.PreviousDerivedKey,
+		PreviousExpiresAt:  ak.PreviousExpiresAt,
+
+		IdleTimeoutSeconds: ak.IdleTimeoutSeconds,
+
+		ImportedHash: ak.ImportedHash,
+		TenantID:     ak.TenantID,
+
+		Name:        ak.Name,
+		Description: ak.Description,
+		Owner:       ak.Owner,
+		Labels:      ak.Labels,
+		Type:        ak.Type,
+
+		CreatedBy: ak.CreatedBy,
+		Team:      ak.Team,
+
+		Restrictions: ak.Restrictions,
+
+		Approval:   ak.Approval,
+		ReviewedBy: ak.ReviewedBy,
+	})
+}
+
+// UnmarshalBSON implements bson.Unmarshaler. The alg is parsed and validated
+// if present.
+func (ak *Key) UnmarshalBSON(b []byte) error {
+	var doc keyBSON
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	decoded := Key{
+		Salt:       doc.Salt,
+		DerivedKey: doc.DerivedKey,
+		ClientID:   doc.ClientID,
+		CreatedAt:  doc.CreatedAt.UTC(),
+		RotatedAt:  doc.RotatedAt.UTC(),
+		RevokedAt:  doc.RevokedAt.UTC(),
+		ExpiresAt:  doc.ExpiresAt.UTC(),
+		LastUsedAt: doc.LastUsedAt.UTC(),
+
+		PreviousDerivedKey: doc.PreviousDerivedKey,
+		PreviousExpiresAt:  doc.PreviousExpiresAt.UTC(),
+
+		IdleTimeoutSeconds: doc.IdleTimeoutSeconds,
+
+		ImportedHash: doc.ImportedHash,
+		TenantID:     doc.TenantID,
+
+		Name:        doc.Name,
+		Description: doc.Description,
+		Owner:       doc.Owner,
+		Labels:      doc.Labels,
+		Type:        doc.Type,
+
+		CreatedBy: doc.CreatedBy,
+		Team:      doc.Team,
+
+		Restrictions: doc.Restrictions,
+
+		Approval:   doc.Approval,
+		ReviewedBy: doc.ReviewedBy,
+	}
+	if doc.Alg != "" {
+		alg, err := ParseAlg(doc.Alg)
+		if err != nil {
+			return err
+		}
+		decoded.alg = alg
+	}
+	decoded.secretLen = doc.SecretLen
+	*ak = decoded
+	return nil
+}
@@ -0,0 +1,75 @@
+package apikeys
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestKeyBSONRoundTrip(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"), WithTenant("acme"), WithName("ci"), WithLabels(map[string]string{"env": "prod"}), WithKeyType(KeyTypeService), WithTeam("payments"), WithCreatedBy("alice"), WithAllowedCIDRs("203.0.113.0/24"), WithAllowedOrigins("https://*.example.com"), WithAllowedRoutes("GET /v1/reports/*"), WithQuota(100, time.Hour), WithIdleTimeout(30*24*time.Hour), WithSecretLength(24), WithExpiresAt(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ak.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	ak.CreatedAt = time.Date(2029, 1, 1, 12, 0, 0, 0, time.UTC)
+	ak.LastUsedAt = time.Date(2029, 6, 1, 12, 0, 0, 0, time.UTC)
+	ak.Approval, ak.ReviewedBy = ApprovalApproved, "bob"
+	ak.PreviousDerivedKey, ak.PreviousExpiresAt = Blob("previous-derived-key"), time.Date(2029, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	b, err := bson.Marshal(ak)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var raw bson.Raw = b
+	for _, name := range []string{"salt", "derived_key", "previous_derived_key"} {
+		if v := raw.Lookup(name); v.Type != bson.TypeBinary {
+			t.Errorf("%s stored as %v, want binary", name, v.Type)
+		}
+	}
+
+	var got Key
+	if err := bson.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, ak) {
+		t.Errorf("round trip = %+v, want %+v", got, ak)
+	}
+
+	// A Key nested in another document uses the same encoding
+	type wrapper struct {
+		Key Key `bson:"key"`
+	}
+	b, err = bson.Marshal(wrapper{ak})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w wrapper
+	if err := bson.Unmarshal(b, &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(w.Key, ak) {
+		t.Errorf("nested round trip = %+v, want %+v", w.Key, ak)
+	}
+}
+
+// TestKeyBSONFields catches a field added to Key but not to keyBSON
+func TestKeyBSONFields(t *testing.T) {
+	doc := reflect.TypeOf(keyBSON{})
+	names := map[string]bool{}
+	for i := range doc.NumField() {
+		names[doc.Field(i).Tag.Get("bson")] = true
+	}
+	kt := reflect.TypeOf(Key{})
+	for i := range kt.NumField() {
+		f := kt.Field(i)
+		tag := f.Tag.Get("bson")
+		if tag != "-" && !names[tag] && !names[tag+",omitempty"] {
+			t.Errorf("Key.%s (bson %q) is missing from keyBSON", f.Name, tag)
+		}
+	}
+}
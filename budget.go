@@ -0,0 +1,58 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// MemoryBudget limits the memory committed by concurrent argon2 derivations.
+// Each derivation acquires the memory parameter of its Alg from the budget
+// and waits until enough is available, so a container limit is never
+// exceeded however many verifications arrive at once.
+type MemoryBudget struct {
+	size int64
+	sem  *semaphore.Weighted
+}
+
+// NewMemoryBudget creates a budget of size bytes, eg 512 << 20 for 512MB
+func NewMemoryBudget(size int64) *MemoryBudget {
+	return &MemoryBudget{size: size, sem: semaphore.NewWeighted(size)}
+}
+
+// WithMemoryBudget makes derivations in Admin and StoreVerifier acquire their
+// memory from b before starting
+func WithMemoryBudget(b *MemoryBudget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
+
+// Acquire waits until the memory needed by alg is available or ctx is done.
+// The returned func must be called to return the memory once the derivation
+// is complete. An alg which needs more than the whole budget is an error
+// rather than waiting forever.
+func (b *MemoryBudget) Acquire(ctx context.Context, alg Alg) (func(), error) {
+	n := algBytes(alg)
+	if n > b.size {
+		return nil, fmt.Errorf("alg `%s' needs %d bytes which exceeds the memory budget of %d", alg.String, n, b.size)
+	}
+	if err := b.sem.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+	return func() { b.sem.Release(n) }, nil
+}
+
+// algBytes is the memory an argon2 derivation with alg allocates
+func algBytes(alg Alg) int64 {
+	return int64(alg.Memory) * 1024
+}
+
+// acquire is Acquire for the configured budget, if any
+func (o *options) acquire(ctx context.Context, alg Alg) (func(), error) {
+	if o.budget == nil {
+		return func() {}, nil
+	}
+	return o.budget.Acquire(ctx, alg)
+}
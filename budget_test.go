@@ -0,0 +1,75 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	alg, err := ParseAlg(testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// room for exactly two 16MB derivations
+	b := NewMemoryBudget(32 << 20)
+	ctx := context.Background()
+
+	r1, err := b.Acquire(ctx, alg)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	r2, err := b.Acquire(ctx, alg)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := b.Acquire(short, alg); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() over budget error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	r1()
+	r3, err := b.Acquire(ctx, alg)
+	if err != nil {
+		t.Errorf("Acquire() after release error = %v", err)
+	}
+	r2()
+	r3()
+
+	big, err := ParseAlg(StandardAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Acquire(ctx, big); err == nil {
+		t.Errorf("Acquire() for an alg larger than the budget succeeded")
+	}
+}
+
+func TestVerifierMemoryBudget(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	b := NewMemoryBudget(16 << 20)
+	apikey, _, err := NewAdmin(store, WithMemoryBudget(b)).Create(ctx, testAlg)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	v := NewStoreVerifier(store, WithMemoryBudget(b))
+	if _, err := v.Verify(ctx, apikey); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// hold the whole budget so the verification can't start
+	alg, _ := ParseAlg(testAlg)
+	release, err := b.Acquire(ctx, alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := v.Verify(short, apikey); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Verify() with exhausted budget error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,89 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoFilter is returned by Admin.RevokeMatching without any filters, which
+// would revoke every key
+var ErrNoFilter = errors.New("bulk revocation needs at least one filter")
+
+// TenantFilter selects records of tenantID
+func TenantFilter(tenantID string) KeyFilter {
+	return func(ak Key) bool { return ak.TenantID == tenantID }
+}
+
+// CreatedBeforeFilter selects records created before t
+func CreatedBeforeFilter(t time.Time) KeyFilter {
+	return func(ak Key) bool { return ak.CreatedAt.Before(t) }
+}
+
+// WeakerAlgFilter selects records whose alg is WeakerThan alg. Imported
+// records, whose legacy hash is weaker than any argon2id alg, and records
+// from stores which don't retain the alg are selected too, as are keyed algs
+// when alg is an argon2id one, since their Cost is 0.
+func WeakerAlgFilter(alg string) (KeyFilter, error) {
+	ref, err := ParseAlg(alg)
+	if err != nil {
+		return nil, err
+	}
+	return func(ak Key) bool {
+		if ak.ImportedHash != "" {
+			return true
+		}
+		return ak.Alg().WeakerThan(ref)
+	}, nil
+}
+
+// RevokeProgress counts the work of Admin.RevokeMatching
+type RevokeProgress struct {
+	// Matched is the number of records selected by the filters
+	Matched int `json:"matched"`
+	// Done is how many of them have been handled so far
+	Done    int `json:"done"`
+	Revoked int `json:"revoked"`
+	// AlreadyRevoked records were left as they were
+	AlreadyRevoked int `json:"already_revoked"`
+	// Failed lists the client ids which could not be revoked
+	Failed []string `json:"failed,omitempty"`
+}
+
+// RevokeMatching revokes every key selected by all of filters, eg every key of
+// a compromised tenant, and reports progress after each key if progress is
+// not nil. Failures don't stop the rest being revoked; their errors are
+// joined in the result. If ctx is done the keys not yet reached are left
+// alone. Each key is revoked as by Revoke, so audit events and hooks fire for
+// it.
+func (a *Admin) RevokeMatching(ctx context.Context, progress func(RevokeProgress), filters ...KeyFilter) (RevokeProgress, error) {
+	if len(filters) == 0 {
+		return RevokeProgress{}, ErrNoFilter
+	}
+	keys, err := a.List(ctx, filters...)
+	if err != nil {
+		return RevokeProgress{}, err
+	}
+	p := RevokeProgress{Matched: len(keys)}
+	var errs []error
+	for _, ak := range keys {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if ak.Revoked() {
+			p.AlreadyRevoked++
+		} else if _, err := a.Revoke(ctx, ak.ClientID); err != nil {
+			p.Failed = append(p.Failed, ak.ClientID)
+			errs = append(errs, fmt.Errorf("revoking `%s': %w", ak.ClientID, err))
+		} else {
+			p.Revoked++
+		}
+		p.Done++
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return p, errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// failUpdateStore fails updates of one client id
+type failUpdateStore struct {
+	*MemStore
+	clientID string
+}
+
+func (s *failUpdateStore) Update(ctx context.Context, ak Key) error {
+	if ak.ClientID == s.clientID {
+		return errDown
+	}
+	return s.MemStore.Update(ctx, ak)
+}
+
+func mustParseAlg(t *testing.T, s string) Alg {
+	t.Helper()
+	alg, err := ParseAlg(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alg
+}
+
+func TestBulkRevokeFilters(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	weaker, err := WeakerAlgFilter("argon2id 2 16MB 16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WeakerAlgFilter("bogus"); err == nil {
+		t.Error("WeakerAlgFilter(bogus) succeeded")
+	}
+	keys := []Key{
+		{ClientID: "a", TenantID: "acme", alg: mustParseAlg(t, "argon2id 1 16MB 16"), CreatedAt: now.Add(-time.Hour)},
+		{ClientID: "b", TenantID: "acme", alg: mustParseAlg(t, "argon2id 2 16MB 16"), CreatedAt: now},
+		{ClientID: "c", TenantID: "other", alg: mustParseAlg(t, "argon2id 3 64MB 32"), CreatedAt: now.Add(-time.Hour)},
+		{ClientID: "d", ImportedHash: "$2a$10$legacy", CreatedAt: now},
+		// Less time but more memory, so the greater cost
+		{ClientID: "e", TenantID: "other", alg: mustParseAlg(t, "argon2id 1 64MB 16"), CreatedAt: now},
+	}
+	type args struct {
+		filters []KeyFilter
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{name: "tenant", args: args{filters: []KeyFilter{TenantFilter("acme")}}, want: []string{"a", "b"}},
+		{name: "created before", args: args{filters: []KeyFilter{CreatedBeforeFilter(now)}}, want: []string{"a", "c"}},
+		{name: "weaker alg", args: args{filters: []KeyFilter{weaker}}, want: []string{"a", "d"}},
+		{name: "all of", args: args{filters: []KeyFilter{TenantFilter("acme"), weaker}}, want: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ak := range FilterKeys(keys, tt.args.filters...) {
+				got = append(got, ak.ClientID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FilterKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevokeMatching(t *testing.T) {
+	ctx := context.Background()
+	store := &failUpdateStore{MemStore: NewMemStore(), clientID: "acme-3"}
+	var revoked []string
+	admin := NewAdmin(store, WithHooks(Hooks{OnRevoke: func(_ context.Context, ak Key) {
+		revoked = append(revoked, ak.ClientID)
+	}}))
+	for _, id := range []string{"acme-1", "acme-2", "acme-3", "acme-4"} {
+		if _, _, err := admin.Create(ctx, testAlg, WithClientID(id), WithTenant("acme")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("other-1"), WithTenant("other")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Revoke(ctx, "acme-2"); err != nil {
+		t.Fatal(err)
+	}
+	revoked = nil
+
+	if _, err := admin.RevokeMatching(ctx, nil); !errors.Is(err, ErrNoFilter) {
+		t.Errorf("RevokeMatching() without filters = %v, want ErrNoFilter", err)
+	}
+
+	var done []int
+	p, err := admin.RevokeMatching(ctx, func(p RevokeProgress) { done = append(done, p.Done) }, TenantFilter("acme"))
+	if !errors.Is(err, errDown) {
+		t.Errorf("RevokeMatching() err = %v, want errDown", err)
+	}
+	if p.Matched != 4 || p.Done != 4 || p.Revoked != 2 || p.AlreadyRevoked != 1 || !slices.Equal(p.Failed, []string{"acme-3"}) {
+		t.Errorf("RevokeMatching() = %+v", p)
+	}
+	if !slices.Equal(done, []int{1, 2, 3, 4}) {
+		t.Errorf("progress Done = %v", done)
+	}
+	if !slices.Equal(revoked, []string{"acme-1", "acme-4"}) {
+		t.Errorf("OnRevoke saw %v", revoked)
+	}
+	if ak, _ := admin.Get(ctx, "other-1"); ak.Revoked() {
+		t.Error("key of another tenant revoked")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if p, err := admin.RevokeMatching(cancelled, nil, TenantFilter("other")); !errors.Is(err, context.Canceled) || p.Done != 0 {
+		t.Errorf("RevokeMatching(cancelled) = %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,278 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// CachedKey is a record that verified, as held by a VerifyCache
+type CachedKey struct {
+	Key Key
+	// Previous is true if it verified with the previous secret of a graceful
+	// rotation
+	Previous bool
+	// Expires is when the entry stops being used
+	Expires time.Time
+	// StaleUntil, if after Expires, is when the entry stops being used while
+	// the store is unavailable, see WithCacheFallback. Caches should keep
+	// entries until both have passed.
+	StaleUntil time.Time
+}
+
+// Retain is when the entry can be dropped
+func (ck CachedKey) Retain() time.Time {
+	if ck.StaleUntil.After(ck.Expires) {
+		return ck.StaleUntil
+	}
+	return ck.Expires
+}
+
+// VerifyCache holds recently verified records so that presenting the same
+// api key again skips the store and the derivation. Entries are looked up by
+// the client id and a digest of the full presented key, so the cache does not
+// hold anything that would verify. Caches may drop entries at any time.
+type VerifyCache interface {
+	Get(ctx context.Context, clientID, digest string) (CachedKey, bool, error)
+	Set(ctx context.Context, digest string, ck CachedKey) error
+	// Invalidate drops every entry for clientID
+	Invalidate(ctx context.Context, clientID string) error
+}
+
+// WithVerifyCache caches successful verifications in cache for ttl. A
+// StoreVerifier still checks revocation, expiry and approval of cached
+// records, but only as they were when cached. Updates and deletes through an
+// Admin or StoreVerifier with the same option invalidate the client's
+// entries; changes made any other way are seen once the entries expire.
+func WithVerifyCache(cache VerifyCache, ttl time.Duration) Option {
+	return func(o *options) {
+		o.cache = cache
+		o.cacheTTL = ttl
+	}
+}
+
+// FallbackMetrics is optionally implemented by a Metrics to count the
+// verifications answered from the cache because the store was unavailable
+type FallbackMetrics interface {
+	ObserveCacheFallback()
+}
+
+// CacheMetrics is optionally implemented by a Metrics to count the
+// verifications a VerifyCache answered, and those it didn't, for its hit
+// ratio
+type CacheMetrics interface {
+	ObserveCacheHit()
+	ObserveCacheMiss()
+}
+
+// WithCacheFallback lets a StoreVerifier with WithVerifyCache keep accepting
+// keys it verified recently while the store is unavailable: when loading the
+// record fails, other than with ErrNotFound or the caller's context ending,
+// a cached verification up to maxStale past its expiry is used. A key
+// revoked during the outage keeps working for as long, so choose maxStale by
+// how much that matters compared to failing every request. Each such
+// verification is counted, see FallbackMetrics, and the first of an outage
+// is logged.
+func WithCacheFallback(maxStale time.Duration) Option {
+	return func(o *options) {
+		o.cacheMaxStale = maxStale
+	}
+}
+
+// verifyDigest is the cache key for a presented api key
+func verifyDigest(apikey string) string {
+	sum := sha256.Sum256([]byte(apikey))
+	return hex.EncodeToString(sum[:])
+}
+
+// cached returns the identity for a cached verification of presented, ok is
+// false if there is none that is still usable. If stale, entries past their
+// expiry but not their StaleUntil are usable.
+func (v *StoreVerifier) cached(ctx context.Context, presented Key, digest string, stale bool) (Identity, bool) {
+	if v.cache == nil {
+		return Identity{}, false
+	}
+	ck, ok, err := v.cache.Get(ctx, presented.ClientID, digest)
+	if err != nil {
+		v.warn(ctx, "verify cache get failed", slog.String("client_id", presented.ClientID), slog.Any("error", err))
+		return Identity{}, false
+	}
+	now := v.now()
+	until := ck.Expires
+	if stale {
+		until = ck.StaleUntil
+	}
+	if !ok || !now.Before(until) {
+		return Identity{}, false
+	}
+	ak := ck.Key
+	if ak.ClientID != presented.ClientID || ak.TenantID != presented.TenantID {
+		return Identity{}, false
+	}
+	inRotation := ak.InRotation(now)
+	if ck.Previous && !inRotation {
+		return Identity{}, false
+	}
+	if err := v.usable(ctx, ak); err != nil {
+		return Identity{}, false
+	}
+	if inRotation {
+		v.observeRotation(ak, ck.Previous, now)
+	}
+	touched := v.touch(ctx, ak)
+	if !touched.LastUsedAt.Equal(ak.LastUsedAt) {
+		ck.Key = touched
+		v.setCache(ctx, digest, ck)
+	}
+	return newIdentity(touched, ck.Previous), true
+}
+
+// lookup is cached for a verification which may be answered by the cache,
+// counting the hits and misses
+func (v *StoreVerifier) lookup(ctx context.Context, presented Key, digest string) (Identity, bool) {
+	if v.cache == nil {
+		return Identity{}, false
+	}
+	id, ok := v.cached(ctx, presented, digest, false)
+	if m, isCache := v.metrics.(CacheMetrics); isCache {
+		if ok {
+			m.ObserveCacheHit()
+		} else {
+			m.ObserveCacheMiss()
+		}
+	}
+	return id, ok
+}
+
+// remember caches a successful verification
+func (v *StoreVerifier) remember(ctx context.Context, digest string, id Identity) {
+	if v.cache == nil {
+		return
+	}
+	ck := CachedKey{Key: id.Key, Previous: id.Previous, Expires: v.now().Add(v.cacheTTL)}
+	if v.cacheMaxStale > 0 {
+		ck.StaleUntil = ck.Expires.Add(v.cacheMaxStale)
+	}
+	v.setCache(ctx, digest, ck)
+	v.degraded.Store(false)
+}
+
+// fallback answers from the cache if err from loading the record means the
+// store is unavailable and WithCacheFallback allows it
+func (v *StoreVerifier) fallback(ctx context.Context, presented Key, digest string, err error) (Identity, bool) {
+	if v.cache == nil || v.cacheMaxStale <= 0 || ctx.Err() != nil {
+		return Identity{}, false
+	}
+	if !errors.Is(err, ErrCircuitOpen) && VerifyResult(err) != ResultError {
+		return Identity{}, false
+	}
+	id, ok := v.cached(ctx, presented, digest, true)
+	if !ok {
+		return Identity{}, false
+	}
+	if m, ok := v.metrics.(FallbackMetrics); ok {
+		m.ObserveCacheFallback()
+	}
+	if !v.degraded.Swap(true) {
+		v.warn(ctx, "store unavailable, verifying from cache", slog.String("client_id", presented.ClientID), slog.Any("error", err))
+	}
+	return id, true
+}
+
+func (v *StoreVerifier) setCache(ctx context.Context, digest string, ck CachedKey) {
+	if err := v.cache.Set(ctx, digest, ck); err != nil {
+		v.warn(ctx, "verify cache set failed", slog.String("client_id", ck.Key.ClientID), slog.Any("error", err))
+	}
+}
+
+// invalidatingStore drops the cached verifications of records it changes
+type invalidatingStore struct {
+	Store
+	o *options
+}
+
+func (s invalidatingStore) Update(ctx context.Context, ak Key) error {
+	err := s.Store.Update(ctx, ak)
+	s.invalidate(ctx, ak.ClientID)
+	return err
+}
+
+func (s invalidatingStore) Delete(ctx context.Context, clientID string) error {
+	err := s.Store.Delete(ctx, clientID)
+	s.invalidate(ctx, clientID)
+	return err
+}
+
+func (s invalidatingStore) invalidate(ctx context.Context, clientID string) {
+	if err := s.o.cache.Invalidate(ctx, clientID); err != nil {
+		s.o.warn(ctx, "verify cache invalidate failed", slog.String("client_id", clientID), slog.Any("error", err))
+	}
+}
+
+// MemVerifyCache is an in process VerifyCache. Entries are kept until Sweep
+// removes them.
+type MemVerifyCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]CachedKey
+}
+
+var _ VerifyCache = (*MemVerifyCache)(nil)
+
+func NewMemVerifyCache() *MemVerifyCache {
+	return &MemVerifyCache{entries: make(map[string]map[string]CachedKey)}
+}
+
+func (c *MemVerifyCache) Get(ctx context.Context, clientID, digest string) (CachedKey, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ck, ok := c.entries[clientID][digest]
+	if !ok {
+		return CachedKey{}, false, nil
+	}
+	ck.Key = ck.Key.clone()
+	return ck, true, nil
+}
+
+func (c *MemVerifyCache) Set(ctx context.Context, digest string, ck CachedKey) error {
+	ck.Key = ck.Key.clone()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.entries[ck.Key.ClientID]
+	if m == nil {
+		m = make(map[string]CachedKey)
+		c.entries[ck.Key.ClientID] = m
+	}
+	m[digest] = ck
+	return nil
+}
+
+func (c *MemVerifyCache) Invalidate(ctx context.Context, clientID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, clientID)
+	return nil
+}
+
+// Sweep drops the entries which can no longer be used at now and returns how
+// many it dropped
+func (c *MemVerifyCache) Sweep(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for clientID, m := range c.entries {
+		for digest, ck := range m {
+			if !now.Before(ck.Retain()) {
+				delete(m, digest)
+				n++
+			}
+		}
+		if len(m) == 0 {
+			delete(c.entries, clientID)
+		}
+	}
+	return n
+}
@@ -0,0 +1,170 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+	store := NewMemStore()
+	cache := NewMemVerifyCache()
+	admin := NewAdmin(store, clock, WithVerifyCache(cache, time.Minute))
+	counters := NewCounters()
+	verifier := NewStoreVerifier(store, clock, WithVerifyCache(cache, time.Minute), WithMetrics(counters))
+
+	apikey, ak, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewKey(testAlg, WithClientID(ak.ClientID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := other.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Fatal(err)
+	}
+	// Removed behind the cache's back, so only the cache can verify it
+	if err := store.Delete(ctx, ak.ClientID); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		apikey  string
+		advance time.Duration
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr error
+	}{
+		{"cached", args{apikey, 30 * time.Second}, nil},
+		{"other secret not cached", args{forged, 0}, ErrNotFound},
+		{"cache expired", args{apikey, 30 * time.Second}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.args.advance)
+			got, err := verifier.Verify(ctx, tt.args.apikey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.ClientID != ak.ClientID {
+				t.Errorf("Verify() ClientID = %s, want %s", got.ClientID, ak.ClientID)
+			}
+		})
+	}
+	if s := counters.Stats(); s.CacheHits != 1 || s.CacheMisses != 3 || s.CacheHitRatio() != 0.25 {
+		t.Errorf("Stats() cache hits %d misses %d ratio %v, want 1, 3 and 0.25", s.CacheHits, s.CacheMisses, s.CacheHitRatio())
+	}
+	if n := cache.Sweep(now); n != 1 {
+		t.Errorf("Sweep() = %d, want 1", n)
+	}
+}
+
+func TestVerifyCacheInvalidate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	cache := NewMemVerifyCache()
+	admin := NewAdmin(store, WithVerifyCache(cache, time.Hour))
+	verifier := NewStoreVerifier(store, WithVerifyCache(cache, time.Hour))
+
+	old, ak, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	current, _, err := admin.Rotate(ctx, ak.ClientID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, old); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() replaced secret error = %v, want ErrMismatch", err)
+	}
+	if _, err := verifier.Verify(ctx, current); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Revoke(ctx, ak.ClientID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, current); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Verify() revoked error = %v, want ErrRevoked", err)
+	}
+}
+
+func TestCacheFallback(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+	store := &downStore{MemStore: NewMemStore()}
+	counters := NewCounters()
+	verifier := NewStoreVerifier(store, clock, WithMetrics(counters),
+		WithVerifyCache(NewMemVerifyCache(), time.Minute), WithCacheFallback(10*time.Minute))
+
+	admin := NewAdmin(store.MemStore)
+	apikey, _, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unseen, unseenKey, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		apikey  string
+		down    bool
+		advance time.Duration
+	}
+	tests := []struct {
+		name          string
+		args          args
+		wantErr       error
+		wantFallbacks uint64
+	}{
+		{"store up after ttl", args{apikey, false, 2 * time.Minute}, nil, 0},
+		{"store down", args{apikey, true, 2 * time.Minute}, nil, 1},
+		{"never verified", args{unseen, true, 0}, errDown, 1},
+		{"within window", args{apikey, true, 9*time.Minute - time.Second}, nil, 2},
+		{"too stale", args{apikey, true, time.Second}, errDown, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.down.Store(tt.args.down)
+			now = now.Add(tt.args.advance)
+			_, err := verifier.Verify(ctx, tt.args.apikey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := counters.Stats().CacheFallbacks; got != tt.wantFallbacks {
+				t.Errorf("CacheFallbacks = %d, want %d", got, tt.wantFallbacks)
+			}
+		})
+	}
+
+	// Not found means the store answered, so the cache is not consulted
+	store.down.Store(false)
+	if _, err := verifier.Verify(ctx, unseen); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MemStore.Delete(ctx, unseenKey.ClientID); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := verifier.Verify(ctx, unseen); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Verify() deleted error = %v, want ErrNotFound", err)
+	}
+}
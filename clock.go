@@ -0,0 +1,33 @@
+package apikeys
+
+import "time"
+
+// Clock is the source of wall clock time for expiry and the timestamps
+// recorded on keys and audit events. Durations used for metrics and tracing
+// are always measured with the real clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// WithClock replaces the system clock, typically so that tests of time
+// dependent behaviour don't need to sleep
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// now returns the current time in UTC from the configured clock
+func (o *options) now() time.Time {
+	if o.clock == nil {
+		return time.Now().UTC()
+	}
+	return o.clock.Now().UTC()
+}
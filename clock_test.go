@@ -0,0 +1,42 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+
+	store := NewMemStore()
+	admin := NewAdmin(store, WithClock(clock))
+	verifier := NewStoreVerifier(store, WithClock(clock))
+
+	apikey, ak, err := admin.Create(ctx, testAlg, WithExpiresAt(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !ak.CreatedAt.Equal(now) {
+		t.Errorf("CreatedAt = %v, want %v", ak.CreatedAt, now)
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Errorf("Verify() before expiry error = %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := verifier.Verify(ctx, apikey); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify() at expiry error = %v, want %v", err, ErrExpired)
+	}
+
+	revoked, err := admin.Revoke(ctx, ak.ClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !revoked.RevokedAt.Equal(now) {
+		t.Errorf("RevokedAt = %v, want %v", revoked.RevokedAt, now)
+	}
+}
@@ -0,0 +1,304 @@
+package apikeys
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// EncodingBase64URL is the url safe base64 encoding Generate produces
+	EncodingBase64URL = "base64url"
+	// EncodingBase64 also accepts standard base64, for clients that re-encode
+	// the keys they are given
+	EncodingBase64 = "base64"
+
+	secretEnvScheme  = "env:"
+	secretFileScheme = "file:"
+)
+
+var ErrConfig = errors.New("invalid apikeys configuration")
+
+// Config declares how a service generates and accepts api keys, so that alg
+// strings and bounds live in configuration rather than in code. Load it with
+// LoadConfig and/or LoadEnv, then call Validate.
+type Config struct {
+	// Alg is the alg string for new keys, StandardAlg if empty
+	Alg string `yaml:"alg" json:"alg"`
+	// Policy bounds the parameters of Alg, checked by Validate, and of the
+	// keys ScanPattern matches. Admin and StoreVerifier don't apply it
+	// themselves; give it to them in a TenantPolicy, see WithTenantPolicy.
+	Policy Policy `yaml:"policy" json:"policy"`
+	// Pepper references where the pepper is loaded from, "env:NAME",
+	// "file:PATH" or "cred:NAME", see LoadSecret. The pepper itself is never
+	// part of the configuration. It is required by the keyed algs, see
+	// RegisterPepper.
+	Pepper string `yaml:"pepper" json:"pepper"`
+	// Encoding accepted on presented keys, EncodingBase64URL if empty
+	Encoding string `yaml:"encoding" json:"encoding"`
+	// Prefixes are the environment prefixes, eg "live_", accepted on
+	// presented keys
+	Prefixes []string `yaml:"prefixes" json:"prefixes"`
+	// Separators are the two characters, client id then part separator,
+	// structuring the keys, eg "|~", DefaultSeparators if empty. See
+	// WithSeparators.
+	Separators string `yaml:"separators" json:"separators"`
+	// ReservedClientIDs and ReservedClientIDPattern are the client ids new
+	// keys may not have, see Config.ReserveClientIDs
+	ReservedClientIDs       []string `yaml:"reserved_client_ids" json:"reserved_client_ids"`
+	ReservedClientIDPattern string   `yaml:"reserved_client_id_pattern" json:"reserved_client_id_pattern"`
+}
+
+// Policy bounds alg parameters. Zero fields take the limits ParseAlg
+// enforces.
+type Policy struct {
+	MinTime     uint32 `yaml:"min_time" json:"min_time"`
+	MaxTime     uint32 `yaml:"max_time" json:"max_time"`
+	MinMemoryMB uint32 `yaml:"min_memory_mb" json:"min_memory_mb"`
+	MaxMemoryMB uint32 `yaml:"max_memory_mb" json:"max_memory_mb"`
+	MinKeyLen   uint32 `yaml:"min_key_len" json:"min_key_len"`
+	MaxKeyLen   uint32 `yaml:"max_key_len" json:"max_key_len"`
+	// Hashers are the prefixes of the algs without argon2id parameters, the
+	// keyed algs and those registered with RegisterAlg, the policy allows,
+	// eg "blake2b" or "hmac-sha256 key=svc-1". It allows none by default.
+	Hashers []string `yaml:"hashers" json:"hashers"`
+}
+
+// LoadConfig reads a yaml configuration. Unknown fields are errors so that
+// typos are not silently ignored.
+func LoadConfig(r io.Reader) (Config, error) {
+	var c Config
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("%w: %v", ErrConfig, err)
+	}
+	return c, nil
+}
+
+// LoadEnv overrides c with any of the following environment variables that
+// are set, each named with prefix, eg "APIKEYS_":
+//
+//	ALG, PEPPER, ENCODING, SEPARATORS, PREFIXES (comma separated),
+//	POLICY_MIN_TIME, POLICY_MAX_TIME, POLICY_MIN_MEMORY_MB,
+//	POLICY_MAX_MEMORY_MB, POLICY_MIN_KEY_LEN, POLICY_MAX_KEY_LEN
+func (c *Config) LoadEnv(prefix string) error {
+	for name, p := range map[string]*string{"ALG": &c.Alg, "PEPPER": &c.Pepper, "ENCODING": &c.Encoding, "SEPARATORS": &c.Separators} {
+		if v, ok := os.LookupEnv(prefix + name); ok {
+			*p = v
+		}
+	}
+	if v, ok := os.LookupEnv(prefix + "PREFIXES"); ok {
+		c.Prefixes = nil
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				c.Prefixes = append(c.Prefixes, p)
+			}
+		}
+	}
+	for name, p := range map[string]*uint32{
+		"POLICY_MIN_TIME":      &c.Policy.MinTime,
+		"POLICY_MAX_TIME":      &c.Policy.MaxTime,
+		"POLICY_MIN_MEMORY_MB": &c.Policy.MinMemoryMB,
+		"POLICY_MAX_MEMORY_MB": &c.Policy.MaxMemoryMB,
+		"POLICY_MIN_KEY_LEN":   &c.Policy.MinKeyLen,
+		"POLICY_MAX_KEY_LEN":   &c.Policy.MaxKeyLen,
+	} {
+		v, ok := os.LookupEnv(prefix + name)
+		if !ok {
+			continue
+		}
+		u, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%w: bad %s%s `%s': %v", ErrConfig, prefix, name, v, err)
+		}
+		*p = uint32(u)
+	}
+	return nil
+}
+
+// Validate checks c is complete and self consistent: the alg parses and
+// satisfies the policy, a keyed alg has a pepper, and the pepper reference,
+// encoding, separators and prefixes are well formed. A keyed alg's pepper
+// need not be registered yet, see Config.RegisterPepper.
+func (c Config) Validate() error {
+	if err := c.Policy.validate(); err != nil {
+		return err
+	}
+	if _, keyed, err := keyedPepperID(c.Alg); keyed {
+		if err != nil {
+			return fmt.Errorf("%w: bad alg `%s': %v", ErrConfig, c.Alg, err)
+		}
+		if c.Pepper == "" {
+			return fmt.Errorf("%w: alg `%s' requires a pepper", ErrConfig, c.Alg)
+		}
+		if err := c.Policy.checkHasher(c.Alg); err != nil {
+			return fmt.Errorf("%w: %v", ErrConfig, err)
+		}
+	} else {
+		a, err := c.ParsedAlg()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrConfig, err)
+		}
+		if err := c.Policy.Check(a); err != nil {
+			return fmt.Errorf("%w: %v", ErrConfig, err)
+		}
+	}
+	if c.Pepper != "" && !validSecretRef(c.Pepper) {
+		return fmt.Errorf("%w: pepper reference `%s' is not env:NAME, file:PATH or cred:NAME", ErrConfig, c.Pepper)
+	}
+	if c.Encoding != "" && c.Encoding != EncodingBase64URL && c.Encoding != EncodingBase64 {
+		return fmt.Errorf("%w: unsupported encoding `%s'", ErrConfig, c.Encoding)
+	}
+	if _, err := c.ParsedSeparators(); err != nil {
+		return err
+	}
+	for _, p := range c.Prefixes {
+		if p == "" || strings.ContainsAny(p, ":.") || !printable(p) {
+			return fmt.Errorf("%w: bad prefix `%s'", ErrConfig, p)
+		}
+	}
+	if _, err := regexp.Compile(c.ReservedClientIDPattern); err != nil {
+		return fmt.Errorf("%w: bad reserved client id pattern `%s': %v", ErrConfig, c.ReservedClientIDPattern, err)
+	}
+	return nil
+}
+
+// ParsedAlg parses the configured alg, StandardAlg if it is empty
+func (c Config) ParsedAlg() (Alg, error) {
+	if c.Alg == "" {
+		return ParseAlg(StandardAlg)
+	}
+	return ParseAlg(c.Alg)
+}
+
+// ParsedSeparators parses the configured separators, the zero Separators,
+// which is DefaultSeparators, if they are empty
+func (c Config) ParsedSeparators() (Separators, error) {
+	if c.Separators == "" {
+		return Separators{}, nil
+	}
+	if len(c.Separators) != 2 {
+		return Separators{}, fmt.Errorf("%w: separators `%s' must be two characters", ErrConfig, c.Separators)
+	}
+	s := Separators{ID: c.Separators[0], Part: c.Separators[1]}
+	if err := s.Validate(); err != nil {
+		return Separators{}, err
+	}
+	return s, nil
+}
+
+// RegisterPepper loads the pepper and registers it under the id named by the
+// configured alg, if that is a keyed alg
+func (c Config) RegisterPepper() error {
+	id, keyed, err := keyedPepperID(c.Alg)
+	if !keyed {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: bad alg `%s': %v", ErrConfig, c.Alg, err)
+	}
+	if c.Pepper == "" {
+		return fmt.Errorf("%w: alg `%s' requires a pepper", ErrConfig, c.Alg)
+	}
+	pepper, err := c.LoadPepper()
+	if err != nil {
+		return err
+	}
+	defer pepper.Wipe()
+	return RegisterPepper(id, pepper)
+}
+
+// ReserveClientIDs reserves the configured client ids and pattern, see
+// ReserveClientIDs and ReserveClientIDPattern
+func (c Config) ReserveClientIDs() error {
+	ReserveClientIDs(c.ReservedClientIDs...)
+	if c.ReservedClientIDPattern == "" {
+		return nil
+	}
+	return ReserveClientIDPattern(c.ReservedClientIDPattern)
+}
+
+// LoadPepper resolves the pepper reference with LoadSecret. It returns nil
+// if no pepper is configured.
+func (c Config) LoadPepper() (Secret, error) {
+	if c.Pepper == "" {
+		return nil, nil
+	}
+	return LoadSecret(c.Pepper)
+}
+
+// Check returns an error if a is outside the policy bounds. Keyed and
+// registered algs have no argon2id parameters and pass only if they start
+// with one of the policy's Hashers.
+func (p Policy) Check(a Alg) error {
+	if a.hasher != nil {
+		return p.checkHasher(a.String)
+	}
+	p = p.withDefaults()
+	mem := a.Memory / memoryUnits
+	switch {
+	case a.Time < p.MinTime || a.Time > p.MaxTime:
+		return fmt.Errorf("alg `%s' time outside policy %d-%d", a.String, p.MinTime, p.MaxTime)
+	case mem < p.MinMemoryMB || mem > p.MaxMemoryMB:
+		return fmt.Errorf("alg `%s' memory outside policy %d-%dMB", a.String, p.MinMemoryMB, p.MaxMemoryMB)
+	case a.KeyLen < p.MinKeyLen || a.KeyLen > p.MaxKeyLen:
+		return fmt.Errorf("alg `%s' key length outside policy %d-%d", a.String, p.MinKeyLen, p.MaxKeyLen)
+	}
+	return nil
+}
+
+// checkHasher returns an error unless the alg without argon2id parameters is
+// allowed by Hashers
+func (p Policy) checkHasher(alg string) error {
+	for _, h := range p.Hashers {
+		if strings.HasPrefix(alg, h) {
+			return nil
+		}
+	}
+	return fmt.Errorf("alg `%s' not allowed by policy hashers", alg)
+}
+
+func (p Policy) withDefaults() Policy {
+	for _, f := range []struct {
+		v   *uint32
+		def uint32
+	}{
+		{&p.MinTime, minTime}, {&p.MaxTime, maxTime},
+		{&p.MinMemoryMB, minMem}, {&p.MaxMemoryMB, maxMem},
+		{&p.MinKeyLen, minKeyLength}, {&p.MaxKeyLen, maxKeyLength},
+	} {
+		if *f.v == 0 {
+			*f.v = f.def
+		}
+	}
+	return p
+}
+
+func (p Policy) validate() error {
+	d := p.withDefaults()
+	for _, b := range []struct {
+		name             string
+		min, max, lo, hi uint32
+	}{
+		{"time", d.MinTime, d.MaxTime, minTime, maxTime},
+		{"memory", d.MinMemoryMB, d.MaxMemoryMB, minMem, maxMem},
+		{"key length", d.MinKeyLen, d.MaxKeyLen, minKeyLength, maxKeyLength},
+	} {
+		if b.min > b.max || b.min < b.lo || b.max > b.hi {
+			return fmt.Errorf("%w: policy %s bounds %d-%d not within %d-%d", ErrConfig, b.name, b.min, b.max, b.lo, b.hi)
+		}
+	}
+	for _, h := range p.Hashers {
+		if strings.TrimSpace(h) == "" {
+			return fmt.Errorf("%w: empty policy hasher", ErrConfig)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package apikeys
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	const doc = `
+alg: argon2id 2 32MB 32
+policy:
+  min_time: 2
+  max_memory_mb: 32
+pepper: env:APIKEYS_PEPPER
+encoding: base64url
+prefixes: [live_, test_]
+`
+	got, err := LoadConfig(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		Alg:      "argon2id 2 32MB 32",
+		Policy:   Policy{MinTime: 2, MaxMemoryMB: 32},
+		Pepper:   "env:APIKEYS_PEPPER",
+		Encoding: EncodingBase64URL,
+		Prefixes: []string{"live_", "test_"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadConfig() = %+v, want %+v", got, want)
+	}
+	if err := got.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	if _, err := LoadConfig(strings.NewReader("algo: typo\n")); !errors.Is(err, ErrConfig) {
+		t.Errorf("LoadConfig() with unknown field error = %v, want ErrConfig", err)
+	}
+	if _, err := LoadConfig(strings.NewReader("")); err != nil {
+		t.Errorf("LoadConfig() empty error = %v", err)
+	}
+}
+
+func TestConfigLoadEnv(t *testing.T) {
+	t.Setenv("APIKEYS_ALG", "argon2id 1 16MB 16")
+	t.Setenv("APIKEYS_PREFIXES", "live_, test_")
+	t.Setenv("APIKEYS_POLICY_MAX_TIME", "3")
+
+	c := Config{Alg: StandardAlg, Pepper: "file:/run/pepper"}
+	if err := c.LoadEnv("APIKEYS_"); err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		Alg:      "argon2id 1 16MB 16",
+		Policy:   Policy{MaxTime: 3},
+		Pepper:   "file:/run/pepper",
+		Prefixes: []string{"live_", "test_"},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("LoadEnv() = %+v, want %+v", c, want)
+	}
+
+	t.Setenv("APIKEYS_POLICY_MIN_KEY_LEN", "many")
+	if err := c.LoadEnv("APIKEYS_"); !errors.Is(err, ErrConfig) {
+		t.Errorf("LoadEnv() with bad number error = %v, want ErrConfig", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	type args struct {
+		c Config
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"zero", args{Config{}}, false},
+		{"bad alg", args{Config{Alg: "argon2id 9 64MB 32"}}, true},
+		{"alg outside policy", args{Config{Alg: StandardAlg, Policy: Policy{MaxMemoryMB: 32}}}, true},
+		{"policy inverted", args{Config{Policy: Policy{MinTime: 4, MaxTime: 2}}}, true},
+		{"policy beyond limits", args{Config{Policy: Policy{MaxKeyLen: 128}}}, true},
+		{"pepper scheme", args{Config{Pepper: "APIKEYS_PEPPER"}}, true},
+		{"keyed alg", args{Config{Alg: "hmac-sha256 key=unregistered", Pepper: "env:APIKEYS_PEPPER", Policy: Policy{Hashers: []string{"hmac-sha256"}}}}, false},
+		{"keyed alg not in hashers", args{Config{Alg: "hmac-sha256 key=unregistered", Pepper: "env:APIKEYS_PEPPER"}}, true},
+		{"empty hasher", args{Config{Policy: Policy{Hashers: []string{""}}}}, true},
+		{"keyed alg without pepper", args{Config{Alg: "hmac-sha256 key=svc-1"}}, true},
+		{"bad keyed alg", args{Config{Alg: "blake2b key=svc-1", Pepper: "env:APIKEYS_PEPPER"}}, true},
+		{"encoding", args{Config{Encoding: "hex"}}, true},
+		{"empty prefix", args{Config{Prefixes: []string{""}}}, true},
+		{"prefix separator", args{Config{Prefixes: []string{"a:b"}}}, true},
+		{"separators", args{Config{Separators: "|~"}}, false},
+		{"one separator", args{Config{Separators: "|"}}, true},
+		{"base64 separator", args{Config{Separators: "|_"}}, true},
+		{"reserved client ids", args{Config{ReservedClientIDs: []string{"admin"}, ReservedClientIDPattern: "^internal-"}}, false},
+		{"bad reserved client id pattern", args{Config{ReservedClientIDPattern: "(internal"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.c.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrConfig) {
+				t.Errorf("Validate() error = %v, want ErrConfig", err)
+			}
+		})
+	}
+}
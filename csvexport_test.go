@@ -0,0 +1,76 @@
+package apikeys
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportCSV(t *testing.T) {
+	now := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemStore()
+	admin := NewAdmin(store, WithClock(ClockFunc(func() time.Time { return now })))
+	_, active, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-2"), WithExpiresAt(now.Add(-time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-3")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Revoke(t.Context(), "client-3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Touch(t.Context(), "client-1", now); err != nil {
+		t.Fatal(err)
+	}
+	earlier := NewAdmin(store, WithClock(ClockFunc(func() time.Time { return now.Add(-2 * time.Hour) })))
+	if _, _, err := earlier.Create(t.Context(), testAlg, WithClientID("client-4"), WithIdleTimeout(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(WithPrincipal(t.Context(), "alice"), testAlg, WithClientID("client-5"), RequireApproval()); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportCSV(t.Context(), store, &buf, now); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		csvHeader,
+		{"client-1", active.Fingerprint(), testAlg, StatusActive, "2030-06-01T00:00:00Z", "", "", "2030-06-01T00:00:00Z"},
+		{"client-2", rows[2][1], testAlg, StatusExpired, "2030-06-01T00:00:00Z", "2030-05-31T23:00:00Z", "", ""},
+		{"client-3", rows[3][1], testAlg, StatusRevoked, "2030-06-01T00:00:00Z", "", "2030-06-01T00:00:00Z", ""},
+		{"client-4", rows[4][1], testAlg, StatusIdle, "2030-05-31T22:00:00Z", "", "", ""},
+		{"client-5", rows[5][1], testAlg, StatusPending, "2030-06-01T00:00:00Z", "", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ExportCSV() =\n%v\nwant\n%v", rows, want)
+	}
+	if got := len(active.Fingerprint()); got != 2*fingerprintLen {
+		t.Errorf("fingerprint length = %d, want %d", got, 2*fingerprintLen)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Key{DerivedKey: []byte("derived-a")}
+	b := Key{DerivedKey: []byte("derived-b")}
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Errorf("different keys have the same fingerprint")
+	}
+	if got := (Key{ImportedHash: "$2b$04$x"}).Fingerprint(); got == "" {
+		t.Errorf("imported record has no fingerprint")
+	}
+	if got := (Key{}).Fingerprint(); got != "" {
+		t.Errorf("empty record fingerprint = %q", got)
+	}
+}
@@ -0,0 +1,161 @@
+package apikeys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DecodeOption tightens or relaxes what Decode accepts
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	strict    bool
+	lenient   bool
+	maxLen    int
+	encodings []*base64.Encoding
+	algs      []string
+	prefixes  []string
+	seps      Separators
+}
+
+func newDecodeOptions(opts []DecodeOption) decodeOptions {
+	o := decodeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithStrict applies the checks described by ValidateEncodedKey. Both
+// layers of the key must also be canonical base64, as Generate produced
+// them: no embedded newlines, no stray bits in the last character and no
+// characters from more than one alphabet.
+func WithStrict() DecodeOption {
+	return func(o *decodeOptions) {
+		o.strict = true
+	}
+}
+
+// WithLenient tolerates what copy and paste leaves around a key: leading and
+// trailing whitespace and newlines, and a pair of enclosing quotes. The key
+// itself must still be intact, and WithStrict still applies to it.
+func WithLenient() DecodeOption {
+	return func(o *decodeOptions) {
+		o.lenient = true
+	}
+}
+
+// WithMaxLength rejects encoded keys, including any prefix, longer than n.
+// It overrides the MaxEncodedKeyLen limit of WithStrict.
+func WithMaxLength(n int) DecodeOption {
+	return func(o *decodeOptions) {
+		o.maxLen = n
+	}
+}
+
+// WithEncodings sets the encodings accepted for the outer layer of the key,
+// tried in order. The default is base64.URLEncoding, which is what Generate
+// produces.
+func WithEncodings(encs ...*base64.Encoding) DecodeOption {
+	return func(o *decodeOptions) {
+		o.encodings = encs
+	}
+}
+
+// WithAllowedAlgs rejects keys whose alg string is not one of algs
+func WithAllowedAlgs(algs ...string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.algs = algs
+	}
+}
+
+// WithPrefix requires the key to start with one of prefixes, eg "live_",
+// which is removed before decoding. Use it to stop keys issued for one
+// environment being accepted in another.
+func WithPrefix(prefixes ...string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.prefixes = prefixes
+	}
+}
+
+// WithDecodeOptions applies opts when the verifier decodes presented keys
+func WithDecodeOptions(opts ...DecodeOption) Option {
+	return func(o *options) {
+		o.decode = newDecodeOptions(opts)
+	}
+}
+
+// pastedQuotes are the quotes WithLenient removes
+var pastedQuotes = []string{`"`, "'", "`"}
+
+// trimPasted removes surrounding whitespace and then a pair of matching
+// quotes and the whitespace within them
+func trimPasted(apikey string) string {
+	apikey = strings.TrimSpace(apikey)
+	for _, q := range pastedQuotes {
+		if len(apikey) >= 2 && strings.HasPrefix(apikey, q) && strings.HasSuffix(apikey, q) {
+			return strings.TrimSpace(apikey[1 : len(apikey)-1])
+		}
+	}
+	return apikey
+}
+
+func (o *decodeOptions) maxLength() int {
+	if o.maxLen == 0 && o.strict {
+		return MaxEncodedKeyLen
+	}
+	return o.maxLen
+}
+
+var defaultEncodings = []*base64.Encoding{base64.URLEncoding}
+
+func (o *decodeOptions) accepted() []*base64.Encoding {
+	if len(o.encodings) == 0 {
+		return defaultEncodings
+	}
+	return o.encodings
+}
+
+func (o *decodeOptions) allowAlg(alg string) bool {
+	return len(o.algs) == 0 || slices.Contains(o.algs, alg)
+}
+
+func (o *decodeOptions) stripPrefix(apikey string) (string, error) {
+	if len(o.prefixes) == 0 {
+		return apikey, nil
+	}
+	if p, ok := o.prefix(apikey); ok {
+		return apikey[len(p):], nil
+	}
+	return "", fmt.Errorf("api key missing expected prefix")
+}
+
+// prefix returns the first of the accepted prefixes apikey starts with
+func (o *decodeOptions) prefix(apikey string) (string, bool) {
+	for _, p := range o.prefixes {
+		if strings.HasPrefix(apikey, p) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// DecodeOptions returns the decode options implied by the configured
+// encoding, separators and prefixes. Separators which don't parse are
+// ignored, so call Validate first.
+func (c Config) DecodeOptions() []DecodeOption {
+	var opts []DecodeOption
+	switch c.Encoding {
+	case EncodingBase64:
+		opts = append(opts, WithEncodings(base64.URLEncoding, base64.StdEncoding))
+	}
+	if s, err := c.ParsedSeparators(); err == nil && s != (Separators{}) {
+		opts = append(opts, WithDecodeSeparators(s))
+	}
+	if len(c.Prefixes) > 0 {
+		opts = append(opts, WithPrefix(c.Prefixes...))
+	}
+	return opts
+}
@@ -0,0 +1,127 @@
+package apikeys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeOptions(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := base64.URLEncoding.DecodeString(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	std := base64.StdEncoding.EncodeToString(inner)
+	short, err := NewKey(testAlg, WithClientID("client 2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	short.Salt = []byte("salt")
+	weak := short.encode([]byte("pw"))
+	// Decoding skips newlines, as when a key is wrapped in an email
+	wrapped := apikey[:20] + "\n" + apikey[20:]
+	pasted := " \"" + apikey + "\"\r\n"
+	// Each of these decodes to the same bytes as a key Generate produced
+	padded, _ := NewKey(testAlg, WithClientID("client-12"))
+	if _, err := padded.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	strayBits := nonCanonical(t, padded.encode(make([]byte, passwordLen)))
+	sep := bytes.LastIndexByte(inner, '.') + 1
+	innerStrayBits := base64.URLEncoding.EncodeToString(
+		append(inner[:sep:sep], nonCanonical(t, string(inner[sep:]))...))
+
+	type args struct {
+		apikey string
+		opts   []DecodeOption
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"default", args{apikey, nil}, false},
+		{"strict", args{apikey, []DecodeOption{WithStrict()}}, false},
+		{"strict weak", args{weak, []DecodeOption{WithStrict()}}, true},
+		{"lenient weak", args{weak, nil}, false},
+		{"max length", args{apikey, []DecodeOption{WithMaxLength(len(apikey) - 1)}}, true},
+		{"strict max length override", args{apikey, []DecodeOption{WithStrict(), WithMaxLength(len(apikey))}}, false},
+		{"allowed alg", args{apikey, []DecodeOption{WithAllowedAlgs(StandardAlg, testAlg)}}, false},
+		{"disallowed alg", args{apikey, []DecodeOption{WithAllowedAlgs(StandardAlg)}}, true},
+		{"prefix", args{"live_" + apikey, []DecodeOption{WithPrefix("test_", "live_")}}, false},
+		{"missing prefix", args{apikey, []DecodeOption{WithPrefix("live_")}}, true},
+		{"wrong prefix", args{"test_" + apikey, []DecodeOption{WithPrefix("live_")}}, true},
+		{"std encoding", args{std, []DecodeOption{WithEncodings(base64.URLEncoding, base64.StdEncoding)}}, false},
+		{"wrapped", args{wrapped, nil}, false},
+		{"strict wrapped", args{wrapped, []DecodeOption{WithStrict()}}, true},
+		{"strict stray bits", args{strayBits, []DecodeOption{WithStrict()}}, true},
+		{"stray bits in the secret", args{innerStrayBits, nil}, false},
+		{"strict stray bits in the secret", args{innerStrayBits, []DecodeOption{WithStrict()}}, true},
+		{"strict std encoding", args{std, []DecodeOption{WithStrict(), WithEncodings(base64.URLEncoding, base64.StdEncoding)}}, false},
+		{"pasted", args{pasted, nil}, true},
+		{"lenient pasted", args{pasted, []DecodeOption{WithLenient()}}, false},
+		{"lenient strict pasted", args{pasted, []DecodeOption{WithLenient(), WithStrict()}}, false},
+		{"lenient backticks", args{"`" + apikey + "`", []DecodeOption{WithLenient()}}, false},
+		{"lenient unmatched quote", args{`"` + apikey, []DecodeOption{WithLenient()}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, password, err := Decode(tt.args.apikey, tt.args.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.args.apikey != weak && !got.MatchPassword(password, ak.DerivedKey) {
+				t.Errorf("Decode() password does not match")
+			}
+		})
+	}
+}
+
+// nonCanonical sets an unused bit in the last character of a padded base64
+// string, which decoding ignores
+func nonCanonical(t *testing.T, s string) string {
+	t.Helper()
+	trimmed := strings.TrimRight(s, "=")
+	if len(trimmed) == len(s) {
+		t.Fatalf("%s has no padding", s)
+	}
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	last := strings.IndexByte(alphabet, trimmed[len(trimmed)-1])
+	return trimmed[:len(trimmed)-1] + string(alphabet[last|1]) + s[len(trimmed):]
+}
+
+func TestVerifierDecodeOptions(t *testing.T) {
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	apikey, _, err := admin.Create(t.Context(), testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewStoreVerifier(store, WithDecodeOptions(WithPrefix("live_")))
+	if _, err := v.Verify(t.Context(), "live_"+apikey); err != nil {
+		t.Errorf("Verify() with prefix error = %v", err)
+	}
+	if _, err := v.Verify(t.Context(), apikey); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify() without prefix error = %v, want ErrInvalid", err)
+	}
+}
+
+func TestConfigDecodeOptions(t *testing.T) {
+	c := Config{Encoding: EncodingBase64, Prefixes: []string{"live_"}, Separators: "|~"}
+	if got := len(c.DecodeOptions()); got != 3 {
+		t.Errorf("DecodeOptions() returned %d options, want 3", got)
+	}
+	if got := len((Config{}).DecodeOptions()); got != 0 {
+		t.Errorf("DecodeOptions() for zero config returned %d options", got)
+	}
+}
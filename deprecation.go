@@ -0,0 +1,114 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrDeprecated is returned when a key's alg is past the sunset of an
+// enforced ParamDeprecation
+var ErrDeprecated = errors.New("alg past its sunset")
+
+// ParamDeprecation schedules the retirement of an alg. Keys under it keep
+// verifying, with a warning and a metric each time, until Sunset, after which
+// they are rejected if Enforce is set.
+type ParamDeprecation struct {
+	// Alg is matched on its canonical form, see Alg.Canonical
+	Alg string `yaml:"alg" json:"alg"`
+	// Sunset is the end of the grace period, warnings only if zero
+	Sunset time.Time `yaml:"sunset" json:"sunset"`
+	// Replacement is the alg keys should be rotated to, given in the warning
+	Replacement string `yaml:"replacement" json:"replacement"`
+	// Enforce rejects keys under Alg once Sunset has passed
+	Enforce bool `yaml:"enforce" json:"enforce"`
+}
+
+// sunset reports whether d rejects keys at now
+func (d ParamDeprecation) sunset(now time.Time) bool {
+	return d.Enforce && !d.Sunset.IsZero() && !now.Before(d.Sunset)
+}
+
+// DeprecationMetrics is optionally implemented by a Metrics to count the
+// successful verifications of keys under a deprecated alg
+type DeprecationMetrics interface {
+	ObserveDeprecated(alg string)
+}
+
+// WithDeprecations has StoreVerifier consult schedule on every verification.
+// Keys past an enforced sunset are rejected with ErrDeprecated, before any
+// derivation, and successful verifications of the others are logged and
+// counted, so the stragglers can be found and rotated before the sunset.
+// The algs are parsed on the first verification, so the peppers of keyed
+// algs may be registered after the verifier is made; until every alg parses,
+// verifications fail with ErrConfig.
+func WithDeprecations(schedule ...ParamDeprecation) Option {
+	return func(o *options) {
+		o.deprecations = &deprecationSchedule{entries: schedule}
+	}
+}
+
+// deprecationSchedule indexes the entries of WithDeprecations by canonical
+// alg once they all parse
+type deprecationSchedule struct {
+	entries []ParamDeprecation
+
+	mu    sync.Mutex
+	byAlg map[string]ParamDeprecation
+}
+
+// resolve returns the entries by canonical alg, or an ErrConfig error if one
+// of them doesn't parse yet
+func (s *deprecationSchedule) resolve() (map[string]ParamDeprecation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byAlg != nil {
+		return s.byAlg, nil
+	}
+	byAlg := make(map[string]ParamDeprecation, len(s.entries))
+	for _, d := range s.entries {
+		a, err := ParseAlg(d.Alg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: deprecated alg `%s': %v", ErrConfig, d.Alg, err)
+		}
+		byAlg[a.Canonical()] = d
+	}
+	s.byAlg = byAlg
+	return byAlg, nil
+}
+
+// deprecation returns the schedule entry for the alg of presented, if any,
+// and an ErrDeprecated error if it is past an enforced sunset
+func (o *options) deprecation(presented Key) (*ParamDeprecation, error) {
+	if o.deprecations == nil {
+		return nil, nil
+	}
+	byAlg, err := o.deprecations.resolve()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := byAlg[presented.alg.Canonical()]
+	if !ok {
+		return nil, nil
+	}
+	if d.sunset(o.now()) {
+		return &d, fmt.Errorf("%w: `%s' retired at %s, rotate to `%s'",
+			ErrDeprecated, presented.alg.String, d.Sunset.Format(time.RFC3339), d.Replacement)
+	}
+	return &d, nil
+}
+
+// observeDeprecated reports a key which verified under a deprecated alg
+func (o *options) observeDeprecated(ctx context.Context, ak Key, d ParamDeprecation) {
+	o.warn(ctx, "api key alg is deprecated",
+		slog.String("client_id", ak.ClientID),
+		slog.String("alg", ak.alg.String),
+		slog.Time("sunset", d.Sunset),
+		slog.String("replacement", d.Replacement))
+	if m, ok := o.metrics.(DeprecationMetrics); ok {
+		m.ObserveDeprecated(ak.alg.Canonical())
+	}
+}
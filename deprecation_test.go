@@ -0,0 +1,113 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeprecations(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+	store := NewMemStore()
+	apikey, _, err := NewAdmin(store).Create(ctx, testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sunset := start.Add(time.Hour)
+
+	type args struct {
+		schedule []ParamDeprecation
+		at       time.Time
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantErr        error
+		wantDeprecated uint64
+	}{
+		{
+			name: "not scheduled",
+			args: args{schedule: []ParamDeprecation{{Alg: "argon2id 3 64MB 32", Sunset: sunset, Enforce: true}}, at: sunset},
+		},
+		{
+			name:           "before sunset",
+			args:           args{schedule: []ParamDeprecation{{Alg: "argon2id t=1,m=16MB,len=16", Sunset: sunset, Replacement: "argon2id 3 64MB 32", Enforce: true}}, at: start},
+			wantDeprecated: 1,
+		},
+		{
+			name:           "after sunset not enforced",
+			args:           args{schedule: []ParamDeprecation{{Alg: testAlg, Sunset: sunset}}, at: sunset},
+			wantDeprecated: 1,
+		},
+		{
+			name:    "after sunset",
+			args:    args{schedule: []ParamDeprecation{{Alg: testAlg, Sunset: sunset, Enforce: true}}, at: sunset},
+			wantErr: ErrDeprecated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = tt.args.at
+			var buf bytes.Buffer
+			counters := NewCounters()
+			v := NewStoreVerifier(store, clock, WithMetrics(counters), WithDeprecations(tt.args.schedule...),
+				WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+			_, err := v.Verify(ctx, apikey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			stats := counters.Stats()
+			if stats.Deprecated != tt.wantDeprecated {
+				t.Errorf("Deprecated = %d, want %d", stats.Deprecated, tt.wantDeprecated)
+			}
+			if tt.wantErr != nil && (!errors.Is(err, ErrInvalid) || stats.Derivations != 0) {
+				t.Errorf("retired key error = %v after %d derivations, want ErrInvalid before any", err, stats.Derivations)
+			}
+			if got := strings.Contains(buf.String(), "deprecated"); got != (tt.wantDeprecated > 0) {
+				t.Errorf("log = %q", buf.String())
+			}
+		})
+	}
+}
+
+// deprecatedAlgs records the alg labels of ObserveDeprecated
+type deprecatedAlgs struct {
+	*Counters
+	algs []string
+}
+
+func (m *deprecatedAlgs) ObserveDeprecated(alg string) {
+	m.algs = append(m.algs, alg)
+}
+
+func TestDeprecationsResolvedLazily(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemStore()
+	apikey, _, err := NewAdmin(store).Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := &deprecatedAlgs{Counters: NewCounters()}
+	v := NewStoreVerifier(store, WithMetrics(metrics), WithDeprecations(
+		ParamDeprecation{Alg: testAlg},
+		ParamDeprecation{Alg: "hmac-sha256 key=deprecation-test"}))
+	if _, err := v.Verify(ctx, apikey); !errors.Is(err, ErrConfig) {
+		t.Fatalf("Verify() before the pepper is registered error = %v, want ErrConfig", err)
+	}
+	if err := RegisterPepper("deprecation-test", Secret(strings.Repeat("p", MinPepperLen))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(ctx, apikey); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if want := "argon2id t=1,m=16MB,len=16"; len(metrics.algs) != 1 || metrics.algs[0] != want {
+		t.Errorf("ObserveDeprecated() labels = %v, want [%s]", metrics.algs, want)
+	}
+}
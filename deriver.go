@@ -0,0 +1,206 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrOverloaded is returned by Deriver.Derive when the queue is full
+	ErrOverloaded = errors.New("api key derivation queue full")
+	// ErrClosed is returned by Deriver.Derive after Close
+	ErrClosed = errors.New("api key deriver closed")
+	// ErrQueueWait is returned by Deriver.Derive when a derivation waits in
+	// the queue for longer than the WithMaxQueueWait limit. It wraps
+	// ErrOverloaded.
+	ErrQueueWait = fmt.Errorf("%w: queue wait exceeded", ErrOverloaded)
+)
+
+// deriveJob states
+const (
+	jobQueued int32 = iota
+	jobRunning
+	jobAbandoned
+)
+
+// Deriver runs argon2 derivations for verification on a fixed number of
+// workers. Each derivation allocates the memory parameter of its Alg (64MB
+// for StandardAlg), so bounding the workers bounds the memory a traffic spike
+// can commit. Requests beyond the queue length are rejected with
+// ErrOverloaded rather than queued without limit.
+type Deriver struct {
+	options
+	jobs chan deriveJob
+	quit chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+
+	// warned implements hysteresis for the queue depth warning
+	warned atomic.Bool
+}
+
+type deriveJob struct {
+	ak       Key
+	password []byte
+	queued   time.Time
+	// state is shared between the caller and the worker so that either a
+	// worker starts the job or the caller abandons it, never both.
+	state  *atomic.Int32
+	result chan []byte
+}
+
+// NewDeriver starts workers goroutines serving a queue of queueLen pending
+// derivations. If workers is not positive runtime.NumCPU() is used, and if
+// queueLen is not positive it defaults to the number of workers. The
+// options which apply are WithMetrics, WithLogger, WithQueueDepthWarning and
+// WithMaxQueueWait.
+func NewDeriver(workers, queueLen int, opts ...Option) *Deriver {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if queueLen <= 0 {
+		queueLen = workers
+	}
+	d := &Deriver{
+		options: newOptions(opts),
+		jobs:    make(chan deriveJob, queueLen),
+		quit:    make(chan struct{}),
+	}
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// WithDeriver makes StoreVerifier run its derivations on d
+func WithDeriver(d *Deriver) Option {
+	return func(o *options) {
+		o.deriver = d
+	}
+}
+
+// WithMaxQueueWait bounds how long a derivation may wait for a Deriver worker
+// before failing with ErrQueueWait. Zero, the default, means no limit beyond
+// the callers context.
+func WithMaxQueueWait(d time.Duration) Option {
+	return func(o *options) {
+		o.maxQueueWait = d
+	}
+}
+
+// WithQueueDepthWarning logs a warning when the Deriver queue depth reaches n.
+// The warning is repeated only after the depth has fallen below n/2.
+func WithQueueDepthWarning(n int) Option {
+	return func(o *options) {
+		o.queueWarn = n
+	}
+}
+
+func (d *Deriver) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.quit:
+			return
+		case job := <-d.jobs:
+			wait := time.Since(job.queued)
+			if d.metrics != nil {
+				d.metrics.ObserveQueue(len(d.jobs), wait)
+			}
+			// The caller gave up while the job was queued
+			if !job.state.CompareAndSwap(jobQueued, jobRunning) {
+				continue
+			}
+			job.result <- job.ak.RecoverKey(job.password)
+		}
+	}
+}
+
+// Derive queues the derivation of the key for password using the alg and salt
+// of ak and waits for the result. If ctx is done while the job is queued the
+// derivation is never started. If ctx is done after it has started Derive
+// waits for it to finish, so that the caller may safely reuse password and
+// the salt once Derive returns, and then returns ctx.Err().
+func (d *Deriver) Derive(ctx context.Context, ak Key, password []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case <-d.quit:
+		return nil, ErrClosed
+	default:
+	}
+	job := deriveJob{
+		ak: ak, password: password, queued: time.Now(),
+		state: &atomic.Int32{}, result: make(chan []byte, 1),
+	}
+	select {
+	case d.jobs <- job:
+	default:
+		return nil, ErrOverloaded
+	}
+	d.checkDepth(ctx)
+
+	var waitLimit <-chan time.Time
+	if d.maxQueueWait > 0 {
+		t := time.NewTimer(d.maxQueueWait)
+		defer t.Stop()
+		waitLimit = t.C
+	}
+	for {
+		select {
+		case key := <-job.result:
+			return key, nil
+		case <-d.quit:
+			if !job.state.CompareAndSwap(jobQueued, jobAbandoned) {
+				clear(<-job.result)
+			}
+			return nil, ErrClosed
+		case <-ctx.Done():
+			if !job.state.CompareAndSwap(jobQueued, jobAbandoned) {
+				clear(<-job.result)
+			}
+			return nil, ctx.Err()
+		case <-waitLimit:
+			if job.state.CompareAndSwap(jobQueued, jobAbandoned) {
+				return nil, ErrQueueWait
+			}
+			// already running, the limit only applies to the queue
+			waitLimit = nil
+		}
+	}
+}
+
+// QueueDepth returns the number of derivations waiting for a worker
+func (d *Deriver) QueueDepth() int {
+	return len(d.jobs)
+}
+
+// Close stops the workers. Derivations in progress complete, those still
+// queued fail with ErrClosed.
+func (d *Deriver) Close() {
+	d.once.Do(func() { close(d.quit) })
+	d.wg.Wait()
+}
+
+func (d *Deriver) checkDepth(ctx context.Context) {
+	if d.queueWarn <= 0 {
+		return
+	}
+	depth := len(d.jobs)
+	if depth < d.queueWarn/2 {
+		d.warned.Store(false)
+		return
+	}
+	if depth >= d.queueWarn && d.warned.CompareAndSwap(false, true) {
+		d.warn(ctx, "api key derivation queue is deep",
+			slog.Int("depth", depth), slog.Int("threshold", d.queueWarn), slog.Int("capacity", cap(d.jobs)))
+	}
+}
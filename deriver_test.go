@@ -0,0 +1,189 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeriver(t *testing.T) {
+	ctx := context.Background()
+	ak, err := NewKey(testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	presented, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counters := NewCounters()
+	d := NewDeriver(2, 16, WithMetrics(counters))
+	defer d.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := d.Derive(ctx, presented, password)
+			if err != nil {
+				t.Errorf("Derive() error = %v", err)
+				return
+			}
+			if !bytes.Equal(key, ak.DerivedKey) {
+				t.Errorf("Derive() = %x, want %x", key, ak.DerivedKey)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := counters.Stats().Queued; got != 8 {
+		t.Errorf("Queued = %d, want 8", got)
+	}
+
+	d.Close()
+	if _, err := d.Derive(ctx, presented, password); !errors.Is(err, ErrClosed) {
+		t.Errorf("Derive() after Close error = %v, want %v", err, ErrClosed)
+	}
+}
+
+// warnedHandler signals warned when a Deriver logs its queue depth warning,
+// which it does once the jobs are queued
+type warnedHandler struct {
+	slog.Handler
+	warned chan struct{}
+}
+
+func (h warnedHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.warned <- struct{}{}
+	return h.Handler.Handle(ctx, r)
+}
+
+func newWarnedHandler(h slog.Handler) warnedHandler {
+	return warnedHandler{Handler: h, warned: make(chan struct{}, 1)}
+}
+
+func TestDeriverOverloaded(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	h := newWarnedHandler(slog.NewTextHandler(&buf, nil))
+	// No workers are started so that the queue fills deterministically
+	d := &Deriver{
+		options: newOptions([]Option{WithLogger(slog.New(h)), WithQueueDepthWarning(2)}),
+		jobs:    make(chan deriveJob, 2),
+		quit:    make(chan struct{}),
+	}
+
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := d.Derive(ctx, Key{}, nil)
+			results <- err
+		}()
+	}
+	// The warning at depth 2 means both are queued
+	<-h.warned
+	if _, err := d.Derive(ctx, Key{}, nil); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Derive() on full queue error = %v, want %v", err, ErrOverloaded)
+	}
+	if d.QueueDepth() != 2 {
+		t.Errorf("QueueDepth() = %d, want 2", d.QueueDepth())
+	}
+	d.Close()
+	for i := 0; i < 2; i++ {
+		if err := <-results; !errors.Is(err, ErrClosed) {
+			t.Errorf("queued Derive() error = %v, want %v", err, ErrClosed)
+		}
+	}
+	if n := strings.Count(buf.String(), "derivation queue is deep"); n != 1 {
+		t.Errorf("logged %d queue depth warnings, want 1: %s", n, buf.String())
+	}
+}
+
+func TestVerifierWithDeriver(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	d := NewDeriver(1, 1)
+	defer d.Close()
+	apikey, _, err := NewAdmin(store).Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStoreVerifier(store, WithDeriver(d)).Verify(ctx, apikey); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestDeriverContext(t *testing.T) {
+	// No workers so jobs stay queued until the test starts one
+	newIdle := func(opts ...Option) *Deriver {
+		return &Deriver{options: newOptions(opts), jobs: make(chan deriveJob, 4), quit: make(chan struct{})}
+	}
+
+	t.Run("canceled before queueing", func(t *testing.T) {
+		d := newIdle()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := d.Derive(ctx, Key{}, nil); !errors.Is(err, context.Canceled) {
+			t.Errorf("Derive() error = %v, want %v", err, context.Canceled)
+		}
+		if d.QueueDepth() != 0 {
+			t.Errorf("QueueDepth() = %d, want 0", d.QueueDepth())
+		}
+	})
+
+	t.Run("canceled while queued", func(t *testing.T) {
+		h := newWarnedHandler(slog.NewTextHandler(io.Discard, nil))
+		d := newIdle(WithLogger(slog.New(h)), WithQueueDepthWarning(1))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			_, err := d.Derive(ctx, Key{}, nil)
+			done <- err
+		}()
+		<-h.warned
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Derive() error = %v, want %v", err, context.Canceled)
+		}
+		// The abandoned job must be skipped rather than derived
+		job := <-d.jobs
+		if job.state.CompareAndSwap(jobQueued, jobRunning) {
+			t.Errorf("abandoned job could still be started")
+		}
+	})
+
+	t.Run("queue wait exceeded", func(t *testing.T) {
+		d := newIdle(WithMaxQueueWait(time.Millisecond))
+		if _, err := d.Derive(context.Background(), Key{}, nil); !errors.Is(err, ErrQueueWait) || !errors.Is(err, ErrOverloaded) {
+			t.Errorf("Derive() error = %v, want %v", err, ErrQueueWait)
+		}
+	})
+}
+
+func TestVerifyCanceled(t *testing.T) {
+	store := NewMemStore()
+	apikey, _, err := NewAdmin(store).Create(context.Background(), testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := NewCounters()
+	if _, err := NewStoreVerifier(store, WithMetrics(c)).Verify(ctx, apikey); !errors.Is(err, context.Canceled) {
+		t.Errorf("Verify() error = %v, want %v", err, context.Canceled)
+	}
+	if c.Stats().Derivations != 0 {
+		t.Errorf("derivation started after cancellation")
+	}
+}
@@ -0,0 +1,64 @@
+package apikeys
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultGroupSize is the number of characters in each group of GroupKey
+const DefaultGroupSize = 4
+
+// groupSep separates the groups of a displayed key
+const groupSep = '-'
+
+// GroupKey splits apikey into dash separated groups of size characters,
+// DefaultGroupSize if size is 0, for keys which are read out or typed by
+// hand, eg "Y2xp-ZW50-LTE6...". The last group may be short.
+//
+// The url safe base64 alphabet contains '-' itself, so only UngroupKey, with
+// the same size, can undo the grouping.
+func GroupKey(apikey string, size int) string {
+	if size <= 0 {
+		size = DefaultGroupSize
+	}
+	var b strings.Builder
+	b.Grow(len(apikey) + len(apikey)/size)
+	for i := 0; i < len(apikey); i += size {
+		if i > 0 {
+			b.WriteByte(groupSep)
+		}
+		b.WriteString(apikey[i:min(i+size, len(apikey))])
+	}
+	return b.String()
+}
+
+// UngroupKey reverses GroupKey for the same size. White space, eg from a
+// key typed across lines, is ignored. It is an ErrInvalid error if a
+// separator is missing where one is due, which catches most dropped or
+// doubled characters before the key is verified.
+func UngroupKey(display string, size int) (string, error) {
+	if size <= 0 {
+		size = DefaultGroupSize
+	}
+	display = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, display)
+	var b strings.Builder
+	b.Grow(len(display))
+	for i := 0; i < len(display); i += size + 1 {
+		if i > 0 {
+			if display[i-1] != groupSep {
+				return "", fmt.Errorf("%w: no group separator at %d", ErrInvalid, i-1)
+			}
+		}
+		b.WriteString(display[i:min(i+size, len(display))])
+	}
+	if strings.HasSuffix(display, string(groupSep)) && len(display)%(size+1) == 0 {
+		return "", fmt.Errorf("%w: trailing group separator", ErrInvalid)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,90 @@
+package apikeys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGroupKey(t *testing.T) {
+	type args struct {
+		apikey string
+		size   int
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"default", args{"abcdefghij", 0}, "abcd-efgh-ij"},
+		{"exact", args{"abcdefgh", 4}, "abcd-efgh"},
+		{"dashes in the key", args{"ab-d-ef_-", 3}, "ab--d-e-f_-"},
+		{"empty", args{"", 4}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupKey(tt.args.apikey, tt.args.size)
+			if got != tt.want {
+				t.Errorf("GroupKey() = %q, want %q", got, tt.want)
+			}
+			back, err := UngroupKey(got, tt.args.size)
+			if err != nil || back != tt.args.apikey {
+				t.Errorf("UngroupKey(%q) = %q, %v, want %q", got, back, err, tt.args.apikey)
+			}
+		})
+	}
+}
+
+func TestUngroupKey(t *testing.T) {
+	type args struct {
+		display string
+		size    int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{"typed across lines", args{" abcd-efgh-\n ij\n", 4}, "abcdefghij", false},
+		{"dropped character", args{"abc-efgh-ij", 4}, "", true},
+		{"doubled character", args{"abcdd-efgh-ij", 4}, "", true},
+		{"no separators", args{"abcdefghij", 4}, "", true},
+		{"trailing separator", args{"abcd-efgh-", 4}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UngroupKey(tt.args.display, tt.args.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UngroupKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalid) {
+				t.Errorf("UngroupKey() error = %v, want ErrInvalid", err)
+			}
+			if got != tt.want {
+				t.Errorf("UngroupKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupKeyRoundTrip(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemStore()
+	// enough keys that some contain '-' at or near a group boundary
+	admin := NewAdmin(store)
+	for range 8 {
+		apikey, _, err := admin.Create(ctx, testAlg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		display := GroupKey(apikey, 5)
+		if strings.Count(display, "-") < len(apikey)/5 {
+			t.Fatalf("GroupKey() = %q has too few groups", display)
+		}
+		back, err := UngroupKey(display, 5)
+		if err != nil || back != apikey {
+			t.Fatalf("UngroupKey(%q) = %q, %v, want %q", display, back, err, apikey)
+		}
+	}
+}
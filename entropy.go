@@ -0,0 +1,169 @@
+package apikeys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// entropyBlock is the size of the blocks an EntropyMonitor compares
+	entropyBlock = 16
+	// entropySelfTestLen is the number of bytes SelfTestEntropy reads
+	entropySelfTestLen = 1024
+)
+
+// ErrEntropy is returned when the randomness source fails a health check.
+// Nothing is generated from a failed source.
+var ErrEntropy = errors.New("entropy source failed")
+
+// EntropyConfig tunes an EntropyMonitor
+type EntropyConfig struct {
+	// Timeout fails a read which takes longer, as a source that blocks is
+	// starved of entropy. Zero never times out. A timed out read is left
+	// running in the background.
+	Timeout time.Duration
+}
+
+// EntropyMonitor is a randomness source which checks every read of the
+// source it wraps. There are no known answers to test a random source
+// against, so it checks for the failures which can be seen: short reads,
+// reads that block for longer than the Timeout, blocks of 16 bytes repeating
+// the block before, and blocks of a single repeated byte. The first failure
+// is permanent, every later read returns the same error. Use it with WithRand
+// or WithEntropy. It is safe for concurrent use.
+type EntropyMonitor struct {
+	r   io.Reader
+	cfg EntropyConfig
+
+	mu sync.Mutex
+	// last is the hash of the previous block, so the monitor doesn't hold a
+	// copy of generated secrets
+	last   [sha256.Size]byte
+	primed bool
+	err    error
+}
+
+var _ io.Reader = (*EntropyMonitor)(nil)
+
+// NewEntropyMonitor checks r, crypto/rand.Reader if nil
+func NewEntropyMonitor(r io.Reader, cfg EntropyConfig) *EntropyMonitor {
+	if r == nil {
+		r = rand.Reader
+	}
+	return &EntropyMonitor{r: r, cfg: cfg}
+}
+
+// Read fills p entirely from the source or fails with ErrEntropy
+func (m *EntropyMonitor) Read(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return 0, m.err
+	}
+	if err := m.fill(p); err != nil {
+		clear(p)
+		m.err = fmt.Errorf("%w: %v", ErrEntropy, err)
+		return 0, m.err
+	}
+	return len(p), nil
+}
+
+// Err returns the failure of the source, nil while it is healthy
+func (m *EntropyMonitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+func (m *EntropyMonitor) fill(p []byte) error {
+	if err := m.read(p); err != nil {
+		return err
+	}
+	for block := range slices.Chunk(p, entropyBlock) {
+		if len(block) < entropyBlock {
+			break
+		}
+		if bytes.Count(block, block[:1]) == len(block) {
+			return fmt.Errorf("stuck at %#02x", block[0])
+		}
+		sum := sha256.Sum256(block)
+		if m.primed && sum == m.last {
+			return errors.New("repeated output")
+		}
+		m.last, m.primed = sum, true
+	}
+	return nil
+}
+
+func (m *EntropyMonitor) read(p []byte) error {
+	if m.cfg.Timeout <= 0 {
+		return readFull(m.r, p)
+	}
+	// Read into a buffer of our own, so a read which times out doesn't
+	// write to p after we return
+	buf := make([]byte, len(p))
+	done := make(chan error, 1)
+	go func() {
+		done <- readFull(m.r, buf)
+	}()
+	t := time.NewTimer(m.cfg.Timeout)
+	defer t.Stop()
+	select {
+	case err := <-done:
+		copy(p, buf)
+		clear(buf)
+		return err
+	case <-t.C:
+		return fmt.Errorf("read blocked for more than %s", m.cfg.Timeout)
+	}
+}
+
+func readFull(r io.Reader, p []byte) error {
+	n, err := io.ReadFull(r, p)
+	if err != nil {
+		return fmt.Errorf("short read of %d bytes, wanted %d: %v", n, len(p), err)
+	}
+	return nil
+}
+
+// SelfTestEntropy reads from r, crypto/rand.Reader if nil, through an
+// EntropyMonitor and returns its error, if any. Call it at startup, before
+// generating keys, to refuse to run on a broken source.
+func SelfTestEntropy(r io.Reader, cfg EntropyConfig) error {
+	m := NewEntropyMonitor(r, cfg)
+	buf := make([]byte, entropySelfTestLen)
+	defer clear(buf)
+	// Two reads, so that a source which restarts its output on every read is
+	// caught too
+	for range 2 {
+		if _, err := m.Read(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithEntropy sets the randomness source Admin generates keys from when they
+// don't have their own, from WithRand. Pass an EntropyMonitor to check the
+// source continuously: once it fails Create and Rotate return ErrEntropy
+// rather than generating weak keys. A failure is logged as an error.
+func WithEntropy(r io.Reader) Option {
+	return func(o *options) {
+		o.entropy = r
+	}
+}
+
+// entropySource is the reader for salts the verifier generates
+func (o *options) entropySource() io.Reader {
+	if o.entropy != nil {
+		return o.entropy
+	}
+	return rand.Reader
+}
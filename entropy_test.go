@@ -0,0 +1,89 @@
+package apikeys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingReader never returns
+type blockingReader chan struct{}
+
+func (r blockingReader) Read(p []byte) (int, error) {
+	<-r
+	return 0, io.EOF
+}
+
+func TestSelfTestEntropy(t *testing.T) {
+	blocked := make(blockingReader)
+	t.Cleanup(func() { close(blocked) })
+	counter := make([]byte, 4*entropySelfTestLen)
+	for i := range counter {
+		counter[i] = byte(i)
+	}
+	type args struct {
+		r   io.Reader
+		cfg EntropyConfig
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr string
+	}{
+		{"crypto/rand", args{nil, EntropyConfig{Timeout: time.Second}}, ""},
+		{"short", args{bytes.NewReader(counter[:entropySelfTestLen+1]), EntropyConfig{}}, "short read"},
+		{"stuck", args{bytes.NewReader(make([]byte, 2*entropySelfTestLen)), EntropyConfig{}}, "stuck"},
+		// Every 256 bytes repeat, but no block repeats the one before it
+		{"periodic", args{bytes.NewReader(counter), EntropyConfig{}}, ""},
+		{"repeated", args{io.MultiReader(bytes.NewReader(counter[:entropyBlock]), bytes.NewReader(counter)), EntropyConfig{}}, "repeated"},
+		{"blocked", args{blocked, EntropyConfig{Timeout: 10 * time.Millisecond}}, "blocked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SelfTestEntropy(tt.args.r, tt.args.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("SelfTestEntropy() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrEntropy) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SelfTestEntropy() error = %v, want ErrEntropy for %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEntropyMonitor(t *testing.T) {
+	ctx := t.Context()
+	// Healthy for the first key, then stuck
+	source := io.MultiReader(io.LimitReader(rand.Reader, saltLen+passwordLen), bytes.NewReader(make([]byte, 1024)))
+	m := NewEntropyMonitor(source, EntropyConfig{})
+	var log bytes.Buffer
+	admin := NewAdmin(NewMemStore(), WithEntropy(m), WithLogger(slog.New(slog.NewTextHandler(&log, nil))))
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("client-1")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := admin.Rotate(ctx, "client-1", ""); !errors.Is(err, ErrEntropy) {
+		t.Errorf("Rotate() from a stuck source error = %v, want ErrEntropy", err)
+	}
+	// The failure is permanent, even once the source looks healthy again
+	if _, err := m.Read(make([]byte, 32)); !errors.Is(err, ErrEntropy) || m.Err() == nil {
+		t.Errorf("Read() after a failure error = %v, want ErrEntropy", err)
+	}
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("client-2")); !errors.Is(err, ErrEntropy) {
+		t.Errorf("Create() after a failure error = %v, want ErrEntropy", err)
+	}
+	if !strings.Contains(log.String(), "level=ERROR") {
+		t.Errorf("entropy failure not logged as an error: %s", log.String())
+	}
+	// A key's own source takes precedence
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("client-3"), WithRand(rand.Reader)); err != nil {
+		t.Errorf("Create() WithRand error = %v", err)
+	}
+}
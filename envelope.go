@@ -0,0 +1,25 @@
+package apikeys
+
+import nanoid "github.com/matoous/go-nanoid"
+
+// AuditSchema identifies the version of the AuditEnvelope json published to
+// message brokers. A new version is only introduced when a field is removed
+// or changes meaning; added fields keep the version.
+const AuditSchema = "apikeys.audit.v1"
+
+// AuditEnvelope wraps an AuditEvent for publishing. The ID is unique per
+// event, so consumers of an at least once broker can drop redeliveries.
+type AuditEnvelope struct {
+	Schema string     `json:"schema"`
+	ID     string     `json:"id"`
+	Event  AuditEvent `json:"event"`
+}
+
+// NewAuditEnvelope wraps ev with a new ID
+func NewAuditEnvelope(ev AuditEvent) (AuditEnvelope, error) {
+	id, err := nanoid.ID(defaultClientNanoIDLen)
+	if err != nil {
+		return AuditEnvelope{}, err
+	}
+	return AuditEnvelope{Schema: AuditSchema, ID: id, Event: ev}, nil
+}
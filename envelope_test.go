@@ -0,0 +1,33 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAuditEnvelope(t *testing.T) {
+	ev := AuditEvent{Time: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), Type: AuditKeyRevoked, ClientID: "client-1"}
+	a, err := NewAuditEnvelope(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewAuditEnvelope(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Schema != AuditSchema || a.ID == "" || a.ID == b.ID {
+		t.Errorf("envelopes %+v and %+v, want schema %s and distinct ids", a, b, AuditSchema)
+	}
+	raw, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["schema"] != AuditSchema || got["event"].(map[string]any)["type"] != AuditKeyRevoked {
+		t.Errorf("envelope json = %s", raw)
+	}
+}
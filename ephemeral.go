@@ -0,0 +1,162 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TTLStore holds short lived keys in memory only, eg for CI jobs or support
+// sessions. Every record must expire, within maxTTL if that is positive, and
+// expired records are never returned, so nothing outlives its key or the
+// process. Call Sweep periodically to reclaim their memory.
+type TTLStore struct {
+	*MemStore
+	maxTTL time.Duration
+	clock  Clock
+}
+
+// NewTTLStore returns an empty TTLStore. Of opts, only WithClock applies: it
+// decides which records have expired and bounds maxTTL, and should be the
+// clock of the Admin setting the expiry.
+func NewTTLStore(maxTTL time.Duration, opts ...Option) *TTLStore {
+	o := newOptions(opts)
+	return &TTLStore{MemStore: NewMemStore(), maxTTL: maxTTL, clock: o.clock}
+}
+
+func (s *TTLStore) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+func (s *TTLStore) check(ak Key) error {
+	if ak.ExpiresAt.IsZero() {
+		return fmt.Errorf("ttl store record `%s' must expire", ak.ClientID)
+	}
+	if s.maxTTL > 0 && ak.ExpiresAt.After(s.now().Add(s.maxTTL)) {
+		return fmt.Errorf("ttl store record `%s' must expire within %s", ak.ClientID, s.maxTTL)
+	}
+	return nil
+}
+
+func (s *TTLStore) Create(ctx context.Context, ak Key) error {
+	if err := s.check(ak); err != nil {
+		return err
+	}
+	return s.MemStore.Create(ctx, ak)
+}
+
+func (s *TTLStore) Get(ctx context.Context, clientID string) (Key, error) {
+	ak, err := s.MemStore.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	if ak.Expired(s.now()) {
+		return Key{}, ErrNotFound
+	}
+	return ak, nil
+}
+
+// GetByName implements NameLookup
+func (s *TTLStore) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	ak, err := s.MemStore.GetByName(ctx, tenantID, name)
+	if err != nil {
+		return Key{}, err
+	}
+	if ak.Expired(s.now()) {
+		return Key{}, ErrNotFound
+	}
+	return ak, nil
+}
+
+// GetByFingerprint implements FingerprintLookup
+func (s *TTLStore) GetByFingerprint(ctx context.Context, fingerprint string) (Key, error) {
+	ak, err := s.MemStore.GetByFingerprint(ctx, fingerprint)
+	if err != nil {
+		return Key{}, err
+	}
+	if ak.Expired(s.now()) {
+		return Key{}, ErrNotFound
+	}
+	return ak, nil
+}
+
+// Transfer implements Transferer
+func (s *TTLStore) Transfer(ctx context.Context, fromClientID string, ak Key) error {
+	if _, err := s.Get(ctx, fromClientID); err != nil {
+		return err
+	}
+	if err := s.check(ak); err != nil {
+		return err
+	}
+	return s.MemStore.Transfer(ctx, fromClientID, ak)
+}
+
+func (s *TTLStore) Update(ctx context.Context, ak Key) error {
+	if _, err := s.Get(ctx, ak.ClientID); err != nil {
+		return err
+	}
+	if err := s.check(ak); err != nil {
+		return err
+	}
+	return s.MemStore.Update(ctx, ak)
+}
+
+func (s *TTLStore) List(ctx context.Context) ([]Key, error) {
+	keys, err := s.MemStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.live(keys), nil
+}
+
+// ListTenant implements TenantLister
+func (s *TTLStore) ListTenant(ctx context.Context, tenantID string) ([]Key, error) {
+	keys, err := s.MemStore.ListTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return s.live(keys), nil
+}
+
+// live filters out the expired keys, in place
+func (s *TTLStore) live(keys []Key) []Key {
+	now := s.now()
+	live := keys[:0]
+	for _, ak := range keys {
+		if !ak.Expired(now) {
+			live = append(live, ak)
+		}
+	}
+	return live
+}
+
+// Sweep deletes the records expired at now and returns how many there were
+func (s *TTLStore) Sweep(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for clientID, ak := range s.keys {
+		if ak.Expired(now) {
+			ak.Wipe()
+			delete(s.keys, clientID)
+			n++
+		}
+	}
+	return n
+}
+
+// CreateEphemeral creates a key of type KeyTypeEphemeral which expires after
+// ttl, bounded by the MaxTTL of the ephemeral KeyTypePolicy. It is meant for
+// an Admin over a TTLStore, so the key is never written to long term storage.
+// The principal on ctx is recorded as the creator, which ties a support
+// session key to the operator who minted it.
+func (a *Admin) CreateEphemeral(ctx context.Context, alg string, ttl time.Duration, opts ...KeyOption) (string, Key, error) {
+	if ttl <= 0 {
+		return "", Key{}, fmt.Errorf("bad ephemeral ttl %s", ttl)
+	}
+	opts = append(opts, WithKeyType(KeyTypeEphemeral), WithExpiresAt(a.now().Add(ttl)))
+	return a.Create(ctx, alg, opts...)
+}
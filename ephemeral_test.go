@@ -0,0 +1,92 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCreateEphemeral(t *testing.T) {
+	ctx := WithPrincipal(context.Background(), "support@example.com")
+	store := NewTTLStore(24 * time.Hour)
+	admin := NewAdmin(store)
+	verifier := NewStoreVerifier(store)
+
+	apikey, ak, err := admin.CreateEphemeral(ctx, testAlg, 30*time.Minute, WithOwner("customer-42"))
+	if err != nil {
+		t.Fatalf("CreateEphemeral() error = %v", err)
+	}
+	if ak.Type != KeyTypeEphemeral || ak.CreatedBy != "support@example.com" || ak.Owner != "customer-42" {
+		t.Errorf("CreateEphemeral() = %+v, want an ephemeral key created by support for customer-42", ak)
+	}
+	if d := time.Until(ak.ExpiresAt); d <= 29*time.Minute || d > 30*time.Minute {
+		t.Errorf("CreateEphemeral() expires in %s, want 30m", d)
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	for _, ttl := range []time.Duration{0, 48 * time.Hour} {
+		if _, _, err := admin.CreateEphemeral(ctx, testAlg, ttl); err == nil {
+			t.Errorf("CreateEphemeral(%s) succeeded", ttl)
+		}
+	}
+}
+
+func TestTTLStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	clock := now
+	store := NewTTLStore(time.Hour, WithClock(ClockFunc(func() time.Time { return clock })))
+
+	type args struct {
+		ak Key
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"expiring", args{Key{ClientID: "soon", ExpiresAt: now.Add(time.Minute)}}, false},
+		{"later", args{Key{ClientID: "later", ExpiresAt: now.Add(50 * time.Minute)}}, false},
+		{"no expiry", args{Key{ClientID: "forever"}}, true},
+		{"beyond max ttl", args{Key{ClientID: "too-long", ExpiresAt: now.Add(2 * time.Hour)}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Create(ctx, tt.args.ak); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if n := store.Sweep(now.Add(10 * time.Minute)); n != 1 {
+		t.Errorf("Sweep() = %d, want 1", n)
+	}
+	if _, err := store.Get(ctx, "soon"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() swept record error = %v, want ErrNotFound", err)
+	}
+	if keys, _ := store.List(ctx); len(keys) != 1 || keys[0].ClientID != "later" {
+		t.Errorf("List() = %v, want later", keys)
+	}
+
+	// Expired but not yet swept records are not returned either
+	later, err := store.MemStore.Get(ctx, "later")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock = now.Add(55 * time.Minute)
+	if _, err := store.Get(ctx, "later"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() expired record error = %v, want ErrNotFound", err)
+	}
+	if keys, _ := store.List(ctx); len(keys) != 0 {
+		t.Errorf("List() = %v, want none", keys)
+	}
+	if keys, _ := store.ListTenant(ctx, ""); len(keys) != 0 {
+		t.Errorf("ListTenant() = %v, want none", keys)
+	}
+	if _, err := store.GetByFingerprint(ctx, later.Fingerprint()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByFingerprint() expired record error = %v, want ErrNotFound", err)
+	}
+}
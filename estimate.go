@@ -0,0 +1,85 @@
+package apikeys
+
+import (
+	"cmp"
+	"context"
+	"runtime"
+	"time"
+)
+
+// estimateRuns is the number of derivations Estimate measures
+const estimateRuns = 3
+
+// Estimate is the measured cost of an Alg on the current host
+type Estimate struct {
+	Runs int
+	Min  time.Duration
+	Mean time.Duration
+	Max  time.Duration
+	// Memory is the largest number of bytes allocated by a single derivation.
+	// It is measured from the runtime allocation counters, so concurrent
+	// activity in the process inflates it.
+	Memory uint64
+}
+
+// Estimate runs a few derivations with the parameters of a and reports how
+// long they took and how much memory they needed, so deployment tooling can
+// check configured parameters against latency and memory limits. It returns
+// ctx.Err() if ctx is done before all runs complete.
+func (a Alg) Estimate(ctx context.Context) (Estimate, error) {
+	password := make([]byte, passwordLen)
+	salt := make([]byte, saltLen)
+	var est Estimate
+	var total time.Duration
+	var before, after runtime.MemStats
+	for i := 0; i < estimateRuns; i++ {
+		if err := ctx.Err(); err != nil {
+			return Estimate{}, err
+		}
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		a.derive(password, salt)
+		d := time.Since(start)
+		runtime.ReadMemStats(&after)
+
+		if mem := after.TotalAlloc - before.TotalAlloc; mem > est.Memory {
+			est.Memory = mem
+		}
+		if est.Runs == 0 || d < est.Min {
+			est.Min = d
+		}
+		if d > est.Max {
+			est.Max = d
+		}
+		total += d
+		est.Runs++
+	}
+	est.Mean = total / time.Duration(est.Runs)
+	return est, nil
+}
+
+// Cost is the approximate work factor of a, for ordering algs by strength:
+// the number of KB argon2id passes over, time times memory. Threads and key
+// length don't change the work of a guess. Keyed algs cost 0, their strength
+// is the pepper, as do registered algs unless their Hasher has a Cost() uint64
+// method.
+func (a Alg) Cost() uint64 {
+	if a.hasher != nil {
+		if c, ok := a.hasher.(interface{ Cost() uint64 }); ok {
+			return c.Cost()
+		}
+		return 0
+	}
+	return uint64(a.Time) * uint64(a.Memory)
+}
+
+// Compare orders a and b by Cost, returning -1 if a is the cheaper to guess
+func (a Alg) Compare(b Alg) int {
+	return cmp.Compare(a.Cost(), b.Cost())
+}
+
+// WeakerThan reports whether a costs less than b, eg to re-hash keys which
+// are weaker than the current standard
+func (a Alg) WeakerThan(b Alg) bool {
+	return a.Compare(b) < 0
+}
@@ -0,0 +1,81 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAlgEstimate(t *testing.T) {
+	alg, err := ParseAlg(testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	est, err := alg.Estimate(context.Background())
+	if err != nil {
+		t.Fatalf("Estimate() error = %v", err)
+	}
+	if est.Runs != estimateRuns {
+		t.Errorf("Runs = %d, want %d", est.Runs, estimateRuns)
+	}
+	if est.Min <= 0 || est.Min > est.Mean || est.Mean > est.Max {
+		t.Errorf("durations not ordered min=%v mean=%v max=%v", est.Min, est.Mean, est.Max)
+	}
+	// argon2 allocates the whole memory parameter up front
+	if want := uint64(alg.Memory) * 1024; est.Memory < want {
+		t.Errorf("Memory = %d, want at least %d", est.Memory, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := alg.Estimate(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Estimate() canceled error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAlgCost(t *testing.T) {
+	if err := RegisterPepper("cost-1", bytes.Repeat([]byte{9}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	type args struct {
+		a string
+		b string
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{"more passes", args{"argon2id 1 64MB 32", "argon2id 3 64MB 32"}, -1},
+		{"more memory", args{"argon2id 3 64MB 32", "argon2id 3 16MB 32"}, 1},
+		{"same work", args{"argon2id 2 32MB 32", "argon2id t=1,m=64MB,len=16,p=4"}, 0},
+		{"keyed", args{"blake2b key=cost-1,len=32", "argon2id 1 16MB 16"}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAlg(tt.args.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseAlg(tt.args.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Compare(b); got != tt.want {
+				t.Errorf("Compare() = %d, want %d", got, tt.want)
+			}
+			if got := a.WeakerThan(b); got != (tt.want < 0) {
+				t.Errorf("WeakerThan() = %v", got)
+			}
+		})
+	}
+	if got, want := (Alg{hasher: costHasher(7)}).Cost(), uint64(7); got != want {
+		t.Errorf("Cost() of a registered alg = %d, want %d", got, want)
+	}
+}
+
+type costHasher uint64
+
+func (h costHasher) Derive(password, salt []byte) []byte { return nil }
+func (h costHasher) Cost() uint64                        { return uint64(h) }
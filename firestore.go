@@ -0,0 +1,275 @@
+package apikeys
+
+import (
+	"fmt"
+	"time"
+)
+
+// Firestore document field names. They match the firestore tags on Key, plus
+// the alg, salt and secret length which the tags exclude.
+const (
+	firestoreAlg        = "alg"
+	firestoreSalt       = "salt"
+	firestoreSecretLen  = "secret_len"
+	firestoreDerivedKey = "derived_key"
+	firestoreClientID   = "client_id"
+	firestoreCreatedAt  = "created_at"
+	firestoreRotatedAt  = "rotated_at"
+	firestoreLastUsedAt = "last_used_at"
+	firestoreRevokedAt  = "revoked_at"
+	firestoreExpiresAt  = "expires_at"
+
+	firestorePreviousDerivedKey = "previous_derived_key"
+	firestorePreviousExpiresAt  = "previous_expires_at"
+
+	firestoreImportedHash = "imported_hash"
+	firestoreTenantID     = "tenant_id"
+
+	firestoreName        = "name"
+	firestoreDescription = "description"
+	firestoreOwner       = "owner"
+	firestoreLabels      = "labels"
+	firestoreType        = "type"
+
+	firestoreCreatedBy = "created_by"
+	firestoreTeam      = "team"
+
+	firestoreApproval   = "approval"
+	firestoreReviewedBy = "reviewed_by"
+
+	firestoreIdleTimeout = "idle_timeout_seconds"
+
+	firestoreRestrictions   = "restrictions"
+	firestoreAllowedCIDRs   = "allowed_cidrs"
+	firestoreAllowedOrigins = "allowed_origins"
+	firestoreAllowedRoutes  = "allowed_routes"
+	firestoreQuota          = "quota"
+	firestoreQuotaLimit     = "limit"
+	firestoreQuotaWindow    = "window_seconds"
+)
+
+// FirestoreData returns the document fields for ak, including the alg and
+// salt, so a record read back with KeyFromFirestore can verify. Pass it to
+// DocumentRef.Set or Create in place of the Key:
+//
+//	_, err := doc.Set(ctx, ak.FirestoreData())
+func (ak Key) FirestoreData() map[string]any {
+	return map[string]any{
+		firestoreAlg:        ak.alg.String,
+		firestoreSalt:       []byte(ak.Salt),
+		firestoreSecretLen:  int64(ak.secretLen),
+		firestoreDerivedKey: []byte(ak.DerivedKey),
+		firestoreClientID:   ak.ClientID,
+		firestoreCreatedAt:  ak.CreatedAt,
+		firestoreRotatedAt:  ak.RotatedAt,
+		firestoreRevokedAt:  ak.RevokedAt,
+		firestoreExpiresAt:  ak.ExpiresAt,
+		firestoreLastUsedAt: ak.LastUsedAt,
+
+		firestorePreviousDerivedKey: []byte(ak.PreviousDerivedKey),
+		firestorePreviousExpiresAt:  ak.PreviousExpiresAt,
+
+		firestoreIdleTimeout: ak.IdleTimeoutSeconds,
+
+		firestoreImportedHash: ak.ImportedHash,
+		firestoreTenantID:     ak.TenantID,
+
+		firestoreName:        ak.Name,
+		firestoreDescription: ak.Description,
+		firestoreOwner:       ak.Owner,
+		firestoreLabels:      ak.Labels,
+		firestoreType:        string(ak.Type),
+
+		firestoreCreatedBy: ak.CreatedBy,
+		firestoreTeam:      ak.Team,
+
+		firestoreApproval:   string(ak.Approval),
+		firestoreReviewedBy: ak.ReviewedBy,
+
+		firestoreRestrictions: map[string]any{
+			firestoreAllowedCIDRs:   ak.Restrictions.AllowedCIDRs,
+			firestoreAllowedOrigins: ak.Restrictions.AllowedOrigins,
+			firestoreAllowedRoutes:  ak.Restrictions.AllowedRoutes,
+			firestoreQuota: map[string]any{
+				firestoreQuotaLimit:  ak.Restrictions.Quota.Limit,
+				firestoreQuotaWindow: ak.Restrictions.Quota.WindowSeconds,
+			},
+		},
+	}
+}
+
+// KeyFromFirestore restores a Key from document fields written by
+// FirestoreData, as returned by DocumentSnapshot.Data. Documents written from
+// the Key struct tags have no alg or salt; they load, but can not verify.
+func KeyFromFirestore(data map[string]any) (Key, error) {
+	var ak Key
+	var err error
+	var alg string
+	if alg, err = firestoreField[string](data, firestoreAlg); err != nil {
+		return Key{}, err
+	}
+	if alg != "" {
+		if err = ak.SetAlg(alg); err != nil {
+			return Key{}, err
+		}
+	}
+	if ak.Salt, err = firestoreField[[]byte](data, firestoreSalt); err != nil {
+		return Key{}, err
+	}
+	secretLen, err := firestoreField[int64](data, firestoreSecretLen)
+	if err != nil {
+		return Key{}, err
+	}
+	ak.secretLen = int(secretLen)
+	if ak.DerivedKey, err = firestoreField[[]byte](data, firestoreDerivedKey); err != nil {
+		return Key{}, err
+	}
+	if ak.ClientID, err = firestoreField[string](data, firestoreClientID); err != nil {
+		return Key{}, err
+	}
+	if ak.CreatedAt, err = firestoreField[time.Time](data, firestoreCreatedAt); err != nil {
+		return Key{}, err
+	}
+	if ak.RotatedAt, err = firestoreField[time.Time](data, firestoreRotatedAt); err != nil {
+		return Key{}, err
+	}
+	if ak.RevokedAt, err = firestoreField[time.Time](data, firestoreRevokedAt); err != nil {
+		return Key{}, err
+	}
+	if ak.ExpiresAt, err = firestoreField[time.Time](data, firestoreExpiresAt); err != nil {
+		return Key{}, err
+	}
+	if ak.LastUsedAt, err = firestoreField[time.Time](data, firestoreLastUsedAt); err != nil {
+		return Key{}, err
+	}
+	if ak.IdleTimeoutSeconds, err = firestoreField[int64](data, firestoreIdleTimeout); err != nil {
+		return Key{}, err
+	}
+	if ak.PreviousDerivedKey, err = firestoreField[[]byte](data, firestorePreviousDerivedKey); err != nil {
+		return Key{}, err
+	}
+	if ak.PreviousExpiresAt, err = firestoreField[time.Time](data, firestorePreviousExpiresAt); err != nil {
+		return Key{}, err
+	}
+	if ak.ImportedHash, err = firestoreField[string](data, firestoreImportedHash); err != nil {
+		return Key{}, err
+	}
+	if ak.TenantID, err = firestoreField[string](data, firestoreTenantID); err != nil {
+		return Key{}, err
+	}
+	for name, p := range map[string]*string{
+		firestoreName: &ak.Name, firestoreDescription: &ak.Description, firestoreOwner: &ak.Owner,
+		firestoreCreatedBy: &ak.CreatedBy, firestoreTeam: &ak.Team, firestoreReviewedBy: &ak.ReviewedBy,
+	} {
+		if *p, err = firestoreField[string](data, name); err != nil {
+			return Key{}, err
+		}
+	}
+	if ak.Labels, err = firestoreLabelMap(data); err != nil {
+		return Key{}, err
+	}
+	typ, err := firestoreField[string](data, firestoreType)
+	if err != nil {
+		return Key{}, err
+	}
+	ak.Type = KeyType(typ)
+	approval, err := firestoreField[string](data, firestoreApproval)
+	if err != nil {
+		return Key{}, err
+	}
+	ak.Approval = ApprovalState(approval)
+	if ak.Restrictions, err = firestoreRestrictionMap(data); err != nil {
+		return Key{}, err
+	}
+	return ak, nil
+}
+
+// firestoreLabelMap returns the labels field. Written, it is a
+// map[string]string, but the client reads maps back as map[string]any.
+func firestoreLabelMap(data map[string]any) (map[string]string, error) {
+	switch v := data[firestoreLabels].(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		return v, nil
+	case map[string]any:
+		labels := make(map[string]string, len(v))
+		for k, lv := range v {
+			s, ok := lv.(string)
+			if !ok {
+				return nil, fmt.Errorf("firestore label `%s' has type %T, want string", k, lv)
+			}
+			labels[k] = s
+		}
+		return labels, nil
+	default:
+		return nil, fmt.Errorf("firestore field `%s' has type %T, want a map", firestoreLabels, v)
+	}
+}
+
+// firestoreRestrictionMap returns the restrictions field
+func firestoreRestrictionMap(data map[string]any) (Restrictions, error) {
+	var r Restrictions
+	m, err := firestoreField[map[string]any](data, firestoreRestrictions)
+	if err != nil || m == nil {
+		return r, err
+	}
+	if r.AllowedCIDRs, err = firestoreStrings(m, firestoreAllowedCIDRs); err != nil {
+		return r, err
+	}
+	if r.AllowedOrigins, err = firestoreStrings(m, firestoreAllowedOrigins); err != nil {
+		return r, err
+	}
+	if r.AllowedRoutes, err = firestoreStrings(m, firestoreAllowedRoutes); err != nil {
+		return r, err
+	}
+	q, err := firestoreField[map[string]any](m, firestoreQuota)
+	if err != nil || q == nil {
+		return r, err
+	}
+	if r.Quota.Limit, err = firestoreField[int64](q, firestoreQuotaLimit); err != nil {
+		return r, err
+	}
+	if r.Quota.WindowSeconds, err = firestoreField[int64](q, firestoreQuotaWindow); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// firestoreStrings returns a string array field. Written, it is a []string,
+// but the client reads arrays back as []any.
+func firestoreStrings(data map[string]any, name string) ([]string, error) {
+	switch v := data[name].(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return v, nil
+	case []any:
+		s := make([]string, 0, len(v))
+		for _, e := range v {
+			str, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("firestore field `%s' has a %T element, want string", name, e)
+			}
+			s = append(s, str)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("firestore field `%s' has type %T, want an array", name, v)
+	}
+}
+
+// firestoreField returns the named field, or the zero value if it is absent
+// or null
+func firestoreField[T any](data map[string]any, name string) (T, error) {
+	var zero T
+	v, ok := data[name]
+	if !ok || v == nil {
+		return zero, nil
+	}
+	t, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("firestore field `%s' has type %T, want %T", name, v, zero)
+	}
+	return t, nil
+}
@@ -0,0 +1,56 @@
+package apikeys
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFirestoreRoundTrip(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"), WithTenant("acme"), WithName("ci"), WithLabels(map[string]string{"env": "prod"}), WithKeyType(KeyTypeService), WithTeam("payments"), WithCreatedBy("alice"), WithAllowedCIDRs("203.0.113.0/24"), WithAllowedOrigins("https://*.example.com"), WithAllowedRoutes("GET /v1/reports/*"), WithQuota(100, time.Hour), WithIdleTimeout(30*24*time.Hour), WithSecretLength(24), WithExpiresAt(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ak.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	ak.CreatedAt = time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC)
+	ak.LastUsedAt = time.Date(2029, 6, 1, 0, 0, 0, 0, time.UTC)
+	ak.Approval, ak.ReviewedBy = ApprovalApproved, "bob"
+	ak.PreviousDerivedKey, ak.PreviousExpiresAt = Blob("previous-derived-key"), time.Date(2029, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	got, err := KeyFromFirestore(ak.FirestoreData())
+	if err != nil {
+		t.Fatalf("KeyFromFirestore() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, ak) {
+		t.Errorf("KeyFromFirestore() = %+v, want %+v", got, ak)
+	}
+}
+
+func TestKeyFromFirestoreErrors(t *testing.T) {
+	type args struct {
+		data map[string]any
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"tag written record", args{map[string]any{"client_id": "c", "derived_key": []byte{1}}}, false},
+		{"null fields", args{map[string]any{"client_id": "c", "salt": nil, "revoked_at": nil}}, false},
+		{"bad alg", args{map[string]any{"alg": "argon2id 0 1MB 1"}}, true},
+		{"wrong type", args{map[string]any{"salt": "not bytes"}}, true},
+		{"wrong time type", args{map[string]any{"created_at": int64(1)}}, true},
+		{"labels as read back", args{map[string]any{"labels": map[string]any{"env": "prod"}}}, false},
+		{"wrong label type", args{map[string]any{"labels": map[string]any{"env": 1}}}, true},
+		{"wrong labels type", args{map[string]any{"labels": "env=prod"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := KeyFromFirestore(tt.args.data); (err != nil) != tt.wantErr {
+				t.Errorf("KeyFromFirestore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// The store instrumentation of options.wrapStore forwards the optional store
+// interfaces to the store it wraps, so that an Admin or StoreVerifier with
+// metrics, tracing, a cache, a breaker or retries still uses them. A wrapper
+// always has the methods, so check for support with supports rather than a
+// type assertion; the methods fail with errors.ErrUnsupported if the wrapped
+// store lacks them.
+
+// storeWrapper is implemented by the instrumentation wrappers
+type storeWrapper interface {
+	unwrap() Store
+}
+
+// Unwrap returns the store underneath the instrumentation of MeasureStore,
+// RetryStore and the other wrappers, or store itself if it isn't wrapped.
+// Use it to check for optional interfaces, such as Pinger, which the
+// wrappers don't forward.
+func Unwrap(store Store) Store {
+	for {
+		w, ok := store.(storeWrapper)
+		if !ok {
+			return store
+		}
+		store = w.unwrap()
+	}
+}
+
+// supports returns store as a T if it is one and, once any instrumentation
+// is unwrapped, so is the store underneath
+func supports[T any](store Store) (T, bool) {
+	t, ok := store.(T)
+	if !ok {
+		return t, false
+	}
+	if _, ok := Unwrap(store).(T); !ok {
+		var zero T
+		return zero, false
+	}
+	return t, true
+}
+
+func (s *breakerStore) unwrap() Store     { return s.store }
+func (s *retryStore) unwrap() Store       { return s.store }
+func (s invalidatingStore) unwrap() Store { return s.Store }
+func (s *measuredStore) unwrap() Store    { return s.store }
+func (s *tracingStore) unwrap() Store     { return s.store }
+
+// Transfer implements Transferer if the wrapped store does
+func (s *breakerStore) Transfer(ctx context.Context, fromClientID string, ak Key) error {
+	tr, ok := s.store.(Transferer)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return s.breaker.Do(ctx, func(ctx context.Context) error {
+		return tr.Transfer(ctx, fromClientID, ak)
+	})
+}
+
+// GetByName implements NameLookup if the wrapped store does
+func (s *breakerStore) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	nl, ok := s.store.(NameLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	var ak Key
+	err := s.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		ak, err = nl.GetByName(ctx, tenantID, name)
+		return err
+	})
+	return ak, err
+}
+
+// Transfer implements Transferer if the wrapped store does
+func (s *retryStore) Transfer(ctx context.Context, fromClientID string, ak Key) error {
+	tr, ok := s.store.(Transferer)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return s.do(ctx, "Transfer", true, func() error {
+		return tr.Transfer(ctx, fromClientID, ak)
+	})
+}
+
+// GetByName implements NameLookup if the wrapped store does
+func (s *retryStore) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	nl, ok := s.store.(NameLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	var ak Key
+	err := s.do(ctx, "GetByName", false, func() error {
+		var err error
+		ak, err = nl.GetByName(ctx, tenantID, name)
+		return err
+	})
+	return ak, err
+}
+
+// Transfer implements Transferer if the wrapped store does, dropping the
+// cached verifications of both client ids
+func (s invalidatingStore) Transfer(ctx context.Context, fromClientID string, ak Key) error {
+	tr, ok := s.Store.(Transferer)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	err := tr.Transfer(ctx, fromClientID, ak)
+	s.invalidate(ctx, fromClientID)
+	s.invalidate(ctx, ak.ClientID)
+	return err
+}
+
+// GetByName implements NameLookup if the wrapped store does
+func (s invalidatingStore) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	nl, ok := s.Store.(NameLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	return nl.GetByName(ctx, tenantID, name)
+}
+
+// Transfer implements Transferer if the wrapped store does
+func (s *measuredStore) Transfer(ctx context.Context, fromClientID string, ak Key) error {
+	tr, ok := s.store.(Transferer)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	start := time.Now()
+	err := tr.Transfer(ctx, fromClientID, ak)
+	s.metrics.ObserveStore("Transfer", time.Since(start), err)
+	return err
+}
+
+// GetByName implements NameLookup if the wrapped store does
+func (s *measuredStore) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	nl, ok := s.store.(NameLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	start := time.Now()
+	ak, err := nl.GetByName(ctx, tenantID, name)
+	s.metrics.ObserveStore("GetByName", time.Since(start), err)
+	return ak, err
+}
+
+// Transfer implements Transferer if the wrapped store does
+func (s *tracingStore) Transfer(ctx context.Context, fromClientID string, ak Key) error {
+	tr, ok := s.store.(Transferer)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	ctx, span := s.tracer.Start(ctx, SpanStore+"Transfer")
+	span.SetAttribute(AttrClientID, ak.ClientID)
+	err := tr.Transfer(ctx, fromClientID, ak)
+	span.End(err)
+	return err
+}
+
+// GetByName implements NameLookup if the wrapped store does
+func (s *tracingStore) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	nl, ok := s.store.(NameLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	ctx, span := s.tracer.Start(ctx, SpanStore+"GetByName")
+	ak, err := nl.GetByName(ctx, tenantID, name)
+	span.End(err)
+	return ak, err
+}
+
+// GetByFingerprint implements FingerprintLookup if the wrapped store does
+func (s *breakerStore) GetByFingerprint(ctx context.Context, fingerprint string) (Key, error) {
+	fl, ok := s.store.(FingerprintLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	var ak Key
+	err := s.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		ak, err = fl.GetByFingerprint(ctx, fingerprint)
+		return err
+	})
+	return ak, err
+}
+
+// GetByFingerprint implements FingerprintLookup if the wrapped store does
+func (s *retryStore) GetByFingerprint(ctx context.Context, fingerprint string) (Key, error) {
+	fl, ok := s.store.(FingerprintLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	var ak Key
+	err := s.do(ctx, "GetByFingerprint", false, func() error {
+		var err error
+		ak, err = fl.GetByFingerprint(ctx, fingerprint)
+		return err
+	})
+	return ak, err
+}
+
+// GetByFingerprint implements FingerprintLookup if the wrapped store does
+func (s invalidatingStore) GetByFingerprint(ctx context.Context, fingerprint string) (Key, error) {
+	fl, ok := s.Store.(FingerprintLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	return fl.GetByFingerprint(ctx, fingerprint)
+}
+
+// GetByFingerprint implements FingerprintLookup if the wrapped store does
+func (s *measuredStore) GetByFingerprint(ctx context.Context, fingerprint string) (Key, error) {
+	fl, ok := s.store.(FingerprintLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	start := time.Now()
+	ak, err := fl.GetByFingerprint(ctx, fingerprint)
+	s.metrics.ObserveStore("GetByFingerprint", time.Since(start), err)
+	return ak, err
+}
+
+// GetByFingerprint implements FingerprintLookup if the wrapped store does
+func (s *tracingStore) GetByFingerprint(ctx context.Context, fingerprint string) (Key, error) {
+	fl, ok := s.store.(FingerprintLookup)
+	if !ok {
+		return Key{}, errors.ErrUnsupported
+	}
+	ctx, span := s.tracer.Start(ctx, SpanStore+"GetByFingerprint")
+	ak, err := fl.GetByFingerprint(ctx, fingerprint)
+	span.End(err)
+	return ak, err
+}
@@ -0,0 +1,81 @@
+package apikeys
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// countingStore counts the optional interface calls reaching the MemStore
+type countingStore struct {
+	*MemStore
+	transfers, names, fingerprints int
+}
+
+func (s *countingStore) GetByFingerprint(ctx context.Context, fingerprint string) (Key, error) {
+	s.fingerprints++
+	return s.MemStore.GetByFingerprint(ctx, fingerprint)
+}
+
+func (s *countingStore) Transfer(ctx context.Context, fromClientID string, ak Key) error {
+	s.transfers++
+	return s.MemStore.Transfer(ctx, fromClientID, ak)
+}
+
+func (s *countingStore) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	s.names++
+	return s.MemStore.GetByName(ctx, tenantID, name)
+}
+
+func TestForwardOptionalInterfaces(t *testing.T) {
+	store := &countingStore{MemStore: NewMemStore()}
+	admin := NewAdmin(store,
+		WithMetrics(NewCounters()),
+		WithVerifyCache(NewMemVerifyCache(), time.Minute),
+		WithCircuitBreaker(NewCircuitBreaker(BreakerConfig{})),
+		WithRetry(RetryConfig{}))
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"), WithName("ci")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.GetByName(t.Context(), "", "ci"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Transfer(t.Context(), "client-1", ToClientID("client-2"), WithReissue("")); err != nil {
+		t.Fatal(err)
+	}
+	if store.transfers != 1 {
+		t.Errorf("Transfer() reached the store %d times, want 1", store.transfers)
+	}
+	if store.names == 0 {
+		t.Error("GetByName() did not reach the store's NameLookup")
+	}
+	if _, err := GenerateUnique(t.Context(), admin.store, testAlg); err != nil {
+		t.Fatal(err)
+	}
+	if store.fingerprints != 1 {
+		t.Errorf("GenerateUnique() reached the store's FingerprintLookup %d times, want 1", store.fingerprints)
+	}
+}
+
+func TestSupports(t *testing.T) {
+	type args struct {
+		store Store
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"plain", args{NewMemStore()}, true},
+		{"wrapped", args{MeasureStore(RetryStore(NewMemStore(), RetryConfig{}), NewCounters())}, true},
+		// TenantStore hides MemStore.Transfer, so the wrappers can't forward it
+		{"wrapped without", args{MeasureStore(TenantStore(NewMemStore(), ""), NewCounters())}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := supports[Transferer](tt.args.store); got != tt.want {
+				t.Errorf("supports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// DefaultEnvName is the variable GeneratedKey.Dotenv assigns unless given
+// another
+const DefaultEnvName = "API_KEY"
+
+// GeneratedKey is a freshly generated api key with the pieces callers persist
+// and display, so they don't have to decode their own output
+type GeneratedKey struct {
+	// Encoded is the api key to deliver. It is the only copy of the secret.
+	Encoded     string
+	ClientID    string
+	Fingerprint string
+	Alg         Alg
+	CreatedAt   time.Time
+	// StoreRecord is the record for a Store. It carries no secret.
+	StoreRecord Key
+}
+
+func newGeneratedKey(apikey string, ak Key) GeneratedKey {
+	return GeneratedKey{
+		Encoded:     apikey,
+		ClientID:    ak.ClientID,
+		Fingerprint: ak.Fingerprint(),
+		Alg:         ak.alg,
+		CreatedAt:   ak.CreatedAt,
+		StoreRecord: ak,
+	}
+}
+
+// GenerateKey is Generate returning a GeneratedKey. The CreatedAt of the key
+// is set to now, in UTC, unless it is already set.
+func (ak *Key) GenerateKey() (GeneratedKey, error) {
+	apikey, err := ak.Generate()
+	if err != nil {
+		return GeneratedKey{}, err
+	}
+	if ak.CreatedAt.IsZero() {
+		ak.CreatedAt = time.Now().UTC()
+	}
+	return newGeneratedKey(apikey, *ak), nil
+}
+
+// CreateKey is Create returning a GeneratedKey, whose StoreRecord is the
+// record added to the store
+func (a *Admin) CreateKey(ctx context.Context, alg string, opts ...KeyOption) (GeneratedKey, error) {
+	apikey, ak, err := a.Create(ctx, alg, opts...)
+	if err != nil {
+		return GeneratedKey{}, err
+	}
+	return newGeneratedKey(apikey, ak), nil
+}
+
+// Output describes g in the machine readable output schema, see
+// GeneratedOutput
+func (g GeneratedKey) Output() Output {
+	return GeneratedOutput(g.Encoded, g.StoreRecord)
+}
+
+// JSON renders g for delivery, eg
+//
+//	{"client_id":"...","api_key":"...","fingerprint":"...","alg":"argon2id t=3,m=64MB,len=32","created_at":"..."}
+//
+// with the alg in its canonical form. Unlike Output it carries no record.
+func (g GeneratedKey) JSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ClientID    string    `json:"client_id"`
+		APIKey      string    `json:"api_key"`
+		Fingerprint string    `json:"fingerprint"`
+		Alg         Alg       `json:"alg"`
+		CreatedAt   time.Time `json:"created_at"`
+	}{g.ClientID, g.Encoded, g.Fingerprint, g.Alg, g.CreatedAt})
+}
+
+// Dotenv renders g as a .env line, name=<key>, DefaultEnvName if name is
+// empty. Encoded keys need no quoting.
+func (g GeneratedKey) Dotenv(name string) string {
+	if name == "" {
+		name = DefaultEnvName
+	}
+	return name + "=" + g.Encoded
+}
+
+// BasicAuth is the value of an Authorization header presenting g. The
+// encoded key is already base64 of client_id:secret, so it is the Basic
+// credential as it is.
+func (g GeneratedKey) BasicAuth() string {
+	return "Basic " + g.Encoded
+}
+
+// Curl renders a curl command requesting url with g, for onboarding docs
+func (g GeneratedKey) Curl(url string) string {
+	return "curl -H " + shellQuote("Authorization: "+g.BasicAuth()) + " " + shellQuote(url)
+}
+
+// shellQuote single quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,39 @@
+package apikeys
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+const hmacSHA256AlgID = "hmac-sha256 "
+
+// hmacSHA256Hasher derives with HMAC-SHA256 keyed with a pepper, truncated
+// to size bytes
+type hmacSHA256Hasher struct {
+	pepper Secret
+	size   int
+}
+
+func (h hmacSHA256Hasher) Derive(password, salt []byte) []byte {
+	m := hmac.New(sha256.New, h.pepper)
+	m.Write(binary.AppendUvarint(nil, uint64(len(salt))))
+	m.Write(salt)
+	m.Write(password)
+	return m.Sum(nil)[:h.size]
+}
+
+// parseHMACSHA256Alg parses an HMAC-SHA256 alg,
+//
+//	hmac-sha256 key=<pepper id>[,len=<keylen>]
+//
+// len defaults to the whole 32 byte mac. The pepper is mandatory: without it
+// a stolen store would let the secrets of keys be checked at the speed of
+// sha256.
+func parseHMACSHA256Alg(alg string) (Alg, error) {
+	p, pepper, err := parseKeyedAlg(hmacSHA256AlgID, alg)
+	if err != nil {
+		return Alg{}, err
+	}
+	return Alg{String: alg, hasher: hmacSHA256Hasher{pepper: pepper, size: int(p.keyLen)}}, nil
+}
@@ -0,0 +1,74 @@
+package apikeys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestHMACSHA256Alg(t *testing.T) {
+	ctx := t.Context()
+	t.Setenv("APIKEYS_TEST_PEPPER", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	cfg := Config{Alg: "hmac-sha256 key=fast-1", Pepper: "env:APIKEYS_TEST_PEPPER", Policy: Policy{Hashers: []string{"hmac-sha256"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.RegisterPepper(); err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemStore()
+	apikey, ak, err := NewAdmin(store).Create(ctx, cfg.Alg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ak.DerivedKey) != 32 {
+		t.Errorf("derived key is %d bytes, want 32", len(ak.DerivedKey))
+	}
+	verifier := NewStoreVerifier(store)
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	// Without the right pepper a stolen store is no use
+	if err := RegisterPepper("fast-1", bytes.Repeat([]byte{8}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, apikey); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() with another pepper = %v, want ErrMismatch", err)
+	}
+
+	type args struct {
+		alg string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantLen int
+		wantErr bool
+	}{
+		{"default len", args{"hmac-sha256 key=fast-1"}, 32, false},
+		{"truncated", args{"hmac-sha256 key=fast-1,len=16"}, 16, false},
+		{"len too large", args{"hmac-sha256 key=fast-1,len=33"}, 0, true},
+		{"no pepper", args{"hmac-sha256 key=nope"}, 0, true},
+		{"missing key", args{"hmac-sha256 len=32"}, 0, true},
+		{"bad pepper id", args{"hmac-sha256 key=a b"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseAlg(tt.args.alg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAlg() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(a.derive([]byte("password"), []byte("salt"))) != tt.wantLen {
+				t.Errorf("derived key length != %d", tt.wantLen)
+			}
+		})
+	}
+
+	if err := (Config{Alg: "hmac-sha256 key=fast-2"}).RegisterPepper(); !errors.Is(err, ErrConfig) {
+		t.Errorf("RegisterPepper() without a pepper = %v, want ErrConfig", err)
+	}
+	if err := (Config{}).RegisterPepper(); err != nil {
+		t.Errorf("RegisterPepper() for argon2id = %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package apikeys
+
+import (
+	"context"
+	"time"
+)
+
+// Hooks are called by Admin and StoreVerifier as keys move through their
+// lifecycle. Any of the functions may be nil. They are called synchronously,
+// after the store has been updated, and can not change the outcome of the
+// operation.
+type Hooks struct {
+	// OnCreate is called after a new key has been stored
+	OnCreate func(ctx context.Context, ak Key)
+	// OnVerifySuccess is called with the stored record after a successful
+	// verification
+	OnVerifySuccess func(ctx context.Context, ak Key)
+	// OnVerifyFailure is called with the presented key, which only has the
+	// fields that could be decoded, and the reason for the failure
+	OnVerifyFailure func(ctx context.Context, presented Key, err error)
+	// OnRevoke is called after a key has been revoked
+	OnRevoke func(ctx context.Context, ak Key)
+	// OnExpire is called when an expired key is presented for verification,
+	// and when a Sweeper finds a key has expired
+	OnExpire func(ctx context.Context, ak Key)
+	// OnExpiringSoon is called by a Sweeper when a key comes within lead of
+	// its expiry, one of the SweepConfig ExpiryWarnings
+	OnExpiringSoon func(ctx context.Context, ak Key, lead time.Duration)
+	// OnLeaked is called by a Sweeper when a live key is found in its
+	// SweepConfig.Leaks
+	OnLeaked func(ctx context.Context, ak Key)
+	// OnApprovalRequested is called after a key pending approval has been
+	// stored, eg to notify reviewers
+	OnApprovalRequested func(ctx context.Context, ak Key)
+	// OnApprove and OnReject are called after a pending key is reviewed
+	OnApprove func(ctx context.Context, ak Key)
+	OnReject  func(ctx context.Context, ak Key)
+}
+
+// WithHooks installs lifecycle hooks
+func WithHooks(h Hooks) Option {
+	return func(o *options) {
+		o.hooks = h
+	}
+}
@@ -0,0 +1,56 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	hooks := Hooks{
+		OnCreate:        func(ctx context.Context, ak Key) { calls = append(calls, "create:"+ak.ClientID) },
+		OnVerifySuccess: func(ctx context.Context, ak Key) { calls = append(calls, "success:"+ak.ClientID) },
+		OnVerifyFailure: func(ctx context.Context, ak Key, err error) {
+			calls = append(calls, "failure:"+ak.ClientID+":"+VerifyResult(err))
+		},
+		OnRevoke: func(ctx context.Context, ak Key) { calls = append(calls, "revoke:"+ak.ClientID) },
+		OnExpire: func(ctx context.Context, ak Key) { calls = append(calls, "expire:"+ak.ClientID) },
+	}
+	store := NewMemStore()
+	admin := NewAdmin(store, WithHooks(hooks))
+	verifier := NewStoreVerifier(store, WithHooks(hooks))
+
+	live, _, err := admin.Create(ctx, testAlg, WithClientID("live"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	expired, _, err := admin.Create(ctx, testAlg, WithClientID("expired"), WithExpiresAt(time.Now().Add(-time.Minute)))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	verifier.Verify(ctx, live)
+	if _, err := verifier.Verify(ctx, expired); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify() expired key error = %v, want %v", err, ErrExpired)
+	}
+	admin.Revoke(ctx, "live")
+	verifier.Verify(ctx, live)
+
+	want := []string{
+		"create:live", "create:expired",
+		"success:live",
+		"expire:expired", "failure:expired:expired",
+		"revoke:live",
+		"failure:live:revoked",
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("hook calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("hook call %d = %s, want %s", i, calls[i], want[i])
+		}
+	}
+}
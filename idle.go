@@ -0,0 +1,129 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Toucher is optionally implemented by a Store that can record when a key was
+// last used without rewriting the rest of the record. A full Update from the
+// verifier could undo a concurrent revoke.
+type Toucher interface {
+	// Touch sets the LastUsedAt of clientID to at, unless it is already
+	// later, returning ErrNotFound if there is no record.
+	Touch(ctx context.Context, clientID string, at time.Time) error
+}
+
+// WithIdleTimeout expires the key once it has gone unused for d, counting
+// from the latest of its creation, rotation and last use. The timeout is
+// rounded down to whole seconds and overrides the TenantPolicy IdleTimeout.
+func WithIdleTimeout(d time.Duration) KeyOption {
+	return func(ak *Key) {
+		ak.IdleTimeoutSeconds = int64(d / time.Second)
+	}
+}
+
+// LastActive is the latest of the key's creation, rotation and last use
+func (ak Key) LastActive() time.Time {
+	t := ak.CreatedAt
+	for _, u := range []time.Time{ak.RotatedAt, ak.LastUsedAt} {
+		if u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
+
+// IdleExpired is true if timeout is positive and the key has not been active
+// for at least timeout
+func (ak Key) IdleExpired(now time.Time, timeout time.Duration) bool {
+	last := ak.LastActive()
+	return timeout > 0 && !last.IsZero() && now.Sub(last) >= timeout
+}
+
+// WithLastUsed makes StoreVerifier record when each key is used, so idle
+// timeouts have something to measure. A use is written at most once per
+// granularity per key, to keep verification from becoming a write for every
+// request. Recording needs a Store that implements Toucher; other stores
+// only measure idleness from creation and rotation.
+func WithLastUsed(granularity time.Duration) Option {
+	return func(o *options) {
+		o.trackLastUsed = true
+		o.lastUsedGranularity = granularity
+	}
+}
+
+// idleTimeout returns the timeout of ak, or of its tenant if it has none
+func (o *options) idleTimeout(ctx context.Context, ak Key) (time.Duration, error) {
+	if ak.IdleTimeoutSeconds > 0 {
+		return time.Duration(ak.IdleTimeoutSeconds) * time.Second, nil
+	}
+	if o.tenantPolicy == nil {
+		return 0, nil
+	}
+	tp, err := o.tenantPolicy(ctx, ak.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return tp.IdleTimeout, nil
+}
+
+// checkIdle returns ErrExpired if ak has been idle for longer than its timeout
+func (o *options) checkIdle(ctx context.Context, ak Key) error {
+	timeout, err := o.idleTimeout(ctx, ak)
+	if err != nil {
+		return err
+	}
+	if ak.IdleExpired(o.now(), timeout) {
+		return fmt.Errorf("%w: unused since %s", ErrExpired, ak.LastActive().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// touch records the use of ak, returning it with LastUsedAt updated. Failing
+// to record a use does not fail the verification.
+func (v *StoreVerifier) touch(ctx context.Context, ak Key) Key {
+	if !v.trackLastUsed || v.toucher == nil {
+		return ak
+	}
+	now := v.now()
+	if !ak.LastUsedAt.IsZero() && now.Sub(ak.LastUsedAt) < v.lastUsedGranularity {
+		return ak
+	}
+	if err := v.toucher.Touch(ctx, ak.ClientID, now); err != nil {
+		v.warn(ctx, "recording last use failed", slog.String("client_id", ak.ClientID), slog.Any("error", err))
+		return ak
+	}
+	ak.LastUsedAt = now
+	return ak
+}
+
+// Touch implements Toucher
+func (s *MemStore) Touch(ctx context.Context, clientID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ak, ok := s.keys[clientID]
+	if !ok {
+		return ErrNotFound
+	}
+	if at.After(ak.LastUsedAt) {
+		ak.LastUsedAt = at
+		s.keys[clientID] = ak
+	}
+	return nil
+}
+
+// Touch implements Toucher if the underlying store does. Otherwise it does
+// nothing.
+func (s *tenantStore) Touch(ctx context.Context, clientID string, at time.Time) error {
+	t, ok := s.Store.(Toucher)
+	if !ok {
+		return nil
+	}
+	if _, err := s.Get(ctx, clientID); err != nil {
+		return err
+	}
+	return t.Touch(ctx, clientID, at)
+}
@@ -0,0 +1,118 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+	store := NewMemStore()
+	admin := NewAdmin(store, WithClock(clock))
+	verifier := NewStoreVerifier(store, WithClock(clock), WithLastUsed(time.Minute))
+
+	apikey, ak, err := admin.Create(ctx, testAlg, WithIdleTimeout(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ak.IdleTimeoutSeconds != 86400 {
+		t.Fatalf("IdleTimeoutSeconds = %d, want 86400", ak.IdleTimeoutSeconds)
+	}
+
+	// Each use pushes the idle expiry back
+	for range 3 {
+		now = now.Add(23 * time.Hour)
+		got, err := verifier.Verify(ctx, apikey)
+		if err != nil {
+			t.Fatalf("Verify() after 23h idle error = %v", err)
+		}
+		if !got.LastUsedAt.Equal(now) {
+			t.Errorf("Verify() LastUsedAt = %v, want %v", got.LastUsedAt, now)
+		}
+	}
+	stored, err := store.Get(ctx, ak.ClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.LastUsedAt.Equal(now) {
+		t.Errorf("stored LastUsedAt = %v, want %v", stored.LastUsedAt, now)
+	}
+
+	// Uses within the granularity are not written
+	used := now
+	now = now.Add(30 * time.Second)
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.Get(ctx, ak.ClientID); !stored.LastUsedAt.Equal(used) {
+		t.Errorf("stored LastUsedAt = %v, want %v", stored.LastUsedAt, used)
+	}
+
+	now = used.Add(24 * time.Hour)
+	if _, err := verifier.Verify(ctx, apikey); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify() after 24h idle error = %v, want ErrExpired", err)
+	}
+}
+
+func TestIdleTimeoutTenantPolicy(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+	store := NewMemStore()
+	policies := WithTenantPolicy(TenantPolicyMap(map[string]TenantPolicy{"acme": {IdleTimeout: time.Hour}}))
+	admin := NewAdmin(store, WithClock(clock), policies)
+	// Without WithLastUsed idleness counts from creation
+	verifier := NewStoreVerifier(store, WithClock(clock), policies)
+
+	type args struct {
+		opts []KeyOption
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"tenant timeout", args{[]KeyOption{WithTenant("acme")}}, true},
+		{"key overrides tenant", args{[]KeyOption{WithTenant("acme"), WithIdleTimeout(3 * time.Hour)}}, false},
+		{"other tenant", args{[]KeyOption{WithTenant("umbrella")}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+			apikey, _, err := admin.Create(ctx, testAlg, tt.args.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now = now.Add(2 * time.Hour)
+			_, err = verifier.Verify(ctx, apikey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrExpired) {
+				t.Errorf("Verify() error = %v, want ErrExpired", err)
+			}
+		})
+	}
+}
+
+func TestMemStoreTouch(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	if err := store.Touch(ctx, "missing", time.Now()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Touch() missing error = %v, want ErrNotFound", err)
+	}
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.Create(ctx, Key{ClientID: "client-1"}); err != nil {
+		t.Fatal(err)
+	}
+	store.Touch(ctx, "client-1", at)
+	// An older use arriving late does not move LastUsedAt back
+	store.Touch(ctx, "client-1", at.Add(-time.Hour))
+	if ak, _ := store.Get(ctx, "client-1"); !ak.LastUsedAt.Equal(at) {
+		t.Errorf("LastUsedAt = %v, want %v", ak.LastUsedAt, at)
+	}
+}
@@ -0,0 +1,100 @@
+package apikeys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// djangoPBKDF2 maps Django hasher names to the PBKDF2 digest
+var djangoPBKDF2 = map[string]string{
+	"pbkdf2_sha256": "sha256",
+	"pbkdf2_sha1":   "sha1",
+}
+
+// passlibPBKDF2 maps passlib PBKDF2 identifiers to the digest
+var passlibPBKDF2 = map[string]string{
+	"pbkdf2-sha256": "sha256",
+	"pbkdf2-sha512": "sha512",
+	"pbkdf2":        "sha1",
+}
+
+// ParseDjangoHash converts the password field of a Django user, eg
+// "pbkdf2_sha256$870000$<salt>$<hash>", into an imported record for clientID,
+// see ImportHash. The argon2, bcrypt, bcrypt_sha256 and pbkdf2 hashers are
+// supported; the unsalted and md5 hashers are not.
+func ParseDjangoHash(clientID, hash string, opts ...KeyOption) (Key, error) {
+	algorithm, rest, ok := strings.Cut(hash, "$")
+	if !ok {
+		return Key{}, fmt.Errorf("%w: not a django hash", ErrUnsupportedHash)
+	}
+	switch algorithm {
+	case "argon2":
+		// argon2$argon2id$v=19$... is a PHC string without the leading '$'
+		return ImportHash(clientID, "$"+rest, opts...)
+	case "bcrypt":
+		// bcrypt$$2b$...
+		return ImportHash(clientID, rest, opts...)
+	case "bcrypt_sha256":
+		return ImportHash(clientID, hash, opts...)
+	}
+	digest, ok := djangoPBKDF2[algorithm]
+	if !ok {
+		return Key{}, fmt.Errorf("%w: django hasher `%s'", ErrUnsupportedHash, algorithm)
+	}
+	// <iterations>$<salt>$<padded base64 hash>, the salt is used as is
+	parts := strings.Split(rest, "$")
+	if len(parts) != 3 {
+		return Key{}, fmt.Errorf("%w: bad django %s hash", ErrUnsupportedHash, algorithm)
+	}
+	derived, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: bad django %s hash: %v", ErrUnsupportedHash, algorithm, err)
+	}
+	return ImportHash(clientID, formatPBKDF2(digest, parts[0], []byte(parts[1]), derived), opts...)
+}
+
+// ParsePasslibHash converts a passlib hash string into an imported record for
+// clientID, see ImportHash. The pbkdf2_sha1, pbkdf2_sha256, pbkdf2_sha512,
+// argon2 and bcrypt schemes are supported.
+func ParsePasslibHash(clientID, hash string, opts ...KeyOption) (Key, error) {
+	if strings.HasPrefix(hash, phcPrefix) || hasAnyPrefix(hash, bcryptPrefixes) {
+		// passlib uses the standard formats for these
+		return ImportHash(clientID, hash, opts...)
+	}
+	// $<ident>$<rounds>$<salt>$<checksum>, both in passlib's adapted base64
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 || parts[0] != "" {
+		return Key{}, fmt.Errorf("%w: not a passlib hash", ErrUnsupportedHash)
+	}
+	digest, ok := passlibPBKDF2[parts[1]]
+	if !ok {
+		return Key{}, fmt.Errorf("%w: passlib scheme `%s'", ErrUnsupportedHash, parts[1])
+	}
+	salt, err := decodeAB64(parts[3])
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: bad passlib salt: %v", ErrUnsupportedHash, err)
+	}
+	derived, err := decodeAB64(parts[4])
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: bad passlib checksum: %v", ErrUnsupportedHash, err)
+	}
+	return ImportHash(clientID, formatPBKDF2(digest, parts[2], salt, derived), opts...)
+}
+
+// decodeAB64 decodes passlib's adapted base64, which is unpadded standard
+// base64 with '.' in place of '+'
+func decodeAB64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.ReplaceAll(s, ".", "+"))
+}
+
+// formatPBKDF2 formats the PBKDF2 hash format accepted by ImportHash. A bad
+// iteration count is left for ImportHash to reject.
+func formatPBKDF2(digest, iterations string, salt, derived []byte) string {
+	if _, err := strconv.Atoi(iterations); err != nil {
+		iterations = "0"
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%s%s$i=%s,l=%d$%s$%s", pbkdf2Prefix, digest, iterations, len(derived), enc.EncodeToString(salt), enc.EncodeToString(derived))
+}
@@ -0,0 +1,88 @@
+package apikeys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestParseFrameworkHashes(t *testing.T) {
+	sum := sha256.Sum256([]byte("django bcrypt"))
+	bc, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(sum[:])), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type parser func(clientID, hash string, opts ...KeyOption) (Key, error)
+	// The pbkdf2 vectors were produced with python's hashlib in the Django
+	// and passlib formats
+	tests := []struct {
+		name   string
+		parse  parser
+		hash   string
+		secret string
+	}{
+		{"django pbkdf2_sha256", ParseDjangoHash, "pbkdf2_sha256$1000$seasalt$aVCvotKswgOuJiTCYi5a17GjjCpAE7Kwi7vo90yA11g=", "django secret"},
+		{"django pbkdf2_sha1", ParseDjangoHash, "pbkdf2_sha1$1000$seasalt$R4lozbCNR/MK48eCoRsrSWL6b5o=", "django secret"},
+		{"django argon2", ParseDjangoHash, "argon2" + phcVectors[1].hash, phcVectors[1].password},
+		{"django bcrypt_sha256", ParseDjangoHash, "bcrypt_sha256$" + string(bc), "django bcrypt"},
+		{"passlib pbkdf2_sha256", ParsePasslibHash, "$pbkdf2-sha256$1000$.vv8/f7/AAECAwQFBgcICQ$FM.imq.3ue3UmcVvTSzwgcqkyuqe3J8O0.6scwSnzhI", "passlib secret"},
+		{"passlib pbkdf2_sha1", ParsePasslibHash, "$pbkdf2$1000$.vv8/f7/AAECAwQFBgcICQ$BPkkQXj.0A5mbMrtEH6BZ5LI2k4", "passlib secret"},
+		{"passlib pbkdf2_sha512", ParsePasslibHash, "$pbkdf2-sha512$1000$.vv8/f7/AAECAwQFBgcICQ$OivaecQDmqeoIGxzlXcCxEaGUFi8cfgUo2NADq4wqT8e5GX6McJMJzmC.eRCcr28wIsZn/xm6REYJhc6ynAH8Q", "passlib secret"},
+		{"passlib argon2", ParsePasslibHash, phcVectors[0].hash, phcVectors[0].password},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ak, err := tt.parse("legacy-1", tt.hash)
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			store := NewMemStore()
+			if err := store.Create(t.Context(), ak); err != nil {
+				t.Fatal(err)
+			}
+			v := NewStoreVerifier(store, WithUpgradeAlg(testAlg))
+			if _, err := v.VerifySecret(t.Context(), "legacy-1", []byte(tt.secret+"x")); !errors.Is(err, ErrMismatch) {
+				t.Errorf("VerifySecret() wrong secret error = %v, want ErrMismatch", err)
+			}
+			if _, err := v.VerifySecret(t.Context(), "legacy-1", []byte(tt.secret)); err != nil {
+				t.Errorf("VerifySecret() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestParseFrameworkHashErrors(t *testing.T) {
+	type args struct {
+		hash string
+	}
+	tests := []struct {
+		name   string
+		django bool
+		args   args
+	}{
+		{"django md5", true, args{"md5$salt$5f4dcc3b5aa765d61d8327deb882cf99"}},
+		{"django unsalted", true, args{"unsalted_sha1$$5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8"}},
+		{"django iterations", true, args{"pbkdf2_sha256$many$salt$aVCvotKswgOuJiTCYi5a17GjjCpAE7Kwi7vo90yA11g="}},
+		{"django parts", true, args{"pbkdf2_sha256$1000$salt"}},
+		{"django bcrypt_sha256 not bcrypt", true, args{"bcrypt_sha256$$pbkdf2-sha256$i=1$c2FsdA$aGFzaA"}},
+		{"django plain", true, args{"password"}},
+		{"passlib scheme", false, args{"$sha512-crypt$5000$salt$hash"}},
+		{"passlib salt", false, args{"$pbkdf2-sha256$1000$*$FM.imq.3ue3UmcVvTSzwgcqkyuqe3J8O0.6scwSnzhI"}},
+		{"passlib plain", false, args{"password"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := ParsePasslibHash
+			if tt.django {
+				parse = ParseDjangoHash
+			}
+			if _, err := parse("legacy-1", tt.args.hash); !errors.Is(err, ErrUnsupportedHash) {
+				t.Errorf("parse(%q) error = %v, want ErrUnsupportedHash", tt.args.hash, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package apikeys
+
+// Info describes an api key without verifying it, see Inspect
+type Info struct {
+	ClientID string
+	// TenantID is set for keys embedding a tenant
+	TenantID string
+	// Prefix is the environment prefix the key carried, one of those given
+	// with WithPrefix
+	Prefix string
+	// Alg is the parsed alg the key was created with
+	Alg Alg
+	// SaltLen and SecretLen are the lengths of the salt and the secret
+	SaltLen   int
+	SecretLen int
+	// Canonical is true if the key passes the WithStrict checks, as every
+	// key Generate produces does
+	Canonical bool
+}
+
+// Inspect decodes apikey, as Decode with opts, and describes it without
+// running the alg's derivation or consulting a store. It is for support
+// tooling which must identify a key but never verify it: the secret is
+// wiped before Inspect returns and is not part of the Info.
+func Inspect(apikey string, opts ...DecodeOption) (Info, error) {
+	ak, password, err := Decode(apikey, opts...)
+	if err != nil {
+		return Info{}, err
+	}
+	defer password.Wipe()
+	o := newDecodeOptions(opts)
+	if o.lenient {
+		apikey = trimPasted(apikey)
+	}
+	info := Info{
+		ClientID:  ak.ClientID,
+		TenantID:  ak.TenantID,
+		Alg:       ak.alg,
+		SaltLen:   len(ak.Salt),
+		SecretLen: len(password),
+	}
+	info.Prefix, _ = o.prefix(apikey)
+	o.strict = true
+	_, strictPassword, err := decode(apikey, nil, &o)
+	strictPassword.Wipe()
+	info.Canonical = err == nil
+	return info, nil
+}
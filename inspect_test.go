@@ -0,0 +1,52 @@
+package apikeys
+
+import (
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"), WithTenant("acme"), WithSecretLength(24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := apikey[:20] + "\n" + apikey[20:]
+
+	type args struct {
+		apikey string
+		opts   []DecodeOption
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    Info
+		wantErr bool
+	}{
+		{"generated", args{apikey, nil}, Info{ClientID: "client-1", TenantID: "acme", SaltLen: saltLen, SecretLen: 24, Canonical: true}, false},
+		{"prefixed", args{"live_" + apikey, []DecodeOption{WithPrefix("test_", "live_")}}, Info{ClientID: "client-1", TenantID: "acme", Prefix: "live_", SaltLen: saltLen, SecretLen: 24, Canonical: true}, false},
+		{"pasted", args{" '" + apikey + "'\n", []DecodeOption{WithLenient()}}, Info{ClientID: "client-1", TenantID: "acme", SaltLen: saltLen, SecretLen: 24, Canonical: true}, false},
+		{"wrapped", args{wrapped, nil}, Info{ClientID: "client-1", TenantID: "acme", SaltLen: saltLen, SecretLen: 24}, false},
+		{"malformed", args{apikey[:12], nil}, Info{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Inspect(tt.args.apikey, tt.args.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Inspect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Alg.String != testAlg || got.Alg.Memory != 16*memoryUnits {
+				t.Errorf("Inspect() alg = %+v", got.Alg)
+			}
+			got.Alg = Alg{}
+			if got != tt.want {
+				t.Errorf("Inspect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
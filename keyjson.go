@@ -0,0 +1,72 @@
+package apikeys
+
+import "encoding/json"
+
+// keyFields has the fields and tags of Key but none of its methods, so it can
+// be embedded to marshal the tagged fields without recursing.
+type keyFields Key
+
+// keyJSON adds the fields Key leaves out of its struct tags
+type keyJSON struct {
+	*keyFields
+	Alg       string `json:"alg,omitempty"`
+	Salt      []byte `json:"salt,omitempty"`
+	SecretLen int    `json:"secret_len,omitempty"`
+}
+
+// JSONOption controls MarshalKeyJSON
+type JSONOption func(*keyJSON)
+
+// JSONOmitAlg leaves the alg string out of the json
+func JSONOmitAlg() JSONOption {
+	return func(j *keyJSON) {
+		j.Alg = ""
+	}
+}
+
+// JSONOmitSalt leaves the salt out of the json
+func JSONOmitSalt() JSONOption {
+	return func(j *keyJSON) {
+		j.Salt = nil
+	}
+}
+
+// MarshalKeyJSON marshals ak including its alg, salt and any secret length
+// set with WithSecretLength, which the struct tags on Key exclude, unless
+// opts say otherwise. Without the alg and salt a record read back from json
+// can not regenerate or re-derive its key. To control the encoding of a Key
+// nested in another struct, marshal it with this into a json.RawMessage.
+func MarshalKeyJSON(ak Key, opts ...JSONOption) ([]byte, error) {
+	j := keyJSON{keyFields: (*keyFields)(&ak), Alg: ak.alg.String, Salt: ak.Salt, SecretLen: ak.secretLen}
+	for _, o := range opts {
+		o(&j)
+	}
+	return json.Marshal(j)
+}
+
+// MarshalJSON includes the alg and salt, see MarshalKeyJSON
+func (ak Key) MarshalJSON() ([]byte, error) {
+	return MarshalKeyJSON(ak)
+}
+
+// UnmarshalJSON restores the alg and salt as well as the tagged fields. The
+// alg is parsed and validated if present.
+func (ak *Key) UnmarshalJSON(b []byte) error {
+	var fields keyFields
+	j := keyJSON{keyFields: &fields}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	decoded := Key(fields)
+	if j.Alg != "" {
+		alg, err := ParseAlg(j.Alg)
+		if err != nil {
+			return err
+		}
+		decoded.alg = alg
+	}
+	decoded.Salt = j.Salt
+	decoded.secretLen = j.SecretLen
+	*ak = decoded
+	return nil
+}
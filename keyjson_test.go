@@ -0,0 +1,71 @@
+package apikeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyJSONRoundTrip(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"), WithSecretLength(24), WithExpiresAt(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ak.CreatedAt = time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	b, err := json.Marshal(ak)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got Key
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", b, err)
+	}
+	if !reflect.DeepEqual(got, ak) {
+		t.Errorf("round trip = %+v, want %+v", got, ak)
+	}
+
+	// The reloaded record must still be able to verify the key
+	presented, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !presented.MatchPassword(password, got.DerivedKey) || !bytes.Equal(presented.Salt, got.Salt) {
+		t.Errorf("reloaded record does not verify")
+	}
+	if got.Alg().String != testAlg {
+		t.Errorf("reloaded alg = %s, want %s", got.Alg().String, testAlg)
+	}
+}
+
+func TestMarshalKeyJSONOptions(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ak.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalKeyJSON(ak, JSONOmitAlg(), JSONOmitSalt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	if strings.Contains(s, `"alg"`) || strings.Contains(s, `"salt"`) {
+		t.Errorf("MarshalKeyJSON() with omit options = %s", s)
+	}
+	if !strings.Contains(s, `"client_id":"client-1"`) || !strings.Contains(s, `"derived_key"`) {
+		t.Errorf("MarshalKeyJSON() dropped the tagged fields: %s", s)
+	}
+
+	if err := json.Unmarshal([]byte(`{"client_id":"c","alg":"argon2id 9 9MB 9"}`), &Key{}); err == nil {
+		t.Errorf("Unmarshal() accepted an invalid alg")
+	}
+}
@@ -0,0 +1,57 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+)
+
+// KeySet verifies api keys against a fixed set of derived keys, eg baked into
+// the configuration of a small service, with no Store. The salt and alg come
+// from the presented key, so a client id and derived key are all a record
+// needs. Keys with a tenant segment never verify against a KeySet.
+type KeySet struct {
+	keys map[string]Key
+	v    *MultiVerifier
+}
+
+var _ Verifier = (*KeySet)(nil)
+
+// NewKeySet creates a KeySet from client ids and their standard base64
+// derived keys, as in the derived_key of an OutputRecord. The options are
+// those of NewStoreVerifier.
+func NewKeySet(derived map[string]string, opts ...Option) (*KeySet, error) {
+	s := &KeySet{keys: make(map[string]Key, len(derived))}
+	for clientID, b64 := range derived {
+		if clientID == "" {
+			return nil, fmt.Errorf("%w: empty key set client id", ErrConfig)
+		}
+		b, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad derived key for `%s': %v", ErrConfig, clientID, err)
+		}
+		if len(b) == 0 {
+			return nil, fmt.Errorf("%w: empty derived key for `%s'", ErrConfig, clientID)
+		}
+		s.keys[clientID] = Key{ClientID: clientID, DerivedKey: b}
+	}
+	s.v = NewMultiVerifier(s.candidates, opts...)
+	return s, nil
+}
+
+func (s *KeySet) candidates(ctx context.Context, clientID string) ([]Key, error) {
+	ak, ok := s.keys[clientID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []Key{ak}, nil
+}
+
+// Len is the number of keys in the set
+func (s *KeySet) Len() int {
+	return len(s.keys)
+}
+
+func (s *KeySet) Verify(ctx context.Context, apikey string) (Identity, error) {
+	return s.v.Verify(ctx, apikey)
+}
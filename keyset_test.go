@@ -0,0 +1,85 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestKeySet(t *testing.T) {
+	ctx := context.Background()
+	newKey := func(t *testing.T, clientID string) (string, string) {
+		t.Helper()
+		ak, err := NewKey(testAlg, WithClientID(clientID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		apikey, err := ak.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return apikey, base64.StdEncoding.EncodeToString(ak.DerivedKey)
+	}
+	billing, billingDerived := newKey(t, "billing")
+	reports, reportsDerived := newKey(t, "reports")
+	unknown, _ := newKey(t, "unknown")
+	// Same client id, different secret
+	forged, _ := newKey(t, "billing")
+
+	s, err := NewKeySet(map[string]string{"billing": billingDerived, "reports": reportsDerived})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", s.Len())
+	}
+
+	type args struct {
+		apikey string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr error
+	}{
+		{"billing", args{billing}, "billing", nil},
+		{"reports", args{reports}, "reports", nil},
+		{"unknown", args{unknown}, "", ErrNotFound},
+		{"forged", args{forged}, "", ErrMismatch},
+		{"garbage", args{"not an api key"}, "", ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Verify(ctx, tt.args.apikey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.ClientID != tt.want {
+				t.Errorf("Verify() ClientID = %q, want %q", got.ClientID, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+	type args struct {
+		derived map[string]string
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"empty client id", args{map[string]string{"": "AAAA"}}},
+		{"bad base64", args{map[string]string{"billing": "not base64!"}}},
+		{"empty derived key", args{map[string]string{"billing": ""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeySet(tt.args.derived); !errors.Is(err, ErrConfig) {
+				t.Errorf("NewKeySet() error = %v, want ErrConfig", err)
+			}
+		})
+	}
+}
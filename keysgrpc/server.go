@@ -0,0 +1,222 @@
+// Package keysgrpc implements the apikeyspb.KeysService on top of an
+// apikeys.Store
+package keysgrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+	"github.com/robinbryce/apikeys/apikeyspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	apikeyspb.UnimplementedKeysServiceServer
+	admin    *apikeys.Admin
+	verifier *apikeys.StoreVerifier
+}
+
+var _ apikeyspb.KeysServiceServer = (*Server)(nil)
+
+func NewServer(store apikeys.Store, opts ...apikeys.Option) *Server {
+	return &Server{
+		admin:    apikeys.NewAdmin(store, opts...),
+		verifier: apikeys.NewStoreVerifier(store, opts...),
+	}
+}
+
+func (s *Server) Create(ctx context.Context, req *apikeyspb.CreateRequest) (*apikeyspb.CreateResponse, error) {
+	var opts []apikeys.KeyOption
+	if req.GetClientId() != "" {
+		opts = append(opts, apikeys.WithClientID(req.GetClientId()))
+	}
+	if req.GetTenantId() != "" {
+		opts = append(opts, apikeys.WithTenant(req.GetTenantId()))
+	}
+	opts = append(opts,
+		apikeys.WithName(req.GetName()),
+		apikeys.WithDescription(req.GetDescription()),
+		apikeys.WithOwner(req.GetOwner()),
+		apikeys.WithKeyType(apikeys.KeyType(req.GetType())),
+		apikeys.WithTeam(req.GetTeam()),
+		apikeys.WithAllowedCIDRs(req.GetRestrictions().GetAllowedCidrs()...),
+		apikeys.WithAllowedOrigins(req.GetRestrictions().GetAllowedOrigins()...),
+		apikeys.WithAllowedRoutes(req.GetRestrictions().GetAllowedRoutes()...))
+	if req.GetIdleTimeoutSeconds() != 0 {
+		opts = append(opts, apikeys.WithIdleTimeout(time.Duration(req.GetIdleTimeoutSeconds())*time.Second))
+	}
+	if q := req.GetRestrictions().GetQuota(); q != nil {
+		opts = append(opts, apikeys.WithQuota(q.GetLimit(), time.Duration(q.GetWindowSeconds())*time.Second))
+	}
+	if len(req.GetLabels()) > 0 {
+		opts = append(opts, apikeys.WithLabels(req.GetLabels()))
+	}
+	if err := checkKey(req.GetAlg(), opts...); err != nil {
+		return nil, err
+	}
+	apikey, ak, err := s.admin.Create(ctx, req.GetAlg(), opts...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &apikeyspb.CreateResponse{ApiKey: apikey, Key: apikeyspb.KeyToProto(ak)}, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *apikeyspb.GetRequest) (*apikeyspb.Key, error) {
+	ak, err := s.admin.Get(ctx, req.GetClientId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return apikeyspb.KeyToProto(ak), nil
+}
+
+func (s *Server) List(ctx context.Context, req *apikeyspb.ListRequest) (*apikeyspb.ListResponse, error) {
+	var filters []apikeys.KeyFilter
+	if req.GetName() != "" {
+		filters = append(filters, apikeys.NameFilter(req.GetName()))
+	}
+	if req.GetOwner() != "" {
+		filters = append(filters, apikeys.OwnerFilter(req.GetOwner()))
+	}
+	if req.GetType() != "" {
+		filters = append(filters, apikeys.TypeFilter(apikeys.KeyType(req.GetType())))
+	}
+	if req.GetTeam() != "" {
+		filters = append(filters, apikeys.TeamFilter(req.GetTeam()))
+	}
+	if req.GetApproval() != "" {
+		filters = append(filters, apikeys.ApprovalFilter(apikeys.ApprovalState(req.GetApproval())))
+	}
+	if req.GetTenantId() != "" {
+		filters = append(filters, apikeys.TenantFilter(req.GetTenantId()))
+	}
+	if req.GetCreatedBefore() != nil {
+		filters = append(filters, apikeys.CreatedBeforeFilter(req.GetCreatedBefore().AsTime()))
+	}
+	if req.GetWeakerThan() != "" {
+		f, err := apikeys.WeakerAlgFilter(req.GetWeakerThan())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		filters = append(filters, f)
+	}
+	for name, value := range req.GetLabels() {
+		filters = append(filters, apikeys.LabelFilter(name, value))
+	}
+	keys, err := s.admin.List(ctx, filters...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &apikeyspb.ListResponse{Keys: make([]*apikeyspb.Key, 0, len(keys))}
+	for _, ak := range keys {
+		resp.Keys = append(resp.Keys, apikeyspb.KeyToProto(ak))
+	}
+	return resp, nil
+}
+
+func (s *Server) Revoke(ctx context.Context, req *apikeyspb.RevokeRequest) (*apikeyspb.Key, error) {
+	ak, err := s.admin.Revoke(ctx, req.GetClientId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return apikeyspb.KeyToProto(ak), nil
+}
+
+func (s *Server) Approve(ctx context.Context, req *apikeyspb.ApproveRequest) (*apikeyspb.Key, error) {
+	ak, err := s.admin.Approve(ctx, req.GetClientId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return apikeyspb.KeyToProto(ak), nil
+}
+
+func (s *Server) Reject(ctx context.Context, req *apikeyspb.RejectRequest) (*apikeyspb.Key, error) {
+	ak, err := s.admin.Reject(ctx, req.GetClientId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return apikeyspb.KeyToProto(ak), nil
+}
+
+func (s *Server) Rotate(ctx context.Context, req *apikeyspb.RotateRequest) (*apikeyspb.CreateResponse, error) {
+	var apikey string
+	var ak apikeys.Key
+	var err error
+	if req.GetAlg() != "" {
+		if err := checkKey(req.GetAlg()); err != nil {
+			return nil, err
+		}
+	}
+	if grace := time.Duration(req.GetGraceSeconds()) * time.Second; grace != 0 {
+		apikey, ak, err = s.admin.RotateGracefully(ctx, req.GetClientId(), req.GetAlg(), grace)
+	} else {
+		apikey, ak, err = s.admin.Rotate(ctx, req.GetClientId(), req.GetAlg())
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &apikeyspb.CreateResponse{ApiKey: apikey, Key: apikeyspb.KeyToProto(ak)}, nil
+}
+
+func (s *Server) FinalizeRotation(ctx context.Context, req *apikeyspb.FinalizeRotationRequest) (*apikeyspb.Key, error) {
+	ak, err := s.admin.FinalizeRotation(ctx, req.GetClientId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return apikeyspb.KeyToProto(ak), nil
+}
+
+func (s *Server) Verify(ctx context.Context, req *apikeyspb.VerifyRequest) (*apikeyspb.Key, error) {
+	ak, err := s.verifier.Verify(ctx, req.GetApiKey())
+	if err != nil {
+		// Don't distinguish unknown client ids from bad secrets to callers
+		if errors.Is(err, apikeys.ErrNotFound) {
+			err = apikeys.ErrMismatch
+		}
+		return nil, statusError(err)
+	}
+	return apikeyspb.KeyToProto(ak), nil
+}
+
+// checkKey returns an InvalidArgument error if a key can't have alg and
+// opts, so that the errors of a bad request are told apart from failures of
+// the Admin
+func checkKey(alg string, opts ...apikeys.KeyOption) error {
+	if alg == "" {
+		alg = apikeys.StandardAlg
+	}
+	if _, err := apikeys.NewKey(alg, opts...); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// statusError maps err to a status. Errors which aren't one of the known
+// apikeys errors are Internal.
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, apikeys.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, apikeys.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, apikeys.ErrRevoked), errors.Is(err, apikeys.ErrExpired), errors.Is(err, apikeys.ErrMismatch),
+		errors.Is(err, apikeys.ErrPendingApproval), errors.Is(err, apikeys.ErrDeprecated), errors.Is(err, apikeys.ErrLeaked):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, apikeys.ErrInvalid), errors.Is(err, apikeys.ErrPolicy), errors.Is(err, apikeys.ErrReservedClientID),
+		errors.Is(err, apikeys.ErrUnsupportedHash):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, apikeys.ErrSelfApproval), errors.Is(err, apikeys.ErrTenant):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, apikeys.ErrApproval):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, apikeys.ErrOverloaded), errors.Is(err, apikeys.ErrCircuitOpen):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
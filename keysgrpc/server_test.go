@@ -0,0 +1,173 @@
+package keysgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+	"github.com/robinbryce/apikeys/apikeyspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const testAlg = "argon2id 1 16MB 16"
+
+func newTestClient(t *testing.T) apikeyspb.KeysServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	apikeyspb.RegisterKeysServiceServer(srv, NewServer(apikeys.NewMemStore()))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return apikeyspb.NewKeysServiceClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+
+	created, err := client.Create(ctx, &apikeyspb.CreateRequest{Alg: testAlg, ClientId: "client-1"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.GetKey().GetClientId() != "client-1" || created.GetApiKey() == "" {
+		t.Errorf("Create() = %v, want client-1 and an api key", created)
+	}
+	_, err = client.Create(ctx, &apikeyspb.CreateRequest{Alg: testAlg, ClientId: "client-1"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Create() duplicate code = %v, want %v", status.Code(err), codes.AlreadyExists)
+	}
+	_, err = client.Create(ctx, &apikeyspb.CreateRequest{Alg: "argon2id 9 64MB 32"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Create() bad alg code = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+
+	if _, err := client.Verify(ctx, &apikeyspb.VerifyRequest{ApiKey: created.GetApiKey()}); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	rotated, err := client.Rotate(ctx, &apikeyspb.RotateRequest{ClientId: "client-1"})
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if rotated.GetKey().GetAlg() != testAlg {
+		t.Errorf("Rotate() alg = %s, want %s", rotated.GetKey().GetAlg(), testAlg)
+	}
+	_, mismatch := client.Verify(ctx, &apikeyspb.VerifyRequest{ApiKey: created.GetApiKey()})
+	if status.Code(mismatch) != codes.Unauthenticated {
+		t.Errorf("Verify() old key code = %v, want %v", status.Code(mismatch), codes.Unauthenticated)
+	}
+
+	list, err := client.List(ctx, &apikeyspb.ListRequest{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list.GetKeys()) != 1 {
+		t.Errorf("List() = %v, want 1 key", list.GetKeys())
+	}
+
+	revoked, err := client.Revoke(ctx, &apikeyspb.RevokeRequest{ClientId: "client-1"})
+	if err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if revoked.GetRevokedAt() == nil {
+		t.Errorf("Revoke() = %v, want revoked_at set", revoked)
+	}
+	// Without the secret a revoked key looks like any other
+	_, err = client.Verify(ctx, &apikeyspb.VerifyRequest{ApiKey: created.GetApiKey()})
+	if status.Convert(err).Message() != status.Convert(mismatch).Message() {
+		t.Errorf("Verify() revoked key with the old secret = %v, want %v", err, mismatch)
+	}
+	got, err := client.Get(ctx, &apikeyspb.GetRequest{ClientId: "client-1"})
+	if err != nil || got.GetRevokedAt() == nil {
+		t.Errorf("Get() = %v, %v, want revoked key", got, err)
+	}
+	_, err = client.Get(ctx, &apikeyspb.GetRequest{ClientId: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Get() missing code = %v, want %v", status.Code(err), codes.NotFound)
+	}
+}
+
+func TestServerListFilters(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	for _, req := range []*apikeyspb.CreateRequest{
+		{Alg: testAlg, ClientId: "acme-1", TenantId: "acme"},
+		{Alg: "argon2id 2 16MB 16", ClientId: "acme-2", TenantId: "acme"},
+		{Alg: testAlg, ClientId: "other-1", TenantId: "other"},
+	} {
+		if _, err := client.Create(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	type args struct {
+		req *apikeyspb.ListRequest
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{"tenant", args{&apikeyspb.ListRequest{TenantId: "acme"}}, 2},
+		{"created before", args{&apikeyspb.ListRequest{CreatedBefore: timestamppb.New(time.Now().Add(-time.Hour))}}, 0},
+		{"created since", args{&apikeyspb.ListRequest{CreatedBefore: timestamppb.New(time.Now().Add(time.Hour))}}, 3},
+		{"weaker than", args{&apikeyspb.ListRequest{WeakerThan: "argon2id 2 16MB 16"}}, 2},
+		{"all of", args{&apikeyspb.ListRequest{TenantId: "acme", WeakerThan: "argon2id 2 16MB 16"}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := client.List(ctx, tt.args.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list.GetKeys()) != tt.want {
+				t.Errorf("List() = %v, want %d keys", list.GetKeys(), tt.want)
+			}
+		})
+	}
+	_, err := client.List(ctx, &apikeyspb.ListRequest{WeakerThan: "bogus"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("List() bad weaker_than code = %v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want codes.Code
+	}{
+		{"not found", args{apikeys.ErrNotFound}, codes.NotFound},
+		{"invalid key", args{fmt.Errorf("%w: bad encoding", apikeys.ErrInvalid)}, codes.InvalidArgument},
+		{"policy", args{fmt.Errorf("%w: alg too weak", apikeys.ErrPolicy)}, codes.InvalidArgument},
+		{"tenant", args{apikeys.ErrTenant}, codes.PermissionDenied},
+		{"unavailable", args{apikeys.ErrCircuitOpen}, codes.Unavailable},
+		{"store failure", args{errors.New("connection reset")}, codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(statusError(tt.args.err)); got != tt.want {
+				t.Errorf("statusError() code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package keyshttp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// NewAuditHandler returns a handler for searching the audit trail in store,
+// relative to wherever it is mounted
+//
+//	GET /                 events for every key
+//	GET /{client_id}      events for one key
+//
+// Both take the query parameters type, which may be repeated, since and
+// until, RFC 3339 times, and limit. Events are returned newest first, so
+// /{client_id}?type=key.rotated&limit=1 answers when a key was last rotated
+// and by whom. The Authorizer is called with OpAudit and the client id,
+// empty for every key.
+func NewAuditHandler(store apikeys.AuditStore, authz Authorizer) http.Handler {
+	h := &auditHandler{store: store, authz: authz}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", h.query)
+	mux.HandleFunc("GET /{client_id}", h.query)
+	return mux
+}
+
+type auditHandler struct {
+	store apikeys.AuditStore
+	authz Authorizer
+}
+
+func (h *auditHandler) query(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("client_id")
+	if h.authz != nil {
+		if err := h.authz(r, OpAudit, clientID); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+	q, err := auditQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q.ClientID = clientID
+	events, err := h.store.Query(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if events == nil {
+		events = []apikeys.AuditEvent{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func auditQuery(r *http.Request) (apikeys.AuditQuery, error) {
+	v := r.URL.Query()
+	q := apikeys.AuditQuery{Types: v["type"]}
+	for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		s := v.Get(name)
+		if s == "" {
+			continue
+		}
+		var err error
+		if *t, err = time.Parse(time.RFC3339, s); err != nil {
+			return q, fmt.Errorf("bad %s `%s': %w", name, s, err)
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("bad limit `%s'", s)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
@@ -0,0 +1,73 @@
+package keyshttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+func TestAuditHandler(t *testing.T) {
+	ctx := context.Background()
+	store := apikeys.NewMemAuditStore()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, ev := range []apikeys.AuditEvent{
+		{Type: apikeys.AuditKeyCreated, ClientID: "client-1", Actor: "alice"},
+		{Type: apikeys.AuditKeyRotated, ClientID: "client-1", Actor: "bob"},
+		{Type: apikeys.AuditKeyCreated, ClientID: "client-2", Actor: "alice"},
+		{Type: apikeys.AuditKeyRotated, ClientID: "client-1", Actor: "carol"},
+	} {
+		ev.Time = start.Add(time.Duration(i) * time.Hour)
+		if err := store.Emit(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ops []string
+	h := NewAuditHandler(store, func(r *http.Request, op, clientID string) error {
+		ops = append(ops, op+":"+clientID)
+		if clientID == "secret" {
+			return errors.New("not yours")
+		}
+		return nil
+	})
+	type args struct {
+		path string
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantCode   int
+		wantActors []string
+	}{
+		{name: "all", args: args{path: "/"}, wantCode: http.StatusOK, wantActors: []string{"carol", "alice", "bob", "alice"}},
+		{name: "last rotation", args: args{path: "/client-1?type=key.rotated&limit=1"}, wantCode: http.StatusOK, wantActors: []string{"carol"}},
+		{name: "time range", args: args{path: "/client-1?since=2030-01-01T01:00:00Z&until=2030-01-01T03:00:00Z"}, wantCode: http.StatusOK, wantActors: []string{"bob"}},
+		{name: "no events", args: args{path: "/client-3"}, wantCode: http.StatusOK, wantActors: nil},
+		{name: "bad since", args: args{path: "/?since=yesterday"}, wantCode: http.StatusBadRequest},
+		{name: "bad limit", args: args{path: "/?limit=-1"}, wantCode: http.StatusBadRequest},
+		{name: "forbidden", args: args{path: "/secret"}, wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []apikeys.AuditEvent
+			code := do(t, h, "GET", tt.args.path, "", &events)
+			if code != tt.wantCode {
+				t.Fatalf("GET %s = %d, want %d", tt.args.path, code, tt.wantCode)
+			}
+			var actors []string
+			for _, ev := range events {
+				actors = append(actors, ev.Actor)
+			}
+			if !slices.Equal(actors, tt.wantActors) {
+				t.Errorf("GET %s actors = %v, want %v", tt.args.path, actors, tt.wantActors)
+			}
+		})
+	}
+	if ops[0] != OpAudit+":" || ops[1] != OpAudit+":client-1" {
+		t.Errorf("authorizer saw %v", ops)
+	}
+}
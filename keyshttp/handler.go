@@ -0,0 +1,440 @@
+// Package keyshttp provides http.Handlers for api key management so that
+// existing services can mount the admin operations under their own router
+// and authentication.
+package keyshttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// The operations passed to the Authorizer
+const (
+	OpCreate   = "create"
+	OpGet      = "get"
+	OpList     = "list"
+	OpRevoke   = "revoke"
+	OpRotate   = "rotate"
+	OpApprove  = "approve"
+	OpReject   = "reject"
+	OpFinalize = "finalize"
+	// OpRevokeMatching revokes every key matching the query filters
+	OpRevokeMatching = "revoke_matching"
+	// OpAudit reads the audit trail, see NewAuditHandler
+	OpAudit = "audit"
+	// OpUsage reads verification counts, see NewUsageHandler
+	OpUsage = "usage"
+)
+
+// Authorizer is called before every operation. The clientID is empty for
+// create, list and revoke_matching. Returning an error rejects the request
+// with 403. A nil Authorizer permits every request, which is only
+// appropriate when the handler is mounted behind middleware that has already
+// authorized the caller.
+type Authorizer func(r *http.Request, op, clientID string) error
+
+// Key is the json representation of a key record
+type Key struct {
+	ClientID   string     `json:"client_id"`
+	TenantID   string     `json:"tenant_id,omitempty"`
+	Alg        string     `json:"alg,omitempty"`
+	DerivedKey []byte     `json:"derived_key,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// PreviousExpiresAt is set while the secret replaced by a graceful
+	// rotation still verifies
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+
+	IdleTimeoutSeconds int64 `json:"idle_timeout_seconds,omitempty"`
+
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	CreatedBy   string            `json:"created_by,omitempty"`
+	Team        string            `json:"team,omitempty"`
+	Approval    string            `json:"approval,omitempty"`
+	ReviewedBy  string            `json:"reviewed_by,omitempty"`
+
+	Restrictions apikeys.Restrictions `json:"restrictions,omitzero"`
+}
+
+// CreateRequest is the body for create and rotate. For rotate the client id is
+// taken from the path.
+type CreateRequest struct {
+	Alg      string `json:"alg,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Team        string            `json:"team,omitempty"`
+
+	Restrictions       apikeys.Restrictions `json:"restrictions,omitzero"`
+	IdleTimeoutSeconds int64                `json:"idle_timeout_seconds,omitempty"`
+
+	// GraceSeconds, for rotate, keeps the previous secret verifying for that
+	// long
+	GraceSeconds int64 `json:"grace_seconds,omitempty"`
+}
+
+type CreateResponse struct {
+	APIKey string `json:"api_key"`
+	Key    Key    `json:"key"`
+}
+
+// RevokeMatchingResponse reports a bulk revocation. Error is set if some of
+// the matched keys could not be revoked, or the request ended before all of
+// them were.
+type RevokeMatchingResponse struct {
+	apikeys.RevokeProgress
+	Error string `json:"error,omitempty"`
+}
+
+type handler struct {
+	admin *apikeys.Admin
+	authz Authorizer
+}
+
+// NewKeysHandler returns a handler for the following routes, relative to
+// wherever it is mounted (use http.StripPrefix when mounting under a path)
+//
+//	POST /                     create a key, responds with the one time api key
+//	GET  /                     list keys, filtered by the query parameters
+//	                           name, owner, type, team, approval, tenant,
+//	                           created_before, weaker_than and
+//	                           label=name=value
+//	POST /revoke               revoke every key matching the same filters,
+//	                           at least one of which is required
+//	GET  /{client_id}          get a key
+//	POST /{client_id}/revoke   revoke a key
+//	POST /{client_id}/rotate   replace the secret for a key, keeping the old
+//	                           one for grace_seconds if given
+//	POST /{client_id}/finalize stop the old secret of a graceful rotation
+//	                           verifying
+//	POST /{client_id}/approve  approve a key pending approval
+//	POST /{client_id}/reject   reject a key pending approval
+func NewKeysHandler(store apikeys.Store, authz Authorizer, opts ...apikeys.Option) http.Handler {
+	h := &handler{admin: apikeys.NewAdmin(store, opts...), authz: authz}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /{$}", h.create)
+	mux.HandleFunc("GET /{$}", h.list)
+	mux.HandleFunc("POST /revoke", h.revokeMatching)
+	mux.HandleFunc("GET /{client_id}", h.get)
+	mux.HandleFunc("POST /{client_id}/revoke", h.revoke)
+	mux.HandleFunc("POST /{client_id}/rotate", h.rotate)
+	mux.HandleFunc("POST /{client_id}/approve", h.review(OpApprove, h.admin.Approve))
+	mux.HandleFunc("POST /{client_id}/reject", h.review(OpReject, h.admin.Reject))
+	mux.HandleFunc("POST /{client_id}/finalize", h.review(OpFinalize, h.admin.FinalizeRotation))
+	return mux
+}
+
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request, op, clientID string) bool {
+	if h.authz == nil {
+		return true
+	}
+	if err := h.authz(r, op, clientID); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return false
+	}
+	return true
+}
+
+func (h *handler) create(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, OpCreate, "") {
+		return
+	}
+	var req CreateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	var opts []apikeys.KeyOption
+	if req.ClientID != "" {
+		opts = append(opts, apikeys.WithClientID(req.ClientID))
+	}
+	if req.TenantID != "" {
+		opts = append(opts, apikeys.WithTenant(req.TenantID))
+	}
+	opts = append(opts, apikeys.WithName(req.Name), apikeys.WithDescription(req.Description), apikeys.WithOwner(req.Owner),
+		apikeys.WithKeyType(apikeys.KeyType(req.Type)), apikeys.WithTeam(req.Team),
+		apikeys.WithAllowedCIDRs(req.Restrictions.AllowedCIDRs...),
+		apikeys.WithAllowedOrigins(req.Restrictions.AllowedOrigins...),
+		apikeys.WithAllowedRoutes(req.Restrictions.AllowedRoutes...))
+	if req.IdleTimeoutSeconds != 0 {
+		opts = append(opts, apikeys.WithIdleTimeout(time.Duration(req.IdleTimeoutSeconds)*time.Second))
+	}
+	if q := req.Restrictions.Quota; q != (apikeys.Quota{}) {
+		opts = append(opts, apikeys.WithQuota(q.Limit, q.Window()))
+	}
+	if len(req.Labels) > 0 {
+		opts = append(opts, apikeys.WithLabels(req.Labels))
+	}
+	if !checkKey(w, req.Alg, opts...) {
+		return
+	}
+	apikey, ak, err := h.admin.Create(r.Context(), req.Alg, opts...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, CreateResponse{APIKey: apikey, Key: fromKey(ak)})
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, OpList, "") {
+		return
+	}
+	filters, err := listFilters(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	keys, err := h.admin.List(r.Context(), filters...)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	out := make([]Key, 0, len(keys))
+	for _, ak := range keys {
+		out = append(out, fromKey(ak))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("client_id")
+	if !h.authorize(w, r, OpGet, clientID) {
+		return
+	}
+	ak, err := h.admin.Get(r.Context(), clientID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fromKey(ak))
+}
+
+func (h *handler) revoke(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("client_id")
+	if !h.authorize(w, r, OpRevoke, clientID) {
+		return
+	}
+	ak, err := h.admin.Revoke(r.Context(), clientID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, fromKey(ak))
+}
+
+func (h *handler) revokeMatching(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, OpRevokeMatching, "") {
+		return
+	}
+	filters, err := listFilters(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	p, err := h.admin.RevokeMatching(r.Context(), nil, filters...)
+	if err != nil && p.Matched == 0 {
+		writeStoreError(w, err)
+		return
+	}
+	resp := RevokeMatchingResponse{RevokeProgress: p}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// review handles the operations on a single key that take no body and
+// return the updated record
+func (h *handler) review(op string, fn func(context.Context, string) (apikeys.Key, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID := r.PathValue("client_id")
+		if !h.authorize(w, r, op, clientID) {
+			return
+		}
+		ak, err := fn(r.Context(), clientID)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, fromKey(ak))
+	}
+}
+
+func (h *handler) rotate(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("client_id")
+	if !h.authorize(w, r, OpRotate, clientID) {
+		return
+	}
+	var req CreateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	var apikey string
+	var ak apikeys.Key
+	var err error
+	if req.Alg != "" && !checkKey(w, req.Alg) {
+		return
+	}
+	if req.GraceSeconds != 0 {
+		apikey, ak, err = h.admin.RotateGracefully(r.Context(), clientID, req.Alg, time.Duration(req.GraceSeconds)*time.Second)
+	} else {
+		apikey, ak, err = h.admin.Rotate(r.Context(), clientID, req.Alg)
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, CreateResponse{APIKey: apikey, Key: fromKey(ak)})
+}
+
+// listFilters builds the List filters from the query parameters name, owner,
+// type, team, approval, tenant, created_before, an RFC 3339 time, weaker_than,
+// an alg, and label, which is name=value and may be repeated
+func listFilters(r *http.Request) ([]apikeys.KeyFilter, error) {
+	q := r.URL.Query()
+	var filters []apikeys.KeyFilter
+	if name := q.Get("name"); name != "" {
+		filters = append(filters, apikeys.NameFilter(name))
+	}
+	if owner := q.Get("owner"); owner != "" {
+		filters = append(filters, apikeys.OwnerFilter(owner))
+	}
+	if typ := q.Get("type"); typ != "" {
+		filters = append(filters, apikeys.TypeFilter(apikeys.KeyType(typ)))
+	}
+	if team := q.Get("team"); team != "" {
+		filters = append(filters, apikeys.TeamFilter(team))
+	}
+	if approval := q.Get("approval"); approval != "" {
+		filters = append(filters, apikeys.ApprovalFilter(apikeys.ApprovalState(approval)))
+	}
+	if tenant := q.Get("tenant"); tenant != "" {
+		filters = append(filters, apikeys.TenantFilter(tenant))
+	}
+	if before := q.Get("created_before"); before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			return nil, fmt.Errorf("bad created_before `%s': %w", before, err)
+		}
+		filters = append(filters, apikeys.CreatedBeforeFilter(t))
+	}
+	if alg := q.Get("weaker_than"); alg != "" {
+		f, err := apikeys.WeakerAlgFilter(alg)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	for _, label := range q["label"] {
+		name, value, ok := strings.Cut(label, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("bad label filter `%s', want name=value", label)
+		}
+		filters = append(filters, apikeys.LabelFilter(name, value))
+	}
+	return filters, nil
+}
+
+func fromKey(ak apikeys.Key) Key {
+	k := Key{
+		ClientID: ak.ClientID, TenantID: ak.TenantID, Alg: ak.Alg().String, DerivedKey: ak.DerivedKey,
+		Name: ak.Name, Description: ak.Description, Owner: ak.Owner, Labels: ak.Labels,
+		Type: string(ak.Type), CreatedBy: ak.CreatedBy, Team: ak.Team,
+		Approval: string(ak.Approval), ReviewedBy: ak.ReviewedBy,
+		Restrictions: ak.Restrictions, IdleTimeoutSeconds: ak.IdleTimeoutSeconds,
+	}
+	if !ak.CreatedAt.IsZero() {
+		k.CreatedAt = &ak.CreatedAt
+	}
+	if !ak.RevokedAt.IsZero() {
+		k.RevokedAt = &ak.RevokedAt
+	}
+	if !ak.ExpiresAt.IsZero() {
+		k.ExpiresAt = &ak.ExpiresAt
+	}
+	if !ak.RotatedAt.IsZero() {
+		k.RotatedAt = &ak.RotatedAt
+	}
+	if !ak.LastUsedAt.IsZero() {
+		k.LastUsedAt = &ak.LastUsedAt
+	}
+	if !ak.PreviousExpiresAt.IsZero() {
+		k.PreviousExpiresAt = &ak.PreviousExpiresAt
+	}
+	return k
+}
+
+// readJSON decodes the request body into v. An empty body leaves v unchanged.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Body == nil || r.ContentLength == 0 {
+		return true
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// checkKey writes a 400 if a key can't have alg and opts, so that the errors
+// of a bad request are told apart from failures of the Admin
+func checkKey(w http.ResponseWriter, alg string, opts ...apikeys.KeyOption) bool {
+	if alg == "" {
+		alg = apikeys.StandardAlg
+	}
+	if _, err := apikeys.NewKey(alg, opts...); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// writeStoreError writes the status for err. Errors which aren't one of the
+// known apikeys errors are a 500.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, apikeys.ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, apikeys.ErrExists):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, apikeys.ErrRevoked), errors.Is(err, apikeys.ErrApproval):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, apikeys.ErrSelfApproval), errors.Is(err, apikeys.ErrTenant):
+		writeError(w, http.StatusForbidden, err)
+	case errors.Is(err, apikeys.ErrPolicy), errors.Is(err, apikeys.ErrReservedClientID), errors.Is(err, apikeys.ErrNoFilter):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, apikeys.ErrOverloaded), errors.Is(err, apikeys.ErrCircuitOpen):
+		writeError(w, http.StatusServiceUnavailable, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,187 @@
+package keyshttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/robinbryce/apikeys"
+)
+
+const testAlg = "argon2id 1 16MB 16"
+
+func do(t *testing.T, h http.Handler, method, path, body string, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: bad response body %s: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestKeysHandler(t *testing.T) {
+	store := apikeys.NewMemStore()
+	mux := http.NewServeMux()
+	mux.Handle("/admin/keys/", http.StripPrefix("/admin/keys", NewKeysHandler(store, nil)))
+
+	var created CreateResponse
+	code := do(t, mux, "POST", "/admin/keys/", `{"alg":"`+testAlg+`","client_id":"client-1"}`, &created)
+	if code != http.StatusCreated || created.APIKey == "" || created.Key.ClientID != "client-1" {
+		t.Fatalf("create = %d %v, want 201 with key for client-1", code, created)
+	}
+	if code := do(t, mux, "POST", "/admin/keys/", `{"alg":"`+testAlg+`","client_id":"client-1"}`, nil); code != http.StatusConflict {
+		t.Errorf("create duplicate = %d, want 409", code)
+	}
+	if code := do(t, mux, "POST", "/admin/keys/", `{"alg":"bogus"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("create bad alg = %d, want 400", code)
+	}
+
+	var got Key
+	if code := do(t, mux, "GET", "/admin/keys/client-1", "", &got); code != http.StatusOK || got.Alg != testAlg {
+		t.Errorf("get = %d %v, want 200 with alg %s", code, got, testAlg)
+	}
+	if code := do(t, mux, "GET", "/admin/keys/missing", "", nil); code != http.StatusNotFound {
+		t.Errorf("get missing = %d, want 404", code)
+	}
+
+	var rotated CreateResponse
+	if code := do(t, mux, "POST", "/admin/keys/client-1/rotate", "", &rotated); code != http.StatusOK || rotated.APIKey == created.APIKey {
+		t.Errorf("rotate = %d %v, want 200 with a new api key", code, rotated)
+	}
+
+	var list []Key
+	if code := do(t, mux, "GET", "/admin/keys/", "", &list); code != http.StatusOK || len(list) != 1 {
+		t.Errorf("list = %d %v, want 200 with one key", code, list)
+	}
+
+	var revoked Key
+	if code := do(t, mux, "POST", "/admin/keys/client-1/revoke", "", &revoked); code != http.StatusOK || revoked.RevokedAt == nil {
+		t.Errorf("revoke = %d %v, want 200 with revoked_at", code, revoked)
+	}
+	if code := do(t, mux, "POST", "/admin/keys/client-1/rotate", "", nil); code != http.StatusConflict {
+		t.Errorf("rotate revoked = %d, want 409", code)
+	}
+}
+
+func TestKeysHandlerStoreFailure(t *testing.T) {
+	h := NewKeysHandler(failingStore{}, nil)
+	if code := do(t, h, "GET", "/client-1", "", nil); code != http.StatusInternalServerError {
+		t.Errorf("get from a failing store = %d, want 500", code)
+	}
+	if code := do(t, h, "POST", "/client-1/rotate", `{"alg":"bogus"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("rotate bad alg = %d, want 400", code)
+	}
+}
+
+func TestKeysHandlerListFilters(t *testing.T) {
+	h := NewKeysHandler(apikeys.NewMemStore(), nil)
+	for _, body := range []string{
+		`{"alg":"` + testAlg + `","client_id":"client-1","owner":"ops","labels":{"env":"prod"}}`,
+		`{"alg":"` + testAlg + `","client_id":"client-2","owner":"ops","labels":{"env":"dev"}}`,
+	} {
+		if code := do(t, h, "POST", "/", body, nil); code != http.StatusCreated {
+			t.Fatalf("create = %d", code)
+		}
+	}
+	var list []Key
+	if code := do(t, h, "GET", "/?owner=ops&label=env%3Ddev", "", &list); code != http.StatusOK || len(list) != 1 || list[0].ClientID != "client-2" {
+		t.Errorf("list = %d %v, want client-2", code, list)
+	}
+	if list[0].Owner != "ops" || list[0].Labels["env"] != "dev" {
+		t.Errorf("list metadata = %+v", list[0])
+	}
+	if code := do(t, h, "GET", "/?label=env", "", nil); code != http.StatusBadRequest {
+		t.Errorf("list bad label = %d, want 400", code)
+	}
+}
+
+func TestKeysHandlerRevokeMatching(t *testing.T) {
+	h := NewKeysHandler(apikeys.NewMemStore(), nil)
+	for _, body := range []string{
+		`{"alg":"` + testAlg + `","client_id":"client-1","tenant_id":"acme"}`,
+		`{"alg":"` + testAlg + `","client_id":"client-2","tenant_id":"acme"}`,
+		`{"alg":"` + testAlg + `","client_id":"client-3","tenant_id":"other"}`,
+	} {
+		if code := do(t, h, "POST", "/", body, nil); code != http.StatusCreated {
+			t.Fatalf("create = %d", code)
+		}
+	}
+	if code := do(t, h, "POST", "/revoke", "", nil); code != http.StatusBadRequest {
+		t.Errorf("revoke without filters = %d, want 400", code)
+	}
+	if code := do(t, h, "POST", "/revoke?created_before=yesterday", "", nil); code != http.StatusBadRequest {
+		t.Errorf("revoke bad created_before = %d, want 400", code)
+	}
+	var resp RevokeMatchingResponse
+	if code := do(t, h, "POST", "/revoke?tenant=acme", "", &resp); code != http.StatusOK || resp.Matched != 2 || resp.Revoked != 2 || resp.Error != "" {
+		t.Errorf("revoke tenant = %d %+v, want 2 revoked", code, resp)
+	}
+	var list []Key
+	if code := do(t, h, "GET", "/?tenant=other&weaker_than=argon2id+3+64MB+32", "", &list); code != http.StatusOK || len(list) != 1 || list[0].RevokedAt != nil {
+		t.Errorf("list other = %d %v, want client-3 not revoked", code, list)
+	}
+}
+
+func TestKeysHandlerAuthorizer(t *testing.T) {
+	var ops []string
+	authz := func(r *http.Request, op, clientID string) error {
+		ops = append(ops, op+":"+clientID)
+		if r.Header.Get("X-Admin") == "" {
+			return errors.New("not an admin")
+		}
+		return nil
+	}
+	h := NewKeysHandler(apikeys.NewMemStore(), authz)
+
+	if code := do(t, h, "GET", "/client-1", "", nil); code != http.StatusForbidden {
+		t.Errorf("get without admin = %d, want 403", code)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Admin", "yes")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("list with admin = %d, want 200", rec.Code)
+	}
+	if strings.Join(ops, ",") != "get:client-1,list:" {
+		t.Errorf("authorizer saw %v", ops)
+	}
+}
+
+func TestKeysHandlerApproval(t *testing.T) {
+	store := apikeys.NewMemStore()
+	admin := apikeys.NewAdmin(store)
+	if _, _, err := admin.Create(apikeys.WithPrincipal(context.Background(), "alice"), testAlg, apikeys.WithClientID("client-1"), apikeys.RequireApproval()); err != nil {
+		t.Fatal(err)
+	}
+	var principal string
+	inner := NewKeysHandler(store, nil)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner.ServeHTTP(w, r.WithContext(apikeys.WithPrincipal(r.Context(), principal)))
+	})
+
+	principal = "alice"
+	if code := do(t, h, "POST", "/client-1/approve", "", nil); code != http.StatusForbidden {
+		t.Errorf("approve by creator = %d, want 403", code)
+	}
+	var pending []Key
+	if code := do(t, h, "GET", "/?approval=pending", "", &pending); code != http.StatusOK || len(pending) != 1 {
+		t.Errorf("list pending = %d %v, want one key", code, pending)
+	}
+	principal = "bob"
+	var approved Key
+	if code := do(t, h, "POST", "/client-1/approve", "", &approved); code != http.StatusOK || approved.Approval != "approved" || approved.ReviewedBy != "bob" {
+		t.Errorf("approve = %d %v, want 200 approved by bob", code, approved)
+	}
+	if code := do(t, h, "POST", "/client-1/reject", "", nil); code != http.StatusConflict {
+		t.Errorf("reject approved = %d, want 409", code)
+	}
+}
@@ -0,0 +1,115 @@
+package keyshttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// Check reports whether a dependency is ready. It should return promptly
+// once ctx is done.
+type Check func(ctx context.Context) error
+
+// defaultCheckTimeout bounds the whole readiness probe
+const defaultCheckTimeout = 5 * time.Second
+
+// NewHealthHandler returns a liveness handler, intended for /healthz. It
+// always succeeds while the process is able to serve http.
+func NewHealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// NewReadyHandler returns a readiness handler, intended for /readyz. All
+// checks are run concurrently on each request. The response is 200 if all
+// pass and 503 otherwise, with a json body reporting each check by name.
+func NewReadyHandler(checks map[string]Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), defaultCheckTimeout)
+		defer cancel()
+
+		results := map[string]string{}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		ready := true
+		for name, check := range checks {
+			wg.Add(1)
+			go func(name string, check Check) {
+				defer wg.Done()
+				err := check(ctx)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					ready = false
+					results[name] = err.Error()
+					return
+				}
+				results[name] = "ok"
+			}(name, check)
+		}
+		wg.Wait()
+
+		code := http.StatusOK
+		if !ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, results)
+	})
+}
+
+// StoreCheck checks store connectivity. If the store, once unwrapped with
+// apikeys.Unwrap, implements apikeys.Pinger its Ping method is used,
+// otherwise a lookup of a client id which can not exist is made and
+// ErrNotFound is treated as success.
+func StoreCheck(store apikeys.Store) Check {
+	return func(ctx context.Context) error {
+		if p, ok := apikeys.Unwrap(store).(apikeys.Pinger); ok {
+			return p.Ping(ctx)
+		}
+		_, err := store.Get(ctx, "")
+		if err == nil || errors.Is(err, apikeys.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+}
+
+// CacheCheck checks the verify cache answers a lookup, of an entry which
+// can not exist. A cold cache is ready: its misses fall through to the
+// store.
+func CacheCheck(cache apikeys.VerifyCache) Check {
+	return func(ctx context.Context) error {
+		_, _, err := cache.Get(ctx, "", "")
+		return err
+	}
+}
+
+// PepperCheck checks the peppers named by ids are registered, so that keys
+// of the keyed algs using them can be verified
+func PepperCheck(ids ...string) Check {
+	return func(ctx context.Context) error {
+		var missing []string
+		for _, id := range ids {
+			if !apikeys.PepperRegistered(id) {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("no pepper registered for `%s'", strings.Join(missing, "', `"))
+		}
+		return nil
+	}
+}
+
+// Mount registers /healthz and /readyz on mux
+func Mount(mux *http.ServeMux, checks map[string]Check) {
+	mux.Handle("GET /healthz", NewHealthHandler())
+	mux.Handle("GET /readyz", NewReadyHandler(checks))
+}
@@ -0,0 +1,114 @@
+package keyshttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/robinbryce/apikeys"
+)
+
+type failingStore struct {
+	apikeys.Store
+}
+
+func (failingStore) Get(ctx context.Context, clientID string) (apikeys.Key, error) {
+	return apikeys.Key{}, errors.New("connection refused")
+}
+
+// pingingStore fails its Ping but not its lookups
+type pingingStore struct {
+	*apikeys.MemStore
+}
+
+func (pingingStore) Ping(ctx context.Context) error {
+	return errors.New("ping failed")
+}
+
+type failingCache struct {
+	apikeys.VerifyCache
+}
+
+func (failingCache) Get(ctx context.Context, clientID, digest string) (apikeys.CachedKey, bool, error) {
+	return apikeys.CachedKey{}, false, errors.New("cache unreachable")
+}
+
+func TestHealthAndReady(t *testing.T) {
+	pepper := apikeys.Secret("0123456789abcdef0123456789abcdef")
+	if err := apikeys.RegisterPepper("health-test", pepper); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		checks   map[string]Check
+		wantCode int
+		want     map[string]string
+	}{
+		{"no checks", nil, http.StatusOK, map[string]string{}},
+		{
+			"store ok", map[string]Check{"store": StoreCheck(apikeys.NewMemStore())},
+			http.StatusOK, map[string]string{"store": "ok"},
+		},
+		{
+			"store down", map[string]Check{
+				"store": StoreCheck(failingStore{}),
+				"other": func(ctx context.Context) error { return nil },
+			},
+			http.StatusServiceUnavailable, map[string]string{"store": "connection refused", "other": "ok"},
+		},
+		{
+			"wrapped pinger", map[string]Check{
+				"store": StoreCheck(apikeys.MeasureStore(pingingStore{apikeys.NewMemStore()}, apikeys.NewCounters())),
+			},
+			http.StatusServiceUnavailable, map[string]string{"store": "ping failed"},
+		},
+		{
+			"cache", map[string]Check{
+				"cache": CacheCheck(apikeys.NewMemVerifyCache()),
+				"down":  CacheCheck(failingCache{}),
+			},
+			http.StatusServiceUnavailable, map[string]string{"cache": "ok", "down": "cache unreachable"},
+		},
+		{
+			"peppers", map[string]Check{
+				"registered": PepperCheck("health-test"),
+				"missing":    PepperCheck("health-test", "health-none"),
+			},
+			http.StatusServiceUnavailable, map[string]string{"registered": "ok", "missing": "no pepper registered for `health-none'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			Mount(mux, tt.checks)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("healthz = %d, want 200", rec.Code)
+			}
+
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("readyz = %d, want %d", rec.Code, tt.wantCode)
+			}
+			got := map[string]string{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("readyz body %s: %v", rec.Body.String(), err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("readyz = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("readyz[%s] = %s, want %s", k, got[k], v)
+				}
+			}
+		})
+	}
+}
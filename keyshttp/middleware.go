@@ -0,0 +1,266 @@
+package keyshttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// HeaderAPIKey is the header the middleware reads the api key from when
+// there is no Authorization header
+const HeaderAPIKey = "X-API-Key"
+
+type keyContextKey struct{}
+
+type anomalyContextKey struct{}
+
+var errQuotaUnavailable = errors.New("api key quota unavailable")
+
+// KeyFromContext returns the verified key the middleware attached to the
+// request context
+func KeyFromContext(ctx context.Context) (apikeys.Key, bool) {
+	ak, ok := ctx.Value(keyContextKey{}).(apikeys.Key)
+	return ak, ok
+}
+
+// AnomalyFromContext returns the result of the anomaly detector if it
+// flagged the request, see apikeys.WithAnomalyDetector
+func AnomalyFromContext(ctx context.Context) (apikeys.AnomalyResult, bool) {
+	res, ok := ctx.Value(anomalyContextKey{}).(apikeys.AnomalyResult)
+	return res, ok
+}
+
+// MiddlewareOption configures NewMiddleware
+type MiddlewareOption func(*middleware)
+
+type middleware struct {
+	verifier       *apikeys.StoreVerifier
+	trustedProxies []netip.Prefix
+	usage          apikeys.UsageCounter
+	geo            func(r *http.Request, addr netip.Addr) apikeys.Geo
+	now            func() time.Time
+}
+
+// WithTrustedProxies makes the middleware take the client address from
+// X-Forwarded-For when the request comes from one of proxies. The client is
+// the right most address that is not itself a trusted proxy. Without this
+// the client address is always RemoteAddr, as X-Forwarded-For can be set by
+// anyone.
+func WithTrustedProxies(proxies ...netip.Prefix) MiddlewareOption {
+	return func(m *middleware) {
+		m.trustedProxies = append(m.trustedProxies, proxies...)
+	}
+}
+
+// WithUsageCounter enforces the quota of keys that have one, counting their
+// requests with counter. Responses for those keys carry X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset, the seconds until the window
+// resets, and requests over the quota get 429.
+func WithUsageCounter(counter apikeys.UsageCounter) MiddlewareOption {
+	return func(m *middleware) {
+		m.usage = counter
+	}
+}
+
+// WithGeoLocator resolves the location of the client for the anomaly
+// detector, eg from a GeoIP database or headers set by a CDN. addr is
+// invalid if the client address is unknown.
+func WithGeoLocator(locate func(r *http.Request, addr netip.Addr) apikeys.Geo) MiddlewareOption {
+	return func(m *middleware) {
+		m.geo = locate
+	}
+}
+
+// NewMiddleware returns middleware that verifies the api key presented with
+// each request, as "Authorization: Bearer <key>", "Authorization: Basic
+// <key>" or an X-API-Key header, and enforces the key's restrictions. The
+// verified key is available to next from KeyFromContext. Requests without a
+// valid key get 401, and keys used outside their restrictions 403. If the
+// verifier has an anomaly detector it sees every request with a verified
+// key: vetoed requests get 403 and flagged ones reach next, which can find
+// the flag with AnomalyFromContext.
+func NewMiddleware(v *apikeys.StoreVerifier, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := &middleware{verifier: v, now: time.Now}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apikey, ok := presentedKey(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				writeError(w, http.StatusUnauthorized, errors.New("api key required"))
+				return
+			}
+			ak, err := m.verifier.Verify(r.Context(), apikey)
+			if err == nil {
+				err = m.restrict(r, ak)
+			}
+			var anomaly apikeys.AnomalyResult
+			if err == nil {
+				anomaly, err = m.verifier.CheckAnomaly(r.Context(), m.keyUse(r, ak))
+			}
+			if err == nil {
+				err = m.quota(w, r, ak)
+			}
+			if err != nil {
+				writeVerifyError(w, err)
+				return
+			}
+			ctx := context.WithValue(r.Context(), keyContextKey{}, ak)
+			if anomaly.Verdict == apikeys.AnomalyFlag {
+				ctx = context.WithValue(ctx, anomalyContextKey{}, anomaly)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// restrict enforces the restrictions of the verified key ak
+func (m *middleware) restrict(r *http.Request, ak apikeys.Key) error {
+	if len(ak.Restrictions.AllowedCIDRs) > 0 {
+		addr, ok := m.clientAddr(r)
+		if !ok {
+			return apikeys.ErrIPNotAllowed
+		}
+		if err := ak.CheckIP(addr); err != nil {
+			return err
+		}
+	}
+	if err := ak.CheckRoute(r.Method, r.URL.Path); err != nil {
+		return err
+	}
+	if len(ak.Restrictions.AllowedOrigins) > 0 {
+		if err := ak.CheckOrigin(requestOrigin(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyUse describes the request for the anomaly detector
+func (m *middleware) keyUse(r *http.Request, ak apikeys.Key) apikeys.KeyUse {
+	use := apikeys.KeyUse{Key: ak, UserAgent: r.UserAgent()}
+	use.Addr, _ = m.clientAddr(r)
+	if m.geo != nil {
+		use.Geo = m.geo(r, use.Addr)
+	}
+	return use
+}
+
+// quota counts the request against the quota of ak and sets the rate limit
+// headers
+func (m *middleware) quota(w http.ResponseWriter, r *http.Request, ak apikeys.Key) error {
+	if m.usage == nil || ak.Restrictions.Quota.Limit <= 0 {
+		return nil
+	}
+	now := m.now()
+	status, err := ak.UseQuota(r.Context(), m.usage, now)
+	if err != nil && !errors.Is(err, apikeys.ErrQuotaExceeded) {
+		return fmt.Errorf("%w: %v", errQuotaUnavailable, err)
+	}
+	reset := strconv.FormatInt(int64(math.Ceil(status.Reset.Sub(now).Seconds())), 10)
+	w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(status.Limit, 10))
+	w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(status.Remaining, 10))
+	w.Header().Set("X-RateLimit-Reset", reset)
+	if err != nil {
+		w.Header().Set("Retry-After", reset)
+	}
+	return err
+}
+
+// requestOrigin returns the Origin header or, as browsers omit it from some
+// same origin requests, the origin of the Referer
+func requestOrigin(r *http.Request) string {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return origin
+	}
+	u, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func (m *middleware) trusted(addr netip.Addr) bool {
+	for _, p := range m.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddr returns the address of the client, see WithTrustedProxies
+func (m *middleware) clientAddr(r *http.Request) (netip.Addr, bool) {
+	ap, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr := ap.Addr().Unmap()
+	if !m.trusted(addr) {
+		return addr, true
+	}
+	// A trusted proxy may make requests of its own, eg health checks
+	if len(r.Header.Values("X-Forwarded-For")) == 0 {
+		return addr, true
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = hop.Unmap()
+		if !m.trusted(addr) {
+			return addr, true
+		}
+	}
+	// Every hop is a trusted proxy, so the request originated inside
+	return addr, true
+}
+
+// presentedKey returns the api key from the request headers
+func presentedKey(r *http.Request) (string, bool) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, key, ok := strings.Cut(auth, " ")
+		if !ok || (!strings.EqualFold(scheme, "Bearer") && !strings.EqualFold(scheme, "Basic")) {
+			return "", false
+		}
+		key = strings.TrimSpace(key)
+		return key, key != ""
+	}
+	key := r.Header.Get(HeaderAPIKey)
+	return key, key != ""
+}
+
+func writeVerifyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, apikeys.ErrIPNotAllowed), errors.Is(err, apikeys.ErrOriginNotAllowed),
+		errors.Is(err, apikeys.ErrRouteNotAllowed), errors.Is(err, apikeys.ErrAnomaly):
+		writeError(w, http.StatusForbidden, err)
+	case errors.Is(err, apikeys.ErrQuotaExceeded):
+		writeError(w, http.StatusTooManyRequests, err)
+	case errors.Is(err, errQuotaUnavailable):
+		// The counter's error may describe its backend
+		writeError(w, http.StatusServiceUnavailable, errQuotaUnavailable)
+	case errors.Is(err, apikeys.ErrOverloaded), errors.Is(err, apikeys.ErrCircuitOpen):
+		writeError(w, http.StatusServiceUnavailable, err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusServiceUnavailable, err)
+	case apikeys.VerifyResult(err) == apikeys.ResultError:
+		writeError(w, http.StatusInternalServerError, errors.New("api key verification failed"))
+	default:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+		writeError(w, http.StatusUnauthorized, errors.New("api key invalid"))
+	}
+}
@@ -0,0 +1,189 @@
+package keyshttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	store := apikeys.NewMemStore()
+	admin := apikeys.NewAdmin(store)
+	open, _, err := admin.Create(ctx, testAlg, apikeys.WithClientID("open"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	partner, _, err := admin.Create(ctx, testAlg, apikeys.WithClientID("partner"), apikeys.WithAllowedCIDRs("203.0.113.0/24"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal, _, err := admin.Create(ctx, testAlg, apikeys.WithClientID("internal"), apikeys.WithAllowedCIDRs("10.0.0.0/8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	browser, _, err := admin.Create(ctx, testAlg, apikeys.WithClientID("browser"), apikeys.WithAllowedOrigins("https://app.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, _, err := admin.Create(ctx, testAlg, apikeys.WithClientID("reports"), apikeys.WithAllowedRoutes("GET /v1/reports/*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ak, ok := KeyFromContext(r.Context())
+		if !ok {
+			t.Error("KeyFromContext() found no key")
+		}
+		w.Write([]byte(ak.ClientID))
+	})
+	mw := NewMiddleware(apikeys.NewStoreVerifier(store), WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8")))(next)
+
+	type args struct {
+		method  string
+		target  string
+		remote  string
+		headers map[string]string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantCode int
+		wantBody string
+	}{
+		{"missing", args{"GET", "/", "198.51.100.7:1234", nil}, http.StatusUnauthorized, ""},
+		{"bad scheme", args{"GET", "/", "198.51.100.7:1234", map[string]string{"Authorization": "Digest " + open}}, http.StatusUnauthorized, ""},
+		{"invalid", args{"GET", "/", "198.51.100.7:1234", map[string]string{"Authorization": "Bearer nope"}}, http.StatusUnauthorized, ""},
+		{"bearer", args{"GET", "/", "198.51.100.7:1234", map[string]string{"Authorization": "Bearer " + open}}, http.StatusOK, "open"},
+		{"header", args{"GET", "/", "198.51.100.7:1234", map[string]string{HeaderAPIKey: open}}, http.StatusOK, "open"},
+		{"allowed", args{"GET", "/", "203.0.113.9:1234", map[string]string{HeaderAPIKey: partner}}, http.StatusOK, "partner"},
+		{"not allowed", args{"GET", "/", "198.51.100.7:1234", map[string]string{HeaderAPIKey: partner}}, http.StatusForbidden, ""},
+		{"forwarded untrusted", args{"GET", "/", "198.51.100.7:1234", map[string]string{HeaderAPIKey: partner, "X-Forwarded-For": "203.0.113.9"}}, http.StatusForbidden, ""},
+		{"forwarded trusted", args{"GET", "/", "10.1.2.3:1234", map[string]string{HeaderAPIKey: partner, "X-Forwarded-For": "203.0.113.9, 10.4.5.6"}}, http.StatusOK, "partner"},
+		{"forwarded spoofed", args{"GET", "/", "10.1.2.3:1234", map[string]string{HeaderAPIKey: partner, "X-Forwarded-For": "203.0.113.9, 198.51.100.7"}}, http.StatusForbidden, ""},
+		{"trusted unforwarded", args{"GET", "/", "10.1.2.3:1234", map[string]string{HeaderAPIKey: internal}}, http.StatusOK, "internal"},
+		{"origin", args{"GET", "/", "198.51.100.7:1234", map[string]string{HeaderAPIKey: browser, "Origin": "https://app.example.com"}}, http.StatusOK, "browser"},
+		{"referer", args{"GET", "/", "198.51.100.7:1234", map[string]string{HeaderAPIKey: browser, "Referer": "https://app.example.com/maps?x=1"}}, http.StatusOK, "browser"},
+		{"wrong origin", args{"GET", "/", "198.51.100.7:1234", map[string]string{HeaderAPIKey: browser, "Origin": "https://evil.example"}}, http.StatusForbidden, ""},
+		{"no origin", args{"GET", "/", "198.51.100.7:1234", map[string]string{HeaderAPIKey: browser}}, http.StatusForbidden, ""},
+		{"route", args{"GET", "/v1/reports/q1", "198.51.100.7:1234", map[string]string{HeaderAPIKey: reports}}, http.StatusOK, "reports"},
+		{"route method", args{"DELETE", "/v1/reports/q1", "198.51.100.7:1234", map[string]string{HeaderAPIKey: reports}}, http.StatusForbidden, ""},
+		{"route path", args{"GET", "/v1/keys", "198.51.100.7:1234", map[string]string{HeaderAPIKey: reports}}, http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.args.method, tt.args.target, nil)
+			req.RemoteAddr = tt.args.remote
+			for k, v := range tt.args.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			mw.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d %s, want %d", rec.Code, rec.Body.String(), tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantCode == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestMiddlewareQuota(t *testing.T) {
+	ctx := context.Background()
+	store := apikeys.NewMemStore()
+	limited, _, err := apikeys.NewAdmin(store).Create(ctx, testAlg, apikeys.WithClientID("limited"), apikeys.WithQuota(2, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mw := NewMiddleware(apikeys.NewStoreVerifier(store), WithUsageCounter(apikeys.NewMemUsageCounter()))(next)
+
+	for i, want := range []struct {
+		code      int
+		remaining string
+	}{{http.StatusOK, "1"}, {http.StatusOK, "0"}, {http.StatusTooManyRequests, "0"}} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(HeaderAPIKey, limited)
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != want.code {
+			t.Fatalf("request %d code = %d, want %d", i, rec.Code, want.code)
+		}
+		h := rec.Header()
+		if h.Get("X-RateLimit-Limit") != "2" || h.Get("X-RateLimit-Remaining") != want.remaining || h.Get("X-RateLimit-Reset") == "" {
+			t.Errorf("request %d headers = %v, want limit 2 remaining %s", i, h, want.remaining)
+		}
+		if want.code == http.StatusTooManyRequests && h.Get("Retry-After") == "" {
+			t.Errorf("request %d 429 without Retry-After", i)
+		}
+	}
+}
+
+func TestMiddlewareAnomaly(t *testing.T) {
+	ctx := context.Background()
+	store := apikeys.NewMemStore()
+	apikey, _, err := apikeys.NewAdmin(store).Create(ctx, testAlg, apikeys.WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen apikeys.KeyUse
+	detector := func(ctx context.Context, use apikeys.KeyUse) apikeys.AnomalyResult {
+		seen = use
+		switch use.Geo.Country {
+		case "NZ":
+			return apikeys.AnomalyResult{Verdict: apikeys.AnomalyVeto, Reason: "impossible travel"}
+		case "FR":
+			return apikeys.AnomalyResult{Verdict: apikeys.AnomalyFlag, Reason: "new country"}
+		}
+		return apikeys.AnomalyResult{}
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if res, ok := AnomalyFromContext(r.Context()); ok {
+			w.Write([]byte(res.Reason))
+		}
+	})
+	geo := WithGeoLocator(func(r *http.Request, addr netip.Addr) apikeys.Geo {
+		return apikeys.Geo{Country: r.Header.Get("CF-IPCountry")}
+	})
+	mw := NewMiddleware(apikeys.NewStoreVerifier(store, apikeys.WithAnomalyDetector(detector)), geo)(next)
+
+	type args struct {
+		country string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantCode int
+		wantBody string
+	}{
+		{name: "allowed", args: args{country: "GB"}, wantCode: http.StatusOK},
+		{name: "flagged", args: args{country: "FR"}, wantCode: http.StatusOK, wantBody: "new country"},
+		{name: "vetoed", args: args{country: "NZ"}, wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "198.51.100.7:1234"
+			req.Header.Set(HeaderAPIKey, apikey)
+			req.Header.Set("User-Agent", "partner-sdk/1.2")
+			req.Header.Set("CF-IPCountry", tt.args.country)
+			rec := httptest.NewRecorder()
+			mw.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode || (tt.wantCode == http.StatusOK && rec.Body.String() != tt.wantBody) {
+				t.Errorf("code = %d body %q, want %d %q", rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+			}
+			if seen.Key.ClientID != "client-1" || seen.Addr != netip.MustParseAddr("198.51.100.7") || seen.UserAgent != "partner-sdk/1.2" {
+				t.Errorf("detector saw %+v", seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package keyshttp
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+// NewUsageHandler returns a handler serving the verification counts in store
+// to usage dashboards
+//
+//	GET /{client_id}      usage of one key
+//
+// The query parameters since and until are RFC 3339 times and period is hour,
+// the default, or day. The Authorizer is called with OpUsage and the client
+// id.
+func NewUsageHandler(store apikeys.UsageStore, authz Authorizer) http.Handler {
+	h := &usageHandler{store: store, authz: authz}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{client_id}", h.query)
+	return mux
+}
+
+type usageHandler struct {
+	store apikeys.UsageStore
+	authz Authorizer
+}
+
+func (h *usageHandler) query(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("client_id")
+	if h.authz != nil {
+		if err := h.authz(r, OpUsage, clientID); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+	q, err := usageQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q.ClientID = clientID
+	buckets, err := h.store.QueryUsage(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if buckets == nil {
+		buckets = []apikeys.UsageBucket{}
+	}
+	writeJSON(w, http.StatusOK, buckets)
+}
+
+func usageQuery(r *http.Request) (apikeys.UsageQuery, error) {
+	v := r.URL.Query()
+	var q apikeys.UsageQuery
+	for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		s := v.Get(name)
+		if s == "" {
+			continue
+		}
+		var err error
+		if *t, err = time.Parse(time.RFC3339, s); err != nil {
+			return q, fmt.Errorf("bad %s `%s': %w", name, s, err)
+		}
+	}
+	switch p := v.Get("period"); p {
+	case "", "hour":
+	case "day":
+		q.Daily = true
+	default:
+		return q, fmt.Errorf("bad period `%s'", p)
+	}
+	return q, nil
+}
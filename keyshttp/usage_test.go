@@ -0,0 +1,63 @@
+package keyshttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/robinbryce/apikeys"
+)
+
+func TestUsageHandler(t *testing.T) {
+	store := apikeys.NewMemUsageStore()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.WriteUsage(context.Background(), []apikeys.UsageBucket{
+		{ClientID: "client-1", Start: start, Verifications: 1},
+		{ClientID: "client-1", Start: start.Add(time.Hour), Verifications: 2, Failures: 1},
+		{ClientID: "client-2", Start: start, Verifications: 4},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewUsageHandler(store, func(r *http.Request, op, clientID string) error {
+		if op != OpUsage || clientID == "secret" {
+			return errors.New("not yours")
+		}
+		return nil
+	})
+	type args struct {
+		path string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantCode int
+		wantOK   []int64
+	}{
+		{name: "hourly", args: args{path: "/client-1"}, wantCode: http.StatusOK, wantOK: []int64{1, 2}},
+		{name: "daily", args: args{path: "/client-1?period=day"}, wantCode: http.StatusOK, wantOK: []int64{3}},
+		{name: "since", args: args{path: "/client-1?since=2030-01-01T01:00:00Z"}, wantCode: http.StatusOK, wantOK: []int64{2}},
+		{name: "no usage", args: args{path: "/client-3"}, wantCode: http.StatusOK},
+		{name: "bad period", args: args{path: "/client-1?period=week"}, wantCode: http.StatusBadRequest},
+		{name: "bad until", args: args{path: "/client-1?until=soon"}, wantCode: http.StatusBadRequest},
+		{name: "forbidden", args: args{path: "/secret"}, wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buckets []apikeys.UsageBucket
+			code := do(t, h, "GET", tt.args.path, "", &buckets)
+			if code != tt.wantCode {
+				t.Fatalf("GET %s = %d, want %d", tt.args.path, code, tt.wantCode)
+			}
+			var ok []int64
+			for _, b := range buckets {
+				ok = append(ok, b.Verifications)
+			}
+			if !slices.Equal(ok, tt.wantOK) {
+				t.Errorf("GET %s verifications = %v, want %v", tt.args.path, ok, tt.wantOK)
+			}
+		})
+	}
+}
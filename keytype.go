@@ -0,0 +1,111 @@
+package apikeys
+
+import (
+	"fmt"
+	"time"
+)
+
+// KeyType classifies a key so that governance rules can differ by category
+type KeyType string
+
+// Key types. A key without a type is unclassified and gets no type defaults.
+const (
+	// KeyTypeService keys are held by software, eg a backend service
+	KeyTypeService KeyType = "service"
+	// KeyTypePersonal keys are held by a person
+	KeyTypePersonal KeyType = "personal"
+	// KeyTypeEphemeral keys are for a single job or session
+	KeyTypeEphemeral KeyType = "ephemeral"
+)
+
+// Valid reports whether t is one of the KeyType constants or empty
+func (t KeyType) Valid() bool {
+	switch t {
+	case "", KeyTypeService, KeyTypePersonal, KeyTypeEphemeral:
+		return true
+	}
+	return false
+}
+
+// KeyTypePolicy is the lifetime and rotation governance for a KeyType
+type KeyTypePolicy struct {
+	// TTL is the expiry given to new keys that don't set one, none if zero
+	TTL time.Duration
+	// MaxTTL bounds how far in the future a new key may expire, and forbids
+	// keys that never expire, unless zero
+	MaxTTL time.Duration
+	// RotateAfter is the age after which a key is due for rotation, see
+	// Admin.RotationDue, never if zero
+	RotateAfter time.Duration
+}
+
+// DefaultKeyTypePolicies are the policies used unless WithKeyTypePolicies
+// replaces them. Service keys are long lived but due for rotation yearly,
+// personal keys expire after 90 days and ephemeral keys after an hour.
+var DefaultKeyTypePolicies = map[KeyType]KeyTypePolicy{
+	KeyTypeService:   {RotateAfter: 365 * 24 * time.Hour},
+	KeyTypePersonal:  {TTL: 90 * 24 * time.Hour, MaxTTL: 365 * 24 * time.Hour, RotateAfter: 90 * 24 * time.Hour},
+	KeyTypeEphemeral: {TTL: time.Hour, MaxTTL: 24 * time.Hour},
+}
+
+// WithKeyType classifies the key
+func WithKeyType(t KeyType) KeyOption {
+	return func(ak *Key) {
+		ak.Type = t
+	}
+}
+
+// WithKeyTypePolicies replaces DefaultKeyTypePolicies. Types missing from
+// policies have no defaults.
+func WithKeyTypePolicies(policies map[KeyType]KeyTypePolicy) Option {
+	return func(o *options) {
+		o.keyTypePolicies = policies
+	}
+}
+
+// TypeFilter selects records of type t
+func TypeFilter(t KeyType) KeyFilter {
+	return func(ak Key) bool { return ak.Type == t }
+}
+
+func (o *options) keyTypePolicy(t KeyType) KeyTypePolicy {
+	if o.keyTypePolicies != nil {
+		return o.keyTypePolicies[t]
+	}
+	return DefaultKeyTypePolicies[t]
+}
+
+// applyKeyTypePolicy sets the default expiry of a new key and checks its
+// lifetime against the policy for its type
+func (o *options) applyKeyTypePolicy(ak *Key, now time.Time) error {
+	p := o.keyTypePolicy(ak.Type)
+	if ak.ExpiresAt.IsZero() && p.TTL > 0 {
+		ak.ExpiresAt = now.Add(p.TTL)
+	}
+	if p.MaxTTL <= 0 {
+		return nil
+	}
+	if ak.ExpiresAt.IsZero() {
+		return fmt.Errorf("%s keys must expire", ak.Type)
+	}
+	if ak.ExpiresAt.After(now.Add(p.MaxTTL)) {
+		return fmt.Errorf("%s keys must expire within %s", ak.Type, p.MaxTTL)
+	}
+	return nil
+}
+
+// RotationDue reports whether the secret of ak, issued when it was created or
+// last rotated, is older than the RotateAfter of its type's policy. Revoked
+// and expired keys are never due.
+func (a *Admin) RotationDue(ak Key) bool {
+	now := a.now()
+	p := a.keyTypePolicy(ak.Type)
+	issued := ak.CreatedAt
+	if ak.RotatedAt.After(issued) {
+		issued = ak.RotatedAt
+	}
+	if p.RotateAfter <= 0 || ak.Revoked() || ak.Expired(now) || issued.IsZero() {
+		return false
+	}
+	return !now.Before(issued.Add(p.RotateAfter))
+}
@@ -0,0 +1,69 @@
+package apikeys
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyTypeDefaults(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	admin := NewAdmin(NewMemStore(), WithClock(ClockFunc(func() time.Time { return now })))
+
+	type args struct {
+		opts []KeyOption
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantExpires time.Time
+		wantErr     bool
+	}{
+		{"unclassified", args{nil}, time.Time{}, false},
+		{"service", args{[]KeyOption{WithKeyType(KeyTypeService)}}, time.Time{}, false},
+		{"personal", args{[]KeyOption{WithKeyType(KeyTypePersonal)}}, now.Add(90 * 24 * time.Hour), false},
+		{"ephemeral", args{[]KeyOption{WithKeyType(KeyTypeEphemeral)}}, now.Add(time.Hour), false},
+		{"explicit expiry", args{[]KeyOption{WithKeyType(KeyTypeEphemeral), WithExpiresAt(now.Add(time.Minute))}}, now.Add(time.Minute), false},
+		{"beyond max ttl", args{[]KeyOption{WithKeyType(KeyTypeEphemeral), WithExpiresAt(now.Add(48 * time.Hour))}}, time.Time{}, true},
+		{"unknown type", args{[]KeyOption{WithKeyType("robot")}}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ak, err := admin.Create(t.Context(), testAlg, tt.args.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !ak.ExpiresAt.Equal(tt.wantExpires) {
+				t.Errorf("Create() ExpiresAt = %v, want %v", ak.ExpiresAt, tt.wantExpires)
+			}
+		})
+	}
+
+	keys, err := admin.List(t.Context(), TypeFilter(KeyTypeEphemeral))
+	if err != nil || len(keys) != 2 {
+		t.Errorf("List(TypeFilter) = %d keys, %v, want 2", len(keys), err)
+	}
+}
+
+func TestRotationDue(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	admin := NewAdmin(NewMemStore(), WithClock(ClockFunc(func() time.Time { return now })),
+		WithKeyTypePolicies(map[KeyType]KeyTypePolicy{KeyTypeService: {RotateAfter: 24 * time.Hour}}))
+	_, ak, err := admin.Create(t.Context(), testAlg, WithKeyType(KeyTypeService))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if admin.RotationDue(ak) {
+		t.Error("new key is due for rotation")
+	}
+	now = now.Add(24 * time.Hour)
+	if !admin.RotationDue(ak) {
+		t.Error("key is not due for rotation after RotateAfter")
+	}
+	_, ak, err = admin.Rotate(t.Context(), ak.ClientID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ak.RotatedAt.Equal(now) || admin.RotationDue(ak) {
+		t.Errorf("rotated key RotatedAt = %v, due = %v", ak.RotatedAt, admin.RotationDue(ak))
+	}
+}
@@ -0,0 +1,79 @@
+package apikeys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// DefaultSecretKey is the data field KubernetesSecret stores the api key
+// under when Key is empty
+const DefaultSecretKey = "apikey"
+
+var (
+	k8sNameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	k8sKeyRe  = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+)
+
+// KubernetesSecret describes the Secret manifest Render produces, so that a
+// generated key can be piped into kubectl apply
+type KubernetesSecret struct {
+	Name      string
+	Namespace string
+	// Key is the data field holding the api key, DefaultSecretKey if empty
+	Key    string
+	Labels map[string]string
+}
+
+type k8sMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type k8sSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+// Render writes an Opaque Secret manifest holding apikey. The value is base64
+// encoded in data rather than put in stringData, so applying it does not
+// leave the plain key in the last-applied annotation.
+func (s KubernetesSecret) Render(w io.Writer, apikey string) error {
+	key := s.Key
+	if key == "" {
+		key = DefaultSecretKey
+	}
+	if len(s.Name) > 253 || !k8sNameRe.MatchString(s.Name) {
+		return fmt.Errorf("bad kubernetes secret name `%s'", s.Name)
+	}
+	if s.Namespace != "" && (len(s.Namespace) > 63 || !k8sNameRe.MatchString(s.Namespace)) {
+		return fmt.Errorf("bad kubernetes namespace `%s'", s.Namespace)
+	}
+	if len(key) > 253 || !k8sKeyRe.MatchString(key) {
+		return fmt.Errorf("bad kubernetes secret key `%s'", key)
+	}
+	if apikey == "" {
+		return fmt.Errorf("empty api key")
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	err := enc.Encode(k8sSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   k8sMetadata{Name: s.Name, Namespace: s.Namespace, Labels: s.Labels},
+		Type:       "Opaque",
+		Data:       map[string]string{key: base64.StdEncoding.EncodeToString([]byte(apikey))},
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,63 @@
+package apikeys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestKubernetesSecretRender(t *testing.T) {
+	var buf bytes.Buffer
+	s := KubernetesSecret{Name: "service-apikey", Namespace: "prod", Labels: map[string]string{"app": "service"}}
+	if err := s.Render(&buf, "the-api-key"); err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: v1
+kind: Secret
+metadata:
+  name: service-apikey
+  namespace: prod
+  labels:
+    app: service
+type: Opaque
+data:
+  apikey: ` + base64.StdEncoding.EncodeToString([]byte("the-api-key")) + "\n"
+	if buf.String() != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", buf.String(), want)
+	}
+	var got k8sSecret
+	if err := yaml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "the-api-key") {
+		t.Error("manifest contains the plain api key")
+	}
+}
+
+func TestKubernetesSecretRenderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret KubernetesSecret
+		apikey string
+	}{
+		{"no name", KubernetesSecret{}, "k"},
+		{"upper case name", KubernetesSecret{Name: "Service"}, "k"},
+		{"bad namespace", KubernetesSecret{Name: "s", Namespace: "a_b"}, "k"},
+		{"bad key", KubernetesSecret{Name: "s", Key: "a/b"}, "k"},
+		{"no api key", KubernetesSecret{Name: "s"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.secret.Render(&buf, tt.apikey); err == nil {
+				t.Error("Render() error = nil")
+			}
+			if buf.Len() != 0 {
+				t.Errorf("Render() wrote %q on error", buf.String())
+			}
+		})
+	}
+}
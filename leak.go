@@ -0,0 +1,112 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// LeakPrefixLen is the number of hex digits of a hash a leak check reveals
+// to the LeakDatabase. Five digits put each query in one of about a million
+// ranges.
+const LeakPrefixLen = 5
+
+// ErrLeaked is returned when a new api key is found in a LeakDatabase
+var ErrLeaked = errors.New("api key found in a known leak")
+
+// LeakDatabase is a client of a breach database answering k-anonymity range
+// queries, in the style of the Pwned Passwords range api, so that the hashes
+// checked never leave the process. The database is expected to index
+// plaintext api keys by LeakHash and stored records by Key.Fingerprint,
+// either of which a scanner can compute for a leaked key it has verified.
+type LeakDatabase interface {
+	// LeakedSuffixes returns the remaining hex digits of every leaked hash
+	// beginning with prefix
+	LeakedSuffixes(ctx context.Context, prefix string) ([]string, error)
+}
+
+// LeakDatabaseFunc adapts a function to a LeakDatabase
+type LeakDatabaseFunc func(ctx context.Context, prefix string) ([]string, error)
+
+func (f LeakDatabaseFunc) LeakedSuffixes(ctx context.Context, prefix string) ([]string, error) {
+	return f(ctx, prefix)
+}
+
+// LeakHash is the hash of a plaintext api key a LeakDatabase is queried with,
+// its sha256 in hex
+func LeakHash(apikey string) string {
+	sum := sha256.Sum256([]byte(apikey))
+	return hex.EncodeToString(sum[:])
+}
+
+// Leaked reports whether hash, in hex, is in db, sending db only its first
+// LeakPrefixLen digits
+func Leaked(ctx context.Context, db LeakDatabase, hash string) (bool, error) {
+	if len(hash) <= LeakPrefixLen {
+		return false, nil
+	}
+	hash = strings.ToLower(hash)
+	suffixes, err := db.LeakedSuffixes(ctx, hash[:LeakPrefixLen])
+	if err != nil {
+		return false, err
+	}
+	for _, s := range suffixes {
+		if strings.ToLower(s) == hash[LeakPrefixLen:] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// WithLeakCheck has Admin check every api key it generates against db, so
+// that a key found in a leak, which can only mean a broken random source, is
+// never handed out. Such keys fail with ErrLeaked. If db can't be reached
+// the key is issued and a warning logged. To check stored keys set
+// SweepConfig.Leaks.
+func WithLeakCheck(db LeakDatabase) Option {
+	return func(o *options) {
+		o.leaks = db
+	}
+}
+
+// checkLeak returns ErrLeaked if the api key generated for ak is in the leak
+// database
+func (o *options) checkLeak(ctx context.Context, ak Key, apikey string) error {
+	if o.leaks == nil {
+		return nil
+	}
+	leaked, err := Leaked(ctx, o.leaks, LeakHash(apikey))
+	if err != nil {
+		o.warn(ctx, "leak check failed", slog.String("client_id", ak.ClientID), slog.Any("error", err))
+		return nil
+	}
+	if leaked {
+		return ErrLeaked
+	}
+	return nil
+}
+
+// leakRanges remembers the ranges a sweep has fetched, so that keys whose
+// fingerprints share a prefix cost one query
+type leakRanges struct {
+	db     LeakDatabase
+	ranges map[string][]string
+}
+
+func (r *leakRanges) LeakedSuffixes(ctx context.Context, prefix string) ([]string, error) {
+	if s, ok := r.ranges[prefix]; ok {
+		return s, nil
+	}
+	s, err := r.db.LeakedSuffixes(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if r.ranges == nil {
+		r.ranges = map[string][]string{}
+	}
+	r.ranges[prefix] = s
+	return s, nil
+}
@@ -0,0 +1,119 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeLeaks is a LeakDatabase of hashes, recording the prefixes it is asked
+// about
+type fakeLeaks struct {
+	hashes   []string
+	prefixes []string
+	err      error
+}
+
+func (f *fakeLeaks) LeakedSuffixes(ctx context.Context, prefix string) ([]string, error) {
+	f.prefixes = append(f.prefixes, prefix)
+	if f.err != nil {
+		return nil, f.err
+	}
+	var suffixes []string
+	for _, h := range f.hashes {
+		if s, ok := strings.CutPrefix(h, prefix); ok {
+			suffixes = append(suffixes, strings.ToUpper(s))
+		}
+	}
+	return suffixes, nil
+}
+
+func TestLeaked(t *testing.T) {
+	db := &fakeLeaks{hashes: []string{LeakHash("leaked-key")}}
+	type args struct {
+		hash string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"leaked", args{LeakHash("leaked-key")}, true},
+		{"upper case", args{strings.ToUpper(LeakHash("leaked-key"))}, true},
+		{"same prefix", args{LeakHash("leaked-key")[:LeakPrefixLen] + strings.Repeat("0", 59)}, false},
+		{"not leaked", args{LeakHash("other-key")}, false},
+		{"too short", args{"abc"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db.prefixes = nil
+			got, err := Leaked(context.Background(), db, tt.args.hash)
+			if err != nil || got != tt.want {
+				t.Errorf("Leaked() = %v, %v, want %v", got, err, tt.want)
+			}
+			for _, p := range db.prefixes {
+				if len(p) != LeakPrefixLen {
+					t.Errorf("database was sent %q", p)
+				}
+			}
+		})
+	}
+}
+
+func TestLeakCheck(t *testing.T) {
+	ctx := context.Background()
+	db := &fakeLeaks{hashes: []string{LeakHash("leaked-key")}}
+	o := newOptions([]Option{WithLeakCheck(db)})
+	if err := o.checkLeak(ctx, Key{}, "leaked-key"); !errors.Is(err, ErrLeaked) {
+		t.Errorf("checkLeak() = %v, want ErrLeaked", err)
+	}
+
+	admin := NewAdmin(NewMemStore(), WithLeakCheck(db))
+	db.prefixes = nil
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("client-1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.prefixes) != 1 {
+		t.Errorf("Create() made %d leak queries, want 1", len(db.prefixes))
+	}
+	db.err = errDown
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("client-2")); err != nil {
+		t.Errorf("Create() with the leak database down = %v", err)
+	}
+}
+
+func TestSweeperLeaks(t *testing.T) {
+	ctx := context.Background()
+	var reported []string
+	admin := NewAdmin(NewMemStore(), WithHooks(Hooks{OnLeaked: func(ctx context.Context, ak Key) {
+		reported = append(reported, ak.ClientID)
+	}}))
+	db := &fakeLeaks{}
+	for _, id := range []string{"client-1", "client-2", "client-3"} {
+		_, ak, err := admin.Create(ctx, testAlg, WithClientID(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != "client-2" {
+			db.hashes = append(db.hashes, ak.Fingerprint())
+		}
+	}
+	if _, err := admin.Revoke(ctx, "client-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := NewSweeper(admin, SweepConfig{Leaks: db, RevokeLeaked: true}).Sweep(ctx)
+	if err != nil || !slices.Equal(res.Leaked, []string{"client-1"}) || !slices.Equal(reported, res.Leaked) {
+		t.Errorf("Sweep() = %+v, %v, reported %v, want client-1 leaked", res, err, reported)
+	}
+	if ak, _ := admin.Get(ctx, "client-1"); !ak.Revoked() {
+		t.Error("leaked key not revoked")
+	}
+
+	db.err = errDown
+	if _, err := NewSweeper(admin, SweepConfig{Leaks: db}).Sweep(ctx); !errors.Is(err, errDown) {
+		t.Errorf("Sweep() with the leak database down = %v", err)
+	}
+}
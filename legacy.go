@@ -0,0 +1,332 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	pbkdf2Prefix = "$pbkdf2-"
+	phcPrefix    = "$" + phcArgon2ID + "$"
+	// bcryptSHA256Prefix marks a bcrypt hash of the hex sha256 of the secret,
+	// as created by Django's BCryptSHA256PasswordHasher
+	bcryptSHA256Prefix = "bcrypt_sha256$"
+
+	// These bound the work an imported hash can demand. They are looser than
+	// the bounds for new keys because imported hashes were chosen elsewhere.
+	pbkdf2MaxIter   = 10_000_000
+	importMaxTime   = 64
+	importMaxMemory = 1 << 20 // KB
+	importMinKeyLen = 4
+	importMaxKeyLen = 1024
+)
+
+// ErrUnsupportedHash is returned when importing a hash in an unknown format
+var ErrUnsupportedHash = errors.New("unsupported imported hash")
+
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+var pbkdf2Hashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// WithUpgradeAlg sets the alg that imported records are re-derived under
+// once they verify, StandardAlg by default
+func WithUpgradeAlg(alg string) Option {
+	return func(o *options) {
+		o.upgradeAlg = alg
+	}
+}
+
+// ImportHash returns a record for clientID that verifies secrets against a
+// hash created by another system, so existing credentials keep working
+// without being re-issued. The supported formats are
+//
+//	bcrypt:        $2a$, $2b$ or $2y$
+//	bcrypt sha256: bcrypt_sha256$<bcrypt hash of the hex sha256 of the secret>
+//	PBKDF2:        $pbkdf2-<sha1|sha256|sha512>$i=<iterations>,l=<length>$<salt>$<hash>
+//	argon2id:      PHC strings, see ParsePHC
+//
+// with PBKDF2 salt and hash in unpadded standard base64. ParseDjangoHash and
+// ParsePasslibHash convert framework hash strings to these formats. Imported
+// argon2id hashes may use up to 1GB of memory, more than new keys, so give
+// verifiers of imported records a MemoryBudget. Imported records are verified
+// with StoreVerifier.VerifySecret, which upgrades them on success. Reserved
+// client ids, see ReserveClientIDs, can't be imported.
+func ImportHash(clientID, hash string, opts ...KeyOption) (Key, error) {
+	if err := checkImported(hash); err != nil {
+		return Key{}, err
+	}
+	ak := Key{ClientID: clientID, ImportedHash: hash}
+	for _, o := range opts {
+		o(&ak)
+	}
+	if err := checkClientID(ak.ClientID); err != nil {
+		return Key{}, err
+	}
+	return ak, nil
+}
+
+// checkImported checks hash is in a supported format without verifying
+// anything against it
+func checkImported(hash string) error {
+	switch {
+	case hasAnyPrefix(hash, bcryptPrefixes):
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("%w: %v", ErrUnsupportedHash, err)
+		}
+		return nil
+	case strings.HasPrefix(hash, bcryptSHA256Prefix):
+		inner := strings.TrimPrefix(hash, bcryptSHA256Prefix)
+		if !hasAnyPrefix(inner, bcryptPrefixes) {
+			return ErrUnsupportedHash
+		}
+		return checkImported(inner)
+	case strings.HasPrefix(hash, pbkdf2Prefix):
+		_, _, _, _, err := parsePBKDF2(hash)
+		return err
+	case strings.HasPrefix(hash, phcPrefix):
+		_, err := parseImportedPHC(hash)
+		return err
+	}
+	return ErrUnsupportedHash
+}
+
+// parseImportedPHC parses an argon2id PHC string within the import bounds
+func parseImportedPHC(hash string) (phcHash, error) {
+	h, err := parsePHC(hash)
+	if err != nil {
+		return phcHash{}, fmt.Errorf("%w: %v", ErrUnsupportedHash, err)
+	}
+	if h.time > importMaxTime || h.memory > importMaxMemory ||
+		len(h.derived) < importMinKeyLen || len(h.derived) > importMaxKeyLen {
+		return phcHash{}, fmt.Errorf("%w: argon2id parameters exceed the import limits", ErrUnsupportedHash)
+	}
+	return h, nil
+}
+
+// verifyImported reports whether secret matches the imported hash. Imported
+// argon2id derivations acquire their memory from the budget like any other.
+func (v *StoreVerifier) verifyImported(ctx context.Context, hash string, secret []byte) (bool, error) {
+	switch {
+	case hasAnyPrefix(hash, bcryptPrefixes):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), secret)
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, bcryptSHA256Prefix):
+		inner := strings.TrimPrefix(hash, bcryptSHA256Prefix)
+		if !hasAnyPrefix(inner, bcryptPrefixes) {
+			return false, ErrUnsupportedHash
+		}
+		sum := sha256.Sum256(secret)
+		return v.verifyImported(ctx, inner, []byte(hex.EncodeToString(sum[:])))
+	case strings.HasPrefix(hash, pbkdf2Prefix):
+		h, iter, salt, want, err := parsePBKDF2(hash)
+		if err != nil {
+			return false, err
+		}
+		got, err := pbkdf2.Key(h, string(secret), salt, iter, len(want))
+		if err != nil {
+			return false, err
+		}
+		return subtle.ConstantTimeCompare(got, want) == 1, nil
+	case strings.HasPrefix(hash, phcPrefix):
+		h, err := parseImportedPHC(hash)
+		if err != nil {
+			return false, err
+		}
+		alg := h.alg()
+		release, err := v.acquire(ctx, alg)
+		if err != nil {
+			return false, err
+		}
+		defer release()
+		start := time.Now()
+		derived := argon2.IDKey(secret, h.salt, h.time, h.memory, h.threads, alg.KeyLen)
+		v.observeDerive(alg, time.Since(start))
+		return subtle.ConstantTimeCompare(derived, h.derived) == 1, nil
+	}
+	return false, ErrUnsupportedHash
+}
+
+// parsePBKDF2 parses $pbkdf2-<digest>$i=<iterations>,l=<length>$<salt>$<hash>
+func parsePBKDF2(s string) (func() hash.Hash, int, []byte, []byte, error) {
+	parts := strings.Split(s, "$")
+	if len(parts) != 5 || parts[0] != "" {
+		return nil, 0, nil, nil, fmt.Errorf("%w: bad pbkdf2 hash", ErrUnsupportedHash)
+	}
+	h, ok := pbkdf2Hashes[strings.TrimPrefix(parts[1], "pbkdf2-")]
+	if !ok {
+		return nil, 0, nil, nil, fmt.Errorf("%w: pbkdf2 digest `%s'", ErrUnsupportedHash, parts[1])
+	}
+	iter, length := 0, 0
+	for _, param := range strings.Split(parts[2], namedSep) {
+		name, value, _ := strings.Cut(param, namedAssign)
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, 0, nil, nil, fmt.Errorf("%w: bad pbkdf2 parameter `%s'", ErrUnsupportedHash, param)
+		}
+		switch name {
+		case "i":
+			iter = n
+		case "l":
+			length = n
+		default:
+			return nil, 0, nil, nil, fmt.Errorf("%w: unknown pbkdf2 parameter `%s'", ErrUnsupportedHash, param)
+		}
+	}
+	if iter == 0 || iter > pbkdf2MaxIter {
+		return nil, 0, nil, nil, fmt.Errorf("%w: pbkdf2 iterations must be 1-%d", ErrUnsupportedHash, pbkdf2MaxIter)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, 0, nil, nil, fmt.Errorf("%w: bad pbkdf2 salt: %v", ErrUnsupportedHash, err)
+	}
+	derived, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(derived) == 0 {
+		return nil, 0, nil, nil, fmt.Errorf("%w: bad pbkdf2 hash", ErrUnsupportedHash)
+	}
+	if length != 0 && length != len(derived) {
+		return nil, 0, nil, nil, fmt.Errorf("%w: pbkdf2 hash is %d bytes, l=%d", ErrUnsupportedHash, len(derived), length)
+	}
+	return h, iter, salt, derived, nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Import adds a record for a hash created by another system, see ImportHash
+func (a *Admin) Import(ctx context.Context, clientID, hash string, opts ...KeyOption) (Key, error) {
+	ak, err := ImportHash(clientID, hash, opts...)
+	if err != nil {
+		return Key{}, err
+	}
+	ak.CreatedAt = a.now()
+	if err := a.store.Create(ctx, ak); err != nil {
+		return Key{}, err
+	}
+	a.emit(ctx, AuditKeyImported, ak, nil)
+	return ak, nil
+}
+
+// VerifySecret verifies a client id and secret presented separately, eg with
+// basic auth, rather than as an encoded api key. It is the verification path
+// for records created by Import. An imported record that verifies is
+// upgraded: re-derived from the secret under the upgrade alg with a new salt,
+// its ImportedHash dropped and the store updated, so the legacy hash is only
+// consulted once. A failure to store the upgrade is logged and does not fail
+// the verification. Records that are not imported verify against their
+// stored alg and salt.
+func (v *StoreVerifier) VerifySecret(ctx context.Context, clientID string, secret []byte) (Key, error) {
+	start := time.Now()
+	ctx, span := v.startSpan(ctx, SpanVerify)
+	span.SetAttribute(AttrClientID, clientID)
+	ak, err := v.verifySecret(ctx, clientID, secret)
+	return v.report(ctx, span, start, Key{ClientID: clientID, alg: ak.alg}, ak, err)
+}
+
+func (v *StoreVerifier) verifySecret(ctx context.Context, clientID string, secret []byte) (Key, error) {
+	if err := v.admit(ctx); err != nil {
+		return Key{}, err
+	}
+	ak, err := v.load(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	if ak.ImportedHash == "" {
+		deprecated, err := v.deprecation(ak)
+		if errors.Is(err, ErrDeprecated) {
+			return Key{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+		} else if err != nil {
+			return Key{}, err
+		}
+		derived, err := v.derive(ctx, ak, secret)
+		if err != nil {
+			return Key{}, err
+		}
+		match := subtle.ConstantTimeCompare(derived, ak.DerivedKey) == 1
+		clear(derived)
+		if !match {
+			return Key{}, ErrMismatch
+		}
+		if err := v.usable(ctx, ak); err != nil {
+			return Key{}, err
+		}
+		if deprecated != nil {
+			v.observeDeprecated(ctx, ak, *deprecated)
+		}
+		return v.touch(ctx, ak), nil
+	}
+
+	ok, err := v.verifyImported(ctx, ak.ImportedHash, secret)
+	if err != nil {
+		return Key{}, err
+	}
+	if !ok {
+		return Key{}, ErrMismatch
+	}
+	if err := v.usable(ctx, ak); err != nil {
+		return Key{}, err
+	}
+	upgraded, err := v.upgrade(ctx, ak, secret)
+	if err != nil {
+		v.warn(ctx, "upgrading imported api key failed", slog.String("client_id", ak.ClientID), slog.Any("error", err))
+		return v.touch(ctx, ak), nil
+	}
+	return v.touch(ctx, upgraded), nil
+}
+
+// upgrade re-derives an imported record from its verified secret and stores
+// the result
+func (v *StoreVerifier) upgrade(ctx context.Context, ak Key, secret []byte) (Key, error) {
+	alg := v.upgradeAlg
+	if alg == "" {
+		alg = StandardAlg
+	}
+	upgraded := ak.clone()
+	if err := upgraded.SetAlg(alg); err != nil {
+		return Key{}, err
+	}
+	upgraded.Salt = make([]byte, saltLen)
+	if _, err := io.ReadFull(v.entropySource(), upgraded.Salt); err != nil {
+		return Key{}, fmt.Errorf("insufficient rand bytes generating salt: %w", err)
+	}
+	derived, err := v.derive(ctx, upgraded, secret)
+	if err != nil {
+		return Key{}, err
+	}
+	upgraded.DerivedKey = derived
+	upgraded.ImportedHash = ""
+	if err := v.store.Update(ctx, upgraded); err != nil {
+		return Key{}, err
+	}
+	v.emit(ctx, AuditKeyUpgraded, upgraded, nil)
+	return upgraded, nil
+}
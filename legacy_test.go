@@ -0,0 +1,161 @@
+package apikeys
+
+import (
+	"bytes"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testPBKDF2(t *testing.T, secret string) string {
+	t.Helper()
+	salt := []byte("0123456789abcdef")
+	dk, err := pbkdf2.Key(sha256.New, secret, salt, 1000, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("$pbkdf2-sha256$i=1000,l=32$%s$%s", enc.EncodeToString(salt), enc.EncodeToString(dk))
+}
+
+func TestImportAndUpgrade(t *testing.T) {
+	bc, err := bcrypt.GenerateFromPassword([]byte("bcrypt secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		hash   string
+		secret string
+	}{
+		{"bcrypt", string(bc), "bcrypt secret"},
+		{"pbkdf2", testPBKDF2(t, "pbkdf2 secret"), "pbkdf2 secret"},
+		{"argon2id", phcVectors[0].hash, phcVectors[0].password},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemStore()
+			var audit bytes.Buffer
+			if _, err := NewAdmin(store).Import(t.Context(), "legacy-1", tt.hash); err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			v := NewStoreVerifier(store, WithUpgradeAlg(testAlg), WithAudit(NewWriterAuditSink(&audit)))
+
+			if _, err := v.VerifySecret(t.Context(), "legacy-1", []byte(tt.secret+"x")); !errors.Is(err, ErrMismatch) {
+				t.Errorf("VerifySecret() wrong secret error = %v, want ErrMismatch", err)
+			}
+			ak, err := v.VerifySecret(t.Context(), "legacy-1", []byte(tt.secret))
+			if err != nil {
+				t.Fatalf("VerifySecret() error = %v", err)
+			}
+			if ak.ImportedHash != "" || ak.Alg().String != testAlg {
+				t.Errorf("VerifySecret() did not upgrade the record: %+v", ak)
+			}
+			stored, err := store.Get(t.Context(), "legacy-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.ImportedHash != "" || len(stored.Salt) != saltLen {
+				t.Errorf("stored record not upgraded: %+v", stored)
+			}
+
+			// The upgraded record still accepts the secret, without the
+			// legacy hash
+			if _, err := v.VerifySecret(t.Context(), "legacy-1", []byte(tt.secret)); err != nil {
+				t.Errorf("VerifySecret() after upgrade error = %v", err)
+			}
+			if _, err := v.VerifySecret(t.Context(), "legacy-1", []byte(tt.secret+"x")); !errors.Is(err, ErrMismatch) {
+				t.Errorf("VerifySecret() after upgrade wrong secret error = %v, want ErrMismatch", err)
+			}
+			upgrades := 0
+			for _, ev := range decodeEvents(t, audit.Bytes()) {
+				if ev.Type == AuditKeyUpgraded {
+					upgrades++
+				}
+			}
+			if upgrades != 1 {
+				t.Errorf("got %d %s audit events, want 1", upgrades, AuditKeyUpgraded)
+			}
+		})
+	}
+}
+
+func TestImportedMemoryBudget(t *testing.T) {
+	store := NewMemStore()
+	if _, err := NewAdmin(store).Import(t.Context(), "legacy-1", phcVectors[0].hash); err != nil {
+		t.Fatal(err)
+	}
+	// The hash needs 64MB, more than the whole budget
+	v := NewStoreVerifier(store, WithMemoryBudget(NewMemoryBudget(32<<20)))
+	if _, err := v.VerifySecret(t.Context(), "legacy-1", []byte(phcVectors[0].password)); err == nil {
+		t.Error("VerifySecret() over the memory budget succeeded")
+	}
+}
+
+func TestImportHashErrors(t *testing.T) {
+	type args struct {
+		hash string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"md5", args{"5f4dcc3b5aa765d61d8327deb882cf99"}, true},
+		{"bad bcrypt", args{"$2b$xx"}, true},
+		{"pbkdf2 digest", args{"$pbkdf2-md5$i=1000,l=32$c2FsdA$aGFzaA"}, true},
+		{"pbkdf2 iterations", args{"$pbkdf2-sha256$i=0$c2FsdA$aGFzaA"}, true},
+		{"pbkdf2 too many iterations", args{"$pbkdf2-sha256$i=100000000$c2FsdA$aGFzaA"}, true},
+		{"pbkdf2 length", args{"$pbkdf2-sha256$i=1000,l=32$c2FsdA$aGFzaA"}, true},
+		{"pbkdf2", args{"$pbkdf2-sha256$i=1000$c2FsdA$aGFzaA"}, false},
+		{"argon2id", args{phcVectors[1].hash}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportHash("legacy-1", tt.args.hash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ImportHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUnsupportedHash) {
+				t.Errorf("ImportHash() error = %v, want ErrUnsupportedHash", err)
+			}
+		})
+	}
+}
+
+func TestVerifySecretNative(t *testing.T) {
+	store := NewMemStore()
+	apikey, ak, err := NewAdmin(store).Create(t.Context(), testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counters := NewCounters()
+	v := NewStoreVerifier(store, WithLastUsed(0), WithMetrics(counters),
+		WithDeprecations(ParamDeprecation{Alg: testAlg}))
+	got, err := v.VerifySecret(t.Context(), ak.ClientID, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := store.Get(t.Context(), ak.ClientID)
+	if got.LastUsedAt.IsZero() || stored.LastUsedAt.IsZero() {
+		t.Errorf("VerifySecret() did not record the use")
+	}
+	if n := counters.Stats().Deprecated; n != 1 {
+		t.Errorf("Deprecated = %d, want 1", n)
+	}
+
+	v = NewStoreVerifier(store, WithDeprecations(ParamDeprecation{Alg: testAlg, Sunset: time.Unix(1, 0), Enforce: true}))
+	if _, err := v.VerifySecret(t.Context(), ak.ClientID, password); !errors.Is(err, ErrDeprecated) {
+		t.Errorf("VerifySecret() past sunset error = %v, want ErrDeprecated", err)
+	}
+}
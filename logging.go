@@ -0,0 +1,43 @@
+package apikeys
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithLogger sets the logger used for operational warnings, such as slow
+// verifications and audit sink failures. Nothing is logged by default.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithSlowVerifyThreshold logs a warning for every verification which takes
+// longer than d. It has no effect unless a logger is also configured. A zero
+// duration disables the warning.
+func WithSlowVerifyThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.slowVerify = d
+	}
+}
+
+func (o *options) warn(ctx context.Context, msg string, args ...any) {
+	if o.logger == nil {
+		return
+	}
+	o.logger.WarnContext(ctx, msg, args...)
+}
+
+func (o *options) checkSlowVerify(ctx context.Context, presented Key, result string, d time.Duration) {
+	if o.slowVerify <= 0 || d <= o.slowVerify {
+		return
+	}
+	o.warn(ctx, "slow api key verification",
+		slog.String("client_id", presented.ClientID),
+		slog.String("alg", presented.alg.String),
+		slog.String("result", result),
+		slog.Duration("duration", d),
+		slog.Duration("threshold", o.slowVerify))
+}
@@ -0,0 +1,66 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failingSink struct{}
+
+func (failingSink) Emit(ctx context.Context, ev AuditEvent) error {
+	return errors.New("disk full")
+}
+
+func TestLoggerWarnings(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantLines []string
+	}{
+		{"no logger", []Option{WithSlowVerifyThreshold(time.Nanosecond)}, nil},
+		{"fast enough", []Option{WithSlowVerifyThreshold(time.Hour)}, nil},
+		{"slow", []Option{WithSlowVerifyThreshold(time.Nanosecond)}, []string{"slow api key verification"}},
+		{"audit failure", []Option{WithAudit(failingSink{})}, []string{"audit sink failed", "audit sink failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := tt.opts
+			if tt.name != "no logger" {
+				opts = append(opts, WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+			}
+			store := NewMemStore()
+			apikey, _, err := NewAdmin(store, opts...).Create(ctx, testAlg, WithClientID("client-1"))
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if _, err := NewStoreVerifier(store, opts...).Verify(ctx, apikey); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			var lines []string
+			for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if l != "" {
+					lines = append(lines, l)
+				}
+			}
+			if len(lines) != len(tt.wantLines) {
+				t.Fatalf("logged %v, want %v", lines, tt.wantLines)
+			}
+			for i, want := range tt.wantLines {
+				if !strings.Contains(lines[i], want) || !strings.Contains(lines[i], "level=WARN") {
+					t.Errorf("line %d = %s, want warning containing %s", i, lines[i], want)
+				}
+				if !strings.Contains(lines[i], "client_id=client-1") {
+					t.Errorf("line %d = %s, want client_id", i, lines[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package apikeys
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// lookupTagDomain keeps the tag distinct from any other sha256 of a secret
+const lookupTagDomain = "apikeys lookup tag v1\x00"
+
+// lookupTag is the first n bytes of a sha256 over salt and password
+func lookupTag(password, salt []byte, n uint32) []byte {
+	h := sha256.New()
+	h.Write([]byte(lookupTagDomain))
+	h.Write(binary.AppendUvarint(nil, uint64(len(salt))))
+	h.Write(salt)
+	h.Write(password)
+	return h.Sum(nil)[:n]
+}
+
+// LookupTag returns the fast tag at the front of the derived key of a record
+// whose alg has a LookupLen, or nil. A store can index records by it and
+// find the one for a presented key with PresentedLookupTag.
+//
+// The tag is a plain hash, so it is only safe because generated secrets are
+// random and far too long to guess; it would undo argon2 for user chosen
+// passwords.
+func (ak Key) LookupTag() []byte {
+	n := int(ak.alg.LookupLen)
+	if n == 0 || len(ak.DerivedKey) <= n {
+		return nil
+	}
+	return ak.DerivedKey[:n]
+}
+
+// PresentedLookupTag decodes apikey and returns its fast tag, without running
+// argon2. It returns nil if the key's alg has no LookupLen.
+func PresentedLookupTag(apikey string, opts ...DecodeOption) ([]byte, error) {
+	presented, password, err := Decode(apikey, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer password.Wipe()
+	if presented.alg.LookupLen == 0 {
+		return nil, nil
+	}
+	return lookupTag(password, presented.Salt, presented.alg.LookupLen), nil
+}
+
+// lookupMisses reports whether the presented key's fast tag matches none of
+// the secrets of candidates it could verify against, so that the derivation
+// can be skipped
+func lookupMisses(presented Key, password []byte, candidates []Key, inRotation func(Key) bool) bool {
+	n := presented.alg.LookupLen
+	if n == 0 {
+		return false
+	}
+	tag := lookupTag(password, presented.Salt, n)
+	hit := func(stored []byte) bool {
+		return len(stored) > int(n) && subtle.ConstantTimeCompare(tag, stored[:n]) == 1
+	}
+	for _, ak := range candidates {
+		if ak.TenantID != presented.TenantID {
+			continue
+		}
+		if hit(ak.DerivedKey) || (inRotation(ak) && hit(ak.PreviousDerivedKey)) {
+			return false
+		}
+	}
+	return true
+}
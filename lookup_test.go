@@ -0,0 +1,83 @@
+package apikeys
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLookupTag(t *testing.T) {
+	ctx := t.Context()
+	const lookupAlg = "argon2id t=1,m=16MB,len=16,lookup=8"
+	store := NewMemStore()
+	counters := NewCounters()
+	admin := NewAdmin(store)
+	verifier := NewStoreVerifier(store, WithMetrics(counters))
+	apikey, ak, err := admin.Create(ctx, lookupAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ak.DerivedKey) != 8+16 {
+		t.Errorf("derived key is %d bytes, want the tag and the argon2 key", len(ak.DerivedKey))
+	}
+	tag, err := PresentedLookupTag(apikey)
+	if err != nil || !bytes.Equal(tag, ak.LookupTag()) || len(tag) != 8 {
+		t.Errorf("PresentedLookupTag() = %x, %v, want the stored %x", tag, err, ak.LookupTag())
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if n := counters.Stats().Derivations; n != 1 {
+		t.Errorf("%d derivations verifying, want 1", n)
+	}
+
+	// The right client and salt with the wrong secret fails on the tag
+	presented, _, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := presented.encode(bytes.Repeat([]byte{1}, passwordLen))
+	if _, err := verifier.Verify(ctx, wrong); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() of a wrong secret = %v, want ErrMismatch", err)
+	}
+	if n := counters.Stats().Derivations; n != 1 {
+		t.Errorf("%d derivations after a wrong secret, want the tag to skip argon2", n)
+	}
+
+	// The previous secret keeps verifying through a graceful rotation to an
+	// alg without a tag
+	apikey2, _, err := admin.RotateGracefully(ctx, "client-1", testAlg, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{apikey, apikey2} {
+		if _, err := verifier.Verify(ctx, k); err != nil {
+			t.Errorf("Verify() in rotation error = %v", err)
+		}
+	}
+	if tag, err := PresentedLookupTag(apikey2); err != nil || tag != nil {
+		t.Errorf("PresentedLookupTag() without a lookup = %x, %v", tag, err)
+	}
+
+	type args struct {
+		alg string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"ok", args{lookupAlg}, false},
+		{"too short", args{"argon2id t=1,m=16MB,len=16,lookup=3"}, true},
+		{"too long", args{"argon2id t=1,m=16MB,len=16,lookup=17"}, true},
+		{"not a number", args{"argon2id t=1,m=16MB,len=16,lookup=x"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAlg(tt.args.alg); (err != nil) != tt.wantErr {
+				t.Errorf("ParseAlg() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
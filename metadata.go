@@ -0,0 +1,165 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"unicode/utf8"
+)
+
+// Metadata limits enforced when a key is created
+const (
+	MaxNameLen        = 128
+	MaxDescriptionLen = 1024
+	MaxOwnerLen       = 256
+	MaxLabels         = 64
+	MaxLabelLen       = 256
+)
+
+// WithName sets a human readable name for the key. Names are unique within
+// a tenant; Admin.Create and Admin.Transfer refuse a name another key in the
+// tenant already has. A record has exactly one client id, so keys that are
+// rotated independently, eg "CI pipeline key" and "production backend key",
+// are separate client ids, grouped by Owner, Team or Labels.
+func WithName(name string) KeyOption {
+	return func(ak *Key) {
+		ak.Name = name
+	}
+}
+
+// WithDescription sets a free text description of the key
+func WithDescription(description string) KeyOption {
+	return func(ak *Key) {
+		ak.Description = description
+	}
+}
+
+// WithOwner sets who is responsible for the key, eg an email address
+func WithOwner(owner string) KeyOption {
+	return func(ak *Key) {
+		ak.Owner = owner
+	}
+}
+
+// WithLabels adds labels to the key. The map is copied.
+func WithLabels(labels map[string]string) KeyOption {
+	return func(ak *Key) {
+		if ak.Labels == nil {
+			ak.Labels = make(map[string]string, len(labels))
+		}
+		maps.Copy(ak.Labels, labels)
+	}
+}
+
+func (ak *Key) validateMetadata() error {
+	for _, f := range []struct {
+		name, v string
+		max     int
+	}{
+		{"name", ak.Name, MaxNameLen},
+		{"description", ak.Description, MaxDescriptionLen},
+		{"owner", ak.Owner, MaxOwnerLen},
+		{"creator", ak.CreatedBy, MaxOwnerLen},
+		{"team", ak.Team, MaxOwnerLen},
+	} {
+		if len(f.v) > f.max || !utf8.ValidString(f.v) {
+			return fmt.Errorf("bad key %s, must be utf8 and at most %d bytes", f.name, f.max)
+		}
+	}
+	if len(ak.Labels) > MaxLabels {
+		return fmt.Errorf("too many labels. got %d, max=%d", len(ak.Labels), MaxLabels)
+	}
+	for k, v := range ak.Labels {
+		if k == "" || len(k) > MaxLabelLen || !printable(k) {
+			return fmt.Errorf("bad label name %q", k)
+		}
+		if len(v) > MaxLabelLen || !utf8.ValidString(v) {
+			return fmt.Errorf("bad value for label `%s'", k)
+		}
+	}
+	return nil
+}
+
+// NameLookup is optionally implemented by a Store that can find a record by
+// name without listing, eg using an index on tenant id and name
+type NameLookup interface {
+	// GetByName returns the record of tenantID named name, or ErrNotFound
+	GetByName(ctx context.Context, tenantID, name string) (Key, error)
+}
+
+// GetByName returns the record of tenantID named name, or ErrNotFound. Names
+// are unique within a tenant, see Admin.Create.
+func (a *Admin) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	if nl, ok := supports[NameLookup](a.store); ok {
+		return nl.GetByName(ctx, tenantID, name)
+	}
+	keys, err := a.store.List(ctx)
+	if err != nil {
+		return Key{}, err
+	}
+	for _, ak := range keys {
+		if ak.TenantID == tenantID && ak.Name == name {
+			return ak, nil
+		}
+	}
+	return Key{}, ErrNotFound
+}
+
+// checkNameFree returns an error wrapping ErrExists if another record of the
+// tenant of ak has its name
+func (a *Admin) checkNameFree(ctx context.Context, ak Key) error {
+	if ak.Name == "" {
+		return nil
+	}
+	existing, err := a.GetByName(ctx, ak.TenantID, ak.Name)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	case existing.ClientID != ak.ClientID:
+		return fmt.Errorf("%w: name `%s' is used by `%s'", ErrExists, ak.Name, existing.ClientID)
+	}
+	return nil
+}
+
+// KeyFilter selects records, see Admin.List
+type KeyFilter func(Key) bool
+
+// NameFilter selects records named name
+func NameFilter(name string) KeyFilter {
+	return func(ak Key) bool { return ak.Name == name }
+}
+
+// OwnerFilter selects records owned by owner
+func OwnerFilter(owner string) KeyFilter {
+	return func(ak Key) bool { return ak.Owner == owner }
+}
+
+// LabelFilter selects records with the label name set to value
+func LabelFilter(name, value string) KeyFilter {
+	return func(ak Key) bool {
+		v, ok := ak.Labels[name]
+		return ok && v == value
+	}
+}
+
+// FilterKeys returns the keys selected by every filter, reusing the backing
+// array of keys
+func FilterKeys(keys []Key, filters ...KeyFilter) []Key {
+	if len(filters) == 0 {
+		return keys
+	}
+	selected := keys[:0]
+next:
+	for _, ak := range keys {
+		for _, f := range filters {
+			if !f(ak) {
+				continue next
+			}
+		}
+		selected = append(selected, ak)
+	}
+	return selected
+}
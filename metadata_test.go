@@ -0,0 +1,134 @@
+package apikeys
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyMetadata(t *testing.T) {
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	labels := map[string]string{"env": "prod", "team": "payments"}
+	_, ak, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"),
+		WithName("production backend key"), WithDescription("checkout service"), WithOwner("ops@example.com"), WithLabels(labels))
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels["env"] = "changed"
+	if ak.Labels["env"] != "prod" {
+		t.Error("WithLabels did not copy the map")
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-2"), WithLabels(map[string]string{"env": "dev"})); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Get(t.Context(), "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "production backend key" || got.Description != "checkout service" || got.Owner != "ops@example.com" ||
+		!reflect.DeepEqual(got.Labels, map[string]string{"env": "prod", "team": "payments"}) {
+		t.Errorf("stored metadata = %+v", got)
+	}
+
+	type args struct {
+		filters []KeyFilter
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{"all", args{nil}, []string{"client-1", "client-2"}},
+		{"label", args{[]KeyFilter{LabelFilter("env", "dev")}}, []string{"client-2"}},
+		{"label and owner", args{[]KeyFilter{LabelFilter("env", "prod"), OwnerFilter("ops@example.com")}}, []string{"client-1"}},
+		{"name", args{[]KeyFilter{NameFilter("production backend key")}}, []string{"client-1"}},
+		{"none", args{[]KeyFilter{LabelFilter("team", "")}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := admin.List(t.Context(), tt.args.filters...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, ak := range keys {
+				ids = append(ids, ak.ClientID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("List() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyMetadataInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  KeyOption
+	}{
+		{"long name", WithName(strings.Repeat("n", MaxNameLen+1))},
+		{"bad utf8", WithDescription("\xff")},
+		{"empty label name", WithLabels(map[string]string{"": "v"})},
+		{"space in label name", WithLabels(map[string]string{"a b": "v"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKey(testAlg, tt.opt); err == nil {
+				t.Error("NewKey() error = nil")
+			}
+		})
+	}
+}
+
+func TestKeyNamesUniquePerTenant(t *testing.T) {
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("ci"), WithName("CI pipeline key"), WithTenant("acme")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("backend"), WithName("production backend key"), WithTenant("acme")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithName("CI pipeline key"), WithTenant("acme")); !errors.Is(err, ErrExists) {
+		t.Errorf("Create() duplicate name error = %v, want %v", err, ErrExists)
+	}
+	// The same name in another tenant is fine
+	if _, _, err := admin.Create(t.Context(), testAlg, WithName("CI pipeline key"), WithTenant("other")); err != nil {
+		t.Errorf("Create() in another tenant error = %v", err)
+	}
+	// The store enforces it too, for callers that bypass Admin
+	if err := store.Create(t.Context(), Key{ClientID: "direct", TenantID: "acme", Name: "CI pipeline key"}); !errors.Is(err, ErrExists) {
+		t.Errorf("MemStore.Create() duplicate name error = %v, want %v", err, ErrExists)
+	}
+
+	ak, err := admin.GetByName(t.Context(), "acme", "CI pipeline key")
+	if err != nil || ak.ClientID != "ci" {
+		t.Errorf("GetByName() = %v, %v", ak.ClientID, err)
+	}
+	if _, err := admin.GetByName(t.Context(), "acme", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByName() missing error = %v, want %v", err, ErrNotFound)
+	}
+
+	// Named keys rotate independently
+	if _, _, err := admin.Rotate(t.Context(), ak.ClientID, ""); err != nil {
+		t.Fatal(err)
+	}
+	backend, _ := admin.GetByName(t.Context(), "acme", "production backend key")
+	if backend.ClientID != "backend" {
+		t.Errorf("GetByName() after rotate = %v", backend.ClientID)
+	}
+}
+
+func TestGetByNameWithoutLookup(t *testing.T) {
+	// A wrapped store doesn't expose NameLookup, so List is used
+	admin := NewAdmin(TenantStore(NewMemStore(), "acme"))
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("ci"), WithName("ci"), WithTenant("acme")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithName("ci"), WithTenant("acme")); !errors.Is(err, ErrExists) {
+		t.Errorf("Create() duplicate name error = %v, want %v", err, ErrExists)
+	}
+}
@@ -0,0 +1,132 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Verification results reported to Metrics
+const (
+	ResultOK         = "ok"
+	ResultMismatch   = "mismatch"
+	ResultRevoked    = "revoked"
+	ResultExpired    = "expired"
+	ResultPending    = "pending"
+	ResultNotFound   = "not_found"
+	ResultInvalid    = "invalid"
+	ResultOverloaded = "overloaded"
+	ResultCanceled   = "canceled"
+	ResultError      = "error"
+)
+
+// Metrics receives measurements from Admin, StoreVerifier and the store
+// wrapper they install. See the apikeysprom package for a prometheus
+// implementation. Implementations must be safe for concurrent use and should
+// not block.
+type Metrics interface {
+	// ObserveGenerate is called once per key generated by Admin
+	ObserveGenerate(alg string, err error)
+	// ObserveVerify is called once per verification with one of the Result
+	// constants and the total time taken.
+	ObserveVerify(result string, d time.Duration)
+	// ObserveDerive is called for every argon2 derivation, both when
+	// generating and when verifying, with the canonical form of the alg of
+	// the record, see Alg.Canonical.
+	ObserveDerive(alg string, d time.Duration)
+	// ObserveStore is called for every store operation. op is the Store
+	// method name.
+	ObserveStore(op string, d time.Duration, err error)
+	// ObserveQueue is called by a Deriver as each derivation leaves the queue
+	// with the remaining queue depth and how long the derivation waited.
+	ObserveQueue(depth int, wait time.Duration)
+}
+
+// AlgMetrics is optionally implemented by a Metrics to break the cost of
+// derivations down by alg: each derivation is reported with its duration and
+// the bytes of memory it committed, zero for the keyed and registered algs.
+type AlgMetrics interface {
+	ObserveAlgDerive(alg string, d time.Duration, memory int64)
+}
+
+// WithMetrics enables reporting of verification, derivation and store
+// measurements to m.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// VerifyResult classifies a Verify error as one of the Result constants
+func VerifyResult(err error) string {
+	switch {
+	case err == nil:
+		return ResultOK
+	case errors.Is(err, ErrMismatch):
+		return ResultMismatch
+	case errors.Is(err, ErrRevoked):
+		return ResultRevoked
+	case errors.Is(err, ErrExpired):
+		return ResultExpired
+	case errors.Is(err, ErrPendingApproval):
+		return ResultPending
+	case errors.Is(err, ErrNotFound):
+		return ResultNotFound
+	case errors.Is(err, ErrInvalid):
+		return ResultInvalid
+	case errors.Is(err, ErrOverloaded), errors.Is(err, ErrCircuitOpen):
+		return ResultOverloaded
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ResultCanceled
+	}
+	return ResultError
+}
+
+// MeasureStore wraps store so that the duration of each operation is reported
+// to m.
+func MeasureStore(store Store, m Metrics) Store {
+	if m == nil {
+		return store
+	}
+	return &measuredStore{store: store, metrics: m}
+}
+
+type measuredStore struct {
+	store   Store
+	metrics Metrics
+}
+
+func (s *measuredStore) Create(ctx context.Context, ak Key) error {
+	start := time.Now()
+	err := s.store.Create(ctx, ak)
+	s.metrics.ObserveStore("Create", time.Since(start), err)
+	return err
+}
+
+func (s *measuredStore) Get(ctx context.Context, clientID string) (Key, error) {
+	start := time.Now()
+	ak, err := s.store.Get(ctx, clientID)
+	s.metrics.ObserveStore("Get", time.Since(start), err)
+	return ak, err
+}
+
+func (s *measuredStore) Update(ctx context.Context, ak Key) error {
+	start := time.Now()
+	err := s.store.Update(ctx, ak)
+	s.metrics.ObserveStore("Update", time.Since(start), err)
+	return err
+}
+
+func (s *measuredStore) Delete(ctx context.Context, clientID string) error {
+	start := time.Now()
+	err := s.store.Delete(ctx, clientID)
+	s.metrics.ObserveStore("Delete", time.Since(start), err)
+	return err
+}
+
+func (s *measuredStore) List(ctx context.Context) ([]Key, error) {
+	start := time.Now()
+	keys, err := s.store.List(ctx)
+	s.metrics.ObserveStore("List", time.Since(start), err)
+	return keys, err
+}
@@ -0,0 +1,178 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// ErrMigrateConflict is returned by Migrate when the destination already has
+// a different record for a client id and overwriting was not requested
+var ErrMigrateConflict = errors.New("migration conflict")
+
+// MigrateOption configures Migrate
+type MigrateOption func(*migrateOptions)
+
+type migrateOptions struct {
+	after      string
+	checkpoint func(ctx context.Context, clientID string) error
+	dryRun     bool
+	overwrite  bool
+}
+
+// WithResumeAfter skips records up to and including clientID, the last
+// checkpoint of an interrupted migration
+func WithResumeAfter(clientID string) MigrateOption {
+	return func(o *migrateOptions) {
+		o.after = clientID
+	}
+}
+
+// WithCheckpoint calls fn with the client id of each record once it has been
+// migrated and verified, so progress can be persisted and passed to
+// WithResumeAfter. An error from fn stops the migration.
+func WithCheckpoint(fn func(ctx context.Context, clientID string) error) MigrateOption {
+	return func(o *migrateOptions) {
+		o.checkpoint = fn
+	}
+}
+
+// WithDryRun reads and checks everything without writing to the destination
+func WithDryRun() MigrateOption {
+	return func(o *migrateOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithOverwrite replaces conflicting destination records instead of failing
+func WithOverwrite() MigrateOption {
+	return func(o *migrateOptions) {
+		o.overwrite = true
+	}
+}
+
+// MigrateResult reports what Migrate did, or in a dry run would have done
+type MigrateResult struct {
+	// Migrated records were written to the destination
+	Migrated int
+	// Skipped records were already present and identical in the destination
+	Skipped int
+	// Conflicts are the client ids with a different record in the destination
+	Conflicts []string
+	// Checkpoint is the client id of the last record processed
+	Checkpoint string
+}
+
+// Migrate copies every record from src to dst in client id order. Each
+// record written is read back and compared, so a destination that can not
+// hold a record faithfully, eg one that drops the salt, fails the migration
+// at that record rather than silently. Times are compared to millisecond
+// precision. Records already identical in dst are skipped, so an
+// interrupted migration can simply be run again, or resumed from a
+// checkpoint with WithResumeAfter.
+func Migrate(ctx context.Context, src, dst Store, opts ...MigrateOption) (MigrateResult, error) {
+	var o migrateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	keys, err := src.List(ctx)
+	if err != nil {
+		return MigrateResult{}, fmt.Errorf("listing source: %w", err)
+	}
+
+	var res MigrateResult
+	for _, ak := range keys {
+		if o.after != "" && ak.ClientID <= o.after {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if err := migrateOne(ctx, dst, ak, &o, &res); err != nil {
+			return res, err
+		}
+		res.Checkpoint = ak.ClientID
+		if o.checkpoint != nil && !o.dryRun {
+			if err := o.checkpoint(ctx, ak.ClientID); err != nil {
+				return res, err
+			}
+		}
+	}
+	if len(res.Conflicts) > 0 {
+		return res, fmt.Errorf("%w: %d records differ in the destination", ErrMigrateConflict, len(res.Conflicts))
+	}
+	return res, nil
+}
+
+func migrateOne(ctx context.Context, dst Store, ak Key, o *migrateOptions, res *MigrateResult) error {
+	existing, err := dst.Get(ctx, ak.ClientID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		if o.dryRun {
+			res.Migrated++
+			return nil
+		}
+		err = dst.Create(ctx, ak)
+	case err != nil:
+		return fmt.Errorf("reading destination `%s': %w", ak.ClientID, err)
+	case sameRecord(existing, ak):
+		res.Skipped++
+		return nil
+	case !o.overwrite:
+		// Keep going in a dry run so every conflict is reported
+		res.Conflicts = append(res.Conflicts, ak.ClientID)
+		if o.dryRun {
+			return nil
+		}
+		return fmt.Errorf("%w: `%s'", ErrMigrateConflict, ak.ClientID)
+	default:
+		if o.dryRun {
+			res.Migrated++
+			return nil
+		}
+		err = dst.Update(ctx, ak)
+	}
+	if err != nil {
+		return fmt.Errorf("writing `%s': %w", ak.ClientID, err)
+	}
+
+	written, err := dst.Get(ctx, ak.ClientID)
+	if err != nil {
+		return fmt.Errorf("reading back `%s': %w", ak.ClientID, err)
+	}
+	if !sameRecord(written, ak) {
+		return fmt.Errorf("record `%s' read back from the destination differs from the source", ak.ClientID)
+	}
+	res.Migrated++
+	return nil
+}
+
+// sameRecord compares everything a store persists, with times to millisecond
+// precision
+func sameRecord(a, b Key) bool {
+	sameTime := func(x, y time.Time) bool {
+		return x.Truncate(time.Millisecond).Equal(y.Truncate(time.Millisecond))
+	}
+	return a.ClientID == b.ClientID &&
+		a.TenantID == b.TenantID &&
+		a.Name == b.Name && a.Description == b.Description && a.Owner == b.Owner &&
+		maps.Equal(a.Labels, b.Labels) && a.Type == b.Type &&
+		a.CreatedBy == b.CreatedBy && a.Team == b.Team &&
+		a.Approval == b.Approval && a.ReviewedBy == b.ReviewedBy &&
+		a.Restrictions.equal(b.Restrictions) &&
+		sameTime(a.RotatedAt, b.RotatedAt) &&
+		a.alg.String == b.alg.String &&
+		bytes.Equal(a.Salt, b.Salt) &&
+		bytes.Equal(a.DerivedKey, b.DerivedKey) &&
+		bytes.Equal(a.PreviousDerivedKey, b.PreviousDerivedKey) &&
+		sameTime(a.PreviousExpiresAt, b.PreviousExpiresAt) &&
+		a.ImportedHash == b.ImportedHash &&
+		sameTime(a.CreatedAt, b.CreatedAt) &&
+		sameTime(a.RevokedAt, b.RevokedAt) &&
+		sameTime(a.ExpiresAt, b.ExpiresAt) &&
+		sameTime(a.LastUsedAt, b.LastUsedAt) &&
+		a.IdleTimeoutSeconds == b.IdleTimeoutSeconds
+}
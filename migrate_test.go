@@ -0,0 +1,119 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func migrateSource(t *testing.T) *MemStore {
+	t.Helper()
+	src := NewMemStore()
+	admin := NewAdmin(src)
+	for _, id := range []string{"client-1", "client-2", "client-3"} {
+		if _, _, err := admin.Create(t.Context(), testAlg, WithClientID(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return src
+}
+
+func TestMigrate(t *testing.T) {
+	src := migrateSource(t)
+	dst := NewMemStore()
+
+	var checkpoints []string
+	res, err := Migrate(t.Context(), src, dst, WithCheckpoint(func(ctx context.Context, clientID string) error {
+		checkpoints = append(checkpoints, clientID)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Migrated != 3 || res.Checkpoint != "client-3" {
+		t.Errorf("Migrate() = %+v", res)
+	}
+	if want := []string{"client-1", "client-2", "client-3"}; !reflect.DeepEqual(checkpoints, want) {
+		t.Errorf("checkpoints = %v, want %v", checkpoints, want)
+	}
+	want, _ := src.List(t.Context())
+	got, _ := dst.List(t.Context())
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("destination = %v, want %v", got, want)
+	}
+
+	// Running again is a no-op
+	res, err = Migrate(t.Context(), src, dst)
+	if err != nil || res.Migrated != 0 || res.Skipped != 3 {
+		t.Errorf("Migrate() again = %+v, %v", res, err)
+	}
+}
+
+func TestMigrateResume(t *testing.T) {
+	src := migrateSource(t)
+	dst := NewMemStore()
+	stop := errors.New("stop")
+	res, err := Migrate(t.Context(), src, dst, WithCheckpoint(func(ctx context.Context, clientID string) error {
+		if clientID == "client-2" {
+			return stop
+		}
+		return nil
+	}))
+	if !errors.Is(err, stop) || res.Checkpoint != "client-2" {
+		t.Fatalf("Migrate() = %+v, %v", res, err)
+	}
+	res, err = Migrate(t.Context(), src, dst, WithResumeAfter(res.Checkpoint))
+	if err != nil || res.Migrated != 1 || res.Skipped != 0 {
+		t.Errorf("Migrate() resumed = %+v, %v", res, err)
+	}
+	if got, _ := dst.List(t.Context()); len(got) != 3 {
+		t.Errorf("destination has %d records", len(got))
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	src := migrateSource(t)
+	dst := NewMemStore()
+	if err := dst.Create(t.Context(), Key{ClientID: "client-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Migrate(t.Context(), src, dst, WithDryRun())
+	if !errors.Is(err, ErrMigrateConflict) {
+		t.Fatalf("Migrate() error = %v, want %v", err, ErrMigrateConflict)
+	}
+	if res.Migrated != 2 || !reflect.DeepEqual(res.Conflicts, []string{"client-2"}) {
+		t.Errorf("Migrate() = %+v", res)
+	}
+	if got, _ := dst.List(t.Context()); len(got) != 1 {
+		t.Errorf("dry run wrote %d records", len(got))
+	}
+
+	// Without a dry run the conflict stops the migration
+	res, err = Migrate(t.Context(), src, dst)
+	if !errors.Is(err, ErrMigrateConflict) || res.Checkpoint != "client-1" {
+		t.Errorf("Migrate() = %+v, %v", res, err)
+	}
+
+	res, err = Migrate(t.Context(), src, dst, WithOverwrite())
+	if err != nil || res.Migrated != 2 || res.Skipped != 1 {
+		t.Errorf("Migrate() overwrite = %+v, %v", res, err)
+	}
+}
+
+// lossyStore drops the salt, as a destination with a bad schema might
+type lossyStore struct{ *MemStore }
+
+func (s lossyStore) Create(ctx context.Context, ak Key) error {
+	ak.Salt = nil
+	return s.MemStore.Create(ctx, ak)
+}
+
+func TestMigrateReadBack(t *testing.T) {
+	src := migrateSource(t)
+	res, err := Migrate(t.Context(), src, lossyStore{NewMemStore()})
+	if err == nil || res.Migrated != 0 {
+		t.Errorf("Migrate() = %+v, %v, want read back failure", res, err)
+	}
+}
@@ -0,0 +1,61 @@
+package apikeys
+
+import (
+	"context"
+)
+
+// CandidateFunc returns the records that a presented key for clientID may
+// match, eg every active key of a client held under separate records
+type CandidateFunc func(ctx context.Context, clientID string) ([]Key, error)
+
+// MultiVerifier verifies a presented key against several candidate records,
+// deriving it once and returning the Identity of the record that matched.
+// The current secret of every candidate is compared, and the previous secret
+// of those in a graceful rotation. Revoked, pending and expired candidates
+// are skipped; if none are left the reason the first was unusable is
+// returned. Last use is not recorded, see WithLastUsed.
+type MultiVerifier struct {
+	v          *StoreVerifier
+	candidates CandidateFunc
+}
+
+var (
+	_ Verifier = (*MultiVerifier)(nil)
+	_ Verifier = VerifierFunc(nil)
+)
+
+// NewMultiVerifier creates a MultiVerifier getting candidates from
+// candidates. The options are those of NewStoreVerifier.
+func NewMultiVerifier(candidates CandidateFunc, opts ...Option) *MultiVerifier {
+	return &MultiVerifier{v: &StoreVerifier{options: newOptions(opts)}, candidates: candidates}
+}
+
+// StoreCandidates makes the record held for the client id in store the only
+// candidate
+func StoreCandidates(store Store) CandidateFunc {
+	return func(ctx context.Context, clientID string) ([]Key, error) {
+		ak, err := store.Get(ctx, clientID)
+		if err != nil {
+			return nil, err
+		}
+		return []Key{ak}, nil
+	}
+}
+
+func (m *MultiVerifier) Verify(ctx context.Context, apikey string) (Identity, error) {
+	return m.v.run(ctx, apikey, m.find)
+}
+
+func (m *MultiVerifier) find(ctx context.Context, presented Key, password []byte) (Identity, error) {
+	candidates, err := m.candidates(ctx, presented.ClientID)
+	if err != nil {
+		return Identity{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Identity{}, err
+	}
+	if len(candidates) == 0 {
+		return Identity{}, ErrNotFound
+	}
+	return m.v.match(ctx, presented, password, candidates)
+}
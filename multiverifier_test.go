@@ -0,0 +1,124 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMultiVerifier(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newCandidate := func(t *testing.T, name string, opts ...KeyOption) (string, Key) {
+		t.Helper()
+		ak, err := NewKey(testAlg, append([]KeyOption{WithClientID("client-1"), WithName(name)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		apikey, err := ak.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return apikey, ak
+	}
+	first, firstKey := newCandidate(t, "first")
+	second, secondKey := newCandidate(t, "second")
+	expired, expiredKey := newCandidate(t, "expired", WithExpiresAt(now.Add(-time.Hour)))
+	revoked, revokedKey := newCandidate(t, "revoked")
+	revokedKey.RevokedAt = now.Add(-time.Hour)
+	// A candidate whose secret has been replaced
+	_, rotatedKey := newCandidate(t, "rotated")
+	current, err := rotatedKey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := newCandidate(t, "other")
+
+	type args struct {
+		candidates []Key
+		apikey     string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantName string
+		wantErr  error
+	}{
+		{"first", args{[]Key{firstKey, secondKey}, first}, "first", nil},
+		{"second", args{[]Key{firstKey, secondKey}, second}, "second", nil},
+		{"none match", args{[]Key{firstKey, secondKey}, other}, "", ErrMismatch},
+		{"skips expired", args{[]Key{expiredKey, firstKey}, first}, "first", nil},
+		{"only expired", args{[]Key{expiredKey}, expired}, "", ErrExpired},
+		{"only revoked", args{[]Key{revokedKey, expiredKey}, revoked}, "", ErrRevoked},
+		{"regenerated", args{[]Key{firstKey, rotatedKey}, current}, "rotated", nil},
+		{"no candidates", args{nil, first}, "", ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewMultiVerifier(func(ctx context.Context, clientID string) ([]Key, error) {
+				if clientID != "client-1" {
+					t.Errorf("candidates for %q", clientID)
+				}
+				return tt.args.candidates, nil
+			}, WithClock(ClockFunc(func() time.Time { return now })))
+			got, err := v.Verify(ctx, tt.args.apikey)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Key.Name != tt.wantName || got.ClientID != "client-1" {
+				t.Errorf("Verify() matched %s, want %s", got.Key.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestMultiVerifierRotation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	old, ak, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _, err := admin.RotateGracefully(ctx, ak.ClientID, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifiers := []struct {
+		name string
+		v    Verifier
+	}{
+		{"multi", NewMultiVerifier(StoreCandidates(store))},
+		{"store", VerifierFunc(NewStoreVerifier(store).Identify)},
+	}
+	for _, vv := range verifiers {
+		type args struct {
+			apikey string
+		}
+		tests := []struct {
+			name         string
+			args         args
+			wantPrevious bool
+		}{
+			{"current", args{current}, false},
+			{"previous", args{old}, true},
+		}
+		for _, tt := range tests {
+			t.Run(vv.name+" "+tt.name, func(t *testing.T) {
+				got, err := vv.v.Verify(ctx, tt.args.apikey)
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				if got.Previous != tt.wantPrevious || got.Key.ClientID != ak.ClientID {
+					t.Errorf("Verify() = %+v, want previous %v", got, tt.wantPrevious)
+				}
+			})
+		}
+	}
+}
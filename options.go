@@ -0,0 +1,123 @@
+package apikeys
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Option configures the optional behaviour shared by Admin and StoreVerifier
+type Option func(*options)
+
+type options struct {
+	tracer  Tracer
+	metrics Metrics
+	audit   AuditSink
+	hooks   Hooks
+
+	logger     *slog.Logger
+	slowVerify time.Duration
+
+	deriver      *Deriver
+	queueWarn    int
+	maxQueueWait time.Duration
+
+	budget *MemoryBudget
+
+	clock Clock
+
+	decode decodeOptions
+
+	upgradeAlg string
+
+	tenantPolicy TenantPolicyFunc
+
+	keyTypePolicies map[KeyType]KeyTypePolicy
+
+	trackLastUsed       bool
+	lastUsedGranularity time.Duration
+
+	approvalRequired func(Key) bool
+
+	cache         VerifyCache
+	cacheTTL      time.Duration
+	cacheMaxStale time.Duration
+
+	breaker *CircuitBreaker
+	retry   *RetryConfig
+
+	admission *Admission
+
+	anomaly AnomalyDetector
+
+	leaks LeakDatabase
+
+	entropy io.Reader
+
+	deprecations *deprecationSchedule
+
+	seps Separators
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.decode.seps == (Separators{}) {
+		o.decode.seps = o.seps
+	}
+	return o
+}
+
+// wrapStore applies the store instrumentation implied by the options
+func (o *options) wrapStore(store Store) Store {
+	if o.breaker != nil {
+		store = BreakStore(store, o.breaker)
+	}
+	if o.retry != nil {
+		store = RetryStore(store, *o.retry)
+	}
+	if o.cache != nil {
+		store = invalidatingStore{Store: store, o: o}
+	}
+	if o.metrics != nil {
+		store = MeasureStore(store, o.metrics)
+	}
+	if o.tracer != nil {
+		store = TraceStore(store, o.tracer)
+	}
+	return store
+}
+
+// startSpan starts a span if a tracer is configured
+func (o *options) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if o.tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return o.tracer.Start(ctx, name)
+}
+
+// WithTracer enables tracing of key generation, verification and store
+// access. See the apikeysotel package for an OpenTelemetry implementation.
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// observeDerive reports a derivation under alg, labelled with its canonical
+// form so that spellings of one alg share their series. Callers pass the alg
+// of a stored record, not a presented one, so that the labels are bounded by
+// the algs keys were issued with.
+func (o *options) observeDerive(alg Alg, d time.Duration) {
+	if o.metrics == nil {
+		return
+	}
+	label := alg.Canonical()
+	o.metrics.ObserveDerive(label, d)
+	if m, ok := o.metrics.(AlgMetrics); ok {
+		m.ObserveAlgDerive(label, d, algBytes(alg))
+	}
+}
@@ -0,0 +1,213 @@
+package apikeys
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// OutputVersion is the version of the machine readable output schema. The
+// field names and meanings of a version never change; fields may be added.
+const OutputVersion = 1
+
+const (
+	outputKindGenerated = "apikeys.generated"
+	outputKindRecord    = "apikeys.record"
+)
+
+// OutputRecord is the store-ready record of a key in the machine readable
+// output schema. Byte fields are standard base64 and times RFC 3339 in UTC,
+// omitted when unset. It carries no secret: the salt is public and the
+// derived key is what stores hold.
+type OutputRecord struct {
+	ClientID     string `json:"client_id"`
+	TenantID     string `json:"tenant_id,omitempty"`
+	Alg          string `json:"alg,omitempty"`
+	Salt         string `json:"salt,omitempty"`
+	DerivedKey   string `json:"derived_key,omitempty"`
+	ImportedHash string `json:"imported_hash,omitempty"`
+	Fingerprint  string `json:"fingerprint,omitempty"`
+	CreatedAt    string `json:"created_at,omitempty"`
+	RotatedAt    string `json:"rotated_at,omitempty"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	RevokedAt    string `json:"revoked_at,omitempty"`
+	LastUsedAt   string `json:"last_used_at,omitempty"`
+
+	IdleTimeoutSeconds int64 `json:"idle_timeout_seconds,omitempty"`
+
+	// PreviousDerivedKey verifies until PreviousExpiresAt, after a graceful
+	// rotation
+	PreviousDerivedKey string `json:"previous_derived_key,omitempty"`
+	PreviousExpiresAt  string `json:"previous_expires_at,omitempty"`
+
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	CreatedBy   string            `json:"created_by,omitempty"`
+	Team        string            `json:"team,omitempty"`
+	Approval    string            `json:"approval,omitempty"`
+	ReviewedBy  string            `json:"reviewed_by,omitempty"`
+
+	Restrictions Restrictions `json:"restrictions,omitzero"`
+}
+
+// Output is the versioned json document provisioning tools, eg Terraform or
+// Pulumi, consume. Kind is "apikeys.generated" for a freshly generated key,
+// when APIKey holds the one-time secret, and "apikeys.record" for a record
+// alone. The record is kept apart from the secret so it can be written to a
+// store without the secret passing through.
+type Output struct {
+	Version int          `json:"version"`
+	Kind    string       `json:"kind"`
+	APIKey  string       `json:"api_key,omitempty"`
+	Record  OutputRecord `json:"record"`
+}
+
+// NewOutputRecord converts ak to the output schema
+func NewOutputRecord(ak Key) OutputRecord {
+	b64 := base64.StdEncoding.EncodeToString
+	r := OutputRecord{
+		ClientID:     ak.ClientID,
+		TenantID:     ak.TenantID,
+		Alg:          ak.alg.String,
+		ImportedHash: ak.ImportedHash,
+		Fingerprint:  ak.Fingerprint(),
+		CreatedAt:    outputTime(ak.CreatedAt),
+		RotatedAt:    outputTime(ak.RotatedAt),
+		ExpiresAt:    outputTime(ak.ExpiresAt),
+		RevokedAt:    outputTime(ak.RevokedAt),
+		LastUsedAt:   outputTime(ak.LastUsedAt),
+
+		IdleTimeoutSeconds: ak.IdleTimeoutSeconds,
+		PreviousExpiresAt:  outputTime(ak.PreviousExpiresAt),
+
+		Name:        ak.Name,
+		Description: ak.Description,
+		Owner:       ak.Owner,
+		Labels:      maps.Clone(ak.Labels),
+		Type:        string(ak.Type),
+		CreatedBy:   ak.CreatedBy,
+		Team:        ak.Team,
+		Approval:    string(ak.Approval),
+		ReviewedBy:  ak.ReviewedBy,
+
+		Restrictions: ak.Restrictions.clone(),
+	}
+	if len(ak.Salt) > 0 {
+		r.Salt = b64(ak.Salt)
+	}
+	if len(ak.DerivedKey) > 0 {
+		r.DerivedKey = b64(ak.DerivedKey)
+	}
+	if len(ak.PreviousDerivedKey) > 0 {
+		r.PreviousDerivedKey = b64(ak.PreviousDerivedKey)
+	}
+	return r
+}
+
+// GeneratedOutput describes a key returned by Admin.Create or Rotate
+func GeneratedOutput(apikey string, ak Key) Output {
+	return Output{Version: OutputVersion, Kind: outputKindGenerated, APIKey: apikey, Record: NewOutputRecord(ak)}
+}
+
+// RecordOutput describes a stored record
+func RecordOutput(ak Key) Output {
+	return Output{Version: OutputVersion, Kind: outputKindRecord, Record: NewOutputRecord(ak)}
+}
+
+// Key converts the record back to a Key, eg to Create it in a Store. The
+// fingerprint is ignored.
+func (r OutputRecord) Key() (Key, error) {
+	if !KeyType(r.Type).Valid() {
+		return Key{}, fmt.Errorf("bad output record type `%s'", r.Type)
+	}
+	if !ApprovalState(r.Approval).Valid() {
+		return Key{}, fmt.Errorf("bad output record approval `%s'", r.Approval)
+	}
+	ak := Key{
+		ClientID: r.ClientID, TenantID: r.TenantID, ImportedHash: r.ImportedHash,
+		Name: r.Name, Description: r.Description, Owner: r.Owner, Labels: maps.Clone(r.Labels),
+		Type: KeyType(r.Type), CreatedBy: r.CreatedBy, Team: r.Team,
+		Approval: ApprovalState(r.Approval), ReviewedBy: r.ReviewedBy,
+		Restrictions: r.Restrictions.clone(), IdleTimeoutSeconds: r.IdleTimeoutSeconds,
+	}
+	if r.Alg != "" {
+		if err := ak.SetAlg(r.Alg); err != nil {
+			return Key{}, err
+		}
+	}
+	var err error
+	if ak.Salt, err = outputBytes("salt", r.Salt); err != nil {
+		return Key{}, err
+	}
+	if ak.DerivedKey, err = outputBytes("derived_key", r.DerivedKey); err != nil {
+		return Key{}, err
+	}
+	if ak.PreviousDerivedKey, err = outputBytes("previous_derived_key", r.PreviousDerivedKey); err != nil {
+		return Key{}, err
+	}
+	for _, f := range []struct {
+		name string
+		s    string
+		t    *time.Time
+	}{
+		{"created_at", r.CreatedAt, &ak.CreatedAt},
+		{"rotated_at", r.RotatedAt, &ak.RotatedAt},
+		{"expires_at", r.ExpiresAt, &ak.ExpiresAt},
+		{"revoked_at", r.RevokedAt, &ak.RevokedAt},
+		{"last_used_at", r.LastUsedAt, &ak.LastUsedAt},
+		{"previous_expires_at", r.PreviousExpiresAt, &ak.PreviousExpiresAt},
+	} {
+		if f.s == "" {
+			continue
+		}
+		if *f.t, err = time.Parse(time.RFC3339Nano, f.s); err != nil {
+			return Key{}, fmt.Errorf("bad output record %s `%s': %w", f.name, f.s, err)
+		}
+	}
+	return ak, nil
+}
+
+// Flatten returns the output as a map of strings, the shape a Terraform
+// external data source requires. The record is included both as its json,
+// under "record", and as separate fields.
+func (o Output) Flatten() (map[string]string, error) {
+	record, err := json.Marshal(o.Record)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]string{
+		"version":     fmt.Sprint(o.Version),
+		"kind":        o.Kind,
+		"record":      string(record),
+		"client_id":   o.Record.ClientID,
+		"alg":         o.Record.Alg,
+		"fingerprint": o.Record.Fingerprint,
+	}
+	if o.APIKey != "" {
+		m["api_key"] = o.APIKey
+	}
+	return m, nil
+}
+
+func outputTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func outputBytes(name, s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("bad output record %s: %w", name, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,99 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGeneratedOutput(t *testing.T) {
+	admin := NewAdmin(NewMemStore())
+	apikey, ak, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"), WithExpiresAt(time.Date(2030, 1, 2, 3, 4, 5, 6, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := GeneratedOutput(apikey, ak)
+	b, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Output
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Version != OutputVersion || decoded.Kind != "apikeys.generated" || decoded.APIKey != apikey {
+		t.Errorf("decoded = %+v", decoded)
+	}
+	got, err := decoded.Record.Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRecord(got, ak) || !got.ExpiresAt.Equal(ak.ExpiresAt) {
+		t.Errorf("Record.Key() = %+v, want %+v", got, ak)
+	}
+
+	// The schema's field names are fixed for the version
+	var fields map[string]map[string]any
+	json.Unmarshal(b, &fields)
+	var names []string
+	for name := range fields["record"] {
+		names = append(names, name)
+	}
+	for _, name := range []string{"client_id", "alg", "salt", "derived_key", "fingerprint", "created_at", "expires_at"} {
+		if _, ok := fields["record"][name]; !ok {
+			t.Errorf("record is missing %s, has %v", name, names)
+		}
+	}
+}
+
+func TestRecordOutputFlatten(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ak.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	out := RecordOutput(ak)
+	if out.APIKey != "" {
+		t.Errorf("RecordOutput() has an api key")
+	}
+	m, err := out.Flatten()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["api_key"]; ok {
+		t.Error("Flatten() has an api key")
+	}
+	var record OutputRecord
+	if err := json.Unmarshal([]byte(m["record"]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(record, out.Record) {
+		t.Errorf("flattened record = %+v, want %+v", record, out.Record)
+	}
+	if m["version"] != "1" || m["kind"] != "apikeys.record" || m["client_id"] != "client-1" || m["alg"] != testAlg {
+		t.Errorf("Flatten() = %v", m)
+	}
+}
+
+func TestOutputRecordKeyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		record OutputRecord
+	}{
+		{"bad alg", OutputRecord{ClientID: "c", Alg: "argon2id x"}},
+		{"bad salt", OutputRecord{ClientID: "c", Salt: "!"}},
+		{"bad derived key", OutputRecord{ClientID: "c", DerivedKey: "!"}},
+		{"bad time", OutputRecord{ClientID: "c", CreatedAt: "yesterday"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.record.Key(); err == nil {
+				t.Error("Key() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package apikeys
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// MinPepperLen is the shortest pepper RegisterPepper accepts
+const MinPepperLen = 32
+
+// pepperID is the form of the id keyed algs name their pepper by in their
+// alg string, which must not contain the alg or api key separators
+var pepperID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+var (
+	peppersMu sync.RWMutex
+	peppers   = map[string]Secret{}
+)
+
+// RegisterPepper makes pepper, a server side secret of at least MinPepperLen
+// random bytes, available under id to the keyed algs, whose alg strings name
+// the pepper they use, eg "blake2b key=<id>,len=32" or
+// "hmac-sha256 key=<id>". Keys of a keyed alg only verify in processes which
+// have registered their pepper, so a stolen store is useless without it.
+// Register a new id to change the pepper: alg strings, and so existing keys,
+// keep naming the pepper they were created with. Registering an id again
+// replaces its pepper. pepper is copied, so the caller may wipe it.
+func RegisterPepper(id string, pepper Secret) error {
+	if !pepperID.MatchString(id) {
+		return fmt.Errorf("%w: bad pepper id `%s'", ErrConfig, id)
+	}
+	if len(pepper) < MinPepperLen {
+		return fmt.Errorf("%w: pepper `%s' too short. got %d, min=%d", ErrConfig, id, len(pepper), MinPepperLen)
+	}
+	peppersMu.Lock()
+	defer peppersMu.Unlock()
+	peppers[id] = append(Secret(nil), pepper...)
+	return nil
+}
+
+// lookupPepper returns the pepper registered for id
+func lookupPepper(id string) (Secret, error) {
+	peppersMu.RLock()
+	defer peppersMu.RUnlock()
+	p, ok := peppers[id]
+	if !ok {
+		return nil, fmt.Errorf("no pepper registered for `%s'", id)
+	}
+	return p, nil
+}
+
+// PepperRegistered reports whether a pepper has been registered for id, see
+// RegisterPepper
+func PepperRegistered(id string) bool {
+	_, err := lookupPepper(id)
+	return err == nil
+}
+
+// keyedAlgIDs are the prefixes of the algs which need a pepper
+var keyedAlgIDs = []string{blake2bAlgID, hmacSHA256AlgID}
+
+// keyedAlgLens are the default and maximum key lengths of each keyed alg
+var keyedAlgLens = map[string][2]uint32{
+	blake2bAlgID:    {0, blake2b.Size},
+	hmacSHA256AlgID: {sha256.Size, sha256.Size},
+}
+
+// keyedParams are the parameters of a keyed alg
+type keyedParams struct {
+	pepperID string
+	keyLen   uint32
+}
+
+// parseKeyedParams parses the named parameters of a keyed alg,
+//
+//	key=<pepper id>[,len=<keylen>]
+//
+// len is required unless defaultLen is set, and at most maxLen
+func parseKeyedParams(params string, defaultLen, maxLen uint32) (keyedParams, error) {
+	p := keyedParams{keyLen: defaultLen}
+	seen := map[string]bool{}
+	for _, param := range strings.Split(params, namedSep) {
+		name, value, ok := strings.Cut(param, namedAssign)
+		if !ok || value == "" {
+			return keyedParams{}, fmt.Errorf("bad alg parameter `%s'", param)
+		}
+		if seen[name] {
+			return keyedParams{}, fmt.Errorf("duplicate alg parameter `%s'", name)
+		}
+		seen[name] = true
+		switch name {
+		case namedKey:
+			if !pepperID.MatchString(value) {
+				return keyedParams{}, fmt.Errorf("bad pepper id `%s'", value)
+			}
+			p.pepperID = value
+		case namedKeyLen:
+			n, err := parseKeyLen(value)
+			if err != nil {
+				return keyedParams{}, err
+			}
+			if n > maxLen {
+				return keyedParams{}, fmt.Errorf("key length `%s' to large. max=%d", value, maxLen)
+			}
+			p.keyLen = n
+		default:
+			return keyedParams{}, fmt.Errorf("unknown alg parameter `%s'", name)
+		}
+	}
+	if !seen[namedKey] {
+		return keyedParams{}, fmt.Errorf("missing alg parameter `%s'", namedKey)
+	}
+	if p.keyLen == 0 {
+		return keyedParams{}, fmt.Errorf("missing alg parameter `%s'", namedKeyLen)
+	}
+	return p, nil
+}
+
+// parseKeyedAlg parses alg, which starts with the keyed alg prefix id, and
+// looks up its pepper
+func parseKeyedAlg(id, alg string) (keyedParams, Secret, error) {
+	lens := keyedAlgLens[id]
+	p, err := parseKeyedParams(alg[len(id):], lens[0], lens[1])
+	if err != nil {
+		return keyedParams{}, nil, err
+	}
+	pepper, err := lookupPepper(p.pepperID)
+	if err != nil {
+		return keyedParams{}, nil, err
+	}
+	return p, pepper, nil
+}
+
+// keyedPepperID returns the pepper id named by alg, and whether alg is a
+// keyed alg at all
+func keyedPepperID(alg string) (string, bool, error) {
+	for _, id := range keyedAlgIDs {
+		if params, ok := strings.CutPrefix(alg, id); ok {
+			lens := keyedAlgLens[id]
+			p, err := parseKeyedParams(params, lens[0], lens[1])
+			return p.pepperID, true, err
+		}
+	}
+	return "", false, nil
+}
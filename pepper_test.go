@@ -0,0 +1,45 @@
+package apikeys
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRegisterPepper(t *testing.T) {
+	type args struct {
+		id     string
+		pepper Secret
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"ok", args{"test-pepper", bytes.Repeat([]byte{1}, MinPepperLen)}, false},
+		{"short", args{"short", bytes.Repeat([]byte{1}, MinPepperLen-1)}, true},
+		{"separator", args{"a.b", bytes.Repeat([]byte{1}, MinPepperLen)}, true},
+		{"comma", args{"a,len=16", bytes.Repeat([]byte{1}, MinPepperLen)}, true},
+		{"empty id", args{"", bytes.Repeat([]byte{1}, MinPepperLen)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterPepper(tt.args.id, tt.args.pepper)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrConfig)) {
+				t.Errorf("RegisterPepper() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	pepper := bytes.Repeat([]byte{2}, MinPepperLen)
+	if err := RegisterPepper("copied", pepper); err != nil {
+		t.Fatal(err)
+	}
+	clear(pepper)
+	if got, err := lookupPepper("copied"); err != nil || got[0] != 2 {
+		t.Errorf("lookupPepper() = %v, %v, want the registered copy", got, err)
+	}
+	if _, err := lookupPepper("missing"); err == nil {
+		t.Error("lookupPepper() of an unregistered id succeeded")
+	}
+}
@@ -0,0 +1,123 @@
+package apikeys
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	phcArgon2ID = "argon2id"
+	phcVersion  = "v=" // followed by the version number
+	phcParts    = 6    // "", id, version, params, salt, hash
+)
+
+// ParsePHC converts an argon2id hash in the PHC string format,
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+//
+// as produced by Python argon2-cffi, node-argon2 and the reference
+// implementation, into a Key record with the same salt, derived key and
+// parameters. Memory, time and key length must be within the bounds ParseAlg
+// enforces; any parallelism is accepted. opts are applied and checked as
+// they are by NewKey, use WithClientID to keep the identity the hash was
+// stored under or one is generated.
+func ParsePHC(hash string, opts ...KeyOption) (Key, error) {
+	h, err := parsePHC(hash)
+	if err != nil {
+		return Key{}, err
+	}
+	alg, err := ParseAlg(fmt.Sprintf("argon2id t=%d,m=%dKB,p=%d,len=%d,v=%d", h.time, h.memory, h.threads, len(h.derived), argon2.Version))
+	if err != nil {
+		return Key{}, err
+	}
+	ak := Key{Salt: h.salt, DerivedKey: h.derived}
+	if err := ak.SetOptions(alg.String, opts...); err != nil {
+		return Key{}, err
+	}
+	return ak, nil
+}
+
+// phcHash is a parsed PHC argon2id string, with only the format checked
+type phcHash struct {
+	time    uint32
+	memory  uint32 // KB
+	threads uint8
+	salt    []byte
+	derived []byte
+}
+
+// alg describes the derivation of h, eg to acquire its memory from a
+// MemoryBudget
+func (h phcHash) alg() Alg {
+	return Alg{String: phcArgon2ID, ParamsArgon2ID: ParamsArgon2ID{
+		Time: h.time, Memory: h.memory, KeyLen: uint32(len(h.derived)), Threads: h.threads,
+	}}
+}
+
+func parsePHC(hash string) (phcHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != phcParts || parts[0] != "" {
+		return phcHash{}, fmt.Errorf("bad phc hash, want %d '$' separated parts", phcParts-1)
+	}
+	if parts[1] != phcArgon2ID {
+		return phcHash{}, fmt.Errorf("unsupported phc algorithm `%s'", parts[1])
+	}
+	if parts[2] != phcVersion+strconv.Itoa(argon2.Version) {
+		return phcHash{}, fmt.Errorf("unsupported phc argon2 version `%s'. want %d", parts[2], argon2.Version)
+	}
+
+	// PHC encodes binary fields as unpadded standard base64
+	var h phcHash
+	var err error
+	enc := base64.RawStdEncoding
+	if h.salt, err = enc.DecodeString(parts[4]); err != nil {
+		return phcHash{}, fmt.Errorf("bad phc salt: %v", err)
+	}
+	if h.derived, err = enc.DecodeString(parts[5]); err != nil {
+		return phcHash{}, fmt.Errorf("bad phc hash: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, param := range strings.Split(parts[3], namedSep) {
+		name, value, _ := strings.Cut(param, namedAssign)
+		bits := 32
+		if name == "p" {
+			bits = 8
+		}
+		u, err := strconv.ParseUint(value, 10, bits)
+		if err != nil || u == 0 {
+			return phcHash{}, fmt.Errorf("bad phc parameter `%s'", param)
+		}
+		switch name {
+		case "m":
+			h.memory = uint32(u)
+		case "t":
+			h.time = uint32(u)
+		case "p":
+			h.threads = uint8(u)
+		default:
+			return phcHash{}, fmt.Errorf("unknown phc parameter `%s'", param)
+		}
+		seen[name] = true
+	}
+	if !seen["m"] || !seen["t"] || !seen["p"] {
+		return phcHash{}, fmt.Errorf("phc parameters `%s' need m, t and p", parts[3])
+	}
+	return h, nil
+}
+
+// VerifyPHC reports whether password matches a PHC argon2id hash, see
+// ParsePHC. It returns an error, rather than false, if the hash can not be
+// parsed.
+func VerifyPHC(hash string, password []byte) (bool, error) {
+	ak, err := ParsePHC(hash)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(ak.RecoverKey(password), ak.DerivedKey) == 1, nil
+}
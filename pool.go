@@ -0,0 +1,36 @@
+package apikeys
+
+import "sync"
+
+// secretPool recycles the short lived buffers which hold plaintext passwords
+// and decoded salts during generation and verification. Buffers are zeroed
+// before they are returned to the pool.
+//
+// Derived keys are not pooled: argon2.IDKey allocates its output (and its
+// working memory) internally. The verifier zeroes the derived key it
+// computes once it has been compared. Buffers which remain live after a call
+// returns are the Salt and DerivedKey of a generated Key, and the Salt and
+// password returned by Decode; those belong to the caller.
+var secretPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, secretBufLen)
+		return &b
+	},
+}
+
+// secretBufLen fits the longest salt and password WithSaltLength and
+// WithSecretLength allow as decode needs them: base64.URLEncoding.DecodedLen
+// of their encodings, which rounds each up to a whole number of 3 byte groups
+const secretBufLen = (maxSaltLen+2)/3*3 + (maxSecretLen+2)/3*3
+
+func getSecretBuf() *[]byte {
+	b := secretPool.Get().(*[]byte)
+	*b = (*b)[:cap(*b)]
+	return b
+}
+
+func putSecretBuf(b *[]byte) {
+	clear((*b)[:cap(*b)])
+	*b = (*b)[:0]
+	secretPool.Put(b)
+}
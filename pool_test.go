@@ -0,0 +1,76 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestSecretBufZeroed(t *testing.T) {
+	b := getSecretBuf()
+	if len(*b) != secretBufLen {
+		t.Fatalf("getSecretBuf() len = %d, want %d", len(*b), secretBufLen)
+	}
+	for i := range *b {
+		(*b)[i] = 0xff
+	}
+	raw := (*b)[:cap(*b)]
+	putSecretBuf(b)
+	for i, c := range raw {
+		if c != 0 {
+			t.Fatalf("byte %d not zeroed before returning to the pool", i)
+		}
+	}
+}
+
+func TestSecretBufFitsDecode(t *testing.T) {
+	type args struct {
+		opts []KeyOption
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"default", args{nil}},
+		{"longest", args{[]KeyOption{WithSaltLength(maxSaltLen), WithSecretLength(maxSecretLen)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ak, err := NewKey(testAlg, tt.args.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			apikey, err := ak.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := getSecretBuf()
+			_, password, err := decode(apikey, *b, &decodeOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The password must be in the pooled buffer, so putting it back
+			// zeroes it
+			putSecretBuf(b)
+			if !bytes.Equal(password, make([]byte, len(password))) {
+				t.Errorf("decode() password is not in the pooled buffer")
+			}
+		})
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	ctx := context.Background()
+	store := NewMemStore()
+	apikey, _, err := NewAdmin(store).Create(ctx, testAlg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	v := NewStoreVerifier(store)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := v.Verify(ctx, apikey); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
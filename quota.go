@@ -0,0 +1,122 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a key has used all of its quota for the
+// current window
+var ErrQuotaExceeded = errors.New("api key quota exceeded")
+
+// MaxQuotaWindow is the longest quota window a key may have
+const MaxQuotaWindow = 366 * 24 * time.Hour
+
+// Quota limits a key to Limit requests in each fixed window of WindowSeconds.
+// Windows are aligned as by time.Time.Truncate, to multiples of the window
+// since the zero time, so every instance counting a key agrees on when they
+// reset.
+type Quota struct {
+	Limit         int64 `firestore:"limit" json:"limit" bson:"limit" mapstructure:"limit"`
+	WindowSeconds int64 `firestore:"window_seconds" json:"window_seconds" bson:"window_seconds" mapstructure:"window_seconds"`
+}
+
+// Window returns the window length
+func (q Quota) Window() time.Duration {
+	return time.Duration(q.WindowSeconds) * time.Second
+}
+
+func (q Quota) validate() error {
+	if q == (Quota{}) {
+		return nil
+	}
+	if q.Limit <= 0 {
+		return fmt.Errorf("bad quota limit %d, want > 0", q.Limit)
+	}
+	if q.WindowSeconds <= 0 {
+		return fmt.Errorf("bad quota window %ds, want > 0", q.WindowSeconds)
+	}
+	if q.WindowSeconds > int64(MaxQuotaWindow/time.Second) {
+		return fmt.Errorf("quota window %ds too long. max=%s", q.WindowSeconds, MaxQuotaWindow)
+	}
+	return nil
+}
+
+// WithQuota limits the key to limit requests per window. The window is
+// rounded down to whole seconds.
+func WithQuota(limit int64, window time.Duration) KeyOption {
+	return func(ak *Key) {
+		ak.Restrictions.Quota = Quota{Limit: limit, WindowSeconds: int64(window / time.Second)}
+	}
+}
+
+// UsageCounter counts the requests made with each key
+type UsageCounter interface {
+	// Add counts one use of clientID in the window of length window which
+	// contains now. It returns the count for the window, including this use.
+	Add(ctx context.Context, clientID string, window time.Duration, now time.Time) (int64, error)
+}
+
+// QuotaStatus is the state of a key's quota after a use, in the form of the
+// X-RateLimit headers
+type QuotaStatus struct {
+	Limit     int64
+	Remaining int64
+	// Reset is when the current window ends
+	Reset time.Time
+}
+
+// UseQuota counts a use of the key at now with counter and returns
+// ErrQuotaExceeded, along with the status, if the key is over its quota. Keys
+// without a quota always succeed with a zero status.
+func (ak Key) UseQuota(ctx context.Context, counter UsageCounter, now time.Time) (QuotaStatus, error) {
+	q := ak.Restrictions.Quota
+	if q.Limit <= 0 || q.WindowSeconds <= 0 {
+		return QuotaStatus{}, nil
+	}
+	window := q.Window()
+	n, err := counter.Add(ctx, ak.ClientID, window, now)
+	if err != nil {
+		return QuotaStatus{}, err
+	}
+	status := QuotaStatus{
+		Limit:     q.Limit,
+		Remaining: max(q.Limit-n, 0),
+		Reset:     now.Truncate(window).Add(window),
+	}
+	if n > q.Limit {
+		return status, fmt.Errorf("%w: %d requests per %s", ErrQuotaExceeded, q.Limit, window)
+	}
+	return status, nil
+}
+
+// MemUsageCounter is a UsageCounter for a single process
+type MemUsageCounter struct {
+	mu     sync.Mutex
+	counts map[string]usageWindow
+}
+
+type usageWindow struct {
+	start time.Time
+	count int64
+}
+
+func NewMemUsageCounter() *MemUsageCounter {
+	return &MemUsageCounter{counts: make(map[string]usageWindow)}
+}
+
+func (c *MemUsageCounter) Add(ctx context.Context, clientID string, window time.Duration, now time.Time) (int64, error) {
+	start := now.Truncate(window)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := c.counts[clientID]
+	if !w.start.Equal(start) {
+		w = usageWindow{start: start}
+	}
+	w.count++
+	c.counts[clientID] = w
+	return w.count, nil
+}
@@ -0,0 +1,62 @@
+package apikeys
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyUseQuota(t *testing.T) {
+	ctx := t.Context()
+	counter := NewMemUsageCounter()
+	var ak Key
+	WithClientID("client-1")(&ak)
+	WithQuota(2, time.Minute)(&ak)
+	start := time.Date(2026, 1, 1, 0, 0, 10, 0, time.UTC)
+
+	type args struct {
+		now time.Time
+	}
+	tests := []struct {
+		name          string
+		args          args
+		wantRemaining int64
+		wantReset     time.Time
+		wantErr       bool
+	}{
+		{"first", args{start}, 1, start.Add(50 * time.Second), false},
+		{"second", args{start.Add(time.Second)}, 0, start.Add(50 * time.Second), false},
+		{"over", args{start.Add(2 * time.Second)}, 0, start.Add(50 * time.Second), true},
+		{"next window", args{start.Add(50 * time.Second)}, 1, start.Add(110 * time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ak.UseQuota(ctx, counter, tt.args.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UseQuota() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("UseQuota() error = %v, want ErrQuotaExceeded", err)
+			}
+			if got.Limit != 2 || got.Remaining != tt.wantRemaining || !got.Reset.Equal(tt.wantReset) {
+				t.Errorf("UseQuota() = %+v, want remaining %d reset %s", got, tt.wantRemaining, tt.wantReset)
+			}
+		})
+	}
+
+	var unlimited Key
+	if got, err := unlimited.UseQuota(ctx, counter, start); err != nil || got != (QuotaStatus{}) {
+		t.Errorf("UseQuota() without quota = %+v, %v", got, err)
+	}
+}
+
+func TestQuotaValidate(t *testing.T) {
+	for _, q := range []Quota{{Limit: 0, WindowSeconds: 60}, {Limit: 10, WindowSeconds: 0}, {Limit: -1, WindowSeconds: 60}, {Limit: 10, WindowSeconds: int64(MaxQuotaWindow/time.Second) + 1}} {
+		if err := q.validate(); err == nil {
+			t.Errorf("validate() accepted %+v", q)
+		}
+	}
+	if _, _, err := NewAdmin(NewMemStore()).Create(t.Context(), testAlg, WithQuota(10, 500*time.Millisecond)); err == nil {
+		t.Error("Create() with a sub second window succeeded")
+	}
+}
@@ -0,0 +1,101 @@
+package apikeys
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Hasher derives keys for an alg registered with RegisterAlg
+type Hasher interface {
+	// Derive returns the key derived from password and salt. It must be
+	// deterministic and safe for concurrent use.
+	Derive(password, salt []byte) []byte
+}
+
+// AlgParser parses an alg string for a registered prefix
+type AlgParser func(alg string) (Hasher, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]AlgParser{}
+)
+
+// RegisterAlg makes a key derivation function available to ParseAlg, and so
+// to NewKey, Decode and the verifiers, for alg strings starting with prefix.
+// The longest registered prefix matching an alg string is used. Registered
+// alg strings must not contain the default separators, '.' and ':', even
+// where keys are encoded with others, see Separators, nor those in use.
+// Alg parameters other than the String, such as the Policy bounds and the
+// memory budget, only apply to the built in argon2id; a Policy allows a
+// registered alg only if it is one of its Hashers.
+//
+// Like database/sql.Register, it is intended to be called from init and
+// panics if prefix is empty or already registered, overlaps a built in
+// prefix, or if parser is nil.
+func RegisterAlg(prefix string, parser AlgParser) {
+	if prefix == "" || parser == nil {
+		panic("apikeys: RegisterAlg needs a prefix and parser")
+	}
+	for _, builtin := range append([]string{argon2idAlgID}, keyedAlgIDs...) {
+		if strings.HasPrefix(builtin, prefix) || strings.HasPrefix(prefix, builtin) {
+			panic(fmt.Sprintf("apikeys: RegisterAlg prefix `%s' overlaps `%s'", prefix, strings.TrimSpace(builtin)))
+		}
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[prefix]; dup {
+		panic(fmt.Sprintf("apikeys: RegisterAlg called twice for `%s'", prefix))
+	}
+	registry[prefix] = parser
+}
+
+// lookupAlg returns the parser registered with the longest prefix of alg
+func lookupAlg(alg string) (AlgParser, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	var best string
+	var parser AlgParser
+	for prefix, p := range registry {
+		if len(prefix) > len(best) && strings.HasPrefix(alg, prefix) {
+			best, parser = prefix, p
+		}
+	}
+	return parser, parser != nil
+}
+
+func parseRegisteredAlg(alg string, parser AlgParser) (Alg, error) {
+	if strings.ContainsAny(alg, ".:") {
+		return Alg{}, fmt.Errorf("alg `%s' contains an api key separator", alg)
+	}
+	h, err := parser(alg)
+	if err != nil {
+		return Alg{}, err
+	}
+	if h == nil {
+		return Alg{}, fmt.Errorf("no hasher for alg `%s'", alg)
+	}
+	return Alg{String: alg, hasher: h}, nil
+}
+
+// derive returns the key for password and salt using the registered hasher,
+// or argon2id, of the prehashed password if set, after any lookup tag
+func (a Alg) derive(password, salt []byte) []byte {
+	if a.hasher != nil {
+		return a.hasher.Derive(password, salt)
+	}
+	input := password
+	if a.Prehash == prehashSHA512 {
+		sum := sha512.Sum512(password)
+		defer clear(sum[:])
+		input = sum[:]
+	}
+	key := argon2.IDKey(input, salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+	if a.LookupLen == 0 {
+		return key
+	}
+	return append(lookupTag(password, salt, a.LookupLen), key...)
+}
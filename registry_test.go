@@ -0,0 +1,88 @@
+package apikeys
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+const testRegisteredAlg = "testkdf v1"
+
+type testHasher struct{}
+
+func (testHasher) Derive(password, salt []byte) []byte {
+	m := hmac.New(sha256.New, salt)
+	m.Write(password)
+	return m.Sum(nil)
+}
+
+func init() {
+	RegisterAlg("testkdf ", func(alg string) (Hasher, error) {
+		if alg != testRegisteredAlg {
+			return nil, fmt.Errorf("unsupported testkdf version `%s'", alg)
+		}
+		return testHasher{}, nil
+	})
+	// A longer prefix takes precedence
+	RegisterAlg("testkdf v2", func(alg string) (Hasher, error) {
+		return nil, fmt.Errorf("testkdf v2 is withdrawn")
+	})
+}
+
+func TestRegisteredAlg(t *testing.T) {
+	store := NewMemStore()
+	apikey, ak, err := NewAdmin(store).Create(t.Context(), testRegisteredAlg)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := ak.Alg().String; got != testRegisteredAlg {
+		t.Errorf("Alg() = %s", got)
+	}
+	if _, err := NewStoreVerifier(store).Verify(t.Context(), apikey); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err := (Policy{}).Check(ak.Alg()); err == nil {
+		t.Errorf("Policy.Check() of a registered alg not in Hashers succeeded")
+	}
+	if err := (Policy{Hashers: []string{"testkdf"}}).Check(ak.Alg()); err != nil {
+		t.Errorf("Policy.Check() error = %v", err)
+	}
+}
+
+func TestParseRegisteredAlg(t *testing.T) {
+	type args struct {
+		alg string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"registered", args{testRegisteredAlg}, false},
+		{"parser rejects", args{"testkdf v0"}, true},
+		{"longest prefix", args{"testkdf v2"}, true},
+		{"separator", args{"testkdf v1.1"}, true},
+		{"unregistered", args{"scrypt 1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAlg(tt.args.alg); (err != nil) != tt.wantErr {
+				t.Errorf("ParseAlg() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterAlgPanics(t *testing.T) {
+	for _, prefix := range []string{"", "testkdf ", "argon2", "argon2id x", "blake2b"} {
+		t.Run(prefix, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterAlg(%q) did not panic", prefix)
+				}
+			}()
+			RegisterAlg(prefix, func(string) (Hasher, error) { return testHasher{}, nil })
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package apikeys
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrReservedClientID is returned when a key is given a client id reserved
+// with ReserveClientIDs or ReserveClientIDPattern
+var ErrReservedClientID = errors.New("client id reserved")
+
+// reservedAttempts bounds the client ids generated in the hope of one which
+// is not reserved
+const reservedAttempts = 10
+
+var (
+	reservedMu       sync.RWMutex
+	reservedIDs      = map[string]bool{}
+	reservedPatterns []*regexp.Regexp
+)
+
+// ReserveClientIDs forbids ids as client ids, ignoring case, so that names
+// implying privileges, eg "admin" or "root", are never minted. Like
+// RegisterPepper it is process wide and meant for startup.
+func ReserveClientIDs(ids ...string) {
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	for _, id := range ids {
+		reservedIDs[strings.ToLower(id)] = true
+	}
+}
+
+// ReserveClientIDPattern forbids client ids matching the regular expression
+// pattern, eg `^internal-` for a prefix kept for internal services. Like
+// ReserveClientIDs it ignores case, so `^internal-` also reserves
+// "Internal-billing".
+func ReserveClientIDPattern(pattern string) error {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return fmt.Errorf("%w: bad reserved client id pattern `%s': %v", ErrConfig, pattern, err)
+	}
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	reservedPatterns = append(reservedPatterns, re)
+	return nil
+}
+
+// ClientIDReserved reports whether clientID has been reserved
+func ClientIDReserved(clientID string) bool {
+	reservedMu.RLock()
+	defer reservedMu.RUnlock()
+	if reservedIDs[strings.ToLower(clientID)] {
+		return true
+	}
+	for _, re := range reservedPatterns {
+		if re.MatchString(clientID) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkClientID returns an ErrReservedClientID error if clientID is reserved
+func checkClientID(clientID string) error {
+	if ClientIDReserved(clientID) {
+		return fmt.Errorf("%w: `%s'", ErrReservedClientID, clientID)
+	}
+	return nil
+}
+
+// resetReservedClientIDs drops every reservation, so tests can reserve ids
+// without affecting each other
+func resetReservedClientIDs() {
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+	reservedIDs = map[string]bool{}
+	reservedPatterns = nil
+}
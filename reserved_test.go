@@ -0,0 +1,69 @@
+package apikeys
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestReservedClientIDs(t *testing.T) {
+	ctx := t.Context()
+	t.Cleanup(resetReservedClientIDs)
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	// created before the reservation, so it must keep rotating
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("Superuser")); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Config{ReservedClientIDs: []string{"superuser"}, ReservedClientIDPattern: "^reserved-test-"}).ReserveClientIDs(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReserveClientIDPattern("(unclosed"); !errors.Is(err, ErrConfig) {
+		t.Errorf("ReserveClientIDPattern(bad) = %v, want ErrConfig", err)
+	}
+
+	type args struct {
+		clientID string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"allowed", args{"client-1"}, false},
+		{"listed", args{"superuser"}, true},
+		{"listed other case", args{"SuperUser"}, true},
+		{"pattern", args{"reserved-test-billing"}, true},
+		{"pattern other case", args{"Reserved-Test-Billing"}, true},
+		{"pattern unanchored elsewhere", args{"my-reserved-test-billing"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKey(testAlg, WithClientID(tt.args.clientID))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrReservedClientID) {
+				t.Errorf("NewKey() error = %v, want ErrReservedClientID", err)
+			}
+		})
+	}
+
+	if _, _, err := admin.Rotate(ctx, "Superuser", ""); err != nil {
+		t.Errorf("Rotate() of a key created before its id was reserved = %v", err)
+	}
+	if _, _, err := admin.Transfer(ctx, "Superuser", ToClientID("reserved-test-1"), WithReissue("")); !errors.Is(err, ErrReservedClientID) {
+		t.Errorf("Transfer() to a reserved id = %v, want ErrReservedClientID", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Import(ctx, "superuser", string(hash)); !errors.Is(err, ErrReservedClientID) {
+		t.Errorf("Import() of a reserved id = %v, want ErrReservedClientID", err)
+	}
+	if ak, err := NewKey(testAlg); err != nil || ClientIDReserved(ak.ClientID) {
+		t.Errorf("NewKey() generated client id %q, %v", ak.ClientID, err)
+	}
+}
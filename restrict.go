@@ -0,0 +1,240 @@
+package apikeys
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// MaxRestrictions bounds each list of restrictions on a key
+const MaxRestrictions = 64
+
+var (
+	// ErrIPNotAllowed is returned when a key is used from an address outside
+	// its allowed CIDRs
+	ErrIPNotAllowed = errors.New("api key not allowed from this address")
+	// ErrOriginNotAllowed is returned when a key is used by a page whose
+	// origin is not one of its allowed origins
+	ErrOriginNotAllowed = errors.New("api key not allowed from this origin")
+	// ErrRouteNotAllowed is returned when a key is used for a method and path
+	// outside its allowed routes
+	ErrRouteNotAllowed = errors.New("api key not allowed for this route")
+)
+
+// Restrictions limit where a key may be used. They are enforced by the
+// keyshttp middleware, after the key has verified. Empty lists allow
+// everything.
+type Restrictions struct {
+	// AllowedCIDRs are the networks, eg a partner's egress addresses, the key
+	// may be used from
+	AllowedCIDRs []string `firestore:"allowed_cidrs" json:"allowed_cidrs,omitempty" bson:"allowed_cidrs,omitempty" mapstructure:"allowed_cidrs"`
+	// AllowedOrigins are the web origins, eg "https://app.example.com" or
+	// "https://*.example.com", that keys embedded in browser code may be used
+	// from
+	AllowedOrigins []string `firestore:"allowed_origins" json:"allowed_origins,omitempty" bson:"allowed_origins,omitempty" mapstructure:"allowed_origins"`
+	// AllowedRoutes are the requests, eg "GET /v1/reports/*", the key may
+	// make. See WithAllowedRoutes.
+	AllowedRoutes []string `firestore:"allowed_routes" json:"allowed_routes,omitempty" bson:"allowed_routes,omitempty" mapstructure:"allowed_routes"`
+	// Quota limits how often the key may be used, see WithQuota
+	Quota Quota `firestore:"quota" json:"quota,omitzero" bson:"quota,omitempty" mapstructure:"quota"`
+}
+
+// IsZero is true if there are no restrictions
+func (r Restrictions) IsZero() bool {
+	return len(r.AllowedCIDRs) == 0 && len(r.AllowedOrigins) == 0 && len(r.AllowedRoutes) == 0 &&
+		r.Quota == (Quota{})
+}
+
+func (r Restrictions) clone() Restrictions {
+	return Restrictions{
+		AllowedCIDRs:   slices.Clone(r.AllowedCIDRs),
+		AllowedOrigins: slices.Clone(r.AllowedOrigins),
+		AllowedRoutes:  slices.Clone(r.AllowedRoutes),
+		Quota:          r.Quota,
+	}
+}
+
+func (r Restrictions) equal(o Restrictions) bool {
+	return slices.Equal(r.AllowedCIDRs, o.AllowedCIDRs) &&
+		slices.Equal(r.AllowedOrigins, o.AllowedOrigins) &&
+		slices.Equal(r.AllowedRoutes, o.AllowedRoutes) &&
+		r.Quota == o.Quota
+}
+
+func (r Restrictions) validate() error {
+	if len(r.AllowedCIDRs) > MaxRestrictions {
+		return fmt.Errorf("too many allowed cidrs. got %d, max=%d", len(r.AllowedCIDRs), MaxRestrictions)
+	}
+	for _, cidr := range r.AllowedCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("bad allowed cidr `%s': %v", cidr, err)
+		}
+	}
+	if len(r.AllowedOrigins) > MaxRestrictions {
+		return fmt.Errorf("too many allowed origins. got %d, max=%d", len(r.AllowedOrigins), MaxRestrictions)
+	}
+	for _, origin := range r.AllowedOrigins {
+		if _, _, err := parseOrigin(origin, true); err != nil {
+			return fmt.Errorf("bad allowed origin `%s': %v", origin, err)
+		}
+	}
+	if len(r.AllowedRoutes) > MaxRestrictions {
+		return fmt.Errorf("too many allowed routes. got %d, max=%d", len(r.AllowedRoutes), MaxRestrictions)
+	}
+	for _, route := range r.AllowedRoutes {
+		if _, _, err := parseRoute(route); err != nil {
+			return fmt.Errorf("bad allowed route `%s': %v", route, err)
+		}
+	}
+	return r.Quota.validate()
+}
+
+// WithAllowedCIDRs restricts the key to clients in cidrs, eg "203.0.113.0/24"
+// or "2001:db8::1/128"
+func WithAllowedCIDRs(cidrs ...string) KeyOption {
+	return func(ak *Key) {
+		ak.Restrictions.AllowedCIDRs = append(ak.Restrictions.AllowedCIDRs, cidrs...)
+	}
+}
+
+// CheckIP returns ErrIPNotAllowed if the key has allowed CIDRs and addr is in
+// none of them
+func (ak Key) CheckIP(addr netip.Addr) error {
+	if len(ak.Restrictions.AllowedCIDRs) == 0 {
+		return nil
+	}
+	addr = addr.Unmap()
+	for _, cidr := range ak.Restrictions.AllowedCIDRs {
+		p, err := netip.ParsePrefix(cidr)
+		if err == nil && p.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrIPNotAllowed, addr)
+}
+
+// WithAllowedOrigins restricts the key to pages served from origins. An
+// origin is a scheme and host, with an optional port, and the host may start
+// with "*." to allow any sub domain.
+func WithAllowedOrigins(origins ...string) KeyOption {
+	return func(ak *Key) {
+		ak.Restrictions.AllowedOrigins = append(ak.Restrictions.AllowedOrigins, origins...)
+	}
+}
+
+// CheckOrigin returns ErrOriginNotAllowed if the key has allowed origins and
+// origin, the value of an Origin header, matches none of them. An empty or
+// opaque ("null") origin never matches.
+func (ak Key) CheckOrigin(origin string) error {
+	if len(ak.Restrictions.AllowedOrigins) == 0 {
+		return nil
+	}
+	scheme, host, err := parseOrigin(origin, false)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOriginNotAllowed, err)
+	}
+	for _, allowed := range ak.Restrictions.AllowedOrigins {
+		ascheme, ahost, err := parseOrigin(allowed, true)
+		if err != nil || ascheme != scheme {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(ahost, "*"); ok {
+			// suffix is ".example.com", which the bare domain does not match
+			if strings.HasSuffix(host, suffix) {
+				return nil
+			}
+		} else if ahost == host {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrOriginNotAllowed, origin)
+}
+
+// parseOrigin returns the lower cased scheme and host, including any port, of
+// origin. Patterns may have a leading "*." wildcard label.
+func parseOrigin(origin string, pattern bool) (string, string, error) {
+	if origin == "" || origin == "null" {
+		return "", "", errors.New("no origin")
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme == "" || u.Host == "" || u.Opaque != "" {
+		return "", "", errors.New("want scheme://host")
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", "", errors.New("an origin has no user, path, query or fragment")
+	}
+	host := strings.ToLower(u.Host)
+	if strings.Contains(host, "*") && (!pattern || !strings.HasPrefix(host, "*.") || strings.Contains(host[1:], "*")) {
+		return "", "", errors.New("only a leading \"*.\" wildcard is allowed")
+	}
+	return strings.ToLower(u.Scheme), host, nil
+}
+
+// WithAllowedRoutes restricts the key to requests matching routes. A route is
+// an optional method and a path, "GET /v1/reports/*". A path ending in "/*"
+// matches everything below it, otherwise it must match exactly. GET also
+// allows HEAD, and a route without a method allows every method.
+func WithAllowedRoutes(routes ...string) KeyOption {
+	return func(ak *Key) {
+		ak.Restrictions.AllowedRoutes = append(ak.Restrictions.AllowedRoutes, routes...)
+	}
+}
+
+// CheckRoute returns ErrRouteNotAllowed if the key has allowed routes and
+// none of them match method and urlPath. urlPath is cleaned first so that dot
+// segments can not escape a route.
+func (ak Key) CheckRoute(method, urlPath string) error {
+	if len(ak.Restrictions.AllowedRoutes) == 0 {
+		return nil
+	}
+	if !strings.HasPrefix(urlPath, "/") {
+		return fmt.Errorf("%w: %s %s", ErrRouteNotAllowed, method, urlPath)
+	}
+	cleaned := path.Clean(urlPath)
+	if strings.HasSuffix(urlPath, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	for _, route := range ak.Restrictions.AllowedRoutes {
+		rmethod, rpath, err := parseRoute(route)
+		if err != nil {
+			continue
+		}
+		if rmethod != "" && rmethod != method && !(rmethod == "GET" && method == "HEAD") {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(rpath, "*"); ok {
+			if strings.HasPrefix(cleaned, prefix) {
+				return nil
+			}
+		} else if rpath == cleaned {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s %s", ErrRouteNotAllowed, method, urlPath)
+}
+
+// parseRoute splits route into its method, empty for any, and path
+func parseRoute(route string) (string, string, error) {
+	method, rpath, ok := strings.Cut(strings.TrimSpace(route), " ")
+	if !ok {
+		method, rpath = "", method
+	}
+	rpath = strings.TrimSpace(rpath)
+	if method != "" && strings.ToUpper(method) != method {
+		return "", "", errors.New("method must be upper case")
+	}
+	if !strings.HasPrefix(rpath, "/") {
+		return "", "", errors.New("path must start with /")
+	}
+	if i := strings.Index(rpath, "*"); i >= 0 && (i != len(rpath)-1 || !strings.HasSuffix(rpath, "/*")) {
+		return "", "", errors.New("only a trailing \"/*\" wildcard is allowed")
+	}
+	return method, rpath, nil
+}
@@ -0,0 +1,139 @@
+package apikeys
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestKeyCheckIP(t *testing.T) {
+	type args struct {
+		cidrs []string
+		addr  string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"unrestricted", args{nil, "198.51.100.7"}, false},
+		{"inside", args{[]string{"203.0.113.0/24"}, "203.0.113.9"}, false},
+		{"outside", args{[]string{"203.0.113.0/24"}, "198.51.100.7"}, true},
+		{"second cidr", args{[]string{"203.0.113.0/24", "2001:db8::/32"}, "2001:db8::1"}, false},
+		{"mapped v4", args{[]string{"203.0.113.0/24"}, "::ffff:203.0.113.9"}, false},
+		{"single host", args{[]string{"203.0.113.9/32"}, "203.0.113.10"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ak Key
+			WithAllowedCIDRs(tt.args.cidrs...)(&ak)
+			err := ak.CheckIP(netip.MustParseAddr(tt.args.addr))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckIP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrIPNotAllowed) {
+				t.Errorf("CheckIP() error = %v, want ErrIPNotAllowed", err)
+			}
+		})
+	}
+}
+
+func TestKeyCheckOrigin(t *testing.T) {
+	type args struct {
+		origins []string
+		origin  string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"unrestricted", args{nil, ""}, false},
+		{"exact", args{[]string{"https://app.example.com"}, "https://app.example.com"}, false},
+		{"case", args{[]string{"https://App.Example.com"}, "HTTPS://app.example.COM"}, false},
+		{"other host", args{[]string{"https://app.example.com"}, "https://evil.example"}, true},
+		{"scheme", args{[]string{"https://app.example.com"}, "http://app.example.com"}, true},
+		{"port", args{[]string{"http://localhost:3000"}, "http://localhost:3000"}, false},
+		{"wrong port", args{[]string{"http://localhost:3000"}, "http://localhost:4000"}, true},
+		{"wildcard", args{[]string{"https://*.example.com"}, "https://a.b.example.com"}, false},
+		{"wildcard bare domain", args{[]string{"https://*.example.com"}, "https://example.com"}, true},
+		{"wildcard suffix trick", args{[]string{"https://*.example.com"}, "https://evilexample.com"}, true},
+		{"missing", args{[]string{"https://app.example.com"}, ""}, true},
+		{"opaque", args{[]string{"https://app.example.com"}, "null"}, true},
+		{"presented wildcard", args{[]string{"https://*.example.com"}, "https://*.example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ak Key
+			WithAllowedOrigins(tt.args.origins...)(&ak)
+			err := ak.CheckOrigin(tt.args.origin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckOrigin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrOriginNotAllowed) {
+				t.Errorf("CheckOrigin() error = %v, want ErrOriginNotAllowed", err)
+			}
+		})
+	}
+}
+
+func TestKeyCheckRoute(t *testing.T) {
+	type args struct {
+		routes []string
+		method string
+		path   string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"unrestricted", args{nil, "DELETE", "/v1/keys"}, false},
+		{"prefix", args{[]string{"GET /v1/reports/*"}, "GET", "/v1/reports/2026/q1"}, false},
+		{"head", args{[]string{"GET /v1/reports/*"}, "HEAD", "/v1/reports/q1"}, false},
+		{"method", args{[]string{"GET /v1/reports/*"}, "POST", "/v1/reports/q1"}, true},
+		{"outside prefix", args{[]string{"GET /v1/reports/*"}, "GET", "/v1/users"}, true},
+		{"prefix parent", args{[]string{"GET /v1/reports/*"}, "GET", "/v1/reports"}, true},
+		{"dot segments", args{[]string{"GET /v1/reports/*"}, "GET", "/v1/reports/../admin"}, true},
+		{"exact", args{[]string{"POST /v1/events"}, "POST", "/v1/events"}, false},
+		{"exact longer", args{[]string{"POST /v1/events"}, "POST", "/v1/events/1"}, true},
+		{"any method", args{[]string{"/v1/events"}, "PUT", "/v1/events"}, false},
+		{"second route", args{[]string{"GET /v1/reports/*", "POST /v1/events"}, "POST", "/v1/events"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ak Key
+			WithAllowedRoutes(tt.args.routes...)(&ak)
+			err := ak.CheckRoute(tt.args.method, tt.args.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrRouteNotAllowed) {
+				t.Errorf("CheckRoute() error = %v, want ErrRouteNotAllowed", err)
+			}
+		})
+	}
+}
+
+func TestRestrictionsValidate(t *testing.T) {
+	if _, _, err := NewAdmin(NewMemStore()).Create(t.Context(), testAlg, WithAllowedCIDRs("203.0.113.0/33")); err == nil {
+		t.Error("Create() with a bad cidr succeeded")
+	}
+	for _, origin := range []string{"app.example.com", "https://app.example.com/path", "https://a.*.example.com", "https://user@example.com"} {
+		if err := (Restrictions{AllowedOrigins: []string{origin}}).validate(); err == nil {
+			t.Errorf("validate() accepted origin %s", origin)
+		}
+	}
+	for _, route := range []string{"GET v1/reports", "get /v1/reports", "GET /v1/*/reports", "GET /v1/reports*"} {
+		if err := (Restrictions{AllowedRoutes: []string{route}}).validate(); err == nil {
+			t.Errorf("validate() accepted route %s", route)
+		}
+	}
+	many := make([]string, MaxRestrictions+1)
+	for i := range many {
+		many[i] = "203.0.113.0/24"
+	}
+	if err := (Restrictions{AllowedCIDRs: many}).validate(); err == nil {
+		t.Error("validate() accepted too many cidrs")
+	}
+}
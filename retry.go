@@ -0,0 +1,128 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryConfig tunes RetryStore. Zero fields take the defaults.
+type RetryConfig struct {
+	// MaxAttempts is the number of tries including the first, 3 by default
+	MaxAttempts int
+	// Backoff is the longest wait before the first retry, 50ms by default.
+	// It doubles for each further retry up to MaxBackoff, 1s by default.
+	// The actual wait is a random fraction of it, so that instances which
+	// failed together do not retry together.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retryable classifies an error from the store. By default every error is
+	// retried except ErrNotFound, ErrExists, ErrCircuitOpen and the context
+	// ending.
+	Retryable func(error) bool
+	// RetryWrites retries Create, Update and Delete as well as Get and List.
+	// A write whose response was lost may have been applied, so a retried
+	// Create can fail with ErrExists and a retried Delete with ErrNotFound.
+	RetryWrites bool
+	// OnRetry, if set, is called before each retry with the store method
+	// name, the attempt that failed, counting from 1, and its error
+	OnRetry func(op string, attempt int, err error)
+}
+
+func isRetryable(err error) bool {
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExists) && !errors.Is(err, ErrCircuitOpen) &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// WithRetry retries failed operations of the store of an Admin or
+// StoreVerifier, see RetryStore. With WithCircuitBreaker each attempt counts
+// towards the breaker, and retrying stops once it opens.
+func WithRetry(cfg RetryConfig) Option {
+	return func(o *options) {
+		o.retry = &cfg
+	}
+}
+
+// RetryStore wraps store so that operations failing with a retryable error
+// are tried again after a jittered exponential backoff, giving up early if
+// the context ends
+func RetryStore(store Store, cfg RetryConfig) Store {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 50 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Second
+	}
+	if cfg.Retryable == nil {
+		cfg.Retryable = isRetryable
+	}
+	return &retryStore{store: store, cfg: cfg}
+}
+
+type retryStore struct {
+	store Store
+	cfg   RetryConfig
+}
+
+func (s *retryStore) do(ctx context.Context, op string, write bool, fn func() error) error {
+	backoff := s.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || (write && !s.cfg.RetryWrites) || attempt >= s.cfg.MaxAttempts || !s.cfg.Retryable(err) {
+			return err
+		}
+		if s.cfg.OnRetry != nil {
+			s.cfg.OnRetry(op, attempt, err)
+		}
+		t := time.NewTimer(rand.N(backoff) + 1)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		backoff = min(2*backoff, s.cfg.MaxBackoff)
+	}
+}
+
+func (s *retryStore) Create(ctx context.Context, ak Key) error {
+	return s.do(ctx, "Create", true, func() error {
+		return s.store.Create(ctx, ak)
+	})
+}
+
+func (s *retryStore) Get(ctx context.Context, clientID string) (Key, error) {
+	var ak Key
+	err := s.do(ctx, "Get", false, func() error {
+		var err error
+		ak, err = s.store.Get(ctx, clientID)
+		return err
+	})
+	return ak, err
+}
+
+func (s *retryStore) Update(ctx context.Context, ak Key) error {
+	return s.do(ctx, "Update", true, func() error {
+		return s.store.Update(ctx, ak)
+	})
+}
+
+func (s *retryStore) Delete(ctx context.Context, clientID string) error {
+	return s.do(ctx, "Delete", true, func() error {
+		return s.store.Delete(ctx, clientID)
+	})
+}
+
+func (s *retryStore) List(ctx context.Context) ([]Key, error) {
+	var keys []Key
+	err := s.do(ctx, "List", false, func() error {
+		var err error
+		keys, err = s.store.List(ctx)
+		return err
+	})
+	return keys, err
+}
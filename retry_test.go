@@ -0,0 +1,117 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyStore fails the next failures calls with errBlip
+type flakyStore struct {
+	*MemStore
+	failures int
+	calls    int
+}
+
+var errBlip = errors.New("connection reset")
+
+func (s *flakyStore) fail() error {
+	s.calls++
+	if s.failures > 0 {
+		s.failures--
+		return errBlip
+	}
+	return nil
+}
+
+func (s *flakyStore) Get(ctx context.Context, clientID string) (Key, error) {
+	if err := s.fail(); err != nil {
+		return Key{}, err
+	}
+	return s.MemStore.Get(ctx, clientID)
+}
+
+func (s *flakyStore) Update(ctx context.Context, ak Key) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.MemStore.Update(ctx, ak)
+}
+
+func TestRetryStore(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemStore()
+	_, ak, err := NewAdmin(mem).Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		cfg      RetryConfig
+		failures int
+		write    bool
+		clientID string
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantErr   error
+		wantCalls int
+	}{
+		{"no failures", args{RetryConfig{}, 0, false, ak.ClientID}, nil, 1},
+		{"recovers", args{RetryConfig{}, 2, false, ak.ClientID}, nil, 3},
+		{"gives up", args{RetryConfig{}, 3, false, ak.ClientID}, errBlip, 3},
+		{"more attempts", args{RetryConfig{MaxAttempts: 5}, 4, false, ak.ClientID}, nil, 5},
+		{"not found not retried", args{RetryConfig{}, 0, false, "missing"}, ErrNotFound, 1},
+		{"classifier", args{RetryConfig{Retryable: func(error) bool { return false }}, 1, false, ak.ClientID}, errBlip, 1},
+		{"writes not retried", args{RetryConfig{}, 1, true, ak.ClientID}, errBlip, 1},
+		{"writes retried", args{RetryConfig{RetryWrites: true}, 1, true, ak.ClientID}, nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyStore{MemStore: mem, failures: tt.args.failures}
+			var retries []int
+			cfg := tt.args.cfg
+			cfg.Backoff = time.Millisecond
+			cfg.OnRetry = func(op string, attempt int, err error) { retries = append(retries, attempt) }
+			store := RetryStore(flaky, cfg)
+			if tt.args.write {
+				err = store.Update(ctx, ak)
+			} else {
+				_, err = store.Get(ctx, tt.args.clientID)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if flaky.calls != tt.wantCalls || len(retries) != tt.wantCalls-1 {
+				t.Errorf("calls = %d retries %v, want %d calls", flaky.calls, retries, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryStoreContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	flaky := &flakyStore{MemStore: NewMemStore(), failures: 10}
+	store := RetryStore(flaky, RetryConfig{MaxAttempts: 10, Backoff: time.Hour, OnRetry: func(string, int, error) { cancel() }})
+	if _, err := store.Get(ctx, "client-1"); !errors.Is(err, errBlip) {
+		t.Errorf("Get() error = %v, want the last store error", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("calls = %d, want 1", flaky.calls)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	// A verifier rides out a blip
+	mem := NewMemStore()
+	apikey, _, err := NewAdmin(mem).Create(context.Background(), testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewStoreVerifier(&flakyStore{MemStore: mem, failures: 1}, WithRetry(RetryConfig{Backoff: time.Millisecond}))
+	if _, err := v.Verify(context.Background(), apikey); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RotateGracefully is Rotate, except the previous secret keeps verifying
+// until grace has passed or FinalizeRotation is called, so that clients can
+// move to the new secret without an outage. While both verify, the
+// verifier's RotationUsage shows how much traffic still uses the old one.
+func (a *Admin) RotateGracefully(ctx context.Context, clientID, alg string, grace time.Duration) (string, Key, error) {
+	if grace <= 0 {
+		return "", Key{}, fmt.Errorf("bad rotation grace period %s", grace)
+	}
+	return a.rotate(ctx, clientID, alg, grace)
+}
+
+// FinalizeRotation stops the previous secret of clientID verifying before its
+// grace period ends. Finalizing a key with no previous secret is not an
+// error.
+func (a *Admin) FinalizeRotation(ctx context.Context, clientID string) (Key, error) {
+	ak, err := a.store.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	if len(ak.PreviousDerivedKey) == 0 && ak.PreviousExpiresAt.IsZero() {
+		return ak, nil
+	}
+	clear(ak.PreviousDerivedKey)
+	ak.PreviousDerivedKey = nil
+	ak.PreviousExpiresAt = time.Time{}
+	if err := a.store.Update(ctx, ak); err != nil {
+		return Key{}, err
+	}
+	a.emit(ctx, AuditKeyFinalized, ak, nil)
+	return ak, nil
+}
+
+// InRotation is true if the previous secret of the key still verifies at now
+func (ak Key) InRotation(now time.Time) bool {
+	return len(ak.PreviousDerivedKey) > 0 && now.Before(ak.PreviousExpiresAt)
+}
+
+// RotationMetrics is optionally implemented by a Metrics to count the
+// successful verifications of keys in a rotation grace period by whether the
+// previous secret was used
+type RotationMetrics interface {
+	ObserveRotationUse(previous bool)
+}
+
+// RotationUsage counts the verifications of a key since its last graceful
+// rotation, as seen by one StoreVerifier
+type RotationUsage struct {
+	RotatedAt time.Time `json:"rotated_at"`
+	Current   uint64    `json:"current"`
+	Previous  uint64    `json:"previous"`
+	// LastPrevious is the last time the previous secret verified
+	LastPrevious time.Time `json:"last_previous,omitzero"`
+	// PreviousExpiresAt is the end of the grace period
+	PreviousExpiresAt time.Time `json:"previous_expires_at"`
+}
+
+// rotationSweepInterval is how often the tracker drops the usage of keys
+// whose grace period has ended
+const rotationSweepInterval = time.Minute
+
+// rotationTracker holds the RotationUsage of the keys seen in rotation
+type rotationTracker struct {
+	mu    sync.Mutex
+	usage map[string]RotationUsage
+	swept time.Time
+}
+
+func (t *rotationTracker) observe(ak Key, previous bool, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.usage == nil {
+		t.usage = make(map[string]RotationUsage)
+	}
+	if now.Sub(t.swept) >= rotationSweepInterval {
+		t.sweep(now)
+	}
+	u := t.usage[ak.ClientID]
+	if !u.RotatedAt.Equal(ak.RotatedAt) {
+		u = RotationUsage{RotatedAt: ak.RotatedAt}
+	}
+	u.PreviousExpiresAt = ak.PreviousExpiresAt
+	if previous {
+		u.Previous++
+		u.LastPrevious = now
+	} else {
+		u.Current++
+	}
+	t.usage[ak.ClientID] = u
+}
+
+// sweep drops the usage of keys out of rotation at now, so the tracker holds
+// only the keys currently in rotation rather than every key ever rotated
+func (t *rotationTracker) sweep(now time.Time) {
+	t.swept = now
+	for clientID, u := range t.usage {
+		if !now.Before(u.PreviousExpiresAt) {
+			delete(t.usage, clientID)
+		}
+	}
+}
+
+// RotationUsage reports how the key clientID has been verified since it was
+// rotated with RotateGracefully, ok is false if the verifier has not seen it
+// in rotation or the grace period has ended. Once Previous stops growing the
+// rotation can be finalized. Each verifier counts only its own traffic; sum
+// them across instances.
+func (v *StoreVerifier) RotationUsage(clientID string) (RotationUsage, bool) {
+	v.rotation.mu.Lock()
+	defer v.rotation.mu.Unlock()
+	u, ok := v.rotation.usage[clientID]
+	if ok && !v.now().Before(u.PreviousExpiresAt) {
+		delete(v.rotation.usage, clientID)
+		return RotationUsage{}, false
+	}
+	return u, ok
+}
+
+// observeRotation records which secret of ak, which is in rotation, verified
+func (v *StoreVerifier) observeRotation(ak Key, previous bool, now time.Time) {
+	v.rotation.observe(ak, previous, now)
+	if m, ok := v.metrics.(RotationMetrics); ok {
+		m.ObserveRotationUse(previous)
+	}
+}
@@ -0,0 +1,140 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRotateGracefully(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+	store := NewMemStore()
+	counters := NewCounters()
+	admin := NewAdmin(store, WithClock(clock))
+	verifier := NewStoreVerifier(store, WithClock(clock), WithMetrics(counters))
+
+	old, ak, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := verifier.RotationUsage(ak.ClientID); ok {
+		t.Errorf("RotationUsage() ok before rotation")
+	}
+
+	if _, _, err := admin.RotateGracefully(ctx, ak.ClientID, "", 0); err == nil {
+		t.Errorf("RotateGracefully() with no grace period succeeded")
+	}
+	current, rotated, err := admin.RotateGracefully(ctx, ak.ClientID, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := now.Add(time.Hour); !rotated.PreviousExpiresAt.Equal(want) {
+		t.Errorf("PreviousExpiresAt = %v, want %v", rotated.PreviousExpiresAt, want)
+	}
+	if !rotated.InRotation(now) {
+		t.Errorf("InRotation() = false after RotateGracefully")
+	}
+
+	type args struct {
+		apikey string
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"current", args{current}},
+		{"previous", args{old}},
+		{"previous again", args{old}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(time.Minute)
+			if _, err := verifier.Verify(ctx, tt.args.apikey); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+
+	u, ok := verifier.RotationUsage(ak.ClientID)
+	if !ok {
+		t.Fatal("RotationUsage() not ok during rotation")
+	}
+	if u.Current != 1 || u.Previous != 2 || !u.LastPrevious.Equal(now) || !u.RotatedAt.Equal(rotated.RotatedAt) {
+		t.Errorf("RotationUsage() = %+v", u)
+	}
+	if s := counters.Stats(); s.RotationCurrent != 1 || s.RotationPrevious != 2 {
+		t.Errorf("Stats() rotation = %d/%d, want 1/2", s.RotationCurrent, s.RotationPrevious)
+	}
+
+	now = rotated.PreviousExpiresAt
+	if _, err := verifier.Verify(ctx, old); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() previous after grace error = %v, want ErrMismatch", err)
+	}
+	if _, err := verifier.Verify(ctx, current); err != nil {
+		t.Errorf("Verify() current after grace error = %v", err)
+	}
+	if _, ok := verifier.RotationUsage(ak.ClientID); ok {
+		t.Errorf("RotationUsage() ok after grace")
+	}
+}
+
+func TestRotationTrackerSweep(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tracker rotationTracker
+	tracker.observe(Key{ClientID: "a", RotatedAt: now, PreviousExpiresAt: now.Add(time.Minute)}, false, now)
+	tracker.observe(Key{ClientID: "b", RotatedAt: now, PreviousExpiresAt: now.Add(time.Hour)}, false, now)
+	now = now.Add(rotationSweepInterval)
+	tracker.observe(Key{ClientID: "c", RotatedAt: now, PreviousExpiresAt: now.Add(time.Hour)}, true, now)
+	if _, ok := tracker.usage["a"]; ok || len(tracker.usage) != 2 {
+		t.Errorf("usage after sweep = %v, want b and c", tracker.usage)
+	}
+}
+
+func TestFinalizeRotation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	verifier := NewStoreVerifier(store)
+
+	old, ak, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Finalizing with nothing to finalize is a no-op
+	if _, err := admin.FinalizeRotation(ctx, ak.ClientID); err != nil {
+		t.Fatal(err)
+	}
+	current, _, err := admin.RotateGracefully(ctx, ak.ClientID, "", 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	final, err := admin.FinalizeRotation(ctx, ak.ClientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(final.PreviousDerivedKey) != 0 || !final.PreviousExpiresAt.IsZero() {
+		t.Errorf("FinalizeRotation() left previous secret %x until %v", final.PreviousDerivedKey, final.PreviousExpiresAt)
+	}
+	if _, err := verifier.Verify(ctx, old); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() previous after finalize error = %v, want ErrMismatch", err)
+	}
+	if _, err := verifier.Verify(ctx, current); err != nil {
+		t.Errorf("Verify() current after finalize error = %v", err)
+	}
+
+	// A plain Rotate drops the previous secret immediately
+	if _, _, err := admin.RotateGracefully(ctx, ak.ClientID, "", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, rotated, err := admin.Rotate(ctx, ak.ClientID, ""); err != nil {
+		t.Fatal(err)
+	} else if len(rotated.PreviousDerivedKey) != 0 {
+		t.Errorf("Rotate() kept previous secret")
+	}
+}
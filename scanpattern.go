@@ -0,0 +1,127 @@
+package apikeys
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ScanPattern describes the keys a Config produces to secret scanning tools,
+// eg GitHub custom patterns, gitleaks or trufflehog. Regenerate it whenever
+// the configured prefixes or encoding change. The expressions are RE2, which
+// those tools all accept, and Secret matches more than just valid keys:
+// run candidates through Validate, or use Find, to rule out false positives.
+type ScanPattern struct {
+	// Secret matches a key
+	Secret string `json:"secret"`
+	// Before and After match the characters either side of a key, the start
+	// or end of the text or a character which can't be part of it. Before
+	// allows a '=', as in APIKEY=..., but After does not, to take in all of
+	// the padding.
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// Prefixes are the prefixes a key starts with, any if empty
+	Prefixes []string `json:"prefixes,omitempty"`
+	// MinLength and MaxLength bound the length of a key, including its
+	// prefix
+	MinLength int `json:"min_length"`
+	MaxLength int `json:"max_length"`
+
+	decode []DecodeOption
+	policy Policy
+	re     *regexp.Regexp
+}
+
+// ScanPattern returns the secret scanning pattern for the keys of c, which
+// must be valid
+func (c Config) ScanPattern() (ScanPattern, error) {
+	if err := c.Validate(); err != nil {
+		return ScanPattern{}, err
+	}
+	chars := `A-Za-z0-9_\-`
+	if c.Encoding == EncodingBase64 {
+		chars += `+/`
+	}
+	// The shortest inner layer strict decoding accepts has a one character
+	// client id and alg, and the minimum salt and password
+	minInner := 5 + 2*base64.URLEncoding.EncodedLen(minSecretLen)
+	minBody := base64.URLEncoding.EncodedLen(minInner)
+	shortest, longest := 0, 0
+	var quoted []string
+	for i, p := range c.Prefixes {
+		quoted = append(quoted, regexp.QuoteMeta(p))
+		if i == 0 || len(p) < shortest {
+			shortest = len(p)
+		}
+		longest = max(longest, len(p))
+	}
+	p := ScanPattern{
+		Before:    `(?:\A|[^` + chars + `])`,
+		After:     `(?:\z|[^` + chars + `=])`,
+		Prefixes:  c.Prefixes,
+		MinLength: shortest + minBody,
+		MaxLength: MaxEncodedKeyLen,
+		decode:    append(c.DecodeOptions(), WithStrict()),
+		policy:    c.Policy,
+	}
+	// Padding is at most two characters, and only ever at the end. RE2
+	// repeats are limited to 1000, so the length is only bounded below and
+	// Validate checks MaxLength.
+	body := fmt.Sprintf(`[%s]{%d,}={0,2}`, chars, minBody-2)
+	p.Secret = body
+	if len(quoted) > 0 {
+		p.Secret = `(?:` + strings.Join(quoted, "|") + `)` + body
+	}
+	var err error
+	if p.re, err = regexp.Compile(p.Regexp()); err != nil {
+		return ScanPattern{}, fmt.Errorf("%w: bad scan pattern: %v", ErrConfig, err)
+	}
+	return p, nil
+}
+
+// Regexp is Before, Secret and After as a single expression, with the key in
+// its first group, for tools without separate boundary patterns
+func (p ScanPattern) Regexp() string {
+	return p.Before + `(` + p.Secret + `)` + p.After
+}
+
+// Validate returns an error if candidate, text matched by Secret, is not a
+// well formed key of the configuration: it must have a configured prefix and
+// encoding, decode strictly, see ValidateEncodedKey, and have an alg within
+// the policy. It never consults a store, so a valid candidate may still be
+// for a key that was never issued.
+func (p ScanPattern) Validate(candidate string) error {
+	if len(candidate) < p.MinLength || len(candidate) > p.MaxLength {
+		return fmt.Errorf("%w: length %d outside %d-%d", ErrInvalid, len(candidate), p.MinLength, p.MaxLength)
+	}
+	ak, _, err := Decode(candidate, p.decode...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := p.policy.Check(ak.alg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+// Find returns the valid keys in text
+func (p ScanPattern) Find(text string) []string {
+	if p.re == nil {
+		return nil
+	}
+	var found []string
+	// Adjacent keys share a boundary character, which the expression
+	// consumes, so search again from the end of each match
+	for text != "" {
+		m := p.re.FindStringSubmatchIndex(text)
+		if m == nil {
+			break
+		}
+		if candidate := text[m[2]:m[3]]; p.Validate(candidate) == nil {
+			found = append(found, candidate)
+		}
+		text = text[m[3]:]
+	}
+	return found
+}
@@ -0,0 +1,118 @@
+package apikeys
+
+import (
+	"encoding/base64"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestScanPattern(t *testing.T) {
+	k1, err := NewKey(testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key1, err := k1.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := NewKey(testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := k2.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const strongAlg = "argon2id 2 16MB 16"
+	strong, err := NewKey(strongAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strongKey, err := strong.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	piped, err := NewKey(testAlg, WithKeySeparators(Separators{ID: '|', Part: '~'}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipedKey, err := piped.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// re-encoded with standard base64, as some clients do
+	inner, _ := base64.URLEncoding.DecodeString(key2)
+	std := base64.StdEncoding.EncodeToString(inner)
+	lookalike := "live_" + base64.URLEncoding.EncodeToString([]byte(strings.Repeat("not a key at all ", 8)))
+
+	type args struct {
+		cfg  Config
+		text string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "prefixed",
+			args: args{
+				cfg:  Config{Alg: testAlg, Prefixes: []string{"live_", "test_"}},
+				text: "APIKEY=live_" + key1 + "\nother: \"test_" + key2 + "\" unprefixed " + key1 + " " + lookalike,
+			},
+			want: []string{"live_" + key1, "test_" + key2},
+		},
+		{
+			name: "adjacent",
+			args: args{cfg: Config{Alg: testAlg, Prefixes: []string{"live_"}}, text: "live_" + key1 + ",live_" + key2},
+			want: []string{"live_" + key1, "live_" + key2},
+		},
+		{
+			name: "unprefixed",
+			args: args{cfg: Config{Alg: testAlg}, text: "[" + key1 + "] x" + key2},
+			want: []string{key1},
+		},
+		{
+			name: "separators",
+			args: args{cfg: Config{Alg: testAlg, Separators: "|~"}, text: key1 + " " + pipedKey},
+			want: []string{pipedKey},
+		},
+		{
+			name: "standard base64",
+			args: args{cfg: Config{Alg: testAlg, Encoding: EncodingBase64}, text: "key " + std},
+			want: []string{std},
+		},
+		{
+			name: "outside policy",
+			args: args{cfg: Config{Alg: strongAlg, Policy: Policy{MinTime: 2}}, text: key1 + " " + strongKey},
+			want: []string{strongKey},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.args.cfg.ScanPattern()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, expr := range []string{p.Secret, p.Before, p.After, p.Regexp()} {
+				if _, err := regexp.Compile(expr); err != nil {
+					t.Errorf("bad expression %q: %v", expr, err)
+				}
+			}
+			if got := p.Find(tt.args.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Find() = %q, want %q", got, tt.want)
+			}
+			for _, k := range tt.want {
+				if n := len(k); n < p.MinLength || n > p.MaxLength {
+					t.Errorf("key length %d outside %d-%d", n, p.MinLength, p.MaxLength)
+				}
+			}
+		})
+	}
+
+	if _, err := (Config{Alg: "nope"}).ScanPattern(); err == nil {
+		t.Error("ScanPattern() of an invalid config succeeded")
+	}
+}
@@ -0,0 +1,31 @@
+package apikeys
+
+// redacted is what a Secret formats and marshals as
+const redacted = "[REDACTED]"
+
+// Secret holds sensitive bytes, such as passwords and salts. It formats and
+// marshals as "[REDACTED]" so that printing or logging a Key, or a struct
+// containing one, can not leak the credential. Convert to []byte to use the
+// value.
+type Secret []byte
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return redacted
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// Wipe zeroes the secret in place
+func (s Secret) Wipe() {
+	clear(s)
+}
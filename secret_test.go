@@ -0,0 +1,82 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSecretRedacted(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	presented, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaks := func(s string) bool {
+		return strings.Contains(s, fmt.Sprint([]byte(password))) ||
+			strings.Contains(s, fmt.Sprintf("%x", []byte(password))) ||
+			strings.Contains(s, fmt.Sprint([]byte(presented.Salt))) ||
+			strings.Contains(s, fmt.Sprintf("%x", []byte(presented.Salt)))
+	}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%x", "%q"} {
+		for name, v := range map[string]interface{}{"key": presented, "password": password, "salt": presented.Salt} {
+			if s := fmt.Sprintf(format, v); leaks(s) {
+				t.Errorf("Sprintf(%s, %s) leaks: %s", format, name, s)
+			}
+		}
+	}
+	if s := fmt.Sprint(password); s != redacted {
+		t.Errorf("Sprint(password) = %s, want %s", s, redacted)
+	}
+
+	b, err := json.Marshal(struct{ Password Secret }{password})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"Password":"[REDACTED]"}` {
+		t.Errorf("json.Marshal() = %s", b)
+	}
+}
+
+func TestWipe(t *testing.T) {
+	ak, err := NewKey(testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.GenerateBytes()
+	if err != nil {
+		t.Fatalf("GenerateBytes() error = %v", err)
+	}
+	presented, password, err := Decode(string(apikey))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !presented.MatchPassword(password, ak.DerivedKey) {
+		t.Fatalf("GenerateBytes() key does not verify")
+	}
+
+	salt, derived := ak.Salt, ak.DerivedKey
+	ak.Wipe()
+	apikey.Wipe()
+	password.Wipe()
+	if ak.Salt != nil || ak.DerivedKey != nil {
+		t.Errorf("Wipe() left Salt or DerivedKey set")
+	}
+	for name, b := range map[string][]byte{"salt": salt, "derived": derived, "apikey": apikey, "password": password} {
+		for _, c := range b {
+			if c != 0 {
+				t.Errorf("%s not zeroed", name)
+				break
+			}
+		}
+	}
+}
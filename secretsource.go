@@ -0,0 +1,182 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretCredScheme names a systemd credential, resolved in
+// $CREDENTIALS_DIRECTORY
+const secretCredScheme = "cred:"
+
+// ErrSecretRef is returned for a secret reference that is malformed or can
+// not be resolved
+var ErrSecretRef = errors.New("bad secret reference")
+
+// LoadSecret resolves a reference to a pepper, signing key, store credential
+// or other secret. References are "env:NAME" for an environment variable,
+// "file:PATH" for a file, eg a mounted Kubernetes secret, and "cred:NAME" for
+// a systemd credential (LoadCredential= and friends). A single trailing
+// newline is removed from files. Empty secrets are errors.
+//
+// "shares:REF,REF,..." loads shares from SplitSecret with each of the other
+// kinds of reference and combines them, eg
+// "shares:cred:share-1,file:/run/share-2", so no one place holds the secret.
+func LoadSecret(ref string) (Secret, error) {
+	var b []byte
+	switch {
+	case strings.HasPrefix(ref, secretEnvScheme):
+		name := ref[len(secretEnvScheme):]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: environment variable `%s' is not set", ErrSecretRef, name)
+		}
+		b = []byte(v)
+	case strings.HasPrefix(ref, secretFileScheme):
+		path := ref[len(secretFileScheme):]
+		var err error
+		if b, err = readSecretFile(path); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(ref, secretCredScheme):
+		name := ref[len(secretCredScheme):]
+		dir := os.Getenv("CREDENTIALS_DIRECTORY")
+		if dir == "" {
+			return nil, fmt.Errorf("%w: `%s' but CREDENTIALS_DIRECTORY is not set", ErrSecretRef, ref)
+		}
+		if name == "" || strings.ContainsRune(name, filepath.Separator) || name == "." || name == ".." {
+			return nil, fmt.Errorf("%w: bad credential name `%s'", ErrSecretRef, name)
+		}
+		var err error
+		if b, err = readSecretFile(filepath.Join(dir, name)); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(ref, secretSharesScheme):
+		return loadShares(ref[len(secretSharesScheme):])
+	default:
+		return nil, fmt.Errorf("%w: `%s' is not env:NAME, file:PATH, cred:NAME or shares:REF,...", ErrSecretRef, ref)
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%w: `%s' is empty", ErrSecretRef, ref)
+	}
+	return Secret(b), nil
+}
+
+func validSecretRef(ref string) bool {
+	if refs, ok := strings.CutPrefix(ref, secretSharesScheme); ok {
+		for _, ref := range strings.Split(refs, ",") {
+			if strings.HasPrefix(ref, secretSharesScheme) || !validSecretRef(ref) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, scheme := range []string{secretEnvScheme, secretFileScheme, secretCredScheme} {
+		if strings.HasPrefix(ref, scheme) && len(ref) > len(scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func readSecretFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSecretRef, err)
+	}
+	if n := len(b); n > 0 && b[n-1] == '\n' {
+		b = b[:n-1]
+		if n := len(b); n > 0 && b[n-1] == '\r' {
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
+
+// SecretWatcher holds the current value of a secret reference, reloading it
+// periodically. Files are re-read rather than watched for events, which also
+// catches the symlink swap Kubernetes uses to update mounted secrets.
+// Environment variables do not change under a running process, so watching
+// one only ever returns its first value.
+type SecretWatcher struct {
+	ref      string
+	onChange func(Secret)
+
+	mu      sync.RWMutex
+	current Secret
+	err     error
+	done    chan struct{}
+}
+
+// WatchSecret loads ref, failing if it can not be resolved, then reloads it
+// every interval until ctx is done. onChange, if not nil, is called with each
+// new value. A failed reload keeps the last good value, see Err. interval
+// must be positive.
+func WatchSecret(ctx context.Context, ref string, interval time.Duration, onChange func(Secret)) (*SecretWatcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: bad watch interval %s for `%s'", ErrConfig, interval, ref)
+	}
+	s, err := LoadSecret(ref)
+	if err != nil {
+		return nil, err
+	}
+	w := &SecretWatcher{ref: ref, onChange: onChange, current: s, done: make(chan struct{})}
+	go w.run(ctx, interval)
+	return w, nil
+}
+
+// Get returns the current value. It must not be modified or wiped.
+func (w *SecretWatcher) Get() Secret {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Err returns the error from the last reload, nil if it succeeded
+func (w *SecretWatcher) Err() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.err
+}
+
+// Done is closed once the watcher has stopped
+func (w *SecretWatcher) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *SecretWatcher) run(ctx context.Context, interval time.Duration) {
+	defer close(w.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *SecretWatcher) reload() {
+	s, err := LoadSecret(w.ref)
+	w.mu.Lock()
+	w.err = err
+	changed := err == nil && !bytes.Equal(s, w.current)
+	if changed {
+		// The previous value may still be in use by callers of Get, so it is
+		// left for the garbage collector rather than wiped
+		w.current = s
+	}
+	w.mu.Unlock()
+	if changed && w.onChange != nil {
+		w.onChange(s)
+	}
+}
@@ -0,0 +1,129 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadSecret(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "pepper"), []byte("from-file\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "empty"), nil, 0600)
+	os.WriteFile(filepath.Join(dir, "signing"), []byte("from-cred"), 0600)
+	t.Setenv("TEST_PEPPER", "from-env")
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	type args struct {
+		ref string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{"env", args{"env:TEST_PEPPER"}, "from-env", false},
+		{"env unset", args{"env:TEST_UNSET_PEPPER"}, "", true},
+		{"file", args{"file:" + filepath.Join(dir, "pepper")}, "from-file", false},
+		{"file missing", args{"file:" + filepath.Join(dir, "missing")}, "", true},
+		{"file empty", args{"file:" + filepath.Join(dir, "empty")}, "", true},
+		{"cred", args{"cred:signing"}, "from-cred", false},
+		{"cred traversal", args{"cred:../signing"}, "", true},
+		{"no scheme", args{"TEST_PEPPER"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadSecret(tt.args.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrSecretRef) {
+				t.Errorf("LoadSecret() error = %v, want %v", err, ErrSecretRef)
+			}
+			if string(got) != tt.want {
+				t.Errorf("LoadSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSecretNoCredentialsDirectory(t *testing.T) {
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	if _, err := LoadSecret("cred:pepper"); !errors.Is(err, ErrSecretRef) {
+		t.Errorf("LoadSecret() error = %v, want %v", err, ErrSecretRef)
+	}
+}
+
+func TestConfigLoadPepper(t *testing.T) {
+	t.Setenv("TEST_PEPPER", "pepper")
+	got, err := Config{Pepper: "env:TEST_PEPPER"}.LoadPepper()
+	if err != nil || string(got) != "pepper" {
+		t.Errorf("LoadPepper() = %q, %v", got, err)
+	}
+	if got, err := (Config{}).LoadPepper(); got != nil || err != nil {
+		t.Errorf("LoadPepper() unset = %q, %v", got, err)
+	}
+}
+
+func TestWatchSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pepper")
+	if err := os.WriteFile(path, []byte("one"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	changes := make(chan string, 1)
+	w, err := WatchSecret(ctx, "file:"+path, time.Millisecond, func(s Secret) { changes <- string(s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(w.Get()); got != "one" {
+		t.Fatalf("Get() = %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-changes:
+		if got != "two" {
+			t.Errorf("onChange(%q), want two", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload")
+	}
+	if got := string(w.Get()); got != "two" {
+		t.Errorf("Get() = %q after reload", got)
+	}
+
+	// A failed reload keeps the last good value
+	os.Remove(path)
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Err() == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !errors.Is(w.Err(), ErrSecretRef) || string(w.Get()) != "two" {
+		t.Errorf("after removal Get() = %q, Err() = %v", w.Get(), w.Err())
+	}
+
+	cancel()
+	<-w.Done()
+}
+
+func TestWatchSecretUnresolved(t *testing.T) {
+	if _, err := WatchSecret(t.Context(), "env:TEST_UNSET_PEPPER", time.Second, nil); !errors.Is(err, ErrSecretRef) {
+		t.Errorf("WatchSecret() error = %v, want %v", err, ErrSecretRef)
+	}
+}
+
+func TestWatchSecretInterval(t *testing.T) {
+	t.Setenv("TEST_WATCH_PEPPER", "pepper")
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := WatchSecret(t.Context(), "env:TEST_WATCH_PEPPER", interval, nil); !errors.Is(err, ErrConfig) {
+			t.Errorf("WatchSecret(%s) error = %v, want %v", interval, err, ErrConfig)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package apikeys
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSeparatorsValidate(t *testing.T) {
+	type args struct {
+		s Separators
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"zero", args{Separators{}}, false},
+		{"default", args{DefaultSeparators}, false},
+		{"custom", args{Separators{ID: '|', Part: '~'}}, false},
+		{"base64 alphabet", args{Separators{ID: '-', Part: '.'}}, true},
+		{"padding", args{Separators{ID: ':', Part: '='}}, true},
+		{"alg grammar", args{Separators{ID: ',', Part: '.'}}, true},
+		{"space", args{Separators{ID: ' ', Part: '.'}}, true},
+		{"not ascii", args{Separators{ID: 0xa7, Part: '.'}}, true},
+		{"same", args{Separators{ID: '|', Part: '|'}}, true},
+		{"half set", args{Separators{ID: '|'}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.s.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrConfig) {
+				t.Errorf("Validate() error = %v, want ErrConfig", err)
+			}
+		})
+	}
+}
+
+func TestKeySeparators(t *testing.T) {
+	seps := Separators{ID: '|', Part: '~'}
+	type args struct {
+		opts []KeyOption
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"custom", args{[]KeyOption{WithClientID("client:1.a")}}, false},
+		{"tenant", args{[]KeyOption{WithClientID("client-1"), WithTenant("acme")}}, false},
+		{"client id with separator", args{[]KeyOption{WithClientID("client|1")}}, true},
+		{"tenant with separator", args{[]KeyOption{WithTenant("ac~me")}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ak, err := NewKey(testAlg, append(tt.args.opts, WithKeySeparators(seps))...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			apikey, err := ak.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, password, err := Decode(apikey, WithDecodeSeparators(seps), WithStrict())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer password.Wipe()
+			if decoded.ClientID != ak.ClientID || decoded.TenantID != ak.TenantID || decoded.alg.String != testAlg {
+				t.Errorf("Decode() = %+v, want %+v", decoded, ak)
+			}
+			if _, _, err := Decode(apikey); err == nil {
+				t.Error("Decode() with the default separators succeeded")
+			}
+		})
+	}
+	if _, _, err := Decode("Y2xpZW50OmFiYw==", WithDecodeSeparators(Separators{ID: 'x', Part: '.'})); !errors.Is(err, ErrConfig) {
+		t.Errorf("Decode() with bad separators = %v, want ErrConfig", err)
+	}
+}
+
+func TestAdminSeparators(t *testing.T) {
+	ctx := t.Context()
+	seps := WithSeparators(Separators{ID: '|', Part: '~'})
+	store := NewMemStore()
+	admin := NewAdmin(store, seps)
+	verifier := NewStoreVerifier(store, seps)
+	apikey, ak, err := admin.Create(ctx, testAlg, WithClientID("client:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, apikey); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	if _, err := NewStoreVerifier(store).Verify(ctx, apikey); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify() with the default separators = %v, want ErrInvalid", err)
+	}
+	rotated, _, err := admin.Rotate(ctx, ak.ClientID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(ctx, rotated); err != nil {
+		t.Errorf("Verify() of the rotated key = %v", err)
+	}
+	tenanted, _, err := admin.Create(ctx, testAlg, WithTenant("team.a"))
+	if err != nil {
+		t.Fatalf("Create() with a tenant containing a default separator = %v", err)
+	}
+	if _, err := verifier.Verify(ctx, tenanted); err != nil {
+		t.Errorf("Verify() of the tenanted key = %v", err)
+	}
+	if _, _, err := admin.Create(ctx, testAlg, WithTenant("team~a")); err == nil {
+		t.Error("Create() of a tenant containing the separator succeeded")
+	}
+	if _, _, err := admin.Create(ctx, testAlg, WithClientID("client|2")); err == nil {
+		t.Error("Create() of a client id containing the separator succeeded")
+	}
+	bad := NewAdmin(store, WithSeparators(Separators{ID: 'a', Part: '~'}))
+	if _, _, err := bad.Create(ctx, testAlg); !errors.Is(err, ErrConfig) {
+		t.Errorf("Create() with bad separators = %v, want ErrConfig", err)
+	}
+}
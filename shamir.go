@@ -0,0 +1,189 @@
+package apikeys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// secretSharesScheme combines shares loaded from a comma separated list
+	// of other references
+	secretSharesScheme = "shares:"
+
+	// shareHeaderLen is the threshold, x coordinate and split id ahead of
+	// the share bytes
+	shareHeaderLen  = 2 + shareSplitIDLen
+	shareSplitIDLen = 4
+	// shareCheckLen is the length of the check split along with the secret
+	shareCheckLen = 4
+	maxShares     = 255
+)
+
+// ErrShares is returned when shares can not be combined, eg there are fewer
+// than the threshold or they come from different splits
+var ErrShares = errors.New("bad secret shares")
+
+var shareEncoding = base64.RawURLEncoding
+
+// SplitSecret splits secret, eg a pepper, into n shares of which any k
+// recover it with CombineShares, and fewer than k reveal nothing about it.
+// It is Shamir's secret sharing over GF(256). Shares are url safe base64
+// text, for handing to operators. They carry the threshold and a random id
+// of the split, and a short check of the secret is split with it, so
+// combining the wrong shares fails rather than producing a different secret
+// while fewer than k shares reveal nothing about the check either.
+func SplitSecret(secret Secret, n, k int) ([]Secret, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("%w: empty secret", ErrShares)
+	}
+	if k < 2 || k > n || n > maxShares {
+		return nil, fmt.Errorf("%w: can't split into %d shares with threshold %d", ErrShares, n, k)
+	}
+	var splitID [shareSplitIDLen]byte
+	if _, err := io.ReadFull(rand.Reader, splitID[:]); err != nil {
+		return nil, fmt.Errorf("insufficient rand bytes splitting secret: %w", err)
+	}
+	check := sha256.Sum256(secret)
+	payload := append(append(make([]byte, 0, len(secret)+shareCheckLen), secret...), check[:shareCheckLen]...)
+	defer clear(payload)
+	raw := make([][]byte, n)
+	for i := range raw {
+		raw[i] = make([]byte, shareHeaderLen+len(payload))
+		raw[i][0], raw[i][1] = byte(k), byte(i+1)
+		copy(raw[i][2:shareHeaderLen], splitID[:])
+	}
+	// The coefficients of each byte's polynomial, the constant term being
+	// the byte itself
+	coeffs := make([]byte, k)
+	defer clear(coeffs)
+	for j, b := range payload {
+		coeffs[0] = b
+		if _, err := io.ReadFull(rand.Reader, coeffs[1:]); err != nil {
+			return nil, fmt.Errorf("insufficient rand bytes splitting secret: %w", err)
+		}
+		for i := range raw {
+			raw[i][shareHeaderLen+j] = gfEval(coeffs, byte(i+1))
+		}
+	}
+	shares := make([]Secret, n)
+	for i, r := range raw {
+		shares[i] = Secret(shareEncoding.AppendEncode(nil, r))
+		clear(r)
+	}
+	return shares, nil
+}
+
+// CombineShares recovers the secret from at least the threshold number of
+// shares made by SplitSecret
+func CombineShares(shares ...Secret) (Secret, error) {
+	var raw [][]byte
+	defer func() {
+		for _, r := range raw {
+			clear(r)
+		}
+	}()
+	for _, s := range shares {
+		r, err := shareEncoding.AppendDecode(nil, bytes.TrimSpace(s))
+		if err != nil || len(r) <= shareHeaderLen+shareCheckLen {
+			return nil, fmt.Errorf("%w: malformed share", ErrShares)
+		}
+		raw = append(raw, r)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: no shares", ErrShares)
+	}
+	first := raw[0]
+	seen := map[byte]bool{}
+	for _, r := range raw {
+		if len(r) != len(first) || !bytes.Equal(r[:1], first[:1]) || !bytes.Equal(r[2:shareHeaderLen], first[2:shareHeaderLen]) {
+			return nil, fmt.Errorf("%w: shares are from different splits", ErrShares)
+		}
+		if r[1] == 0 || seen[r[1]] {
+			return nil, fmt.Errorf("%w: duplicate share %d", ErrShares, r[1])
+		}
+		seen[r[1]] = true
+	}
+	if k := int(first[0]); len(raw) < k {
+		return nil, fmt.Errorf("%w: got %d shares, need %d", ErrShares, len(raw), k)
+	}
+
+	// Lagrange interpolation at x = 0. Subtraction is xor in GF(256).
+	payload := make([]byte, len(first)-shareHeaderLen)
+	defer clear(payload)
+	for i, ri := range raw {
+		basis := byte(1)
+		for j, rj := range raw {
+			if i != j {
+				basis = gfMul(basis, gfMul(rj[1], gfInv(rj[1]^ri[1])))
+			}
+		}
+		for n, y := range ri[shareHeaderLen:] {
+			payload[n] ^= gfMul(y, basis)
+		}
+	}
+	secret, got := payload[:len(payload)-shareCheckLen], payload[len(payload)-shareCheckLen:]
+	check := sha256.Sum256(secret)
+	if !bytes.Equal(check[:shareCheckLen], got) {
+		return nil, fmt.Errorf("%w: shares do not combine to the secret they were split from", ErrShares)
+	}
+	return append(Secret(nil), secret...), nil
+}
+
+// loadShares resolves the comma separated references of a shares: reference
+// and combines them
+func loadShares(refs string) (Secret, error) {
+	var shares []Secret
+	defer func() {
+		for _, s := range shares {
+			s.Wipe()
+		}
+	}()
+	for _, ref := range strings.Split(refs, ",") {
+		if strings.HasPrefix(ref, secretSharesScheme) {
+			return nil, fmt.Errorf("%w: nested `%s'", ErrSecretRef, secretSharesScheme)
+		}
+		s, err := LoadSecret(ref)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return CombineShares(shares...)
+}
+
+// gfMul multiplies in GF(256) with the AES polynomial, without tables or
+// branches on the operands
+func gfMul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		p ^= -(b & 1) & a
+		a = a<<1 ^ (0x1b & -(a >> 7))
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv is a^254, the inverse of a non zero a
+func gfInv(a byte) byte {
+	r := a
+	for range 6 {
+		a = gfMul(a, a)
+		r = gfMul(r, a)
+	}
+	return gfMul(r, r)
+}
+
+// gfEval evaluates the polynomial with coeffs, lowest degree first, at x
+func gfEval(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return y
+}
@@ -0,0 +1,114 @@
+package apikeys
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitSecret(t *testing.T) {
+	secret := Secret(bytes.Repeat([]byte("pepper-0123456789"), 2))
+	shares, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := shareEncoding.DecodeString(string(shares[2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] ^= 1
+	tampered := Secret(shareEncoding.EncodeToString(raw))
+	type args struct {
+		shares []Secret
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"threshold", args{shares[:3]}, false},
+		{"any threshold", args{[]Secret{shares[4], shares[1], shares[2]}}, false},
+		{"all", args{shares}, false},
+		{"too few", args{shares[:2]}, true},
+		{"duplicate", args{[]Secret{shares[0], shares[0], shares[1]}}, true},
+		{"mixed splits", args{[]Secret{shares[0], shares[1], other[2]}}, true},
+		{"malformed", args{[]Secret{shares[0], shares[1], Secret("!")}}, true},
+		{"tampered", args{[]Secret{shares[0], shares[1], tampered}}, true},
+		{"none", args{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CombineShares(tt.args.shares...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CombineShares() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrShares) {
+					t.Errorf("CombineShares() error = %v, want ErrShares", err)
+				}
+				return
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("CombineShares() = %x, want %x", []byte(got), []byte(secret))
+			}
+		})
+	}
+	check := sha256.Sum256(secret)
+	for _, s := range shares {
+		if bytes.Contains(s, secret[:4]) {
+			t.Errorf("share %s contains the secret", s)
+		}
+		raw, err := shareEncoding.DecodeString(string(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(raw, check[:shareCheckLen]) {
+			t.Errorf("share %s contains the check of the secret", s)
+		}
+	}
+	for _, nk := range [][2]int{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := SplitSecret(secret, nk[0], nk[1]); !errors.Is(err, ErrShares) {
+			t.Errorf("SplitSecret(%d of %d) error = %v, want ErrShares", nk[1], nk[0], err)
+		}
+	}
+}
+
+func TestGFInv(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if got := gfMul(byte(a), gfInv(byte(a))); got != 1 {
+			t.Fatalf("%#02x * inv = %#02x", a, got)
+		}
+	}
+}
+
+func TestLoadSecretShares(t *testing.T) {
+	pepper := Secret(bytes.Repeat([]byte{7}, MinPepperLen))
+	shares, err := SplitSecret(pepper, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "share-2"), append(shares[1], '\n'), 0600)
+	t.Setenv("TEST_SHARE_1", string(shares[0]))
+
+	got, err := LoadSecret("shares:env:TEST_SHARE_1,file:" + filepath.Join(dir, "share-2"))
+	if err != nil || !bytes.Equal(got, pepper) {
+		t.Errorf("LoadSecret(shares) = %x, %v", []byte(got), err)
+	}
+	if _, err := LoadSecret("shares:env:TEST_SHARE_1"); !errors.Is(err, ErrShares) {
+		t.Errorf("LoadSecret() of one share error = %v, want ErrShares", err)
+	}
+	if _, err := LoadSecret("shares:env:TEST_SHARE_1,shares:env:TEST_SHARE_1"); !errors.Is(err, ErrSecretRef) {
+		t.Errorf("LoadSecret() of nested shares error = %v, want ErrSecretRef", err)
+	}
+	if !validSecretRef("shares:env:A,cred:b") || validSecretRef("shares:env:A,nope") {
+		t.Errorf("validSecretRef() of shares")
+	}
+}
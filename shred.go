@@ -0,0 +1,85 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	nanoid "github.com/matoous/go-nanoid"
+)
+
+// tombstonePrefix starts the client id PurgeSubject gives a tombstone
+const tombstonePrefix = "tombstone-"
+
+// Shredded is true for a tombstone left by Admin.Shred: a revoked record with
+// no derived key or imported hash
+func (ak Key) Shredded() bool {
+	return ak.Revoked() && len(ak.DerivedKey) == 0 && ak.ImportedHash == ""
+}
+
+// tombstone destroys the credential material and descriptive metadata of ak,
+// keeping what audit continuity needs: client id, tenant, type, alg and
+// times
+func (ak Key) tombstone(now time.Time) Key {
+	ak.Wipe()
+	t := Key{
+		alg:       ak.alg,
+		ClientID:  ak.ClientID,
+		TenantID:  ak.TenantID,
+		Type:      ak.Type,
+		CreatedAt: ak.CreatedAt,
+		RotatedAt: ak.RotatedAt,
+		RevokedAt: ak.RevokedAt,
+		ExpiresAt: ak.ExpiresAt,
+
+		LastUsedAt: ak.LastUsedAt,
+	}
+	if t.RevokedAt.IsZero() {
+		t.RevokedAt = now
+	}
+	return t
+}
+
+// Shred replaces the record for clientID with a tombstone. The salt, derived
+// key and imported hash are destroyed, as are the name, description, owner,
+// labels, creator and team. The tombstone keeps the client id, tenant, type,
+// alg and times so the audit trail still resolves, and it never verifies.
+// Shredding a tombstone again is not an error.
+func (a *Admin) Shred(ctx context.Context, clientID string) (Key, error) {
+	ak, err := a.store.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	if ak.Shredded() {
+		return ak, nil
+	}
+	t := ak.tombstone(a.now())
+	if err := a.store.Update(ctx, t); err != nil {
+		return Key{}, err
+	}
+	a.emit(ctx, AuditKeyShredded, t, nil)
+	return t, nil
+}
+
+// PurgeSubject handles a data subject erasure request for the key of
+// clientID. It shreds the record, as Shred does, and then moves the
+// tombstone to a random client id so that nothing in the store refers to
+// the subject. The audit event records only the new id. It returns the
+// tombstone.
+func (a *Admin) PurgeSubject(ctx context.Context, clientID string) (Key, error) {
+	ak, err := a.store.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	t := ak.tombstone(a.now())
+	id, err := nanoid.ID(defaultClientNanoIDLen)
+	if err != nil {
+		return Key{}, err
+	}
+	t.ClientID = tombstonePrefix + id
+	if err := a.replace(ctx, clientID, t); err != nil {
+		return Key{}, fmt.Errorf("purging subject: %w", err)
+	}
+	a.emit(ctx, AuditKeyPurged, t, nil)
+	return t, nil
+}
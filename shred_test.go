@@ -0,0 +1,70 @@
+package apikeys
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShred(t *testing.T) {
+	var audit bytes.Buffer
+	store := NewMemStore()
+	admin := NewAdmin(store, WithAudit(NewWriterAuditSink(&audit)))
+	apikey, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"), WithTenant("acme"),
+		WithOwner("subject@example.com"), WithName("personal key"), WithLabels(map[string]string{"email": "subject@example.com"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tomb, err := admin.Shred(t.Context(), "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := store.Get(t.Context(), "client-1")
+	for _, ak := range []Key{tomb, stored} {
+		if !ak.Shredded() || ak.Owner != "" || ak.Name != "" || ak.Labels != nil || len(ak.Salt) != 0 {
+			t.Errorf("tombstone = %+v", ak)
+		}
+		if ak.ClientID != "client-1" || ak.TenantID != "acme" || ak.CreatedAt.IsZero() {
+			t.Errorf("tombstone lost its audit fields: %+v", ak)
+		}
+	}
+	if _, err := NewStoreVerifier(store).Verify(t.Context(), apikey); err == nil {
+		t.Error("Verify() of a shredded key succeeded")
+	}
+	if _, err := admin.Shred(t.Context(), "client-1"); err != nil {
+		t.Errorf("Shred() again error = %v", err)
+	}
+	events := decodeEvents(t, audit.Bytes())
+	if n := len(events); n != 2 || events[1].Type != AuditKeyShredded {
+		t.Errorf("events = %+v, want created and a single shredded", events)
+	}
+}
+
+func TestPurgeSubject(t *testing.T) {
+	var audit bytes.Buffer
+	store := NewMemStore()
+	admin := NewAdmin(store, WithAudit(NewWriterAuditSink(&audit)))
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("subject@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	audit.Reset()
+
+	tomb, err := admin.PurgeSubject(t.Context(), "subject@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tomb.Shredded() || !strings.HasPrefix(tomb.ClientID, tombstonePrefix) {
+		t.Errorf("PurgeSubject() = %+v", tomb)
+	}
+	if _, err := store.Get(t.Context(), "subject@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("subject record remains: %v", err)
+	}
+	if _, err := store.Get(t.Context(), tomb.ClientID); err != nil {
+		t.Errorf("tombstone missing: %v", err)
+	}
+	if strings.Contains(audit.String(), "subject@example.com") {
+		t.Errorf("audit trail names the subject: %s", audit.String())
+	}
+}
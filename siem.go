@@ -0,0 +1,192 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ECSVersion is the Elastic Common Schema version ECSAuditSink events follow
+const ECSVersion = "8.11.0"
+
+// CEF severities, on CEF's 0-10 scale
+const (
+	cefSeverityInfo    = 1
+	cefSeverityChange  = 3
+	cefSeverityFailure = 5
+)
+
+// lineSink writes each event as a line in the format of its formatter
+type lineSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format func(AuditEvent) ([]byte, error)
+}
+
+func (s *lineSink) Emit(ctx context.Context, ev AuditEvent) error {
+	b, err := s.format(ev)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(b)
+	return err
+}
+
+// ECSAuditSink writes events as Elastic Common Schema json lines, ready for
+// ingestion by a SIEM. The client id is reported as user.id.
+type ECSAuditSink struct {
+	lineSink
+}
+
+func NewECSAuditSink(w io.Writer) *ECSAuditSink {
+	return &ECSAuditSink{lineSink{w: w, format: formatECS}}
+}
+
+type ecsEvent struct {
+	Timestamp time.Time         `json:"@timestamp"`
+	Message   string            `json:"message"`
+	ECS       ecsVersion        `json:"ecs"`
+	Event     ecsEventFields    `json:"event"`
+	User      *ecsUser          `json:"user,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type ecsVersion struct {
+	Version string `json:"version"`
+}
+
+type ecsEventFields struct {
+	Kind     string   `json:"kind"`
+	Category []string `json:"category"`
+	Type     []string `json:"type"`
+	Action   string   `json:"action"`
+	Outcome  string   `json:"outcome"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+type ecsUser struct {
+	ID string `json:"id"`
+}
+
+func formatECS(ev AuditEvent) ([]byte, error) {
+	e := ecsEvent{
+		Timestamp: ev.Time.UTC(),
+		Message:   "api key " + ev.Type,
+		ECS:       ecsVersion{ECSVersion},
+		Event: ecsEventFields{
+			Kind:    "event",
+			Action:  ev.Type,
+			Outcome: auditOutcome(ev),
+			Reason:  ev.Error,
+		},
+	}
+	switch ev.Type {
+	case AuditVerifySuccess, AuditVerifyFailed:
+		e.Event.Category = []string{"authentication"}
+		e.Event.Type = []string{"info"}
+	case AuditKeyCreated, AuditKeyImported:
+		e.Event.Category = []string{"iam"}
+		e.Event.Type = []string{"creation"}
+	case AuditKeyAnomaly:
+		e.Event.Kind = "alert"
+		e.Event.Category = []string{"intrusion_detection"}
+		e.Event.Type = []string{"info"}
+		if ev.Result == AnomalyVeto.String() {
+			e.Event.Type = []string{"denied"}
+		}
+	case AuditKeyRevoked, AuditKeyShredded, AuditKeyPurged, AuditKeyRejected, AuditKeyDeleted:
+		e.Event.Category = []string{"iam"}
+		e.Event.Type = []string{"deletion"}
+	default:
+		e.Event.Category = []string{"iam"}
+		e.Event.Type = []string{"change"}
+	}
+	if ev.ClientID != "" {
+		e.User = &ecsUser{ID: ev.ClientID}
+	}
+	for k, v := range map[string]string{"alg": ev.Alg, "result": ev.Result, "actor": ev.Actor, "team": ev.Team} {
+		if v == "" {
+			continue
+		}
+		if e.Labels == nil {
+			e.Labels = map[string]string{}
+		}
+		e.Labels[k] = v
+	}
+	return json.Marshal(e)
+}
+
+// CEFAuditSink writes events in ArcSight Common Event Format, one per line
+type CEFAuditSink struct {
+	lineSink
+}
+
+// NewCEFAuditSink writes CEF lines identifying the device as vendor and
+// product
+func NewCEFAuditSink(w io.Writer, vendor, product string) *CEFAuditSink {
+	format := func(ev AuditEvent) ([]byte, error) {
+		return formatCEF(ev, vendor, product), nil
+	}
+	return &CEFAuditSink{lineSink{w: w, format: format}}
+}
+
+func formatCEF(ev AuditEvent, vendor, product string) []byte {
+	severity := cefSeverityChange
+	switch {
+	case auditOutcome(ev) == "failure":
+		severity = cefSeverityFailure
+	case ev.Type == AuditVerifySuccess:
+		severity = cefSeverityInfo
+	}
+	var b strings.Builder
+	b.WriteString("CEF:0|")
+	for _, f := range []string{vendor, product, "1", ev.Type, "api key " + ev.Type, strconv.Itoa(severity)} {
+		b.WriteString(cefHeaderEscaper.Replace(f))
+		b.WriteByte('|')
+	}
+	ext := []string{
+		"rt", strconv.FormatInt(ev.Time.UnixMilli(), 10),
+		"act", ev.Type,
+		"outcome", auditOutcome(ev),
+		"suser", ev.ClientID,
+		"reason", ev.Error,
+	}
+	for _, cs := range []struct{ n, label, v string }{{"1", "alg", ev.Alg}, {"2", "actor", ev.Actor}, {"3", "team", ev.Team}} {
+		if cs.v != "" {
+			ext = append(ext, "cs"+cs.n+"Label", cs.label, "cs"+cs.n, cs.v)
+		}
+	}
+	sep := ""
+	for i := 0; i < len(ext); i += 2 {
+		if ext[i+1] == "" {
+			continue
+		}
+		b.WriteString(sep)
+		b.WriteString(ext[i])
+		b.WriteByte('=')
+		b.WriteString(cefExtensionEscaper.Replace(ext[i+1]))
+		sep = " "
+	}
+	return []byte(b.String())
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// auditOutcome is "failure" for failed verifications and events carrying
+// an error, otherwise "success"
+func auditOutcome(ev AuditEvent) string {
+	if ev.Type == AuditVerifyFailed || ev.Error != "" {
+		return "failure"
+	}
+	return "success"
+}
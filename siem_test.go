@@ -0,0 +1,60 @@
+package apikeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var siemEvents = []AuditEvent{
+	{Time: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), Type: AuditVerifyFailed, ClientID: "client-1", Alg: testAlg, Result: ResultMismatch, Error: ErrMismatch.Error()},
+	{Time: time.Date(2030, 1, 1, 0, 0, 1, 0, time.UTC), Type: AuditKeyRevoked, ClientID: "client=1|x"},
+}
+
+func TestECSAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewECSAuditSink(&buf)
+	for _, ev := range siemEvents {
+		if err := sink.Emit(t.Context(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	event := got["event"].(map[string]any)
+	if got["@timestamp"] != "2030-01-01T00:00:00Z" || event["outcome"] != "failure" ||
+		event["action"] != AuditVerifyFailed || event["category"].([]any)[0] != "authentication" ||
+		got["user"].(map[string]any)["id"] != "client-1" || got["ecs"].(map[string]any)["version"] != ECSVersion {
+		t.Errorf("ECS event = %s", lines[0])
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if event := got["event"].(map[string]any); event["type"].([]any)[0] != "deletion" || event["outcome"] != "success" {
+		t.Errorf("ECS revocation event = %s", lines[1])
+	}
+}
+
+func TestCEFAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewCEFAuditSink(&buf, "Example|Corp", "apikeys")
+	for _, ev := range siemEvents {
+		if err := sink.Emit(t.Context(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := `CEF:0|Example\|Corp|apikeys|1|key.verify_failed|api key key.verify_failed|5|rt=1893456000000 act=key.verify_failed outcome=failure suser=client-1 reason=api key does not match cs1Label=alg cs1=argon2id 1 16MB 16
+CEF:0|Example\|Corp|apikeys|1|key.revoked|api key key.revoked|3|rt=1893456001000 act=key.revoked outcome=success suser=client\=1|x
+`
+	if got := buf.String(); got != want {
+		t.Errorf("CEF =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package apikeys
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	snapshotFormat = "apikeys-snapshot"
+	// SnapshotVersion is the snapshot format version ExportSnapshot writes
+	SnapshotVersion = 1
+)
+
+// ErrSnapshot is returned for snapshots that are malformed, truncated or of
+// an unsupported version
+var ErrSnapshot = errors.New("invalid apikeys snapshot")
+
+// snapshotHeader is the first line of a snapshot
+type snapshotHeader struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// snapshotEntry is each following line, a record or the closing count
+type snapshotEntry struct {
+	Key   *Key `json:"key,omitempty"`
+	Count *int `json:"count,omitempty"`
+}
+
+// ExportSnapshot writes every record in store to w as a versioned stream of
+// json lines: a header, one line per record and a closing count, so that a
+// truncated snapshot is detected on import. Records are written with their
+// alg and salt, as by MarshalKeyJSON, and so are as sensitive as the store
+// itself. It returns the number of records written.
+func ExportSnapshot(ctx context.Context, store Store, w io.Writer) (int, error) {
+	keys, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(snapshotHeader{Format: snapshotFormat, Version: SnapshotVersion, CreatedAt: time.Now().UTC()}); err != nil {
+		return 0, err
+	}
+	for i := range keys {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		if err := enc.Encode(snapshotEntry{Key: &keys[i]}); err != nil {
+			return i, err
+		}
+	}
+	n := len(keys)
+	if err := enc.Encode(snapshotEntry{Count: &n}); err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// ImportSnapshot reads a snapshot written by ExportSnapshot into store.
+// Records already in the store are replaced by those in the snapshot, other
+// records in the store are left alone. The snapshot is checked as it is
+// read, so an error part way leaves the records before it imported. It
+// returns the number of records imported.
+func ImportSnapshot(ctx context.Context, store Store, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	var h snapshotHeader
+	if err := dec.Decode(&h); err != nil {
+		return 0, fmt.Errorf("%w: bad header: %v", ErrSnapshot, err)
+	}
+	if h.Format != snapshotFormat {
+		return 0, fmt.Errorf("%w: format `%s'", ErrSnapshot, h.Format)
+	}
+	if h.Version != SnapshotVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrSnapshot, h.Version)
+	}
+
+	n := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		var e snapshotEntry
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("missing count, the snapshot is truncated")
+			}
+			return n, fmt.Errorf("%w: %v", ErrSnapshot, err)
+		}
+		if e.Count != nil {
+			if *e.Count != n {
+				return n, fmt.Errorf("%w: count %d but read %d records", ErrSnapshot, *e.Count, n)
+			}
+			return n, nil
+		}
+		if e.Key == nil {
+			return n, fmt.Errorf("%w: entry %d has no key", ErrSnapshot, n)
+		}
+		err := store.Create(ctx, *e.Key)
+		if errors.Is(err, ErrExists) {
+			err = store.Update(ctx, *e.Key)
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
@@ -0,0 +1,81 @@
+package apikeys
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	src := NewMemStore()
+	admin := NewAdmin(src)
+	apikey, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Revoke(t.Context(), "client-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := ExportSnapshot(t.Context(), src, &buf); err != nil || n != 2 {
+		t.Fatalf("ExportSnapshot() = %d, %v", n, err)
+	}
+
+	dst := NewMemStore()
+	// An existing record is replaced by the snapshot
+	if err := dst.Create(t.Context(), Key{ClientID: "client-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := ImportSnapshot(t.Context(), dst, bytes.NewReader(buf.Bytes())); err != nil || n != 2 {
+		t.Fatalf("ImportSnapshot() = %d, %v", n, err)
+	}
+	want, _ := src.List(t.Context())
+	got, _ := dst.List(t.Context())
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imported records = %+v, want %+v", got, want)
+	}
+	if _, err := NewStoreVerifier(dst).Verify(t.Context(), apikey); err != nil {
+		t.Errorf("Verify() against restored store error = %v", err)
+	}
+}
+
+func TestImportSnapshotErrors(t *testing.T) {
+	var buf bytes.Buffer
+	store := NewMemStore()
+	if _, _, err := NewAdmin(store).Create(t.Context(), testAlg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExportSnapshot(t.Context(), store, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	type args struct {
+		snapshot string
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"empty", args{""}},
+		{"wrong format", args{`{"format":"other","version":1}` + "\n"}},
+		{"future version", args{`{"format":"apikeys-snapshot","version":2}` + "\n"}},
+		{"truncated", args{lines[0] + lines[1]}},
+		{"wrong count", args{lines[0] + lines[1] + `{"count":2}` + "\n"}},
+		{"empty entry", args{lines[0] + "{}\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportSnapshot(t.Context(), NewMemStore(), strings.NewReader(tt.args.snapshot))
+			if !errors.Is(err, ErrSnapshot) {
+				t.Errorf("ImportSnapshot() error = %v, want ErrSnapshot", err)
+			}
+		})
+	}
+}
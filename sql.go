@@ -0,0 +1,157 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Blob is binary data, such as a derived key, that reads and writes directly
+// as a database/sql column.
+type Blob []byte
+
+// Value implements driver.Valuer
+func (b Blob) Value() (driver.Value, error) {
+	if b == nil {
+		return nil, nil
+	}
+	return []byte(b), nil
+}
+
+// Scan implements sql.Scanner. The column value is copied, the driver may
+// reuse its buffer.
+func (b *Blob) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*b = nil
+	case []byte:
+		*b = append(Blob(nil), v...)
+	case string:
+		*b = Blob(v)
+	default:
+		return fmt.Errorf("can not scan `%T' into a Blob", src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer, storing the canonical alg string
+func (a Alg) Value() (driver.Value, error) {
+	if a.String == "" {
+		return nil, nil
+	}
+	return a.String, nil
+}
+
+// Scan implements sql.Scanner, parsing and validating the alg string
+func (a *Alg) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*a = Alg{}
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("can not scan `%T' into an Alg", src)
+	}
+	alg, err := ParseAlg(s)
+	if err != nil {
+		return err
+	}
+	*a = alg
+	return nil
+}
+
+// Value implements driver.Valuer, storing the json encoding of the key
+// including its alg and salt. See MarshalKeyJSON.
+func (ak Key) Value() (driver.Value, error) {
+	b, err := MarshalKeyJSON(ak)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner for a column written by Key.Value
+func (ak *Key) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*ak = Key{}
+		return nil
+	case []byte:
+		return ak.UnmarshalJSON(v)
+	case string:
+		return ak.UnmarshalJSON([]byte(v))
+	default:
+		return fmt.Errorf("can not scan `%T' into a Key", src)
+	}
+}
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// sqlTable is a table accessed through database/sql
+type sqlTable struct {
+	db     *sql.DB
+	table  string
+	dollar bool
+}
+
+// SQLOption configures the sql stores
+type SQLOption func(*sqlTable)
+
+// WithDollarPlaceholders numbers the query parameters $1, $2, ... as postgres
+// requires, instead of using ?
+func WithDollarPlaceholders() SQLOption {
+	return func(t *sqlTable) {
+		t.dollar = true
+	}
+}
+
+func newSQLTable(db *sql.DB, table string, opts []SQLOption) (sqlTable, error) {
+	if !sqlIdentifier.MatchString(table) {
+		return sqlTable{}, fmt.Errorf("%w: bad table name `%s'", ErrConfig, table)
+	}
+	t := sqlTable{db: db, table: table}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	return t, nil
+}
+
+// placeholder renders query parameter i, counting from 1
+func (t sqlTable) placeholder(i int) string {
+	if t.dollar {
+		return "$" + strconv.Itoa(i)
+	}
+	return "?"
+}
+
+// placeholders renders the first n query parameters as a list
+func (t sqlTable) placeholders(n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = t.placeholder(i + 1)
+	}
+	return strings.Join(ph, ", ")
+}
+
+// createTable runs the create statements for the table and its index on
+// columns
+func (t sqlTable) createTable(ctx context.Context, columns, index string) error {
+	name := strings.ReplaceAll(t.table, ".", "_") + "_idx"
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS ` + t.table + ` (` + columns + `)`,
+		`CREATE INDEX IF NOT EXISTS ` + name + ` ON ` + t.table + ` (` + index + `)`,
+	} {
+		if _, err := t.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("creating table `%s': %w", t.table, err)
+		}
+	}
+	return nil
+}
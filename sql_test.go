@@ -0,0 +1,84 @@
+package apikeys
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ sql.Scanner   = (*Key)(nil)
+	_ driver.Valuer = Key{}
+	_ sql.Scanner   = (*Alg)(nil)
+	_ driver.Valuer = Alg{}
+	_ sql.Scanner   = (*Blob)(nil)
+	_ driver.Valuer = Blob{}
+)
+
+func TestKeySQLRoundTrip(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ak.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	v, err := ak.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []any{v, []byte(v.(string))} {
+		var got Key
+		if err := got.Scan(src); err != nil {
+			t.Fatalf("Scan(%T) error = %v", src, err)
+		}
+		if !reflect.DeepEqual(got, ak) {
+			t.Errorf("Scan(%T) = %+v, want %+v", src, got, ak)
+		}
+	}
+}
+
+func TestSQLScan(t *testing.T) {
+	type args struct {
+		dest sql.Scanner
+		src  any
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"alg string", args{new(Alg), testAlg}, false},
+		{"alg bytes", args{new(Alg), []byte(testAlg)}, false},
+		{"alg null", args{new(Alg), nil}, false},
+		{"alg invalid", args{new(Alg), "argon2id 0 1MB 1"}, true},
+		{"alg wrong type", args{new(Alg), int64(1)}, true},
+		{"blob bytes", args{new(Blob), []byte{1, 2}}, false},
+		{"blob null", args{new(Blob), nil}, false},
+		{"blob wrong type", args{new(Blob), 1.5}, true},
+		{"key null", args{new(Key), nil}, false},
+		{"key bad json", args{new(Key), "{"}, true},
+		{"key wrong type", args{new(Key), true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.args.dest.Scan(tt.args.src); (err != nil) != tt.wantErr {
+				t.Errorf("Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBlobScanCopies(t *testing.T) {
+	src := []byte{1, 2, 3}
+	var b Blob
+	if err := b.Scan(src); err != nil {
+		t.Fatal(err)
+	}
+	src[0] = 9
+	if !bytes.Equal(b, []byte{1, 2, 3}) {
+		t.Errorf("Scan() aliased the driver buffer: %v", b)
+	}
+}
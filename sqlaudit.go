@@ -0,0 +1,105 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLAuditStore is an AuditStore over a database/sql table, see CreateTable
+// for its schema. Its queries are plain sql which postgres, mysql and sqlite
+// all accept; use WithDollarPlaceholders for postgres.
+type SQLAuditStore struct {
+	sqlTable
+}
+
+var _ AuditStore = (*SQLAuditStore)(nil)
+
+// NewSQLAuditStore stores events in table, which may be schema qualified
+func NewSQLAuditStore(db *sql.DB, table string, opts ...SQLOption) (*SQLAuditStore, error) {
+	t, err := newSQLTable(db, table, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLAuditStore{t}, nil
+}
+
+// CreateTable creates the table and its index on client id and time if they
+// don't exist. The statements suit postgres and sqlite; for other databases
+// create the equivalent table with a migration tool.
+func (s *SQLAuditStore) CreateTable(ctx context.Context) error {
+	return s.createTable(ctx, `
+	time TIMESTAMP NOT NULL,
+	type VARCHAR(64) NOT NULL,
+	client_id VARCHAR(255) NOT NULL DEFAULT '',
+	alg VARCHAR(64) NOT NULL DEFAULT '',
+	result VARCHAR(32) NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	actor VARCHAR(255) NOT NULL DEFAULT '',
+	team VARCHAR(255) NOT NULL DEFAULT ''
+`, "client_id, time")
+}
+
+func (s *SQLAuditStore) Emit(ctx context.Context, ev AuditEvent) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO `+s.table+` (time, type, client_id, alg, result, error, actor, team) VALUES (`+s.placeholders(8)+`)`,
+		ev.Time.UTC(), ev.Type, ev.ClientID, ev.Alg, ev.Result, ev.Error, ev.Actor, ev.Team)
+	return err
+}
+
+func (s *SQLAuditStore) Query(ctx context.Context, q AuditQuery) ([]AuditEvent, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return s.placeholder(len(args))
+	}
+	if q.ClientID != "" {
+		where = append(where, "client_id = "+arg(q.ClientID))
+	}
+	if len(q.Types) > 0 {
+		var in []string
+		for _, typ := range q.Types {
+			in = append(in, arg(typ))
+		}
+		where = append(where, "type IN ("+strings.Join(in, ", ")+")")
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "time >= "+arg(q.Since.UTC()))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "time < "+arg(q.Until.UTC()))
+	}
+	query := `SELECT time, type, client_id, alg, result, error, actor, team FROM ` + s.table
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY time DESC"
+	if q.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []AuditEvent
+	for rows.Next() {
+		var ev AuditEvent
+		if err := rows.Scan(&ev.Time, &ev.Type, &ev.ClientID, &ev.Alg, &ev.Result, &ev.Error, &ev.Actor, &ev.Team); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLAuditStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE time < `+s.placeholder(1), before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
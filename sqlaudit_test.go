@@ -0,0 +1,187 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSQL is a database/sql driver which records the statements it is given
+// and answers queries with canned rows
+type fakeSQL struct {
+	mu    sync.Mutex
+	stmts []fakeStmt
+	rows  [][]driver.Value
+}
+
+type fakeStmt struct {
+	query string
+	args  []driver.Value
+}
+
+func (f *fakeSQL) Open(string) (driver.Conn, error) { return &fakeSQLConn{f}, nil }
+
+type fakeSQLConn struct{ f *fakeSQL }
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *fakeSQLConn) Close() error              { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error) { return fakeSQLTx{}, nil }
+
+type fakeSQLTx struct{}
+
+func (fakeSQLTx) Commit() error   { return nil }
+func (fakeSQLTx) Rollback() error { return nil }
+
+func (c *fakeSQLConn) record(query string, args []driver.NamedValue) {
+	var values []driver.Value
+	for _, a := range args {
+		values = append(values, a.Value)
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.stmts = append(c.f.stmts, fakeStmt{query: query, args: values})
+}
+
+func (c *fakeSQLConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query, args)
+	return driver.RowsAffected(3), nil
+}
+
+func (c *fakeSQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query, args)
+	return &fakeSQLRows{rows: c.f.rows}, nil
+}
+
+type fakeSQLRows struct{ rows [][]driver.Value }
+
+func (r *fakeSQLRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+func (r *fakeSQLRows) Close() error { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func newFakeSQL(t *testing.T) (*fakeSQL, *sql.DB) {
+	t.Helper()
+	f := &fakeSQL{}
+	db := sql.OpenDB(fakeSQLConnector{f})
+	t.Cleanup(func() { db.Close() })
+	return f, db
+}
+
+type fakeSQLConnector struct{ f *fakeSQL }
+
+func (c fakeSQLConnector) Connect(context.Context) (driver.Conn, error) { return c.f.Open("") }
+func (c fakeSQLConnector) Driver() driver.Driver                        { return c.f }
+
+func TestSQLAuditStoreQuery(t *testing.T) {
+	since := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	type args struct {
+		opts []SQLOption
+		q    AuditQuery
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantQuery string
+		wantArgs  []driver.Value
+	}{
+		{
+			name:      "everything",
+			wantQuery: "SELECT time, type, client_id, alg, result, error, actor, team FROM audit ORDER BY time DESC",
+		},
+		{
+			name: "client and types",
+			args: args{q: AuditQuery{ClientID: "client-1", Types: []string{AuditKeyRotated, AuditKeyRevoked}, Limit: 1}},
+			wantQuery: "SELECT time, type, client_id, alg, result, error, actor, team FROM audit" +
+				" WHERE client_id = ? AND type IN (?, ?) ORDER BY time DESC LIMIT 1",
+			wantArgs: []driver.Value{"client-1", AuditKeyRotated, AuditKeyRevoked},
+		},
+		{
+			name: "time range with dollars",
+			args: args{opts: []SQLOption{WithDollarPlaceholders()}, q: AuditQuery{ClientID: "client-1", Since: since, Until: since.Add(time.Hour)}},
+			wantQuery: "SELECT time, type, client_id, alg, result, error, actor, team FROM audit" +
+				" WHERE client_id = $1 AND time >= $2 AND time < $3 ORDER BY time DESC",
+			wantArgs: []driver.Value{"client-1", since, since.Add(time.Hour)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, db := newFakeSQL(t)
+			f.rows = [][]driver.Value{{since, AuditKeyRotated, "client-1", testAlg, "", "", "alice", "payments"}}
+			s, err := NewSQLAuditStore(db, "audit", tt.args.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			events, err := s.Query(context.Background(), tt.args.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := AuditEvent{Time: since, Type: AuditKeyRotated, ClientID: "client-1", Alg: testAlg, Actor: "alice", Team: "payments"}
+			if len(events) != 1 || !reflect.DeepEqual(events[0], want) {
+				t.Errorf("Query() = %+v, want %+v", events, want)
+			}
+			if got := f.stmts[0]; got.query != tt.wantQuery || !reflect.DeepEqual(got.args, tt.wantArgs) {
+				t.Errorf("query = %q %v, want %q %v", got.query, got.args, tt.wantQuery, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSQLAuditStoreWrites(t *testing.T) {
+	ctx := context.Background()
+	f, db := newFakeSQL(t)
+	if _, err := NewSQLAuditStore(db, "audit; DROP TABLE keys"); !errors.Is(err, ErrConfig) {
+		t.Errorf("NewSQLAuditStore(bad table) = %v, want ErrConfig", err)
+	}
+	s, err := NewSQLAuditStore(db, "ops.audit", WithDollarPlaceholders())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.Emit(ctx, AuditEvent{Time: at, Type: AuditKeyRevoked, ClientID: "client-1", Actor: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := s.Prune(ctx, at)
+	if err != nil || n != 3 {
+		t.Errorf("Prune() = %d, %v", n, err)
+	}
+	if len(f.stmts) != 4 {
+		t.Fatalf("got %d statements", len(f.stmts))
+	}
+	if q := f.stmts[0].query; !strings.HasPrefix(q, "CREATE TABLE IF NOT EXISTS ops.audit (\n\ttime TIMESTAMP") {
+		t.Errorf("create = %q", q)
+	}
+	if q := f.stmts[1].query; q != "CREATE INDEX IF NOT EXISTS ops_audit_idx ON ops.audit (client_id, time)" {
+		t.Errorf("index = %q", q)
+	}
+	insert := f.stmts[2]
+	if !strings.HasSuffix(insert.query, "VALUES ($1, $2, $3, $4, $5, $6, $7, $8)") ||
+		!reflect.DeepEqual(insert.args, []driver.Value{at, AuditKeyRevoked, "client-1", "", "", "", "bob", ""}) {
+		t.Errorf("insert = %q %v", insert.query, insert.args)
+	}
+	if prune := f.stmts[3]; prune.query != "DELETE FROM ops.audit WHERE time < $1" || !reflect.DeepEqual(prune.args, []driver.Value{at}) {
+		t.Errorf("prune = %q %v", prune.query, prune.args)
+	}
+}
@@ -0,0 +1,109 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+)
+
+// sqlUsageBatch bounds the rows of one insert
+const sqlUsageBatch = 100
+
+// SQLUsageStore is a UsageStore over a database/sql table, with a row per
+// increment written, which QueryUsage adds up
+type SQLUsageStore struct {
+	sqlTable
+}
+
+var _ UsageStore = (*SQLUsageStore)(nil)
+
+// NewSQLUsageStore stores usage in table, which may be schema qualified
+func NewSQLUsageStore(db *sql.DB, table string, opts ...SQLOption) (*SQLUsageStore, error) {
+	t, err := newSQLTable(db, table, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLUsageStore{t}, nil
+}
+
+// CreateTable creates the table and its index on client id and bucket start
+// if they don't exist
+func (s *SQLUsageStore) CreateTable(ctx context.Context) error {
+	return s.createTable(ctx, `
+	client_id VARCHAR(255) NOT NULL,
+	bucket_start TIMESTAMP NOT NULL,
+	verifications BIGINT NOT NULL,
+	failures BIGINT NOT NULL
+`, "client_id, bucket_start")
+}
+
+// WriteUsage inserts the buckets in one transaction
+func (s *SQLUsageStore) WriteUsage(ctx context.Context, buckets []UsageBucket) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for batch := range slices.Chunk(buckets, sqlUsageBatch) {
+		var rows []string
+		var args []any
+		for _, b := range batch {
+			var ph []string
+			for _, v := range []any{b.ClientID, b.Start.UTC(), b.Verifications, b.Failures} {
+				args = append(args, v)
+				ph = append(ph, s.placeholder(len(args)))
+			}
+			rows = append(rows, "("+strings.Join(ph, ", ")+")")
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO `+s.table+` (client_id, bucket_start, verifications, failures) VALUES `+strings.Join(rows, ", "),
+			args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLUsageStore) QueryUsage(ctx context.Context, q UsageQuery) ([]UsageBucket, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return s.placeholder(len(args))
+	}
+	if q.ClientID != "" {
+		where = append(where, "client_id = "+arg(q.ClientID))
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "bucket_start >= "+arg(q.Since.UTC()))
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "bucket_start < "+arg(q.Until.UTC()))
+	}
+	query := `SELECT client_id, bucket_start, SUM(verifications), SUM(failures) FROM ` + s.table
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " GROUP BY client_id, bucket_start"
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var buckets []UsageBucket
+	for rows.Next() {
+		var b UsageBucket
+		if err := rows.Scan(&b.ClientID, &b.Start, &b.Verifications, &b.Failures); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rollUp(buckets, q.Daily), nil
+}
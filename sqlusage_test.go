@@ -0,0 +1,53 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLUsageStore(t *testing.T) {
+	ctx := context.Background()
+	f, db := newFakeSQL(t)
+	s, err := NewSQLUsageStore(db, "usage", WithDollarPlaceholders())
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2030, 1, 1, 5, 0, 0, 0, time.UTC)
+	if err := s.WriteUsage(ctx, nil); err != nil || len(f.stmts) != 0 {
+		t.Errorf("WriteUsage(nil) = %v after %d statements", err, len(f.stmts))
+	}
+	if err := s.WriteUsage(ctx, []UsageBucket{
+		{ClientID: "client-1", Start: at, Verifications: 2, Failures: 1},
+		{ClientID: "client-2", Start: at, Verifications: 5},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	insert := f.stmts[0]
+	if !strings.HasSuffix(insert.query, "VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)") ||
+		!reflect.DeepEqual(insert.args, []driver.Value{"client-1", at, int64(2), int64(1), "client-2", at, int64(5), int64(0)}) {
+		t.Errorf("insert = %q %v", insert.query, insert.args)
+	}
+
+	f.rows = [][]driver.Value{
+		{"client-1", at, int64(2), int64(1)},
+		{"client-1", at.Add(time.Hour), int64(3), int64(0)},
+	}
+	got, err := s.QueryUsage(ctx, UsageQuery{ClientID: "client-1", Since: at.Add(-time.Hour), Daily: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []UsageBucket{{ClientID: "client-1", Start: at.Truncate(24 * time.Hour), Verifications: 5, Failures: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QueryUsage() = %+v, want %+v", got, want)
+	}
+	query := f.stmts[1]
+	if query.query != "SELECT client_id, bucket_start, SUM(verifications), SUM(failures) FROM usage"+
+		" WHERE client_id = $1 AND bucket_start >= $2 GROUP BY client_id, bucket_start" ||
+		!reflect.DeepEqual(query.args, []driver.Value{"client-1", at.Add(-time.Hour)}) {
+		t.Errorf("query = %q %v", query.query, query.args)
+	}
+}
@@ -0,0 +1,204 @@
+package apikeys
+
+import (
+	"expvar"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counters is a dependency free Metrics implementation for services which
+// don't run prometheus. Read it with Stats or publish it with Publish.
+type Counters struct {
+	generated      atomic.Uint64
+	generateErrors atomic.Uint64
+	verifications  atomic.Uint64
+	failures       atomic.Uint64
+	derivations    atomic.Uint64
+	deriveNanos    atomic.Uint64
+	storeErrors    atomic.Uint64
+	queued         atomic.Uint64
+	queueNanos     atomic.Uint64
+
+	rotationCurrent  atomic.Uint64
+	rotationPrevious atomic.Uint64
+
+	cacheHits      atomic.Uint64
+	cacheMisses    atomic.Uint64
+	cacheFallbacks atomic.Uint64
+
+	deprecated atomic.Uint64
+
+	mu   sync.Mutex
+	algs map[string]AlgStats
+}
+
+var (
+	_ Metrics            = (*Counters)(nil)
+	_ RotationMetrics    = (*Counters)(nil)
+	_ CacheMetrics       = (*Counters)(nil)
+	_ FallbackMetrics    = (*Counters)(nil)
+	_ DeprecationMetrics = (*Counters)(nil)
+	_ AlgMetrics         = (*Counters)(nil)
+)
+
+// Stats is a point in time snapshot of Counters
+type Stats struct {
+	Generated      uint64        `json:"generated"`
+	GenerateErrors uint64        `json:"generate_errors"`
+	Verifications  uint64        `json:"verifications"`
+	Failures       uint64        `json:"failures"`
+	Derivations    uint64        `json:"derivations"`
+	DeriveTime     time.Duration `json:"derive_time_ns"`
+	StoreErrors    uint64        `json:"store_errors"`
+	Queued         uint64        `json:"queued"`
+	QueueWaitTime  time.Duration `json:"queue_wait_time_ns"`
+	// RotationCurrent and RotationPrevious count the verifications of keys
+	// in a graceful rotation by the secret used
+	RotationCurrent  uint64 `json:"rotation_current"`
+	RotationPrevious uint64 `json:"rotation_previous"`
+	// CacheHits and CacheMisses count the verifications answered, or not,
+	// by the VerifyCache, see CacheHitRatio
+	CacheHits   uint64 `json:"cache_hits"`
+	CacheMisses uint64 `json:"cache_misses"`
+	// CacheFallbacks counts verifications answered from the cache while the
+	// store was unavailable
+	CacheFallbacks uint64 `json:"cache_fallbacks"`
+	// Deprecated counts the successful verifications of keys under an alg
+	// with a deprecation scheduled, see WithDeprecations
+	Deprecated uint64 `json:"deprecated"`
+	// Algs breaks the derivations down by alg string
+	Algs map[string]AlgStats `json:"algs,omitempty"`
+}
+
+// AlgStats is the cost of the derivations under one alg
+type AlgStats struct {
+	Derivations uint64        `json:"derivations"`
+	DeriveTime  time.Duration `json:"derive_time_ns"`
+	// Memory is the memory committed by each derivation, in bytes
+	Memory int64 `json:"memory"`
+	// ByteSeconds is the memory held over time, the sum over derivations of
+	// their memory times their duration
+	ByteSeconds float64 `json:"byte_seconds"`
+}
+
+func NewCounters() *Counters {
+	return &Counters{}
+}
+
+func (c *Counters) ObserveGenerate(alg string, err error) {
+	if err != nil {
+		c.generateErrors.Add(1)
+		return
+	}
+	c.generated.Add(1)
+}
+
+func (c *Counters) ObserveVerify(result string, d time.Duration) {
+	c.verifications.Add(1)
+	if result != ResultOK {
+		c.failures.Add(1)
+	}
+}
+
+func (c *Counters) ObserveDerive(alg string, d time.Duration) {
+	c.derivations.Add(1)
+	c.deriveNanos.Add(uint64(d))
+}
+
+func (c *Counters) ObserveAlgDerive(alg string, d time.Duration, memory int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.algs == nil {
+		c.algs = map[string]AlgStats{}
+	}
+	s := c.algs[alg]
+	s.Derivations++
+	s.DeriveTime += d
+	s.Memory = memory
+	s.ByteSeconds += float64(memory) * d.Seconds()
+	c.algs[alg] = s
+}
+
+func (c *Counters) ObserveStore(op string, d time.Duration, err error) {
+	// not found is the normal outcome for an unknown client id
+	if err != nil && VerifyResult(err) != ResultNotFound {
+		c.storeErrors.Add(1)
+	}
+}
+
+func (c *Counters) ObserveQueue(depth int, wait time.Duration) {
+	c.queued.Add(1)
+	c.queueNanos.Add(uint64(wait))
+}
+
+func (c *Counters) ObserveRotationUse(previous bool) {
+	if previous {
+		c.rotationPrevious.Add(1)
+		return
+	}
+	c.rotationCurrent.Add(1)
+}
+
+func (c *Counters) ObserveCacheHit() {
+	c.cacheHits.Add(1)
+}
+
+func (c *Counters) ObserveCacheMiss() {
+	c.cacheMisses.Add(1)
+}
+
+func (c *Counters) ObserveCacheFallback() {
+	c.cacheFallbacks.Add(1)
+}
+
+func (c *Counters) ObserveDeprecated(alg string) {
+	c.deprecated.Add(1)
+}
+
+// Stats returns a snapshot of the counters. The fields are read individually
+// so the snapshot is not atomic across fields.
+func (c *Counters) Stats() Stats {
+	c.mu.Lock()
+	algs := maps.Clone(c.algs)
+	c.mu.Unlock()
+	return Stats{
+		Generated:      c.generated.Load(),
+		GenerateErrors: c.generateErrors.Load(),
+		Verifications:  c.verifications.Load(),
+		Failures:       c.failures.Load(),
+		Derivations:    c.derivations.Load(),
+		DeriveTime:     time.Duration(c.deriveNanos.Load()),
+		StoreErrors:    c.storeErrors.Load(),
+		Queued:         c.queued.Load(),
+		QueueWaitTime:  time.Duration(c.queueNanos.Load()),
+
+		RotationCurrent:  c.rotationCurrent.Load(),
+		RotationPrevious: c.rotationPrevious.Load(),
+
+		CacheHits:      c.cacheHits.Load(),
+		CacheMisses:    c.cacheMisses.Load(),
+		CacheFallbacks: c.cacheFallbacks.Load(),
+
+		Deprecated: c.deprecated.Load(),
+
+		Algs: algs,
+	}
+}
+
+// CacheHitRatio is the fraction of cache lookups which were hits, 0 if there
+// have been none
+func (s Stats) CacheHitRatio() float64 {
+	lookups := s.CacheHits + s.CacheMisses
+	if lookups == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(lookups)
+}
+
+// Publish exposes the counters under name via expvar (and so on
+// /debug/vars). Like expvar.Publish it panics if name is already in use.
+func (c *Counters) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return c.Stats() }))
+}
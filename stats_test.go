@@ -0,0 +1,77 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"reflect"
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	ctx := context.Background()
+	c := NewCounters()
+	store := NewMemStore()
+	admin := NewAdmin(store, WithMetrics(c))
+	verifier := NewStoreVerifier(store, WithMetrics(c))
+
+	apikey, _, err := admin.Create(ctx, testAlg)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := admin.Create(ctx, "bogus"); err == nil {
+		t.Fatalf("Create() with bad alg succeeded")
+	}
+	verifier.Verify(ctx, apikey)
+	verifier.Verify(ctx, "garbage")
+
+	got := c.Stats()
+	want := Stats{Generated: 1, Verifications: 2, Failures: 1, Derivations: 2}
+	if got.DeriveTime <= 0 {
+		t.Errorf("Stats().DeriveTime = %v, want > 0", got.DeriveTime)
+	}
+	got.DeriveTime = 0
+	parsed, _ := ParseAlg(testAlg)
+	alg := got.Algs[parsed.Canonical()]
+	if alg.Derivations != 2 || alg.DeriveTime <= 0 || alg.Memory != 16<<20 || alg.ByteSeconds <= 0 || len(got.Algs) != 1 {
+		t.Errorf("Stats().Algs = %+v, want the two derivations of %q", got.Algs, testAlg)
+	}
+	got.Algs = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	c.Publish("apikeys_test")
+	var published Stats
+	if err := json.Unmarshal([]byte(expvar.Get("apikeys_test").String()), &published); err != nil {
+		t.Fatalf("expvar value: %v", err)
+	}
+	if published.Verifications != 2 {
+		t.Errorf("published verifications = %d, want 2", published.Verifications)
+	}
+}
+
+func TestCountersAlgLabel(t *testing.T) {
+	c := NewCounters()
+	store := NewMemStore()
+	apikey, _, err := NewAdmin(store).Create(t.Context(), testAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewStoreVerifier(store, WithMetrics(c))
+	presented, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Respelt or altered algs chosen by the caller are reported under the
+	// alg of the record
+	for _, alg := range []string{testAlg, "argon2id len=16,t=1,m=16384KB", "argon2id 2 16MB 16"} {
+		if err := presented.SetAlg(alg); err != nil {
+			t.Fatal(err)
+		}
+		verifier.Verify(t.Context(), presented.encode(password))
+	}
+	if algs := c.Stats().Algs; len(algs) != 1 {
+		t.Errorf("Stats().Algs = %v, want one alg", algs)
+	}
+}
@@ -0,0 +1,130 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned by a Store when there is no key for the client id
+	ErrNotFound = errors.New("api key not found")
+	// ErrExists is returned by Store.Create if the client id is already present
+	ErrExists = errors.New("api key already exists")
+)
+
+// Store persists Key records. Records are identified by their ClientID.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Create adds a new record and returns ErrExists if the client id is taken.
+	Create(ctx context.Context, ak Key) error
+	// Get returns the record for clientID or ErrNotFound.
+	Get(ctx context.Context, clientID string) (Key, error)
+	// Update replaces an existing record, returning ErrNotFound if there isn't one.
+	Update(ctx context.Context, ak Key) error
+	// Delete removes the record for clientID, returning ErrNotFound if there isn't one.
+	Delete(ctx context.Context, clientID string) error
+	// List returns all records ordered by client id.
+	List(ctx context.Context) ([]Key, error)
+}
+
+// Pinger is optionally implemented by a Store to support cheap connectivity
+// checks
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// MemStore is an in memory Store. It is intended for tests and for small
+// deployments where the records are loaded at startup.
+type MemStore struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{keys: map[string]Key{}}
+}
+
+func (s *MemStore) Create(ctx context.Context, ak Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[ak.ClientID]; ok {
+		return ErrExists
+	}
+	if ak.Name != "" {
+		for _, other := range s.keys {
+			if other.TenantID == ak.TenantID && other.Name == ak.Name {
+				return fmt.Errorf("%w: name `%s' is used by `%s'", ErrExists, ak.Name, other.ClientID)
+			}
+		}
+	}
+	s.keys[ak.ClientID] = ak.clone()
+	return nil
+}
+
+func (s *MemStore) Get(ctx context.Context, clientID string) (Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ak, ok := s.keys[clientID]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	return ak.clone(), nil
+}
+
+func (s *MemStore) Update(ctx context.Context, ak Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[ak.ClientID]; !ok {
+		return ErrNotFound
+	}
+	s.keys[ak.ClientID] = ak.clone()
+	return nil
+}
+
+func (s *MemStore) Delete(ctx context.Context, clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[clientID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.keys, clientID)
+	return nil
+}
+
+func (s *MemStore) List(ctx context.Context) ([]Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]Key, 0, len(s.keys))
+	for _, ak := range s.keys {
+		keys = append(keys, ak.clone())
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ClientID < keys[j].ClientID })
+	return keys, nil
+}
+
+// GetByName implements NameLookup
+func (s *MemStore) GetByName(ctx context.Context, tenantID, name string) (Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, ak := range s.keys {
+		if ak.TenantID == tenantID && ak.Name == name {
+			return ak.clone(), nil
+		}
+	}
+	return Key{}, ErrNotFound
+}
+
+// GetByFingerprint implements FingerprintLookup
+func (s *MemStore) GetByFingerprint(ctx context.Context, fingerprint string) (Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, ak := range s.keys {
+		if ak.Fingerprint() == fingerprint {
+			return ak.clone(), nil
+		}
+	}
+	return Key{}, ErrNotFound
+}
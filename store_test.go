@@ -0,0 +1,60 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemStore()
+
+	if err := s.Create(ctx, Key{ClientID: "b", DerivedKey: []byte("kb")}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := s.Create(ctx, Key{ClientID: "a", DerivedKey: []byte("ka")}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := s.Create(ctx, Key{ClientID: "a"}); !errors.Is(err, ErrExists) {
+		t.Errorf("Create() duplicate error = %v, want %v", err, ErrExists)
+	}
+
+	got, err := s.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	// mutating the returned record must not change the stored one
+	got.DerivedKey[0] = 'X'
+	if got, _ = s.Get(ctx, "a"); string(got.DerivedKey) != "ka" {
+		t.Errorf("Get() DerivedKey = %s, want ka", got.DerivedKey)
+	}
+
+	if _, err := s.Get(ctx, "c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() missing error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Update(ctx, Key{ClientID: "c"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() missing error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Update(ctx, Key{ClientID: "a", DerivedKey: []byte("ka2")}); err != nil {
+		t.Errorf("Update() error = %v", err)
+	}
+
+	keys, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 2 || keys[0].ClientID != "a" || keys[1].ClientID != "b" {
+		t.Errorf("List() = %v, want records for a and b in order", keys)
+	}
+	if string(keys[0].DerivedKey) != "ka2" {
+		t.Errorf("List() DerivedKey = %s, want ka2", keys[0].DerivedKey)
+	}
+
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := s.Delete(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() missing error = %v, want %v", err, ErrNotFound)
+	}
+}
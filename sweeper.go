@@ -0,0 +1,221 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SweepConfig tunes a Sweeper. A zero retention keeps those records forever.
+type SweepConfig struct {
+	// ExpiredRetention is how long an expired record is kept after it expires
+	ExpiredRetention time.Duration
+	// RevokedRetention is how long a revoked record is kept after it was
+	// revoked. It covers the tombstones left by Shred and PurgeSubject too,
+	// so set it no shorter than the audit trail is kept.
+	RevokedRetention time.Duration
+	// ExpiryWarnings are lead times, eg 30, 7 and 1 days, at which to warn
+	// that a key is about to expire with an AuditKeyExpiring event and
+	// the OnExpiringSoon hook. A key whose expiry comes within several lead
+	// times between two sweeps is warned once, with the shortest.
+	ExpiryWarnings []time.Duration
+	// Leaks, if set, is checked for the fingerprint of every live key. Keys
+	// found are reported with an AuditKeyLeaked event and the OnLeaked hook,
+	// and revoked if RevokeLeaked.
+	Leaks        LeakDatabase
+	RevokeLeaked bool
+	// Audit, if set, has the events older than AuditRetention pruned by
+	// each sweep
+	Audit          AuditStore
+	AuditRetention time.Duration
+	// Interval is the time between the sweeps of Run, 1h by default
+	Interval time.Duration
+	// DryRun counts the records which would be purged without deleting them
+	DryRun bool
+	// OnSweep, if set, is called by Run with the outcome of each sweep
+	OnSweep func(SweepResult, error)
+}
+
+// SweepResult counts the work of one sweep
+type SweepResult struct {
+	// Expired is the number of records found expired since the previous sweep
+	Expired int `json:"expired"`
+	// ExpiringSoon is the number of expiry warnings given
+	ExpiringSoon int `json:"expiring_soon"`
+	// Leaked lists the client ids of the live keys found in SweepConfig.Leaks
+	Leaked []string `json:"leaked,omitempty"`
+	// Purged is the number of records deleted, or which would have been for
+	// a dry run
+	Purged int `json:"purged"`
+	// Failed lists the client ids which could not be deleted
+	Failed []string `json:"failed,omitempty"`
+	// AuditPruned is the number of audit events deleted
+	AuditPruned int64 `json:"audit_pruned,omitempty"`
+}
+
+// Sweeper finds expired and revoked records, warns of keys about to expire
+// and of keys found in leaks, reports newly expired ones with an
+// AuditKeyExpired event and the OnExpire hook, and deletes the ones past
+// their retention, emitting AuditKeyDeleted.
+// Use Run in a goroutine, or call Sweep from a cron job. A Sweeper only
+// remembers what it has reported while the process lives, so the first sweep
+// after a restart reports every expired record not yet purged again, and
+// repeats the latest warning due for each key.
+type Sweeper struct {
+	admin *Admin
+	cfg   SweepConfig
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewSweeper returns a Sweeper over the store of admin, whose clock, audit
+// sink, hooks and cache it shares
+func NewSweeper(admin *Admin, cfg SweepConfig) *Sweeper {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	return &Sweeper{admin: admin, cfg: cfg}
+}
+
+// Sweep makes a single pass over the store. Failures to delete a record don't
+// stop the rest; their errors are joined in the result.
+func (s *Sweeper) Sweep(ctx context.Context) (SweepResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.admin
+	keys, err := a.store.List(ctx)
+	if err != nil {
+		return SweepResult{}, err
+	}
+	now := a.now()
+	var res SweepResult
+	var errs []error
+	var leaks *leakRanges
+	if s.cfg.Leaks != nil {
+		leaks = &leakRanges{db: s.cfg.Leaks}
+	}
+	for _, ak := range keys {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if lead, ok := s.warning(ak, now); ok {
+			res.ExpiringSoon++
+			a.emit(ctx, AuditKeyExpiring, ak, nil)
+			if a.hooks.OnExpiringSoon != nil {
+				a.hooks.OnExpiringSoon(ctx, ak, lead)
+			}
+		}
+		if !ak.Revoked() && ak.Expired(now) && ak.ExpiresAt.After(s.last) {
+			res.Expired++
+			a.emit(ctx, AuditKeyExpired, ak, nil)
+			if a.hooks.OnExpire != nil {
+				a.hooks.OnExpire(ctx, ak)
+			}
+		}
+		if leaks != nil && !ak.Revoked() && !ak.Expired(now) {
+			if err := s.checkLeak(ctx, leaks, ak, &res); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if !s.due(ak, now) {
+			continue
+		}
+		if s.cfg.DryRun {
+			res.Purged++
+			continue
+		}
+		if err := a.store.Delete(ctx, ak.ClientID); err != nil && !errors.Is(err, ErrNotFound) {
+			res.Failed = append(res.Failed, ak.ClientID)
+			errs = append(errs, fmt.Errorf("purging `%s': %w", ak.ClientID, err))
+			continue
+		}
+		ak.Wipe()
+		res.Purged++
+		a.emit(ctx, AuditKeyDeleted, ak, nil)
+	}
+	if s.cfg.Audit != nil && s.cfg.AuditRetention > 0 && !s.cfg.DryRun {
+		res.AuditPruned, err = s.cfg.Audit.Prune(ctx, now.Add(-s.cfg.AuditRetention))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pruning audit trail: %w", err))
+		}
+	}
+	s.last = now
+	return res, errors.Join(errs...)
+}
+
+// checkLeak looks for the fingerprint of ak in the leak database, and
+// reports and, if configured, revokes it if found
+func (s *Sweeper) checkLeak(ctx context.Context, leaks LeakDatabase, ak Key, res *SweepResult) error {
+	a := s.admin
+	leaked, err := Leaked(ctx, leaks, ak.Fingerprint())
+	if err != nil {
+		return fmt.Errorf("leak check of `%s': %w", ak.ClientID, err)
+	}
+	if !leaked {
+		return nil
+	}
+	res.Leaked = append(res.Leaked, ak.ClientID)
+	a.emit(ctx, AuditKeyLeaked, ak, nil)
+	if a.hooks.OnLeaked != nil {
+		a.hooks.OnLeaked(ctx, ak)
+	}
+	if !s.cfg.RevokeLeaked || s.cfg.DryRun {
+		return nil
+	}
+	if _, err := a.Revoke(ctx, ak.ClientID); err != nil {
+		return fmt.Errorf("revoking leaked `%s': %w", ak.ClientID, err)
+	}
+	return nil
+}
+
+// warning returns the shortest lead time of the ExpiryWarnings reached by ak
+// since the previous sweep, if it is still live
+func (s *Sweeper) warning(ak Key, now time.Time) (time.Duration, bool) {
+	if ak.ExpiresAt.IsZero() || ak.Revoked() || ak.Expired(now) {
+		return 0, false
+	}
+	var lead time.Duration
+	found := false
+	for _, d := range s.cfg.ExpiryWarnings {
+		at := ak.ExpiresAt.Add(-d)
+		if at.After(now) || !at.After(s.last) || (found && d >= lead) {
+			continue
+		}
+		lead, found = d, true
+	}
+	return lead, found
+}
+
+// due reports whether ak is past its retention at now
+func (s *Sweeper) due(ak Key, now time.Time) bool {
+	if ak.Revoked() {
+		return s.cfg.RevokedRetention > 0 && !now.Before(ak.RevokedAt.Add(s.cfg.RevokedRetention))
+	}
+	return s.cfg.ExpiredRetention > 0 && ak.Expired(now) && !now.Before(ak.ExpiresAt.Add(s.cfg.ExpiredRetention))
+}
+
+// Run sweeps at once and then every Interval until ctx is done, which it
+// returns
+func (s *Sweeper) Run(ctx context.Context) error {
+	t := time.NewTicker(s.cfg.Interval)
+	defer t.Stop()
+	for {
+		res, err := s.Sweep(ctx)
+		if err != nil {
+			s.admin.warn(ctx, "api key sweep failed", slog.Any("error", err))
+		}
+		if s.cfg.OnSweep != nil {
+			s.cfg.OnSweep(res, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// failDeleteStore fails deletes of one client id
+type failDeleteStore struct {
+	*MemStore
+	clientID string
+}
+
+func (s *failDeleteStore) Delete(ctx context.Context, clientID string) error {
+	if clientID == s.clientID {
+		return errDown
+	}
+	return s.MemStore.Delete(ctx, clientID)
+}
+
+func TestSweeper(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	type args struct {
+		cfg SweepConfig
+	}
+	tests := []struct {
+		name string
+		args args
+		// after a day, and after a week
+		wantPurged [2]int
+		wantLeft   []string
+	}{
+		{name: "keep forever", wantPurged: [2]int{0, 0}, wantLeft: []string{"expired", "live", "revoked", "shredded"}},
+		{
+			name:       "expired for a day",
+			args:       args{cfg: SweepConfig{ExpiredRetention: 24 * time.Hour}},
+			wantPurged: [2]int{1, 0}, wantLeft: []string{"live", "revoked", "shredded"},
+		},
+		{
+			name:       "revoked for a week",
+			args:       args{cfg: SweepConfig{RevokedRetention: 7 * 24 * time.Hour}},
+			wantPurged: [2]int{0, 2}, wantLeft: []string{"expired", "live"},
+		},
+		{
+			name:       "dry run",
+			args:       args{cfg: SweepConfig{ExpiredRetention: time.Hour, RevokedRetention: time.Hour, DryRun: true}},
+			wantPurged: [2]int{3, 3}, wantLeft: []string{"expired", "live", "revoked", "shredded"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = start
+			var audit bytes.Buffer
+			var expired []string
+			admin := NewAdmin(NewMemStore(), WithClock(ClockFunc(func() time.Time { return now })),
+				WithAudit(NewWriterAuditSink(&audit)),
+				WithHooks(Hooks{OnExpire: func(_ context.Context, ak Key) { expired = append(expired, ak.ClientID) }}))
+			for _, c := range []struct {
+				id      string
+				expires time.Time
+			}{
+				{"expired", start.Add(time.Hour)}, {"live", start.Add(30 * 24 * time.Hour)},
+				{"revoked", time.Time{}}, {"shredded", time.Time{}},
+			} {
+				if _, _, err := admin.Create(ctx, testAlg, WithClientID(c.id), WithExpiresAt(c.expires)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := admin.Revoke(ctx, "revoked"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := admin.Shred(ctx, "shredded"); err != nil {
+				t.Fatal(err)
+			}
+			audit.Reset()
+
+			s := NewSweeper(admin, tt.args.cfg)
+			for i, d := range []time.Duration{25 * time.Hour, 7 * 24 * time.Hour} {
+				now = start.Add(d)
+				res, err := s.Sweep(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Purged != tt.wantPurged[i] {
+					t.Errorf("sweep %d Purged = %d, want %d", i, res.Purged, tt.wantPurged[i])
+				}
+			}
+			if !slices.Equal(expired, []string{"expired"}) {
+				t.Errorf("OnExpire saw %v, want it once for expired", expired)
+			}
+			var left []string
+			keys, err := admin.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, ak := range keys {
+				left = append(left, ak.ClientID)
+			}
+			if !slices.Equal(left, tt.wantLeft) {
+				t.Errorf("left %v, want %v", left, tt.wantLeft)
+			}
+			deleted := 0
+			for _, ev := range decodeEvents(t, audit.Bytes()) {
+				if ev.Type == AuditKeyDeleted {
+					deleted++
+				}
+			}
+			if want := len(tt.wantLeft); !tt.args.cfg.DryRun && deleted != 4-want {
+				t.Errorf("got %d deleted events, want %d", deleted, 4-want)
+			}
+		})
+	}
+}
+
+func TestSweeperFailures(t *testing.T) {
+	ctx := context.Background()
+	store := &failDeleteStore{MemStore: NewMemStore(), clientID: "client-1"}
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	admin := NewAdmin(store, WithClock(ClockFunc(func() time.Time { return now })))
+	for _, id := range []string{"client-1", "client-2"} {
+		if _, _, err := admin.Create(ctx, testAlg, WithClientID(id)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := admin.Revoke(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(time.Hour)
+	s := NewSweeper(admin, SweepConfig{RevokedRetention: time.Hour})
+	res, err := s.Sweep(ctx)
+	if !errors.Is(err, errDown) || res.Purged != 1 || !slices.Equal(res.Failed, []string{"client-1"}) {
+		t.Errorf("Sweep() = %+v, %v", res, err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	var sweeps int
+	s = NewSweeper(admin, SweepConfig{Interval: time.Hour, OnSweep: func(SweepResult, error) {
+		sweeps++
+		cancel()
+	}})
+	if err := s.Run(runCtx); !errors.Is(err, context.Canceled) || sweeps != 1 {
+		t.Errorf("Run() = %v after %d sweeps", err, sweeps)
+	}
+}
+
+func TestSweeperExpiryWarnings(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	now := start
+	var warned []string
+	admin := NewAdmin(NewMemStore(), WithClock(ClockFunc(func() time.Time { return now })),
+		WithHooks(Hooks{OnExpiringSoon: func(_ context.Context, ak Key, lead time.Duration) {
+			warned = append(warned, ak.ClientID+" "+lead.String())
+		}}))
+	for id, expires := range map[string]time.Time{"soon": start.Add(12 * time.Hour), "later": start.Add(10 * day)} {
+		if _, _, err := admin.Create(ctx, testAlg, WithClientID(id), WithExpiresAt(expires)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewSweeper(admin, SweepConfig{ExpiryWarnings: []time.Duration{30 * day, 7 * day, day}})
+	type args struct {
+		at time.Duration
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{name: "first sweep", args: args{at: 0}, want: []string{"later 720h0m0s", "soon 24h0m0s"}},
+		{name: "nothing new", args: args{at: day}, want: nil},
+		{name: "a week before", args: args{at: 4 * day}, want: []string{"later 168h0m0s"}},
+		{name: "a day before", args: args{at: 9*day + 12*time.Hour}, want: []string{"later 24h0m0s"}},
+		{name: "expired", args: args{at: 11 * day}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warned = nil
+			now = start.Add(tt.args.at)
+			res, err := s.Sweep(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(warned, tt.want) || res.ExpiringSoon != len(tt.want) {
+				t.Errorf("Sweep() warned %v (%d), want %v", warned, res.ExpiringSoon, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTenant is returned by a TenantStore for a record belonging to another
+// tenant
+var ErrTenant = errors.New("api key belongs to another tenant")
+
+// TenantLister is optionally implemented by a Store that can list the records
+// of a single tenant more efficiently than filtering List
+type TenantLister interface {
+	// ListTenant returns the records with TenantID tenantID ordered by client
+	// id
+	ListTenant(ctx context.Context, tenantID string) ([]Key, error)
+}
+
+// tenantStore restricts a Store to the records of one tenant
+type tenantStore struct {
+	Store
+	tenantID string
+}
+
+// TenantStore returns a Store which only sees the records of tenantID.
+// Records of other tenants are reported as ErrNotFound, so their existence
+// is not revealed, and creating or updating a record for another tenant
+// fails with ErrTenant. Client ids remain unique across the underlying
+// store.
+func TenantStore(store Store, tenantID string) Store {
+	return &tenantStore{Store: store, tenantID: tenantID}
+}
+
+func (s *tenantStore) Create(ctx context.Context, ak Key) error {
+	if ak.TenantID != s.tenantID {
+		return fmt.Errorf("%w: `%s'", ErrTenant, ak.TenantID)
+	}
+	return s.Store.Create(ctx, ak)
+}
+
+func (s *tenantStore) Get(ctx context.Context, clientID string) (Key, error) {
+	ak, err := s.Store.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	if ak.TenantID != s.tenantID {
+		return Key{}, ErrNotFound
+	}
+	return ak, nil
+}
+
+func (s *tenantStore) Update(ctx context.Context, ak Key) error {
+	if ak.TenantID != s.tenantID {
+		return fmt.Errorf("%w: `%s'", ErrTenant, ak.TenantID)
+	}
+	if _, err := s.Get(ctx, ak.ClientID); err != nil {
+		return err
+	}
+	return s.Store.Update(ctx, ak)
+}
+
+func (s *tenantStore) Delete(ctx context.Context, clientID string) error {
+	if _, err := s.Get(ctx, clientID); err != nil {
+		return err
+	}
+	return s.Store.Delete(ctx, clientID)
+}
+
+func (s *tenantStore) List(ctx context.Context) ([]Key, error) {
+	if tl, ok := s.Store.(TenantLister); ok {
+		return tl.ListTenant(ctx, s.tenantID)
+	}
+	keys, err := s.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped := keys[:0]
+	for _, ak := range keys {
+		if ak.TenantID == s.tenantID {
+			scoped = append(scoped, ak)
+		}
+	}
+	return scoped, nil
+}
+
+// ListTenant implements TenantLister
+func (s *MemStore) ListTenant(ctx context.Context, tenantID string) ([]Key, error) {
+	keys, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped := keys[:0]
+	for _, ak := range keys {
+		if ak.TenantID == tenantID {
+			scoped = append(scoped, ak)
+		}
+	}
+	return scoped, nil
+}
@@ -0,0 +1,96 @@
+package apikeys
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTenantEncoding(t *testing.T) {
+	ak, err := NewKey(testAlg, WithClientID("client-1"), WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikey, err := ak.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	presented, password, err := Decode(apikey, WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presented.TenantID != "acme" || presented.ClientID != "client-1" || presented.alg.String != testAlg {
+		t.Errorf("Decode() = %+v", presented)
+	}
+	if !presented.MatchPassword(password, ak.DerivedKey) {
+		t.Error("tenant key does not match")
+	}
+
+	// Keys without a tenant keep the original format
+	plain, _ := NewKey(testAlg, WithClientID("client-2"))
+	apikey, _ = plain.Generate()
+	if presented, _, err := Decode(apikey); err != nil || presented.TenantID != "" {
+		t.Errorf("Decode() = %+v, %v", presented, err)
+	}
+}
+
+func TestWithTenantInvalid(t *testing.T) {
+	for _, tenant := range []string{"a.b", "a:b", "a b", string(make([]byte, MaxTenantIDLen+1))} {
+		if _, err := NewKey(testAlg, WithTenant(tenant)); err == nil {
+			t.Errorf("NewKey(WithTenant(%q)) error = nil", tenant)
+		}
+	}
+}
+
+func TestTenantStore(t *testing.T) {
+	store := NewMemStore()
+	acme := NewAdmin(TenantStore(store, "acme"))
+	other := TenantStore(store, "other")
+
+	_, ak, err := acme.Create(t.Context(), testAlg, WithClientID("client-1"), WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := acme.Create(t.Context(), testAlg, WithClientID("client-2"), WithTenant("other")); !errors.Is(err, ErrTenant) {
+		t.Errorf("Create() for another tenant error = %v, want %v", err, ErrTenant)
+	}
+	if _, err := other.Get(t.Context(), "client-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := other.Delete(t.Context(), "client-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() error = %v, want %v", err, ErrNotFound)
+	}
+	ak.TenantID = "other"
+	if err := other.Update(t.Context(), ak); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() error = %v, want %v", err, ErrNotFound)
+	}
+	if keys, _ := other.List(t.Context()); len(keys) != 0 {
+		t.Errorf("List() = %v, want none", keys)
+	}
+	if keys, _ := acme.List(t.Context()); len(keys) != 1 || keys[0].TenantID != "acme" {
+		t.Errorf("List() = %v", keys)
+	}
+}
+
+func TestVerifyTenantMismatch(t *testing.T) {
+	store := NewMemStore()
+	apikey, _, err := NewAdmin(store).Create(t.Context(), testAlg, WithClientID("client-1"), WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewStoreVerifier(store)
+	if _, err := v.Verify(t.Context(), apikey); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// The same secret re-encoded to claim another tenant
+	presented, password, err := Decode(apikey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tenant := range []string{"other", ""} {
+		presented.TenantID = tenant
+		if _, err := v.Verify(t.Context(), presented.encode(password)); !errors.Is(err, ErrMismatch) {
+			t.Errorf("Verify() as tenant %q error = %v, want %v", tenant, err, ErrMismatch)
+		}
+	}
+}
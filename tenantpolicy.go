@@ -0,0 +1,84 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPolicy is returned when a key's alg is outside the policy of its tenant
+var ErrPolicy = errors.New("alg not allowed by policy")
+
+// TenantPolicy is the alg new keys of a tenant are created with and the
+// bounds its keys must satisfy. The zero value applies the defaults, which
+// allow no keyed or registered algs, see Policy.Hashers.
+type TenantPolicy struct {
+	// Alg for new and rotated keys when none is requested, StandardAlg if empty
+	Alg    string `yaml:"alg" json:"alg"`
+	Policy Policy `yaml:"policy" json:"policy"`
+	// IdleTimeout expires the tenant's keys once unused for this long, unless
+	// a key has its own, see WithIdleTimeout
+	IdleTimeout time.Duration `yaml:"idle_timeout" json:"idle_timeout"`
+}
+
+// TenantPolicyFunc resolves the policy for a tenant. It is called with an
+// empty tenant id for keys without one. It is called on every verification,
+// before the derivation, so it should be cheap.
+type TenantPolicyFunc func(ctx context.Context, tenantID string) (TenantPolicy, error)
+
+// TenantPolicyMap resolves tenant policies from m. Tenants not in m get the
+// zero TenantPolicy.
+func TenantPolicyMap(m map[string]TenantPolicy) TenantPolicyFunc {
+	return func(ctx context.Context, tenantID string) (TenantPolicy, error) {
+		return m[tenantID], nil
+	}
+}
+
+// WithTenantPolicy resolves the alg and policy per tenant with fn. Admin uses
+// the tenant's alg when Create or Rotate is not given one, and refuses algs
+// outside the tenant's policy. StoreVerifier rejects presented keys outside
+// it, without deriving anything, so a tenant can demand stronger parameters
+// than everyone else without the cost being forced on the others.
+func WithTenantPolicy(fn TenantPolicyFunc) Option {
+	return func(o *options) {
+		o.tenantPolicy = fn
+	}
+}
+
+// applyTenantPolicy sets the tenant's alg on ak if requested is empty and
+// checks the result against the tenant's policy
+func (o *options) applyTenantPolicy(ctx context.Context, ak *Key, requested string) error {
+	if o.tenantPolicy == nil {
+		return nil
+	}
+	tp, err := o.tenantPolicy(ctx, ak.TenantID)
+	if err != nil {
+		return err
+	}
+	if requested == "" && tp.Alg != "" {
+		if err := ak.SetAlg(tp.Alg); err != nil {
+			return err
+		}
+	}
+	return checkTenantPolicy(tp, ak)
+}
+
+// checkTenantPolicy checks the alg of ak against the tenant's policy
+func (o *options) checkTenantPolicy(ctx context.Context, ak Key) error {
+	if o.tenantPolicy == nil {
+		return nil
+	}
+	tp, err := o.tenantPolicy(ctx, ak.TenantID)
+	if err != nil {
+		return err
+	}
+	return checkTenantPolicy(tp, &ak)
+}
+
+func checkTenantPolicy(tp TenantPolicy, ak *Key) error {
+	if err := tp.Policy.Check(ak.alg); err != nil {
+		return fmt.Errorf("%w: tenant `%s': %v", ErrPolicy, ak.TenantID, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const strongTestAlg = "argon2id 2 16MB 16"
+
+func tenantPolicies() Option {
+	return WithTenantPolicy(TenantPolicyMap(map[string]TenantPolicy{
+		"enterprise": {Alg: strongTestAlg, Policy: Policy{MinTime: 2}},
+	}))
+}
+
+func TestTenantPolicyAdmin(t *testing.T) {
+	admin := NewAdmin(NewMemStore(), tenantPolicies())
+
+	_, ak, err := admin.Create(t.Context(), "", WithTenant("enterprise"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ak.alg.String != strongTestAlg {
+		t.Errorf("Create() alg = %s, want the tenant alg %s", ak.alg.String, strongTestAlg)
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithTenant("enterprise")); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Create() weak alg error = %v, want %v", err, ErrPolicy)
+	}
+	// Other tenants are unaffected
+	if _, _, err := admin.Create(t.Context(), testAlg, WithTenant("smallco")); err != nil {
+		t.Errorf("Create() for another tenant error = %v", err)
+	}
+	if _, _, err := admin.Rotate(t.Context(), ak.ClientID, testAlg); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Rotate() weak alg error = %v, want %v", err, ErrPolicy)
+	}
+
+	// Registered algs have no parameters to bound, so must be allowed
+	if _, _, err := admin.Create(t.Context(), testRegisteredAlg, WithTenant("smallco")); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Create() registered alg error = %v, want %v", err, ErrPolicy)
+	}
+	admin = NewAdmin(NewMemStore(), WithTenantPolicy(TenantPolicyMap(map[string]TenantPolicy{
+		"smallco": {Policy: Policy{Hashers: []string{"testkdf"}}},
+	})))
+	if _, _, err := admin.Create(t.Context(), testRegisteredAlg, WithTenant("smallco")); err != nil {
+		t.Errorf("Create() allowed registered alg error = %v", err)
+	}
+}
+
+func TestTenantPolicyVerify(t *testing.T) {
+	store := NewMemStore()
+	// Created before the tenant's policy was tightened
+	apikey, _, err := NewAdmin(store).Create(t.Context(), testAlg, WithTenant("enterprise"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewStoreVerifier(store, tenantPolicies())
+	if _, err := v.Verify(t.Context(), apikey); !errors.Is(err, ErrPolicy) || !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify() error = %v, want %v and %v", err, ErrPolicy, ErrInvalid)
+	}
+
+	failing := errors.New("no policy")
+	v = NewStoreVerifier(store, WithTenantPolicy(func(ctx context.Context, tenantID string) (TenantPolicy, error) {
+		return TenantPolicy{}, failing
+	}))
+	if _, err := v.Verify(t.Context(), apikey); !errors.Is(err, failing) || errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify() error = %v, want %v", err, failing)
+	}
+}
@@ -0,0 +1,99 @@
+package apikeys
+
+import (
+	"context"
+	"time"
+)
+
+// Span names used for instrumentation
+const (
+	SpanGenerate = "apikeys.Generate"
+	SpanDecode   = "apikeys.Decode"
+	SpanDerive   = "apikeys.Derive"
+	SpanVerify   = "apikeys.Verify"
+	SpanStore    = "apikeys.Store."
+)
+
+// Attribute keys set on spans
+const (
+	AttrClientID       = "apikeys.client_id"
+	AttrAlg            = "apikeys.alg"
+	AttrDeriveDuration = "apikeys.argon2.duration_ms"
+)
+
+// Tracer starts spans. The package depends on no tracing library; adapt yours
+// to this interface (the apikeysotel package does this for OpenTelemetry).
+// When no tracer is configured the operations are not instrumented at all.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation
+type Span interface {
+	// SetAttribute records a string, int64, float64 or bool value
+	SetAttribute(key string, value interface{})
+	// End finishes the span, recording err if it is not nil
+	End(err error)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value interface{}) {}
+func (nopSpan) End(err error)                              {}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// TraceStore wraps store so that each operation is traced as a span named
+// SpanStore + the method name.
+func TraceStore(store Store, t Tracer) Store {
+	if t == nil {
+		return store
+	}
+	return &tracingStore{store: store, tracer: t}
+}
+
+type tracingStore struct {
+	store  Store
+	tracer Tracer
+}
+
+func (s *tracingStore) Create(ctx context.Context, ak Key) error {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"Create")
+	span.SetAttribute(AttrClientID, ak.ClientID)
+	err := s.store.Create(ctx, ak)
+	span.End(err)
+	return err
+}
+
+func (s *tracingStore) Get(ctx context.Context, clientID string) (Key, error) {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"Get")
+	span.SetAttribute(AttrClientID, clientID)
+	ak, err := s.store.Get(ctx, clientID)
+	span.End(err)
+	return ak, err
+}
+
+func (s *tracingStore) Update(ctx context.Context, ak Key) error {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"Update")
+	span.SetAttribute(AttrClientID, ak.ClientID)
+	err := s.store.Update(ctx, ak)
+	span.End(err)
+	return err
+}
+
+func (s *tracingStore) Delete(ctx context.Context, clientID string) error {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"Delete")
+	span.SetAttribute(AttrClientID, clientID)
+	err := s.store.Delete(ctx, clientID)
+	span.End(err)
+	return err
+}
+
+func (s *tracingStore) List(ctx context.Context) ([]Key, error) {
+	ctx, span := s.tracer.Start(ctx, SpanStore+"List")
+	keys, err := s.store.List(ctx)
+	span.End(err)
+	return keys, err
+}
@@ -0,0 +1,174 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Transferer is optionally implemented by a Store that can replace a record
+// atomically, including under a different client id. Admin.Transfer uses it
+// when the client id changes; without it the new record is created and the
+// old one deleted as two operations.
+type Transferer interface {
+	// Transfer replaces the record for fromClientID with ak. It returns
+	// ErrNotFound if there is no record for fromClientID and ErrExists if
+	// ak.ClientID is a different client id that is already taken.
+	Transfer(ctx context.Context, fromClientID string, ak Key) error
+}
+
+// TransferOption describes the change Admin.Transfer makes
+type TransferOption func(*transfer)
+
+type transfer struct {
+	owner, team, tenantID, clientID *string
+	reissue                         bool
+	alg                             string
+}
+
+// ToOwner assigns the key to owner
+func ToOwner(owner string) TransferOption {
+	return func(t *transfer) { t.owner = &owner }
+}
+
+// ToTeam assigns the key to team
+func ToTeam(team string) TransferOption {
+	return func(t *transfer) { t.team = &team }
+}
+
+// ToTenant moves the key to tenantID. The tenant is part of the encoded key,
+// so this requires WithReissue.
+func ToTenant(tenantID string) TransferOption {
+	return func(t *transfer) { t.tenantID = &tenantID }
+}
+
+// ToClientID moves the key to clientID. The client id is part of the encoded
+// key, so this requires WithReissue.
+func ToClientID(clientID string) TransferOption {
+	return func(t *transfer) { t.clientID = &clientID }
+}
+
+// WithReissue generates a new secret as part of the transfer, under alg or
+// the key's current alg if alg is empty. The previous api key stops
+// verifying, as does the secret kept by a graceful rotation.
+func WithReissue(alg string) TransferOption {
+	return func(t *transfer) {
+		t.reissue = true
+		t.alg = alg
+	}
+}
+
+// Transfer reassigns the key for clientID to a new owner, team, tenant or
+// client id. Only a reissued key has a new api key, which is returned;
+// otherwise the returned string is empty and the existing api key keeps
+// working. Revoked keys can not be transferred.
+func (a *Admin) Transfer(ctx context.Context, clientID string, opts ...TransferOption) (string, Key, error) {
+	var t transfer
+	for _, opt := range opts {
+		opt(&t)
+	}
+	ak, err := a.store.Get(ctx, clientID)
+	if err != nil {
+		return "", Key{}, err
+	}
+	if ak.Revoked() {
+		return "", Key{}, fmt.Errorf("can't transfer `%s': %w", clientID, ErrRevoked)
+	}
+
+	if t.owner != nil {
+		ak.Owner = *t.owner
+	}
+	if t.team != nil {
+		ak.Team = *t.team
+	}
+	moved, retenanted := false, false
+	if t.tenantID != nil && *t.tenantID != ak.TenantID {
+		ak.TenantID = *t.tenantID
+		moved, retenanted = true, true
+	}
+	if t.clientID != nil && *t.clientID != ak.ClientID {
+		if err := checkClientID(*t.clientID); err != nil {
+			return "", Key{}, err
+		}
+		ak.ClientID = *t.clientID
+		moved = true
+	}
+	if moved && !t.reissue {
+		return "", Key{}, fmt.Errorf("can't transfer `%s' to a new tenant or client id without reissuing it", clientID)
+	}
+	if ak.ClientID == "" || (ak.TenantID != "" && !wellFormedTenantID(ak.TenantID)) {
+		return "", Key{}, fmt.Errorf("bad transfer target `%s' tenant `%s'", ak.ClientID, ak.TenantID)
+	}
+	if err := ak.validateMetadata(); err != nil {
+		return "", Key{}, err
+	}
+	// Within its tenant the name is already the key's own
+	if retenanted {
+		if err := a.checkNameFree(ctx, ak); err != nil {
+			return "", Key{}, err
+		}
+	}
+
+	var apikey string
+	if t.reissue {
+		alg := t.alg
+		if alg == "" {
+			alg = ak.alg.String
+		}
+		if alg == "" {
+			alg = StandardAlg
+		}
+		if err := ak.SetOptions(alg, a.keyOptions(nil)...); err != nil {
+			return "", Key{}, err
+		}
+		if err := a.applyTenantPolicy(ctx, &ak, t.alg); err != nil {
+			return "", Key{}, err
+		}
+		if apikey, err = a.generate(ctx, &ak); err != nil {
+			return "", Key{}, err
+		}
+		ak.RotatedAt = a.now()
+		// A secret kept by an earlier graceful rotation belongs to the old
+		// owner
+		clear(ak.PreviousDerivedKey)
+		ak.PreviousDerivedKey, ak.PreviousExpiresAt = nil, time.Time{}
+	}
+
+	if err := a.replace(ctx, clientID, ak); err != nil {
+		return "", Key{}, err
+	}
+	a.emit(ctx, AuditKeyTransferred, ak, nil)
+	return apikey, ak, nil
+}
+
+// replace stores ak in place of the record for fromClientID
+func (a *Admin) replace(ctx context.Context, fromClientID string, ak Key) error {
+	if ak.ClientID == fromClientID {
+		return a.store.Update(ctx, ak)
+	}
+	if tr, ok := supports[Transferer](a.store); ok {
+		return tr.Transfer(ctx, fromClientID, ak)
+	}
+	if err := a.store.Create(ctx, ak); err != nil {
+		return err
+	}
+	if err := a.store.Delete(ctx, fromClientID); err != nil {
+		return fmt.Errorf("transferred to `%s' but the record for `%s' remains: %w", ak.ClientID, fromClientID, err)
+	}
+	return nil
+}
+
+// Transfer implements Transferer
+func (s *MemStore) Transfer(ctx context.Context, fromClientID string, ak Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[fromClientID]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := s.keys[ak.ClientID]; ok && ak.ClientID != fromClientID {
+		return ErrExists
+	}
+	delete(s.keys, fromClientID)
+	s.keys[ak.ClientID] = ak.clone()
+	return nil
+}
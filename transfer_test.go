@@ -0,0 +1,117 @@
+package apikeys
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransferOwner(t *testing.T) {
+	var audit bytes.Buffer
+	store := NewMemStore()
+	admin := NewAdmin(store, WithAudit(NewWriterAuditSink(&audit)))
+	apikey, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"), WithOwner("leaver@example.com"), WithTeam("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reissued, ak, err := admin.Transfer(t.Context(), "client-1", ToOwner("manager@example.com"), ToTeam("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reissued != "" || ak.Owner != "manager@example.com" || ak.Team != "new" {
+		t.Errorf("Transfer() = %q, %+v", reissued, ak)
+	}
+	// Changing the owner doesn't disturb the key holder
+	if _, err := NewStoreVerifier(store).Verify(t.Context(), apikey); err != nil {
+		t.Errorf("Verify() after transfer error = %v", err)
+	}
+	events := decodeEvents(t, audit.Bytes())
+	if ev := events[len(events)-1]; ev.Type != AuditKeyTransferred || ev.Team != "new" {
+		t.Errorf("last event = %+v", ev)
+	}
+}
+
+func TestTransferReissue(t *testing.T) {
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	old, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"), WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Transfer(t.Context(), "client-1", ToTenant("globex")); err == nil {
+		t.Error("Transfer() to a new tenant without reissue succeeded")
+	}
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("taken")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Transfer(t.Context(), "client-1", ToClientID("taken"), WithReissue("")); !errors.Is(err, ErrExists) {
+		t.Errorf("Transfer() to a taken client id error = %v, want %v", err, ErrExists)
+	}
+
+	apikey, ak, err := admin.Transfer(t.Context(), "client-1", ToTenant("globex"), ToClientID("client-2"), WithReissue(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ak.ClientID != "client-2" || ak.TenantID != "globex" || ak.RotatedAt.IsZero() {
+		t.Errorf("Transfer() = %+v", ak)
+	}
+	if _, err := store.Get(t.Context(), "client-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("old record still present: %v", err)
+	}
+	v := NewStoreVerifier(store)
+	if _, err := v.Verify(t.Context(), old); err == nil {
+		t.Error("Verify() of the old api key succeeded")
+	}
+	if got, err := v.Verify(t.Context(), apikey); err != nil || got.TenantID != "globex" {
+		t.Errorf("Verify() reissued = %+v, %v", got, err)
+	}
+}
+
+func TestTransferReissueDropsPrevious(t *testing.T) {
+	store := NewMemStore()
+	admin := NewAdmin(store)
+	old, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-1"), WithOwner("leaver@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.RotateGracefully(t.Context(), "client-1", "", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	_, ak, err := admin.Transfer(t.Context(), "client-1", ToOwner("manager@example.com"), WithReissue(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ak.PreviousDerivedKey) != 0 || !ak.PreviousExpiresAt.IsZero() {
+		t.Errorf("Transfer() kept previous secret %x until %v", ak.PreviousDerivedKey, ak.PreviousExpiresAt)
+	}
+	if _, err := NewStoreVerifier(store).Verify(t.Context(), old); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify() of the pre-rotation key error = %v, want ErrMismatch", err)
+	}
+}
+
+func TestTransferWithoutTransferer(t *testing.T) {
+	// TenantStore hides MemStore.Transfer, so Create and Delete are used
+	store := NewMemStore()
+	admin := NewAdmin(TenantStore(store, ""))
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Transfer(t.Context(), "client-1", ToClientID("client-2"), WithReissue("")); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := store.List(t.Context()); len(keys) != 1 || keys[0].ClientID != "client-2" {
+		t.Errorf("records after transfer = %v", keys)
+	}
+}
+
+func TestTransferRevoked(t *testing.T) {
+	admin := NewAdmin(NewMemStore())
+	if _, _, err := admin.Create(t.Context(), testAlg, WithClientID("client-1")); err != nil {
+		t.Fatal(err)
+	}
+	admin.Revoke(t.Context(), "client-1")
+	if _, _, err := admin.Transfer(t.Context(), "client-1", ToOwner("x")); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Transfer() error = %v, want %v", err, ErrRevoked)
+	}
+}
@@ -0,0 +1,77 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// uniqueAttempts bounds the keys GenerateUnique tries
+const uniqueAttempts = 5
+
+// FingerprintLookup is optionally implemented by a Store that can find a
+// record by Key.Fingerprint without listing
+type FingerprintLookup interface {
+	// GetByFingerprint returns a record with fingerprint, or ErrNotFound
+	GetByFingerprint(ctx context.Context, fingerprint string) (Key, error)
+}
+
+// GenerateUnique generates a key for alg and adds its record to store, so
+// bulk provisioning never overwrites another client's record. A generated
+// client id which is already taken, or a fingerprint which another record
+// has, is retried with a new key, a few times. A client id set by opts is
+// never replaced: if it is taken the ErrExists from store is returned.
+// Fingerprints are only checked on stores which are a FingerprintLookup;
+// listing every record of a large store per key would be too slow.
+//
+// Unlike Admin.Create it applies no policy and emits no audit events; it is
+// for provisioning tools writing straight to a store.
+func GenerateUnique(ctx context.Context, store Store, alg string, opts ...KeyOption) (GeneratedKey, error) {
+	var probe Key
+	for _, o := range opts {
+		o(&probe)
+	}
+	for range uniqueAttempts {
+		ak, err := NewKey(alg, opts...)
+		if err != nil {
+			return GeneratedKey{}, err
+		}
+		g, err := ak.GenerateKey()
+		if err != nil {
+			return GeneratedKey{}, err
+		}
+		taken, err := fingerprintTaken(ctx, store, g.Fingerprint)
+		if err != nil {
+			return GeneratedKey{}, err
+		}
+		if taken {
+			continue
+		}
+		err = store.Create(ctx, g.StoreRecord)
+		if err == nil {
+			return g, nil
+		}
+		if probe.ClientID != "" || !errors.Is(err, ErrExists) {
+			return GeneratedKey{}, err
+		}
+		// ErrExists is also a name in use, which another client id won't fix
+		if _, getErr := store.Get(ctx, ak.ClientID); getErr != nil {
+			return GeneratedKey{}, err
+		}
+	}
+	return GeneratedKey{}, fmt.Errorf("%w: no unique key in %d attempts", ErrExists, uniqueAttempts)
+}
+
+// fingerprintTaken reports whether a record in store has fingerprint, or
+// false if store can't look records up by fingerprint
+func fingerprintTaken(ctx context.Context, store Store, fingerprint string) (bool, error) {
+	fl, ok := supports[FingerprintLookup](store)
+	if !ok {
+		return false, nil
+	}
+	_, err := fl.GetByFingerprint(ctx, fingerprint)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,115 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+// zeroThenRand yields the salt and password of one key of zeros, then random
+// bytes
+func zeroThenRand() io.Reader {
+	return io.MultiReader(bytes.NewReader(make([]byte, saltLen+passwordLen)), rand.Reader)
+}
+
+// takenStore claims the first client id it is asked to create
+type takenStore struct {
+	*MemStore
+	claimed bool
+}
+
+func (s *takenStore) Create(ctx context.Context, ak Key) error {
+	if !s.claimed {
+		s.claimed = true
+		s.MemStore.Create(ctx, Key{ClientID: ak.ClientID})
+	}
+	return s.MemStore.Create(ctx, ak)
+}
+
+func TestGenerateUnique(t *testing.T) {
+	ctx := t.Context()
+	existing, err := NewKey(testAlg, WithClientID("existing"), WithName("billing"), WithRand(zeroThenRand()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := existing.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		// wrap, if set, wraps the store GenerateUnique is given
+		wrap func(*MemStore) Store
+		opts []KeyOption
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantErr     error
+		wantRecords int
+	}{
+		{name: "fresh", args: args{}, wantRecords: 2},
+		{name: "client id taken", args: args{wrap: func(s *MemStore) Store { return &takenStore{MemStore: s} }}, wantRecords: 3},
+		{name: "fingerprint taken", args: args{opts: []KeyOption{WithRand(zeroThenRand())}}, wantRecords: 2},
+		{name: "fingerprint taken wrapped", args: args{wrap: func(s *MemStore) Store { return MeasureStore(s, NewCounters()) }, opts: []KeyOption{WithRand(zeroThenRand())}}, wantRecords: 2},
+		{name: "fingerprint always taken", args: args{opts: []KeyOption{WithRand(zeroReader{})}}, wantErr: ErrExists, wantRecords: 1},
+		{name: "explicit client id", args: args{opts: []KeyOption{WithClientID("existing")}}, wantErr: ErrExists, wantRecords: 1},
+		{name: "name taken", args: args{opts: []KeyOption{WithName("billing")}}, wantErr: ErrExists, wantRecords: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := NewMemStore()
+			if err := mem.Create(ctx, existing); err != nil {
+				t.Fatal(err)
+			}
+			var store Store = mem
+			if tt.args.wrap != nil {
+				store = tt.args.wrap(mem)
+			}
+			g, err := GenerateUnique(ctx, store, testAlg, tt.args.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateUnique() error = %v, want %v", err, tt.wantErr)
+			}
+			if keys, _ := mem.List(ctx); len(keys) != tt.wantRecords {
+				t.Errorf("%d records, want %d", len(keys), tt.wantRecords)
+			}
+			if err != nil {
+				return
+			}
+			stored, err := mem.Get(ctx, g.ClientID)
+			if err != nil || !sameRecord(stored, g.StoreRecord) || g.Fingerprint == existing.Fingerprint() {
+				t.Errorf("stored %+v, %v, want %+v", stored, err, g.StoreRecord)
+			}
+		})
+	}
+}
+
+// listingStore counts its List calls and has no FingerprintLookup
+type listingStore struct {
+	Store
+	lists int
+}
+
+func (s *listingStore) List(ctx context.Context) ([]Key, error) {
+	s.lists++
+	return s.Store.List(ctx)
+}
+
+func TestGenerateUniqueWithoutLookup(t *testing.T) {
+	store := &listingStore{Store: NewMemStore()}
+	if _, err := GenerateUnique(t.Context(), store, testAlg); err != nil {
+		t.Fatal(err)
+	}
+	if store.lists != 0 {
+		t.Errorf("GenerateUnique() listed the store %d times, want 0", store.lists)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,227 @@
+package apikeys
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// UsageBucket counts the verifications of a key in the hour, or day, from
+// Start. The bigquery tags let a bigquery.Inserter put buckets directly, see
+// UsageSinkFunc.
+type UsageBucket struct {
+	ClientID      string    `json:"client_id" bigquery:"client_id"`
+	Start         time.Time `json:"start" bigquery:"start"`
+	Verifications int64     `json:"verifications" bigquery:"verifications"`
+	Failures      int64     `json:"failures" bigquery:"failures"`
+}
+
+// UsageSink receives the counts a UsageAggregator has gathered. The buckets
+// are increments: a flush may write several buckets for the same key and
+// hour, which readers add up.
+type UsageSink interface {
+	WriteUsage(ctx context.Context, buckets []UsageBucket) error
+}
+
+// UsageSinkFunc adapts a function to a UsageSink, eg
+//
+//	apikeys.UsageSinkFunc(func(ctx context.Context, b []apikeys.UsageBucket) error {
+//		return bq.Dataset("apikeys").Table("usage").Inserter().Put(ctx, b)
+//	})
+type UsageSinkFunc func(ctx context.Context, buckets []UsageBucket) error
+
+func (f UsageSinkFunc) WriteUsage(ctx context.Context, buckets []UsageBucket) error {
+	return f(ctx, buckets)
+}
+
+// UsageQuery selects usage. Zero fields don't restrict the result.
+type UsageQuery struct {
+	ClientID string
+	// Since and Until bound the bucket start times, Until exclusive
+	Since time.Time
+	Until time.Time
+	// Daily rolls the hourly buckets up into UTC days
+	Daily bool
+}
+
+func (q UsageQuery) match(b UsageBucket) bool {
+	return (q.ClientID == "" || b.ClientID == q.ClientID) &&
+		(q.Since.IsZero() || !b.Start.Before(q.Since)) &&
+		(q.Until.IsZero() || b.Start.Before(q.Until))
+}
+
+// UsageStore is a UsageSink which can be queried, feeding usage dashboards
+type UsageStore interface {
+	UsageSink
+	// QueryUsage returns one bucket per key and hour, or day, ordered by
+	// client id and start
+	QueryUsage(ctx context.Context, q UsageQuery) ([]UsageBucket, error)
+}
+
+// rollUp adds together the buckets of each key and hour, or day if daily,
+// and orders them by client id and start
+func rollUp(buckets []UsageBucket, daily bool) []UsageBucket {
+	type bucketKey struct {
+		clientID string
+		start    int64
+	}
+	sums := map[bucketKey]*UsageBucket{}
+	for _, b := range buckets {
+		b.Start = b.Start.UTC().Truncate(time.Hour)
+		if daily {
+			b.Start = b.Start.Truncate(24 * time.Hour)
+		}
+		k := bucketKey{b.ClientID, b.Start.Unix()}
+		if sum, ok := sums[k]; ok {
+			sum.Verifications += b.Verifications
+			sum.Failures += b.Failures
+			continue
+		}
+		sums[k] = &b
+	}
+	out := make([]UsageBucket, 0, len(sums))
+	for _, b := range sums {
+		out = append(out, *b)
+	}
+	slices.SortFunc(out, func(a, b UsageBucket) int {
+		return cmp.Or(cmp.Compare(a.ClientID, b.ClientID), a.Start.Compare(b.Start))
+	})
+	return out
+}
+
+// UsageConfig tunes a UsageAggregator
+type UsageConfig struct {
+	// FlushInterval is the time between the flushes of Run, 1m by default
+	FlushInterval time.Duration
+}
+
+// UsageAggregator is an AuditSink counting the verification events of each
+// key into hourly buckets, which it flushes to a UsageSink. Install it with
+// WithAudit on the StoreVerifier, with MultiAuditSink to keep another audit
+// sink. Counts which fail to flush are kept for the next flush.
+type UsageAggregator struct {
+	sink UsageSink
+	cfg  UsageConfig
+
+	mu      sync.Mutex
+	pending map[usageKey]*UsageBucket
+}
+
+type usageKey struct {
+	clientID string
+	hour     int64
+}
+
+var _ AuditSink = (*UsageAggregator)(nil)
+
+func NewUsageAggregator(sink UsageSink, cfg UsageConfig) *UsageAggregator {
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Minute
+	}
+	return &UsageAggregator{sink: sink, cfg: cfg, pending: map[usageKey]*UsageBucket{}}
+}
+
+// Emit counts verification events and ignores the rest. Failures of keys
+// that are not found or don't decode are ignored too, as their client id is
+// whatever the caller presented.
+func (a *UsageAggregator) Emit(ctx context.Context, ev AuditEvent) error {
+	if ev.ClientID == "" || (ev.Type != AuditVerifySuccess && ev.Type != AuditVerifyFailed) {
+		return nil
+	}
+	if ev.Result == ResultNotFound || ev.Result == ResultInvalid {
+		return nil
+	}
+	start := ev.Time.UTC().Truncate(time.Hour)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	k := usageKey{ev.ClientID, start.Unix()}
+	b, ok := a.pending[k]
+	if !ok {
+		b = &UsageBucket{ClientID: ev.ClientID, Start: start}
+		a.pending[k] = b
+	}
+	if ev.Type == AuditVerifySuccess {
+		b.Verifications++
+	} else {
+		b.Failures++
+	}
+	return nil
+}
+
+// Flush writes the counts gathered since the last flush
+func (a *UsageAggregator) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = map[usageKey]*UsageBucket{}
+	a.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	buckets := make([]UsageBucket, 0, len(pending))
+	for _, b := range pending {
+		buckets = append(buckets, *b)
+	}
+	err := a.sink.WriteUsage(ctx, rollUp(buckets, false))
+	if err == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, b := range pending {
+		if cur, ok := a.pending[k]; ok {
+			cur.Verifications += b.Verifications
+			cur.Failures += b.Failures
+			continue
+		}
+		a.pending[k] = b
+	}
+	return err
+}
+
+// Run flushes every FlushInterval until ctx is done, then flushes once more
+// with a context which is not and returns the error of that flush
+func (a *UsageAggregator) Run(ctx context.Context) error {
+	t := time.NewTicker(a.cfg.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return a.Flush(context.WithoutCancel(ctx))
+		case <-t.C:
+			a.Flush(ctx)
+		}
+	}
+}
+
+// MemUsageStore is an in memory UsageStore
+type MemUsageStore struct {
+	mu      sync.Mutex
+	buckets []UsageBucket
+}
+
+var _ UsageStore = (*MemUsageStore)(nil)
+
+func NewMemUsageStore() *MemUsageStore {
+	return &MemUsageStore{}
+}
+
+func (s *MemUsageStore) WriteUsage(ctx context.Context, buckets []UsageBucket) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets = rollUp(append(s.buckets, buckets...), false)
+	return nil
+}
+
+func (s *MemUsageStore) QueryUsage(ctx context.Context, q UsageQuery) ([]UsageBucket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []UsageBucket
+	for _, b := range s.buckets {
+		if q.match(b) {
+			out = append(out, b)
+		}
+	}
+	return rollUp(out, q.Daily), nil
+}
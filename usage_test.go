@@ -0,0 +1,107 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUsageAggregator(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2030, 1, 1, 23, 0, 0, 0, time.UTC)
+	now := start
+	clock := WithClock(ClockFunc(func() time.Time { return now }))
+	store := NewMemStore()
+	apikey, _, err := NewAdmin(store, clock).Create(ctx, testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sinkErr error
+	usage := NewMemUsageStore()
+	agg := NewUsageAggregator(UsageSinkFunc(func(ctx context.Context, b []UsageBucket) error {
+		if sinkErr != nil {
+			return sinkErr
+		}
+		return usage.WriteUsage(ctx, b)
+	}), UsageConfig{})
+	v := NewStoreVerifier(store, clock, WithAudit(agg))
+	for _, at := range []time.Duration{0, 10 * time.Minute, time.Hour} {
+		now = start.Add(at)
+		if _, err := v.Verify(ctx, apikey); err != nil {
+			t.Fatal(err)
+		}
+	}
+	agg.Emit(ctx, AuditEvent{Time: now, Type: AuditVerifyFailed, ClientID: "client-1"})
+	agg.Emit(ctx, AuditEvent{Time: now, Type: AuditKeyRotated, ClientID: "client-1"})
+	agg.Emit(ctx, AuditEvent{Time: now, Type: AuditVerifyFailed, ClientID: "ghost", Result: ResultNotFound})
+	agg.Emit(ctx, AuditEvent{Time: now, Type: AuditVerifyFailed, ClientID: "ghost", Result: ResultInvalid})
+
+	sinkErr = errors.New("sink down")
+	if err := agg.Flush(ctx); !errors.Is(err, sinkErr) {
+		t.Fatalf("Flush() = %v, want the sink error", err)
+	}
+	sinkErr = nil
+	v.Verify(ctx, apikey)
+	if err := agg.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := agg.Flush(ctx); err != nil {
+		t.Errorf("Flush() with nothing pending = %v", err)
+	}
+
+	type args struct {
+		q UsageQuery
+	}
+	tests := []struct {
+		name string
+		args args
+		want []UsageBucket
+	}{
+		{
+			name: "hourly",
+			args: args{q: UsageQuery{ClientID: "client-1"}},
+			want: []UsageBucket{
+				{ClientID: "client-1", Start: start, Verifications: 2},
+				{ClientID: "client-1", Start: start.Add(time.Hour), Verifications: 2, Failures: 1},
+			},
+		},
+		{
+			name: "daily",
+			args: args{q: UsageQuery{ClientID: "client-1", Daily: true}},
+			want: []UsageBucket{
+				{ClientID: "client-1", Start: start.Truncate(24 * time.Hour), Verifications: 2},
+				{ClientID: "client-1", Start: start.Add(time.Hour), Verifications: 2, Failures: 1},
+			},
+		},
+		{
+			name: "since",
+			args: args{q: UsageQuery{Since: start.Add(time.Hour)}},
+			want: []UsageBucket{{ClientID: "client-1", Start: start.Add(time.Hour), Verifications: 2, Failures: 1}},
+		},
+		{
+			name: "other key",
+			args: args{q: UsageQuery{ClientID: "client-2"}},
+		},
+		{
+			name: "unknown key",
+			args: args{q: UsageQuery{ClientID: "ghost"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := usage.QueryUsage(ctx, tt.args.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryUsage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrRevoked is returned when verifying a key that has been revoked
+	ErrRevoked = errors.New("api key revoked")
+	// ErrMismatch is returned when the presented secret does not match the stored key
+	ErrMismatch = errors.New("api key does not match")
+	// ErrExpired is returned when verifying a key after its expiry time
+	ErrExpired = errors.New("api key expired")
+	// ErrInvalid wraps the error when a presented api key can not be decoded
+	ErrInvalid = errors.New("api key invalid")
+)
+
+// StoreVerifier verifies presented api keys against the records in a Store.
+type StoreVerifier struct {
+	options
+	store Store
+	// toucher is the unwrapped store, if it implements Toucher
+	toucher  Toucher
+	rotation rotationTracker
+	// degraded is set while verifications are answered by WithCacheFallback
+	degraded atomic.Bool
+}
+
+func NewStoreVerifier(store Store, opts ...Option) *StoreVerifier {
+	o := newOptions(opts)
+	toucher, _ := store.(Toucher)
+	return &StoreVerifier{options: o, store: o.wrapStore(store), toucher: toucher}
+}
+
+// Verifier checks a presented api key and reports who it identifies
+type Verifier interface {
+	Verify(ctx context.Context, presented string) (Identity, error)
+}
+
+// VerifierFunc adapts a function to the Verifier interface, eg
+// VerifierFunc(storeVerifier.Identify)
+type VerifierFunc func(ctx context.Context, presented string) (Identity, error)
+
+func (f VerifierFunc) Verify(ctx context.Context, presented string) (Identity, error) {
+	return f(ctx, presented)
+}
+
+// Identity is the result of a successful verification
+type Identity struct {
+	ClientID string
+	TenantID string
+	Team     string
+	Type     KeyType
+	// Key is the stored record the presented key matched
+	Key Key
+	// Previous is true if the presented key matched the previous secret of a
+	// record in a graceful rotation
+	Previous bool
+}
+
+func newIdentity(ak Key, previous bool) Identity {
+	return Identity{
+		ClientID: ak.ClientID, TenantID: ak.TenantID, Team: ak.Team, Type: ak.Type,
+		Key: ak, Previous: previous,
+	}
+}
+
+// Verify decodes the presented api key, loads the record for its client id
+// and checks the secret against the stored derived key. The stored record is
+// returned on success.
+func (v *StoreVerifier) Verify(ctx context.Context, apikey string) (Key, error) {
+	id, err := v.Identify(ctx, apikey)
+	return id.Key, err
+}
+
+// Identify is Verify returning the Identity, so that
+// VerifierFunc(v.Identify) is a Verifier
+func (v *StoreVerifier) Identify(ctx context.Context, apikey string) (Identity, error) {
+	digest := verifyDigest(apikey)
+	return v.run(ctx, apikey, func(ctx context.Context, presented Key, password []byte) (Identity, error) {
+		if id, ok := v.lookup(ctx, presented, digest); ok {
+			return id, nil
+		}
+		ak, err := v.load(ctx, presented.ClientID)
+		if err != nil {
+			if id, ok := v.fallback(ctx, presented, digest, err); ok {
+				return id, nil
+			}
+			return Identity{}, err
+		}
+		id, err := v.match(ctx, presented, password, []Key{ak})
+		if err == nil {
+			v.remember(ctx, digest, id)
+		}
+		return id, err
+	})
+}
+
+// run verifies apikey, using find to match the decoded key against the
+// stored records, and reports the outcome
+func (v *StoreVerifier) run(ctx context.Context, apikey string, find func(ctx context.Context, presented Key, password []byte) (Identity, error)) (Identity, error) {
+	start := time.Now()
+	ctx, span := v.startSpan(ctx, SpanVerify)
+	buf := getSecretBuf()
+	presented, id, err := v.verify(ctx, span, apikey, *buf, find)
+	// presented.Salt aliases buf
+	presented.Salt = nil
+	putSecretBuf(buf)
+	if _, err := v.report(ctx, span, start, presented, id.Key, err); err != nil {
+		return Identity{}, err
+	}
+	return id, nil
+}
+
+// report ends the verification span and records the outcome in the metrics,
+// logs, audit trail and hooks
+func (v *StoreVerifier) report(ctx context.Context, span Span, start time.Time, presented, ak Key, err error) (Key, error) {
+	span.End(err)
+	elapsed := time.Since(start)
+	if v.metrics != nil {
+		v.metrics.ObserveVerify(VerifyResult(err), elapsed)
+	}
+	v.checkSlowVerify(ctx, presented, VerifyResult(err), elapsed)
+	if err != nil {
+		v.emit(ctx, AuditVerifyFailed, presented, err)
+		if v.hooks.OnVerifyFailure != nil {
+			v.hooks.OnVerifyFailure(ctx, presented, err)
+		}
+		return Key{}, err
+	}
+	v.emit(ctx, AuditVerifySuccess, presented, nil)
+	if v.hooks.OnVerifySuccess != nil {
+		v.hooks.OnVerifySuccess(ctx, ak)
+	}
+	return ak, nil
+}
+
+// verify returns the decoded presented key, which is only partially
+// populated if decoding fails, and the identity if verification succeeds.
+func (v *StoreVerifier) verify(ctx context.Context, span Span, apikey string, buf []byte, find func(ctx context.Context, presented Key, password []byte) (Identity, error)) (Key, Identity, error) {
+	if err := v.admit(ctx); err != nil {
+		return Key{}, Identity{}, err
+	}
+	_, decodeSpan := v.startSpan(ctx, SpanDecode)
+	presented, password, err := decode(apikey, buf, &v.decode)
+	decodeSpan.End(err)
+	if err != nil {
+		return presented, Identity{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	// password is in buf, which run zeroes, unless the key was too long for
+	// it
+	defer clear(password)
+	span.SetAttribute(AttrClientID, presented.ClientID)
+	span.SetAttribute(AttrAlg, presented.alg.String)
+	if err := v.checkTenantPolicy(ctx, presented); errors.Is(err, ErrPolicy) {
+		return presented, Identity{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+	} else if err != nil {
+		return presented, Identity{}, err
+	}
+	deprecated, err := v.deprecation(presented)
+	if errors.Is(err, ErrDeprecated) {
+		return presented, Identity{}, fmt.Errorf("%w: %w", ErrInvalid, err)
+	} else if err != nil {
+		return presented, Identity{}, err
+	}
+	id, err := find(ctx, presented, password)
+	if err == nil && deprecated != nil {
+		v.observeDeprecated(ctx, presented, *deprecated)
+	}
+	return presented, id, err
+}
+
+// match derives the presented key once and compares it with the secret, and
+// the previous secret if in rotation, of each candidate in turn
+func (v *StoreVerifier) match(ctx context.Context, presented Key, password []byte, candidates []Key) (Identity, error) {
+	// The tenant segment is not covered by the derivation, so a key edited to
+	// claim another tenant would otherwise verify
+	if !slices.ContainsFunc(candidates, func(ak Key) bool { return ak.TenantID == presented.TenantID }) {
+		return Identity{}, ErrMismatch
+	}
+	now := v.now()
+	// With a lookup tag most wrong secrets are rejected without argon2
+	if lookupMisses(presented, password, candidates, func(ak Key) bool { return ak.InRotation(now) }) {
+		return Identity{}, ErrMismatch
+	}
+
+	_, deriveSpan := v.startSpan(ctx, SpanDerive)
+	start := time.Now()
+	derived, err := v.derive(ctx, presented, password)
+	elapsed := time.Since(start)
+	v.observeDerive(storedAlg(presented, candidates), elapsed)
+	deriveSpan.SetAttribute(AttrDeriveDuration, durationMS(elapsed))
+	deriveSpan.End(err)
+	if err != nil {
+		return Identity{}, err
+	}
+	defer clear(derived)
+	// The error of the first matching record which isn't usable, reported if
+	// no other record matches
+	var unusable error
+	for _, ak := range candidates {
+		if ak.TenantID != presented.TenantID {
+			continue
+		}
+		inRotation := ak.InRotation(now)
+		previous := false
+		if subtle.ConstantTimeCompare(derived, ak.DerivedKey) != 1 {
+			if !inRotation || subtle.ConstantTimeCompare(derived, ak.PreviousDerivedKey) != 1 {
+				continue
+			}
+			previous = true
+		}
+		if err := v.usable(ctx, ak); err != nil {
+			if unusable == nil {
+				unusable = err
+			}
+			continue
+		}
+		if inRotation {
+			v.observeRotation(ak, previous, now)
+		}
+		return newIdentity(v.touch(ctx, ak), previous), nil
+	}
+	if unusable != nil {
+		return Identity{}, unusable
+	}
+	return Identity{}, ErrMismatch
+}
+
+// storedAlg is the alg of the first candidate of the tenant of presented
+// which records one, for metrics, as the alg of presented is chosen by
+// whoever presented it
+func storedAlg(presented Key, candidates []Key) Alg {
+	for _, ak := range candidates {
+		if ak.TenantID == presented.TenantID && ak.alg.String != "" {
+			return ak.alg
+		}
+	}
+	return presented.alg
+}
+
+// load gets the record for clientID. Whether it is usable is only checked
+// once the secret matches, see usable.
+func (v *StoreVerifier) load(ctx context.Context, clientID string) (Key, error) {
+	ak, err := v.store.Get(ctx, clientID)
+	if err != nil {
+		return Key{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Key{}, err
+	}
+	return ak, nil
+}
+
+// usable checks that ak is not revoked, pending or expired. Only check a
+// record whose secret has matched, so that the errors don't tell someone
+// with just a client id what state its key is in.
+func (v *StoreVerifier) usable(ctx context.Context, ak Key) error {
+	if ak.Revoked() {
+		return ErrRevoked
+	}
+	if ak.Pending() {
+		return ErrPendingApproval
+	}
+	if ak.Expired(v.now()) {
+		if v.hooks.OnExpire != nil {
+			v.hooks.OnExpire(ctx, ak)
+		}
+		return ErrExpired
+	}
+	if err := v.checkIdle(ctx, ak); err != nil {
+		if errors.Is(err, ErrExpired) && v.hooks.OnExpire != nil {
+			v.hooks.OnExpire(ctx, ak)
+		}
+		return err
+	}
+	return nil
+}
+
+// derive runs the derivation on the Deriver, if there is one, or directly
+func (v *StoreVerifier) derive(ctx context.Context, presented Key, password []byte) ([]byte, error) {
+	release, err := v.acquire(ctx, presented.alg)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if v.deriver != nil {
+		return v.deriver.Derive(ctx, presented, password)
+	}
+	return presented.RecoverKeyContext(ctx, password)
+}
@@ -0,0 +1,323 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	nanoid "github.com/matoous/go-nanoid"
+)
+
+// Headers set on every webhook delivery
+const (
+	// WebhookSignatureHeader is "t=<unix seconds>,v1=<hex hmac>", the hmac
+	// being HMAC-SHA256 with the endpoint secret of "<unix seconds>.<body>",
+	// see VerifyWebhookSignature
+	WebhookSignatureHeader = "X-Apikeys-Signature"
+	WebhookEventHeader     = "X-Apikeys-Event"
+	// WebhookDeliveryHeader identifies a delivery. It is the same for every
+	// attempt, so receivers can drop duplicates.
+	WebhookDeliveryHeader = "X-Apikeys-Delivery"
+)
+
+var (
+	ErrWebhookClosed    = errors.New("webhook dispatcher closed")
+	ErrWebhookQueueFull = errors.New("webhook queue full")
+	ErrWebhookSignature = errors.New("bad webhook signature")
+)
+
+// WebhookEndpoint is a url lifecycle events are posted to
+type WebhookEndpoint struct {
+	URL string
+	// Secret signs the deliveries to this endpoint
+	Secret Secret
+	// Events are the audit event types delivered. By default every event
+	// except verifications is.
+	Events []string
+}
+
+func (e *WebhookEndpoint) wants(typ string) bool {
+	if len(e.Events) == 0 {
+		return typ != AuditVerifySuccess && typ != AuditVerifyFailed
+	}
+	return slices.Contains(e.Events, typ)
+}
+
+// WebhookConfig configures a WebhookDispatcher. Zero fields take the
+// defaults.
+type WebhookConfig struct {
+	Endpoints []WebhookEndpoint
+	// Client posts the deliveries, http.DefaultClient if nil
+	Client *http.Client
+	// QueueSize bounds the deliveries waiting to be made, 1024 by default.
+	// Events beyond it go straight to the dead letter log.
+	QueueSize int
+	// MaxAttempts is the number of tries of each delivery, 5 by default
+	MaxAttempts int
+	// Backoff is the longest wait before the first retry, 1s by default,
+	// doubling for each further retry up to MaxBackoff, 1m by default. The
+	// actual wait is a random fraction of it.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// DeadLetter receives a WebhookDeadLetter json line for each delivery
+	// which is given up on. Nil discards them.
+	DeadLetter io.Writer
+	// Clock stamps the signatures, the system clock if nil
+	Clock Clock
+}
+
+// WebhookDeadLetter records a delivery which was given up on, with enough to
+// replay it
+type WebhookDeadLetter struct {
+	Time       time.Time  `json:"time"`
+	URL        string     `json:"url"`
+	DeliveryID string     `json:"delivery_id"`
+	Attempts   int        `json:"attempts"`
+	Error      string     `json:"error"`
+	Event      AuditEvent `json:"event"`
+}
+
+type webhookDelivery struct {
+	endpoint *WebhookEndpoint
+	id       string
+	ev       AuditEvent
+}
+
+// WebhookDispatcher is an AuditSink which posts lifecycle events to external
+// systems, eg to open a ticket when a key is revoked. Emit only queues the
+// event, so slow or failing endpoints don't hold up the operation; a single
+// worker delivers the queue in order, retrying 5xx, 429 and network errors
+// with a jittered exponential backoff. Install it with WithAudit, alongside
+// any other sink with MultiAuditSink.
+type WebhookDispatcher struct {
+	cfg   WebhookConfig
+	queue chan webhookDelivery
+	// ctx aborts the deliveries still being made when Close gives up
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+
+	dlMu sync.Mutex
+}
+
+// NewWebhookDispatcher checks the endpoints and starts the worker. Call Close
+// to stop it.
+func NewWebhookDispatcher(cfg WebhookConfig) (*WebhookDispatcher, error) {
+	for _, e := range cfg.Endpoints {
+		u, err := url.Parse(e.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("%w: bad webhook url `%s'", ErrConfig, e.URL)
+		}
+		if len(e.Secret) == 0 {
+			return nil, fmt.Errorf("%w: webhook `%s' has no secret", ErrConfig, e.URL)
+		}
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	d := &WebhookDispatcher{cfg: cfg, queue: make(chan webhookDelivery, cfg.QueueSize), done: make(chan struct{})}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	go d.run()
+	return d, nil
+}
+
+func (d *WebhookDispatcher) now() time.Time {
+	if d.cfg.Clock == nil {
+		return time.Now()
+	}
+	return d.cfg.Clock.Now()
+}
+
+// Emit queues ev for every endpoint that wants it. It fails with
+// ErrWebhookQueueFull, after dead lettering the event, if the queue is full.
+func (d *WebhookDispatcher) Emit(ctx context.Context, ev AuditEvent) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrWebhookClosed
+	}
+	var errs []error
+	for i := range d.cfg.Endpoints {
+		e := &d.cfg.Endpoints[i]
+		if !e.wants(ev.Type) {
+			continue
+		}
+		id, err := nanoid.ID(defaultClientNanoIDLen)
+		if err != nil {
+			return err
+		}
+		w := webhookDelivery{endpoint: e, id: id, ev: ev}
+		select {
+		case d.queue <- w:
+		default:
+			d.deadLetter(w, 0, ErrWebhookQueueFull)
+			errs = append(errs, fmt.Errorf("webhook `%s': %w", e.URL, ErrWebhookQueueFull))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops accepting events and waits for the queue to be delivered. If
+// ctx ends first the deliveries left are abandoned to the dead letter log and
+// the ctx error is returned.
+func (d *WebhookDispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+	select {
+	case <-d.done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+func (d *WebhookDispatcher) run() {
+	defer close(d.done)
+	for w := range d.queue {
+		d.deliver(w)
+	}
+}
+
+func (d *WebhookDispatcher) deliver(w webhookDelivery) {
+	body, err := json.Marshal(w.ev)
+	if err != nil {
+		d.deadLetter(w, 0, err)
+		return
+	}
+	backoff := d.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(w, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.cfg.MaxAttempts || d.ctx.Err() != nil {
+			d.deadLetter(w, attempt, err)
+			return
+		}
+		t := time.NewTimer(rand.N(backoff) + 1)
+		select {
+		case <-d.ctx.Done():
+			t.Stop()
+			d.deadLetter(w, attempt, err)
+			return
+		case <-t.C:
+		}
+		backoff = min(2*backoff, d.cfg.MaxBackoff)
+	}
+}
+
+// post makes one attempt at a delivery and reports whether a failure is
+// worth retrying
+func (d *WebhookDispatcher) post(w webhookDelivery, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, w.endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, w.ev.Type)
+	req.Header.Set(WebhookDeliveryHeader, w.id)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(w.endpoint.Secret, d.now(), body))
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook `%s' returned %s", w.endpoint.URL, resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+func (d *WebhookDispatcher) deadLetter(w webhookDelivery, attempts int, err error) {
+	if d.cfg.DeadLetter == nil {
+		return
+	}
+	b, merr := json.Marshal(WebhookDeadLetter{
+		Time: d.now(), URL: w.endpoint.URL, DeliveryID: w.id,
+		Attempts: attempts, Error: err.Error(), Event: w.ev,
+	})
+	if merr != nil {
+		return
+	}
+	d.dlMu.Lock()
+	defer d.dlMu.Unlock()
+	d.cfg.DeadLetter.Write(append(b, '\n'))
+}
+
+func webhookMAC(secret Secret, t int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(t, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// SignWebhook returns the WebhookSignatureHeader value for body sent at t
+func SignWebhook(secret Secret, t time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), hex.EncodeToString(webhookMAC(secret, t.Unix(), body)))
+}
+
+// VerifyWebhookSignature checks the WebhookSignatureHeader value of a
+// delivery received at now. Signatures older than tolerance are rejected to
+// limit replays; a zero tolerance accepts any age.
+func VerifyWebhookSignature(secret Secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var t int64
+	var sig []byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			t, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			sig, _ = hex.DecodeString(v)
+		}
+	}
+	if t == 0 || sig == nil {
+		return fmt.Errorf("%w: malformed `%s'", ErrWebhookSignature, header)
+	}
+	if tolerance > 0 && now.Sub(time.Unix(t, 0)) > tolerance {
+		return fmt.Errorf("%w: too old", ErrWebhookSignature)
+	}
+	if !hmac.Equal(sig, webhookMAC(secret, t, body)) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package apikeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	ctx := context.Background()
+	secret := Secret("whsec-test")
+	var mu sync.Mutex
+	var attempts int
+	var got []string
+	var ids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := VerifyWebhookSignature(secret, r.Header.Get(WebhookSignatureHeader), body, time.Now(), time.Minute); err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		var ev AuditEvent
+		if err := json.Unmarshal(body, &ev); err != nil || ev.Type != r.Header.Get(WebhookEventHeader) {
+			t.Errorf("delivery %s: %v", body, err)
+		}
+		got = append(got, ev.Type+" "+ev.ClientID)
+		ids = append(ids, r.Header.Get(WebhookDeliveryHeader))
+	}))
+	defer srv.Close()
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+
+	var dead bytes.Buffer
+	d, err := NewWebhookDispatcher(WebhookConfig{
+		Endpoints: []WebhookEndpoint{
+			{URL: srv.URL, Secret: secret},
+			{URL: rejecting.URL, Secret: secret, Events: []string{AuditKeyRevoked}},
+		},
+		Backoff:    time.Millisecond,
+		DeadLetter: &dead,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemStore()
+	admin := NewAdmin(store, WithAudit(d))
+	apikey, _, err := admin.Create(ctx, testAlg, WithClientID("client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStoreVerifier(store, WithAudit(d)).Verify(ctx, apikey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Revoke(ctx, "client-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Emit(ctx, AuditEvent{Type: AuditKeyRevoked}); !errors.Is(err, ErrWebhookClosed) {
+		t.Errorf("Emit() after Close = %v, want ErrWebhookClosed", err)
+	}
+
+	want := []string{AuditKeyCreated + " client-1", AuditKeyRevoked + " client-1"}
+	if !slices.Equal(got, want) || attempts != 3 {
+		t.Errorf("delivered %v in %d attempts, want %v in 3", got, attempts, want)
+	}
+	if len(ids) != 2 || ids[0] == "" || ids[0] == ids[1] {
+		t.Errorf("delivery ids %v", ids)
+	}
+	var letter WebhookDeadLetter
+	if err := json.Unmarshal(dead.Bytes(), &letter); err != nil {
+		t.Fatalf("dead letter %s: %v", dead.String(), err)
+	}
+	if letter.URL != rejecting.URL || letter.Attempts != 1 || letter.Event.Type != AuditKeyRevoked {
+		t.Errorf("dead letter = %+v", letter)
+	}
+}
+
+func TestWebhookDispatcherQueueFull(t *testing.T) {
+	ctx := context.Background()
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+	var dead bytes.Buffer
+	d, err := NewWebhookDispatcher(WebhookConfig{
+		Endpoints:  []WebhookEndpoint{{URL: srv.URL, Secret: Secret("s")}},
+		QueueSize:  1,
+		DeadLetter: &dead,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := AuditEvent{Type: AuditKeyCreated}
+	if err := d.Emit(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	<-entered
+	if err := d.Emit(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Emit(ctx, ev); !errors.Is(err, ErrWebhookQueueFull) {
+		t.Errorf("Emit() to a full queue = %v, want ErrWebhookQueueFull", err)
+	}
+	close(release)
+	if err := d.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(decodeDeadLetters(t, dead.Bytes())); n != 1 {
+		t.Errorf("got %d dead letters, want 1", n)
+	}
+}
+
+func decodeDeadLetters(t *testing.T, b []byte) []WebhookDeadLetter {
+	t.Helper()
+	var letters []WebhookDeadLetter
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for dec.More() {
+		var l WebhookDeadLetter
+		if err := dec.Decode(&l); err != nil {
+			t.Fatal(err)
+		}
+		letters = append(letters, l)
+	}
+	return letters
+}
+
+func TestNewWebhookDispatcher(t *testing.T) {
+	type args struct {
+		endpoint WebhookEndpoint
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{name: "happy", args: args{endpoint: WebhookEndpoint{URL: "https://hooks.example.com/keys", Secret: Secret("s")}}},
+		{name: "no secret", args: args{endpoint: WebhookEndpoint{URL: "https://hooks.example.com/keys"}}, wantErr: true},
+		{name: "relative url", args: args{endpoint: WebhookEndpoint{URL: "/keys", Secret: Secret("s")}}, wantErr: true},
+		{name: "bad scheme", args: args{endpoint: WebhookEndpoint{URL: "ftp://example.com", Secret: Secret("s")}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewWebhookDispatcher(WebhookConfig{Endpoints: []WebhookEndpoint{tt.args.endpoint}})
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrConfig)) {
+				t.Fatalf("NewWebhookDispatcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if d != nil {
+				d.Close(context.Background())
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := Secret("whsec-test")
+	sent := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"key.revoked"}`)
+	header := SignWebhook(secret, sent, body)
+	type args struct {
+		secret Secret
+		header string
+		body   []byte
+		now    time.Time
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{name: "happy", args: args{secret: secret, header: header, body: body, now: sent.Add(time.Second)}},
+		{name: "wrong secret", args: args{secret: Secret("other"), header: header, body: body, now: sent}, wantErr: true},
+		{name: "tampered", args: args{secret: secret, header: header, body: []byte(`{"type":"key.created"}`), now: sent}, wantErr: true},
+		{name: "too old", args: args{secret: secret, header: header, body: body, now: sent.Add(time.Hour)}, wantErr: true},
+		{name: "malformed", args: args{secret: secret, header: "v1=abc", body: body, now: sent}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.args.secret, tt.args.header, tt.args.body, tt.args.now, 5*time.Minute)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrWebhookSignature)) {
+				t.Errorf("VerifyWebhookSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}